@@ -53,6 +53,7 @@ func runProbe(cmd *cobra.Command, args []string) {
 	klog.V(2).Infoln("qps: ", qps)
 	klog.V(2).Infoln("burst: ", burst)
 	klog.V(2).Infoln("port: ", port)
+	klog.V(2).Infoln("user-agent: ", userAgent)
 
 	// set up signals so we handle the first shutdown signal gracefully
 	stopCh := setupSignalHandler()
@@ -71,6 +72,7 @@ func runProbe(cmd *cobra.Command, args []string) {
 
 	config.QPS = qps
 	config.Burst = burst
+	config.UserAgent = userAgent
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -22,7 +22,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/informers"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	kubescheme "k8s.io/client-go/kubernetes/scheme"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -43,6 +43,13 @@ const (
 var (
 	masterURL                   string
 	configFile                  string
+	candidateConfigFile         string
+	configTemplate              bool
+	configTemplateValues        map[string]string
+	auditLogPath                string
+	auditLogMaxSize             int64
+	namespaceAllowlistFile      string
+	enableDebugOverrides        bool
 	kubeconfig                  string
 	deployedNamespace           string
 	strWatchDuration            string
@@ -52,6 +59,8 @@ var (
 	qps                         float32
 	burst                       int
 	port                        int
+	adminPort                   int
+	userAgent                   string
 
 	onlyOneSignalHandler = make(chan struct{})
 	shutdownSignals      = []os.Signal{os.Interrupt, syscall.SIGTERM}
@@ -89,7 +98,7 @@ func init() {
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
-	rootCmd.PersistentFlags().StringVar(&configFile, "config-file", "config.yaml", "path to the config file that has the service depenancies")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config-file", "config.yaml", "path to the config file (or a directory of config files) that has the service depenancies")
 	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "path to the kube config file")
 	rootCmd.PersistentFlags().StringVar(&deployedNamespace, "deployed-namespace", "default", "namespace into which the dependency-watchdog is deployed")
 	rootCmd.PersistentFlags().StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
@@ -97,6 +106,15 @@ func init() {
 	rootCmd.PersistentFlags().Float32Var(&qps, "qps", rest.DefaultQPS, "Throttling QPS configuration for the client to host apiserver.")
 	rootCmd.PersistentFlags().IntVar(&burst, "burst", rest.DefaultBurst, "Throttling burst configuration for the client to host apiserver.")
 	rootCmd.PersistentFlags().IntVar(&port, "port", defaultPort, "The port on which health and prometheus metrics are exposed.")
+	rootCmd.Flags().IntVar(&adminPort, "admin-port", 0, "The port on which the unauthenticated admin endpoints, e.g. /status and /reconcile, are exposed. 0 disables them.")
+	rootCmd.PersistentFlags().StringVar(&userAgent, "user-agent", dependencyWatchdogAgentName, "The user agent and field manager used for the requests to the apiserver.")
+	rootCmd.PersistentFlags().BoolVar(&configTemplate, "config-template", false, "render the config files as Go templates referencing the environment variables and the config template values, e.g. {{ .NAMESPACE_PREFIX }}")
+	rootCmd.PersistentFlags().StringToStringVar(&configTemplateValues, "config-template-values", nil, "values the config templates can reference in addition to the environment variables, taking precedence over them, e.g. NAMESPACE_PREFIX=shoot--")
+	rootCmd.Flags().StringVar(&candidateConfigFile, "candidate-config-file", "", "path to a candidate config file (or a directory of config files) whose decisions are compared with the active config without acting on them")
+	rootCmd.Flags().StringVar(&auditLogPath, "audit-log-path", "", "path to the file the decisions on the dependant pods are appended to as JSON lines, or - for stdout")
+	rootCmd.Flags().StringVar(&namespaceAllowlistFile, "namespace-allowlist-file", "", "path to a file with a YAML list of the only namespaces the dependency-watchdog may act in, independently of the config")
+	rootCmd.Flags().BoolVar(&enableDebugOverrides, "enable-debug-overrides", false, "DO NOT USE IN PRODUCTION. Serves "+restarter.DebugDependencyPath+"<namespace>/<service> on the admin port to force the readiness of services for integration tests")
+	rootCmd.Flags().Int64Var(&auditLogMaxSize, "audit-log-max-size", 0, "The size in bytes after which the audit log file is rotated. 0 disables the rotation.")
 	rootCmd.Flags().StringVar(&strWatchDuration, "watch-duration", defaultWatchDuration, "The duration to watch dependencies after the service is ready.")
 
 	klog.InitFlags(nil)
@@ -108,6 +126,11 @@ func runRoot(cmd *cobra.Command, args []string) {
 	klog.V(5).Info("Running root command")
 	klog.V(2).Infoln("Running root command with the following parameters:")
 	klog.V(2).Infoln("config-file: ", configFile)
+	klog.V(2).Infoln("config-template: ", configTemplate)
+	klog.V(2).Infoln("candidate-config-file: ", candidateConfigFile)
+	klog.V(2).Infoln("audit-log-path: ", auditLogPath)
+	klog.V(2).Infoln("audit-log-max-size: ", auditLogMaxSize)
+	klog.V(2).Infoln("namespace-allowlist-file: ", namespaceAllowlistFile)
 	klog.V(2).Infoln("kubeconfig: ", kubeconfig)
 	klog.V(2).Infoln("master: ", deployedNamespace)
 	klog.V(2).Infoln("deployed-namespace: ", masterURL)
@@ -116,6 +139,7 @@ func runRoot(cmd *cobra.Command, args []string) {
 	klog.V(2).Infoln("qps: ", qps)
 	klog.V(2).Infoln("burst: ", burst)
 	klog.V(2).Infoln("port: ", port)
+	klog.V(2).Infoln("user-agent: ", userAgent)
 
 	watchDuration, err := time.ParseDuration(strWatchDuration)
 	if err != nil {
@@ -124,12 +148,15 @@ func runRoot(cmd *cobra.Command, args []string) {
 
 	// set up signals so we handle the first shutdown signal gracefully
 	stopCh := setupSignalHandler()
-	deps, err := restarter.LoadServiceDependants(configFile)
+	deps, err := loadServiceDependants(configFile)
 	if err != nil {
 		klog.Fatalf("Error parsing config file: %s", err.Error())
 	}
+	if err := restarter.CheckServiceDependants(deps); err != nil {
+		klog.Fatalf("Error checking the config: %s", err.Error())
+	}
 
-	configContent, err := restarterapi.Encode(deps)
+	configContent, err := restarterapi.Encode(restarter.RedactServiceDependants(deps))
 	klog.V(2).Infof("Endpoints configuration: \n %s", configContent)
 
 	config, err := clientcmd.BuildConfigFromFlags(masterURL, kubeconfig)
@@ -139,25 +166,90 @@ func runRoot(cmd *cobra.Command, args []string) {
 
 	config.QPS = qps
 	config.Burst = burst
+	config.UserAgent = userAgent
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		klog.Fatalf("Error creating k8s clientset: %s", err.Error())
 	}
 
-	var opts []informers.SharedInformerOption
-	if deps.Namespace != "" {
-		opts = append(opts, informers.WithNamespace(deps.Namespace))
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		klog.Fatalf("Error creating dynamic client: %s", err.Error())
+	}
+
+	factory := restarter.NewSharedInformerFactory(clientset, deps, defaultSyncDuration)
+	controller := restarter.NewController(clientset, dynamicClient, factory, deps, watchDuration, stopCh)
+	controller.FieldManager = userAgent
+	if err := controller.RegisterMetrics(); err != nil {
+		klog.Fatalf("Error registering metrics: %s", err.Error())
+	}
+	if err := controller.CheckPermissions(); err != nil {
+		klog.Fatalf("Error checking RBAC permissions: %s", err.Error())
+	}
+	if deps.PreflightCheck {
+		controller.RunPreflightCheck()
+	}
+	loadCandidateConfig := func() (*restarterapi.ServiceDependants, error) { return loadServiceDependants(candidateConfigFile) }
+	if candidateConfigFile != "" {
+		if err := controller.ReloadCandidateConfig(loadCandidateConfig); err != nil {
+			klog.Fatalf("Error parsing candidate config file: %s", err.Error())
+		}
+	}
+	if namespaceAllowlistFile != "" {
+		if controller.NamespaceAllowlist, err = restarter.LoadNamespaceAllowlist(namespaceAllowlistFile); err != nil {
+			klog.Fatalf("Error loading namespace allowlist: %s", err.Error())
+		}
+	}
+	if auditLogPath != "" {
+		if controller.AuditLog, err = restarter.NewAuditLog(auditLogPath, auditLogMaxSize); err != nil {
+			klog.Fatalf("Error opening audit log: %s", err.Error())
+		}
 	}
-	factory := informers.NewSharedInformerFactoryWithOptions(
-		clientset,
-		defaultSyncDuration,
-		opts...)
-	controller := restarter.NewController(clientset, factory, deps, watchDuration, stopCh)
 	leaderElectionClient := kubernetes.NewForConfigOrDie(rest.AddUserAgent(config, "dependency-watchdog-election"))
 	recorder := createRecorder(leaderElectionClient)
+	controller.Recorder = recorder
+	// The admin endpoints are not authenticated and /reconcile acts on the cluster, so they are never served on the
+	// metrics port, but only on the admin port if it is enabled.
+	admin := http.NewServeMux()
+	admin.Handle("/reconcile", controller.ReconcileHandler())
+	admin.Handle("/status", controller.StatusHandler())
+	admin.Handle("/dependencies", controller.DependenciesHandler())
+	admin.Handle("/impact", controller.ImpactHandler())
+	admin.Handle("/config", controller.ConfigHandler())
+	admin.Handle("/history", controller.HistoryHandler())
+	admin.Handle("/dryrun-report", controller.DryRunReportHandler())
+	if enableDebugOverrides {
+		if adminPort == 0 {
+			klog.Fatalf("Debug overrides require the admin port to be enabled")
+		}
+		klog.Warningf("Debug overrides are enabled. The readiness of the services can be forced through %s, which must never happen in production.", restarter.DebugDependencyPath)
+		controller.EnableDebugOverrides = true
+		admin.Handle(restarter.DebugDependencyPath, controller.DebugDependencyHandler())
+	}
+	go handleReconcileSignal(func() {
+		if err := controller.ReloadServiceDependants(func() (*restarterapi.ServiceDependants, error) {
+			return loadServiceDependants(configFile)
+		}); err != nil {
+			klog.Errorf("Keeping the active config: %s", err)
+		}
+		if candidateConfigFile != "" {
+			if err := controller.ReloadCandidateConfig(loadCandidateConfig); err != nil {
+				klog.Errorf("Keeping the active candidate config: %s", err)
+			}
+		}
+		if controller.NamespaceAllowlist != nil {
+			if err := controller.NamespaceAllowlist.Reload(namespaceAllowlistFile); err != nil {
+				klog.Errorf("Keeping the active namespace allowlist: %s", err)
+			}
+		}
+		controller.TriggerReconcile()
+	})
 	run := func(ctx context.Context) {
 		go serveMetrics()
+		if adminPort != 0 {
+			go serveAdmin(admin)
+		}
 		klog.Info("Starting endpoint controller.")
 		if err = controller.Run(concurrentSyncs); err != nil {
 			klog.Fatalf("Error running controller: %s", err.Error())
@@ -202,6 +294,21 @@ func runRoot(cmd *cobra.Command, args []string) {
 	panic("unreachable")
 }
 
+// loadServiceDependants loads the service dependants from the config-file or,
+// if a directory is given, merges them from all the config-files in it, rendering
+// them as templates if configured.
+func loadServiceDependants(path string) (*restarterapi.ServiceDependants, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	opts := restarter.LoadOptions{Template: configTemplate, Values: configTemplateValues}
+	if fi.IsDir() {
+		return restarter.LoadServiceDependantsFromDirWithOptions(path, opts)
+	}
+	return restarter.LoadServiceDependantsWithOptions(path, opts)
+}
+
 func createRecorder(kubeClient *kubernetes.Clientset) record.EventRecorder {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartLogging(klog.Infof)
@@ -228,7 +335,22 @@ func setupSignalHandler() (stopCh <-chan struct{}) {
 	return stop
 }
 
+// handleReconcileSignal calls trigger whenever a SIGHUP is received.
+func handleReconcileSignal(trigger func()) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		klog.Info("Received signal SIGHUP. Reloading the config and triggering an immediate reconcile.")
+		trigger()
+	}
+}
+
 func serveMetrics() error {
 	http.Handle("/metrics", promhttp.Handler())
 	return http.ListenAndServe(fmt.Sprintf("%s%d", ":", port), nil)
 }
+
+// serveAdmin serves the admin endpoints on the admin port.
+func serveAdmin(mux *http.ServeMux) error {
+	return http.ListenAndServe(fmt.Sprintf("%s%d", ":", adminPort), mux)
+}
@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+)
+
+func isValidAction(action api.Action) bool {
+	switch action {
+	case api.ActionDelete, api.ActionEvict, api.ActionRollout:
+		return true
+	default:
+		return false
+	}
+}
+
+// PodDesiredAction returns the action requested by the action annotation of the pod. It returns the fallback
+// if the pod does not request an action or the requested action is invalid.
+func PodDesiredAction(pod *v1.Pod, fallback api.Action) api.Action {
+	value, ok := pod.Annotations[actionAnnotationKey]
+	if !ok {
+		return fallback
+	}
+	if action := api.Action(value); isValidAction(action) {
+		return action
+	}
+	klog.Warningf("Pod %s/%s requests the invalid action %q. Falling back to %s.", pod.Namespace, pod.Name, value, fallback)
+	return fallback
+}
+
+// SelectActionBySize returns the rollout action instead of the delete action for pods with at least the given number
+// of containers, for which restarting the rollout of their owner is gentler than deleting them. Pods without an owner
+// to roll out are still deleted. Other actions and pods below the threshold keep the given action.
+func SelectActionBySize(pod *v1.Pod, action api.Action, rolloutContainerThreshold *int32) api.Action {
+	if action != api.ActionDelete || rolloutContainerThreshold == nil || len(pod.Spec.Containers) < int(*rolloutContainerThreshold) {
+		return action
+	}
+	if ControllerOwnerRef(pod) == nil {
+		return action
+	}
+	return api.ActionRollout
+}
+
+// getPodAction returns the action taken on the pod, i.e. the action requested by the pod, if any, or the action
+// configured for its namespace, adapted to the size of the pod if configured by its dependant pods.
+func getPodAction(pod *v1.Pod, opts api.Options, depPods *api.DependantPods) api.Action {
+	if _, ok := pod.Annotations[actionAnnotationKey]; ok || depPods == nil {
+		return PodDesiredAction(pod, getAction(opts))
+	}
+	return SelectActionBySize(pod, getAction(opts), depPods.RolloutContainerThreshold)
+}
+
+func getAction(opts api.Options) api.Action {
+	if opts.Action == nil {
+		return api.ActionDelete
+	}
+	if !isValidAction(*opts.Action) {
+		klog.Warningf("Invalid action %q configured. Falling back to %s.", *opts.Action, api.ActionDelete)
+		return api.ActionDelete
+	}
+	return *opts.Action
+}
+
+// recyclePod takes the action on the pod.
+func (c *Controller) recyclePod(ctx context.Context, po *v1.Pod, action api.Action, propagation *metav1.DeletionPropagation) error {
+	switch action {
+	case api.ActionEvict:
+		klog.Infof("Evicting pod: %v", po.Name)
+		return c.clientset.PolicyV1beta1().Evictions(po.Namespace).Evict(&policyv1beta1.Eviction{
+			ObjectMeta:    metav1.ObjectMeta{Namespace: po.Namespace, Name: po.Name},
+			DeleteOptions: &metav1.DeleteOptions{PropagationPolicy: propagation},
+		})
+	case api.ActionRollout:
+		return c.restartOwnerRollout(ctx, po)
+	default:
+		klog.Infof("Deleting pod: %v", po.Name)
+		return c.clientset.CoreV1().Pods(po.Namespace).Delete(po.Name, &metav1.DeleteOptions{PropagationPolicy: propagation})
+	}
+}
+
+// restartOwnerRollout restarts the rollout of the top-level owner of the pod by annotating its pod template,
+// the same way as `kubectl rollout restart` does.
+func (c *Controller) restartOwnerRollout(ctx context.Context, po *v1.Pod) error {
+	owner, err := c.getTopLevelOwner(ctx, po)
+	if err != nil {
+		return err
+	}
+	if owner == nil {
+		return fmt.Errorf("pod %s has no owner to roll out", po.Name)
+	}
+	if c.dynamicClient == nil {
+		return fmt.Errorf("no dynamic client configured to roll out %s", owner.Name)
+	}
+	gvr, err := getOwnerGVR(owner)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotationKey: c.clock.Now().UTC().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("Restarting rollout of %s %s/%s for pod %s", owner.Kind, owner.Namespace, owner.Name, po.Name)
+	_, err = c.dynamicClient.Resource(gvr).Namespace(owner.Namespace).Patch(owner.Name, types.MergePatchType, patch, c.patchOptions())
+	return err
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPodDesiredAction(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		expected    api.Action
+	}{
+		{map[string]string{actionAnnotationKey: "delete"}, api.ActionDelete},
+		{map[string]string{actionAnnotationKey: "evict"}, api.ActionEvict},
+		{map[string]string{actionAnnotationKey: "rollout"}, api.ActionRollout},
+		{map[string]string{actionAnnotationKey: "reboot"}, api.ActionEvict},
+		{nil, api.ActionEvict},
+	}
+	for _, tc := range tests {
+		pod := newPodInCrashloop("pod-0", nil)
+		pod.Annotations = tc.annotations
+		if actual := PodDesiredAction(pod, api.ActionEvict); actual != tc.expected {
+			t.Errorf("Expected action %s for annotations %v but got %s", tc.expected, tc.annotations, actual)
+		}
+	}
+}
+
+func TestSelectActionBySize(t *testing.T) {
+	threshold := int32(3)
+	tests := []struct {
+		name       string
+		containers int
+		owned      bool
+		action     api.Action
+		threshold  *int32
+		expected   api.Action
+	}{
+		{"small pod", 2, true, api.ActionDelete, &threshold, api.ActionDelete},
+		{"large pod", 3, true, api.ActionDelete, &threshold, api.ActionRollout},
+		{"large pod without owner", 3, false, api.ActionDelete, &threshold, api.ActionDelete},
+		{"large pod to be evicted", 5, true, api.ActionEvict, &threshold, api.ActionEvict},
+		{"large pod without threshold", 5, true, api.ActionDelete, nil, api.ActionDelete},
+	}
+	for _, tc := range tests {
+		pod := newPodInCrashloop("pod-0", nil)
+		pod.Spec.Containers = make([]v1.Container, tc.containers)
+		if tc.owned {
+			pod.OwnerReferences = newControllerRef("apps/v1", "StatefulSet", "etcd")
+		}
+		if actual := SelectActionBySize(pod, tc.action, tc.threshold); actual != tc.expected {
+			t.Errorf("%s: expected action %s but got %s", tc.name, tc.expected, actual)
+		}
+	}
+
+	// The action requested by the pod takes precedence over the size of the pod.
+	pod := newPodInCrashloop("pod-0", nil)
+	pod.Spec.Containers = make([]v1.Container, 5)
+	pod.OwnerReferences = newControllerRef("apps/v1", "StatefulSet", "etcd")
+	pod.Annotations = map[string]string{actionAnnotationKey: "delete"}
+	if actual := getPodAction(pod, api.Options{}, &api.DependantPods{RolloutContainerThreshold: &threshold}); actual != api.ActionDelete {
+		t.Errorf("Expected the requested action %s but got %s", api.ActionDelete, actual)
+	}
+}
+
+func TestDeletePodRollsOutOwner(t *testing.T) {
+	isController := true
+	deployment := &unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+	deployment.SetNamespace(metav1.NamespaceDefault)
+	deployment.SetName("kube-controller-manager")
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment)
+	pod := newPodInCrashloop("pod-0", nil)
+	pod.Annotations = map[string]string{actionAnnotationKey: "rollout"}
+	pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "kube-controller-manager", Controller: &isController}}
+	client := fake.NewSimpleClientset(pod)
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	c := &Controller{clientset: client, dynamicClient: dynamicClient, clock: fakeClock}
+
+	if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+		t.Fatalf("error recycling pod: %v", err)
+	}
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected the pod to be left to the rollout but got: %v", err)
+	}
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	d, err := dynamicClient.Resource(gvr).Namespace(metav1.NamespaceDefault).Get("kube-controller-manager", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error fetching deployment: %v", err)
+	}
+	annotations, _, _ := unstructured.NestedStringMap(d.Object, "spec", "template", "metadata", "annotations")
+	if annotations[restartedAtAnnotationKey] != "2021-06-01T12:00:00Z" {
+		t.Errorf("Expected the pod template of the deployment to be annotated with the time of the clock but got %v", annotations)
+	}
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// NamespaceAllowlist restricts the namespaces the controller acts in independently of the config, e.g. if the
+// allowlist is maintained in a separate ConfigMap with tighter RBAC. A namespace is only reconciled if it is
+// reconciled according to the config and allowed by the allowlist.
+type NamespaceAllowlist struct {
+	lock       sync.RWMutex
+	namespaces sets.String
+}
+
+// NewNamespaceAllowlist returns an allowlist allowing the given namespaces.
+func NewNamespaceAllowlist(namespaces ...string) *NamespaceAllowlist {
+	return &NamespaceAllowlist{namespaces: sets.NewString(namespaces...)}
+}
+
+// LoadNamespaceAllowlist loads the allowlist from a file containing a YAML or JSON list of namespaces.
+func LoadNamespaceAllowlist(file string) (*NamespaceAllowlist, error) {
+	a := &NamespaceAllowlist{}
+	if err := a.Reload(file); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload replaces the allowed namespaces with the ones in the file. The allowed namespaces are kept if the file
+// cannot be loaded.
+func (a *NamespaceAllowlist) Reload(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var namespaces []string
+	if err := yaml.Unmarshal(data, &namespaces); err != nil {
+		return fmt.Errorf("error decoding namespace allowlist %s: %v", file, err)
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.namespaces = sets.NewString(namespaces...)
+	return nil
+}
+
+// Allows checks if the namespace is allowed. All the namespaces are allowed without an allowlist.
+func (a *NamespaceAllowlist) Allows(namespace string) bool {
+	if a == nil {
+		return true
+	}
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.namespaces.Has(namespace)
+}
+
+// isNamespaceAllowed checks if the services in the namespace are reconciled according to the config and the
+// namespace is allowed by the allowlist of the controller, if any.
+func (c *Controller) isNamespaceAllowed(deps *api.ServiceDependants, namespace string) bool {
+	return isNamespaceReconciled(deps, namespace) && c.NamespaceAllowlist.Allows(namespace)
+}
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadNamespaceAllowlist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "allowlist")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "allowlist.yaml")
+	if err := ioutil.WriteFile(file, []byte("- shoot--a\n- shoot--b\n"), 0644); err != nil {
+		t.Fatalf("error writing allowlist: %v", err)
+	}
+	a, err := LoadNamespaceAllowlist(file)
+	if err != nil {
+		t.Fatalf("error loading allowlist: %v", err)
+	}
+	if !a.Allows("shoot--a") || !a.Allows("shoot--b") || a.Allows("shoot--c") {
+		t.Errorf("Expected only the listed namespaces to be allowed")
+	}
+
+	if err := ioutil.WriteFile(file, []byte("- shoot--c\n"), 0644); err != nil {
+		t.Fatalf("error writing allowlist: %v", err)
+	}
+	if err := a.Reload(file); err != nil {
+		t.Fatalf("error reloading allowlist: %v", err)
+	}
+	if a.Allows("shoot--a") || !a.Allows("shoot--c") {
+		t.Errorf("Expected the reloaded namespaces to be allowed")
+	}
+
+	if err := ioutil.WriteFile(file, []byte("namespaces: invalid"), 0644); err != nil {
+		t.Fatalf("error writing allowlist: %v", err)
+	}
+	if err := a.Reload(file); err == nil {
+		t.Errorf("Expected an error reloading an invalid allowlist")
+	}
+	if !a.Allows("shoot--c") {
+		t.Errorf("Expected the allowlist to be kept if it cannot be reloaded")
+	}
+
+	var none *NamespaceAllowlist
+	if !none.Allows("shoot--a") {
+		t.Errorf("Expected all the namespaces to be allowed without an allowlist")
+	}
+}
+
+func TestNamespaceNotInAllowlistSkipped(t *testing.T) {
+	deps := &api.ServiceDependants{
+		AllNamespaces: true,
+		Services:      map[string]api.Service{"kube-apiserver": {}},
+	}
+	client := fake.NewSimpleClientset()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c := NewController(client, nil, informers.NewSharedInformerFactory(client, 0), deps, watchDuration, stopCh)
+	c.NamespaceAllowlist = NewNamespaceAllowlist("shoot--a")
+
+	c.enqueueEndpoint(newEndpoint("kube-apiserver", "shoot--b", nil))
+	c.enqueueEndpoint(newEndpoint("kube-apiserver", "shoot--a", nil))
+	if key, _ := c.workqueue.Get(); key != "shoot--a/kube-apiserver" {
+		t.Errorf("Expected only the endpoint in the allowed namespace to be enqueued but got %v", key)
+	}
+	c.workqueue.Done("shoot--a/kube-apiserver")
+	if c.workqueue.Len() != 0 {
+		t.Errorf("Expected the endpoint in a namespace absent from the allowlist not to be enqueued")
+	}
+	if err := c.processEndpoint(context.TODO(), "shoot--b/kube-apiserver"); err != nil {
+		t.Fatalf("error processing endpoint: %v", err)
+	}
+	if actions := client.Actions(); len(actions) != 0 {
+		t.Errorf("Expected no requests for a namespace absent from the allowlist but got %v", actions)
+	}
+
+	if !c.isNamespaceAllowed(deps, "shoot--a") {
+		t.Errorf("Expected the namespace in the allowlist to be reconciled")
+	}
+	if c.isNamespaceAllowed(deps, "kube-system") {
+		t.Errorf("Expected the excluded namespace not to be reconciled despite the allowlist")
+	}
+}
+
+func TestNamespaceRemovedFromAllowlistSkipsDeletion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "allowlist")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "allowlist.yaml")
+	if err := ioutil.WriteFile(file, []byte("- shoot--c\n"), 0644); err != nil {
+		t.Fatalf("error writing allowlist: %v", err)
+	}
+
+	pod := newPodInCrashloop("pod-0", nil)
+	client := fake.NewSimpleClientset(pod)
+	c := &Controller{
+		clientset:          client,
+		clock:              clock.RealClock{},
+		serviceDependants:  &api.ServiceDependants{AllNamespaces: true},
+		NamespaceAllowlist: NewNamespaceAllowlist(pod.Namespace),
+	}
+	// The allowlist is reloaded while the reconcile of the namespace is in progress.
+	if err := c.NamespaceAllowlist.Reload(file); err != nil {
+		t.Fatalf("error reloading allowlist: %v", err)
+	}
+	outcome, err := c.recyclePodForReason(context.TODO(), pod, "kube-apiserver", ReasonCrashLoopBackOff, nil)
+	if err != nil {
+		t.Fatalf("error recycling pod: %v", err)
+	}
+	if outcome != AuditOutcomeNamespaceNotAllowed {
+		t.Errorf("Expected outcome %s but got %s", AuditOutcomeNamespaceNotAllowed, outcome)
+	}
+	if _, err := client.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected the pod in the namespace removed from the allowlist not to be deleted but got: %v", err)
+	}
+}
@@ -5,6 +5,9 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
+
 	"github.com/ghodss/yaml"
 )
 
@@ -26,3 +29,15 @@ func Decode(data []byte) (*ServiceDependants, error) {
 	}
 	return dependants, nil
 }
+
+// DecodeStrictJSON decodes the JSON byte stream to ServiceDependants objects
+// rejecting unknown fields.
+func DecodeStrictJSON(data []byte) (*ServiceDependants, error) {
+	dependants := new(ServiceDependants)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dependants); err != nil {
+		return nil, err
+	}
+	return dependants, nil
+}
@@ -5,6 +5,7 @@
 package api
 
 import (
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -13,15 +14,408 @@ import (
 type ServiceDependants struct {
 	Services  map[string]Service `json:"services"`
 	Namespace string             `json:"namespace"`
+	// AllNamespaces explicitly enables reconciling all the namespaces if no namespace is configured, which any
+	// cluster-scoped operation requires, i.e. listing and watching across the namespaces or reading nodes and
+	// namespaces. With a configured namespace, the controller can run with a Role limited to it. Without a configured
+	// namespace, all the namespaces are reconciled even if it is not enabled, which is deprecated.
+	AllNamespaces bool `json:"allNamespaces,omitempty"`
+	// ConcurrentReconciles bounds the number of services whose dependant pods are decided on and deleted at the same
+	// time. Reconciles only hold a slot while processing their pods, not while watching them.
+	ConcurrentReconciles *int32 `json:"concurrentReconciles,omitempty"`
+	// ExcludedNamespaces are not reconciled if no namespace is configured.
+	// Defaults to the system namespaces kube-system, kube-public and kube-node-lease.
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+	// Webhook is notified about every pod deleted by the dependency-watchdog.
+	Webhook *Webhook `json:"webhook,omitempty"`
+	// RequireRBAC fails the startup if the permissions required to recycle the dependant pods are missing.
+	// Otherwise, missing permissions are only logged.
+	RequireRBAC bool `json:"requireRBAC,omitempty"`
+	// PreflightCheck checks at startup whether the selectors of the dependants match any pods and whether the
+	// endpoints of the services exist, logging a warning otherwise to reveal typos in the config early.
+	PreflightCheck bool `json:"preflightCheck,omitempty"`
+	// AllowUnknownReasons accepts restart reasons in the restart rules and the restart reason thresholds which are
+	// not in the registry of the known reasons, e.g. reasons of a newer kubelet. Unknown reasons are rejected otherwise.
+	AllowUnknownReasons bool `json:"allowUnknownReasons,omitempty"`
+	// ReconcileSummaryVerbosity is the log verbosity of the summary line logged at the end of every reconcile of a
+	// service. Defaults to 0, i.e. the summary is always logged.
+	ReconcileSummaryVerbosity *int32 `json:"reconcileSummaryVerbosity,omitempty"`
+	// DeletionsPerSecond bounds the rate of the pod deletions. The rate is lowered adaptively while the
+	// apiserver throttles the requests. The deletions are not limited if it is not set.
+	DeletionsPerSecond *float64 `json:"deletionsPerSecond,omitempty"`
+	// MetricLabels are the keys of the pod or namespace labels added as labels to the metrics of the unhealthy
+	// and deleted pods. Only the listed keys are added to bound the cardinality of the metrics.
+	MetricLabels []string `json:"metricLabels,omitempty"`
+	// CircuitBreaker suppresses all pod deletions while the dependencies are not ready in too many namespaces at
+	// once, e.g. during a cluster-wide outage, where recycling all the dependant pods would only worsen the storm.
+	CircuitBreaker *CircuitBreaker `json:"circuitBreaker,omitempty"`
+	// Informer tunes the informers of the dependency-watchdog, e.g. to reduce their memory usage in large clusters.
+	Informer *Informer `json:"informer,omitempty"`
+	// Options are the global restarter options.
+	Options `json:",inline"`
+	// Namespaces overrides the global restarter options for individual namespaces.
+	Namespaces map[string]Options `json:"namespaces,omitempty"`
 }
 
-// Service struct defines the dependent pods of a service.
+// Informer captures the resync period of the informers and the fields dropped from the cached objects.
+type Informer struct {
+	// ResyncPeriod is the period of the resyncs of the informers. It defaults to the default resync period.
+	ResyncPeriod *metav1.Duration `json:"resyncPeriod,omitempty"`
+	// StripFields are the metadata fields dropped from the cached objects as the dependency-watchdog does not
+	// need them, i.e. managedFields, labels, ownerReferences and annotations.
+	StripFields []string `json:"stripFields,omitempty"`
+}
+
+// Fields which can be dropped from the cached objects.
+const (
+	FieldManagedFields   = "managedFields"
+	FieldLabels          = "labels"
+	FieldOwnerReferences = "ownerReferences"
+	FieldAnnotations     = "annotations"
+)
+
+// CircuitBreaker captures the thresholds of degraded namespaces, i.e. namespaces with a watched service which is
+// not ready, above which the circuit opens. The circuit closes again once the thresholds are no longer exceeded.
+type CircuitBreaker struct {
+	// MaxDegradedNamespaces is the maximum number of degraded namespaces.
+	MaxDegradedNamespaces *int32 `json:"maxDegradedNamespaces,omitempty"`
+	// MaxDegradedFraction is the maximum fraction of degraded namespaces of all the namespaces with a watched service.
+	MaxDegradedFraction *float64 `json:"maxDegradedFraction,omitempty"`
+}
+
+// Options captures the restarter options which can be overridden per namespace.
+// Options which are not set are inherited from the global options.
+type Options struct {
+	// MaxEndpointsStaleness is the duration after which an unchanged Endpoints object is no longer
+	// trusted to reflect the readiness of the service. Pods are not deleted while the readiness is unknown.
+	MaxEndpointsStaleness *metav1.Duration `json:"maxEndpointsStaleness,omitempty"`
+	// DryRun only logs the pod deletions instead of performing them.
+	DryRun *bool `json:"dryRun,omitempty"`
+	// RecoveryEdgeOnly restricts the pod deletions to the RecoveryActionWindow after the service
+	// has been observed to transition from not-ready to ready.
+	RecoveryEdgeOnly *bool `json:"recoveryEdgeOnly,omitempty"`
+	// RecoveryActionWindow is the duration after a recovery of the service in which pods may be deleted
+	// if RecoveryEdgeOnly is set.
+	RecoveryActionWindow *metav1.Duration `json:"recoveryActionWindow,omitempty"`
+	// RecoveryGracePeriod is the duration after a recovery of the service during which the dependant pods
+	// get the chance to recover on their own before they are deleted.
+	RecoveryGracePeriod *metav1.Duration `json:"recoveryGracePeriod,omitempty"`
+	// DeletionPropagation is the propagation policy used to delete the pods, i.e. Background, Foreground or Orphan.
+	// Defaults to the propagation policy of the apiserver.
+	DeletionPropagation *metav1.DeletionPropagation `json:"deletionPropagation,omitempty"`
+	// RequireDependencyExists treats a missing Endpoints object of a service as an error which is retried
+	// instead of a service which is not ready.
+	RequireDependencyExists *bool `json:"requireDependencyExists,omitempty"`
+	// ReportRecoveredCondition sets the DependencyWatchdogRecovered condition in the status of the top-level
+	// owner of the deleted pods. Owners without a status subresource are skipped.
+	ReportRecoveredCondition *bool `json:"reportRecoveredCondition,omitempty"`
+	// Action is the action taken on the unhealthy dependant pods unless a pod requests another one by annotation.
+	// Defaults to delete.
+	Action *Action `json:"action,omitempty"`
+	// QuietHours are the time windows during which no pods are deleted.
+	QuietHours *QuietHours `json:"quietHours,omitempty"`
+	// ReconcileTimeout bounds the duration of a reconcile of a service, including the requests to reconcile the
+	// dependant resources and the watch of the dependant pods. Defaults to the watch duration, which also caps it:
+	// a reconcile timeout longer than the watch duration has no effect.
+	ReconcileTimeout *metav1.Duration `json:"reconcileTimeout,omitempty"`
+	// WarnBeforeDeleteDelay is the duration between the warning about an upcoming pod deletion and the deletion,
+	// during which an operator can veto the deletion by annotating the pod to be ignored. It needs to be shorter
+	// than the reconcile timeout, as the pending deletions are dropped once the reconcile ends.
+	WarnBeforeDeleteDelay *metav1.Duration `json:"warnBeforeDeleteDelay,omitempty"`
+	// WaitForDeletion is the maximum duration to wait after deleting or evicting a pod until the apiserver confirms
+	// that the pod is terminating or gone, before the next decision is taken.
+	WaitForDeletion *metav1.Duration `json:"waitForDeletion,omitempty"`
+	// DependencySnapshotAnnotation is the key of the annotation of the top-level owner of a deleted pod recording a
+	// snapshot of the dependency which triggered the deletion, for the analysis after the fact. It is not recorded
+	// if the key is not set.
+	DependencySnapshotAnnotation *string `json:"dependencySnapshotAnnotation,omitempty"`
+	// RecentOwnerChangeWindow is the duration after the creation or the last rollout of the controller of a pod
+	// during which the pod is not deleted, as the pods of a fresh rollout churn while the dependency initializes.
+	RecentOwnerChangeWindow *metav1.Duration `json:"recentOwnerChangeWindow,omitempty"`
+	// RetryBudget bounds the total number of retries of the conflicting pod deletions, evictions and rollouts during
+	// a reconcile of a service. Once it is exhausted, the remaining pods are deferred to the next reconcile. The
+	// retries are only bounded per call by default.
+	RetryBudget *int32 `json:"retryBudget,omitempty"`
+}
+
+// QuietHours captures the daily time windows during which no pods are deleted.
+type QuietHours struct {
+	// Timezone is the IANA name of the time zone of the windows. Defaults to UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// Windows are the daily time windows. A window whose end is before its start spans midnight.
+	Windows []TimeWindow `json:"windows"`
+}
+
+// TimeWindow is a daily time window from Start (inclusive) to End (exclusive) in the format HH:MM.
+type TimeWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Action is the action taken on an unhealthy dependant pod.
+type Action string
+
+const (
+	// ActionDelete deletes the pod.
+	ActionDelete Action = "delete"
+	// ActionEvict evicts the pod, respecting its PodDisruptionBudgets.
+	ActionEvict Action = "evict"
+	// ActionRollout restarts the rollout of the top-level owner of the pod.
+	ActionRollout Action = "rollout"
+)
+
+// Webhook captures the details of an HTTP endpoint that is notified about pod deletions.
+type Webhook struct {
+	URL string `json:"url"`
+	// Secret is used to sign the notification payload with HMAC-SHA256 if set.
+	Secret string `json:"secret,omitempty"`
+}
+
+// Service struct defines the dependent pods and resources of a service.
 type Service struct {
-	Dependants []DependantPods `json:"dependantPods"`
+	Dependants         []DependantPods     `json:"dependantPods"`
+	DependantResources []DependantResource `json:"dependantResources,omitempty"`
+	// OnDependencyLost is the preemptive action taken on the dependant pods once the service has been lost,
+	// i.e. became not ready after being ready. It is reverted once the service recovers.
+	OnDependencyLost *DependencyLostAction `json:"onDependencyLost,omitempty"`
+	// Readiness selects the predicate deciding if the service is ready. The readiness is evaluated once per service
+	// and shared by all of its dependants. Defaults to the service having any ready address.
+	Readiness *Readiness `json:"readiness,omitempty"`
+	// ReadinessAll selects further predicates which all need to be ready besides the one selected by Readiness,
+	// e.g. a probe of the service. The predicates are evaluated cheapest first and the evaluation stops at the first
+	// one which is not ready, so that expensive predicates are skipped while a cheap one already reports not ready.
+	ReadinessAll []Readiness `json:"readinessAll,omitempty"`
+	// ReadyStableFor is the duration the readiness predicates of the service need to hold continuously before it is
+	// treated as ready, e.g. so that a service which has the minimum ready addresses only momentarily during its
+	// rollout does not trigger the recycling of all of its dependants at once.
+	ReadyStableFor *metav1.Duration `json:"readyStableFor,omitempty"`
+	// ActionCooldown is the duration after the last pod deletion of a reconcile of the service during which the
+	// later reconciles of the service do not delete any pods, so that the recycled dependants can settle.
+	ActionCooldown *metav1.Duration `json:"actionCooldown,omitempty"`
+	// MaxNotReadyDuration is the duration the service may stay not ready before the controller stops waiting for
+	// it and takes the alternate action once, e.g. scaling the dependants to zero or raising a critical alert.
+	MaxNotReadyDuration *metav1.Duration `json:"maxNotReadyDuration,omitempty"`
+}
+
+// Readiness captures the name and the parameters of a readiness predicate of a service.
+type Readiness struct {
+	// Predicate is the name of a built-in or a custom readiness predicate.
+	Predicate string `json:"predicate"`
+	// MinReadyAddresses is the number of ready addresses required by the MinReadyAddresses predicate.
+	MinReadyAddresses *int32 `json:"minReadyAddresses,omitempty"`
+	// PortName is the name of the port which needs a ready address for the NamedPortReady predicate.
+	PortName string `json:"portName,omitempty"`
+	// Cost orders the evaluation of the readiness predicates of a service, cheapest first. Predicates of the same
+	// cost are evaluated in the order of the config. Defaults to 0 for the built-in predicates reading only the
+	// endpoints, 1 for AllZonesReady listing the endpoint slices and 10 for the custom predicates.
+	Cost *int32 `json:"cost,omitempty"`
+}
+
+// The names of the built-in readiness predicates.
+const (
+	// ReadinessAnyReadyAddress requires any ready address of the service.
+	ReadinessAnyReadyAddress = "AnyReadyAddress"
+	// ReadinessMinReadyAddresses requires at least MinReadyAddresses ready addresses of the service.
+	ReadinessMinReadyAddresses = "MinReadyAddresses"
+	// ReadinessNamedPortReady requires a ready address serving the port named PortName.
+	ReadinessNamedPortReady = "NamedPortReady"
+	// ReadinessAllZonesReady requires a ready endpoint in every zone with an endpoint of the service.
+	ReadinessAllZonesReady = "AllZonesReady"
+)
+
+// DependencyLostAction captures the action taken on the dependant pods of a lost service.
+type DependencyLostAction struct {
+	// After is the duration the service needs to stay not ready before the action is taken.
+	After *metav1.Duration `json:"after,omitempty"`
+	// Annotations are added to the dependant pods while the service is lost, e.g. to make another controller
+	// hold them back, and removed once it recovers.
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // DependantPods struct captures the details needed to identify dependant pods.
 type DependantPods struct {
 	Name     string                `json:"name,omitempty"`
 	Selector *metav1.LabelSelector `json:"selector"`
+	// ServiceAccountName restricts the dependant pods to the pods matching the selector which also run with the given
+	// service account, e.g. if the labels of the pods are controlled by tenants but the service account is not.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// PriorityClassNames restricts the dependant pods to the pods matching the selector with one of the given
+	// priority classes, e.g. to only recycle lower-priority pods. Pods without a priority class match an empty name.
+	PriorityClassNames []string `json:"priorityClassNames,omitempty"`
+	// OrderByPriority deletes the dependant pods with the lowest priority first if all of them are deleted.
+	OrderByPriority bool `json:"orderByPriority,omitempty"`
+	// RolloutContainerThreshold restarts the rollout of the owner of the pods with at least the given number of
+	// containers instead of deleting them, as deleting large pods is more disruptive. It only applies if the pods
+	// would be deleted otherwise.
+	RolloutContainerThreshold *int32 `json:"rolloutContainerThreshold,omitempty"`
+	// RequireAllUnhealthy restricts the pod deletions to the case where all the dependant pods are in CrashloopBackoff.
+	RequireAllUnhealthy bool `json:"requireAllUnhealthy,omitempty"`
+	// ObserveOnly only detects and records the pods which would be deleted instead of deleting them,
+	// regardless of the dry run setting of the namespace.
+	ObserveOnly bool `json:"observeOnly,omitempty"`
+	// Sentinel selects the pods whose availability reflects the health of the dependency for these dependant pods.
+	// If set, the dependency is only treated as ready while one of the sentinel pods is available.
+	Sentinel *metav1.LabelSelector `json:"sentinel,omitempty"`
+	// SentinelUnknownAvailable treats sentinel pods whose readiness is unknown, i.e. without a Ready condition early in
+	// their lifecycle or with an unknown one, as available. They are treated as unavailable otherwise.
+	SentinelUnknownAvailable bool `json:"sentinelUnknownAvailable,omitempty"`
+	// Lease references the Lease in the namespace of the dependant pods whose renewals reflect the health of the
+	// dependency, e.g. of a leader-elected controller. If set, the dependency is only treated as ready while the
+	// Lease is fresh.
+	Lease *LeaseReference `json:"lease,omitempty"`
+	// HealthCheck references a custom resource in the namespace of the dependant pods whose status reflects the
+	// health of the dependency. If set, the dependency is only treated as ready while the referenced field of the
+	// custom resource has the ready value.
+	HealthCheck *HealthCheckReference `json:"healthCheck,omitempty"`
+	// WaveSize bounds the number of pods deleted at once if all the dependant pods are deleted together.
+	// The next wave is only deleted once the replacements of the previous waves are available.
+	WaveSize *int32 `json:"waveSize,omitempty"`
+	// ReadyConditionType is the type of the pod condition signalling the readiness of the dependant pods when
+	// waiting for them to become available, e.g. a custom condition set by the workload. Defaults to Ready.
+	ReadyConditionType v1.PodConditionType `json:"readyConditionType,omitempty"`
+	// MinReadySeconds is the minimum duration the dependant pods need to be ready to be treated as available.
+	// Defaults to the minReadySeconds of the controllers of the pods.
+	MinReadySeconds *int32 `json:"minReadySeconds,omitempty"`
+	// RequireAllContainersReady only treats the dependant pods as available if all of their containers are ready,
+	// e.g. also the service mesh sidecar through which the pods reach the dependency.
+	RequireAllContainersReady bool `json:"requireAllContainersReady,omitempty"`
+	// WaveSettleTimeout is the maximum duration to wait for the replacements of a wave to become available.
+	WaveSettleTimeout *metav1.Duration `json:"waveSettleTimeout,omitempty"`
+	// MinHealthyReplicas is the number of replicas of the owner of the pods that must not be recycled in a
+	// single reconcile, computed against the desired replica count of the owner.
+	MinHealthyReplicas *int32 `json:"minHealthyReplicas,omitempty"`
+	// MaxUnavailableFraction is the fraction of the desired replicas of the owner of the pods that may be
+	// recycled in a single reconcile. It is rounded down.
+	MaxUnavailableFraction *float64 `json:"maxUnavailableFraction,omitempty"`
+	// MaxConcurrentDeletionsPerOwner is the number of pods of a single top-level owner which may be recycled in a
+	// single reconcile, e.g. to keep the quorum of a StatefulSet. Defaults to 1.
+	MaxConcurrentDeletionsPerOwner *int32 `json:"maxConcurrentDeletionsPerOwner,omitempty"`
+	// NodeLocalizedCrashloopFraction is the fraction of the crashlooping pods which, if located on a single node
+	// while pods on other nodes are fine, prevents the deletion of the pods on that node.
+	NodeLocalizedCrashloopFraction *float64 `json:"nodeLocalizedCrashloopFraction,omitempty"`
+	// OwnerCrashloopHoldFraction is the fraction of the pods of a controller which, if exceeded by its crashlooping
+	// pods, holds the deletion of its pods, as so many crashlooping pods rather suggest a bug than the dependency.
+	// A warning event is recorded for the top-level owner and the deletions resume once an operator acknowledges the
+	// hold by annotating the owner with dependency-watchdog.gardener.cloud/hold-acknowledged=true.
+	OwnerCrashloopHoldFraction *float64 `json:"ownerCrashloopHoldFraction,omitempty"`
+	// RestartRules restrict the pod deletions to pods matching one of the rules instead of
+	// all the pods in CrashloopBackoff.
+	RestartRules []RestartRule `json:"restartRules,omitempty"`
+	// RestartReasonThresholds maps the reasons a container may be waiting for to the minimum number of
+	// restarts of the container before the pod is deleted, e.g. CrashLoopBackOff: 3 and ImagePullBackOff: 0.
+	RestartReasonThresholds map[string]int32 `json:"restartReasonThresholds,omitempty"`
+	// MinRestartRate is the minimum number of restarts per minute of a container, averaged since the start of
+	// the pod, for the pod to be deleted because of its restarts. It separates pods restarting quickly from pods
+	// which accumulated their restarts over a long time.
+	MinRestartRate *float64 `json:"minRestartRate,omitempty"`
+	// Predicates select additional pods to be deleted besides the ones in CrashloopBackoff.
+	Predicates []PodPredicate `json:"predicates,omitempty"`
+	// UnschedulableThreshold selects additional pods to be deleted which have been unschedulable for at least
+	// the given duration, so that the scheduler retries them once the dependency is ready.
+	UnschedulableThreshold *metav1.Duration `json:"unschedulableThreshold,omitempty"`
+	// VolumeMountThreshold selects additional pods to be deleted with a persistent volume claim which have been
+	// waiting for their volumes to be mounted for at least the given duration, e.g. because the service is the
+	// provisioner or the CSI driver of the volumes, so that the volumes are attached again once it recovered.
+	VolumeMountThreshold *metav1.Duration `json:"volumeMountThreshold,omitempty"`
+	// RecycleLivenessKilled selects additional pods to be deleted with a container which was killed by the kubelet
+	// after its liveness probe failed, e.g. because the dependency was not reachable, before it reaches CrashLoopBackOff.
+	RecycleLivenessKilled bool `json:"recycleLivenessKilled,omitempty"`
+	// RecycleInitCrashloop selects additional pods to be deleted with restart policy OnFailure which are stuck in an
+	// init container in CrashLoopBackOff, e.g. setup pods retrying an init step which requires the service.
+	RecycleInitCrashloop bool `json:"recycleInitCrashloop,omitempty"`
+	// ZonePinned restricts the pod deletions to pods in zones where the service has a ready endpoint.
+	ZonePinned bool `json:"zonePinned,omitempty"`
+	// SkipExitCodes excludes pods with a container which terminated with one of the given exit codes, e.g. because
+	// of a misconfiguration which a restart does not fix.
+	SkipExitCodes []int32 `json:"skipExitCodes,omitempty"`
+	// ExitCodeSource is the state of the containers the exit codes are read from. It defaults to
+	// LastTerminationState, as a container in CrashLoopBackOff is waiting and only the previous run terminated.
+	ExitCodeSource *ContainerStateSource `json:"exitCodeSource,omitempty"`
+	// SkipDebuggedPods excludes pods with ephemeral containers, e.g. because an engineer attached a debugging
+	// session to them which should not be interrupted.
+	SkipDebuggedPods bool `json:"skipDebuggedPods,omitempty"`
+	// AllowDependencyOverlap recycles pods matching the selector which also back the service. Such pods are skipped
+	// otherwise, as a selector accidentally matching the pods of the service makes the dependency recycle itself.
+	AllowDependencyOverlap bool `json:"allowDependencyOverlap,omitempty"`
+	// HealthScore selects additional pods to be deleted whose weighted health score reaches the threshold.
+	HealthScore *HealthScore `json:"healthScore,omitempty"`
+}
+
+// HealthScore combines the restarts, the time in CrashLoopBackOff and the failed readiness gates of a pod into a
+// weighted score. The higher the score, the more unhealthy the pod.
+type HealthScore struct {
+	// Threshold is the minimum score of a pod to be deleted.
+	Threshold float64 `json:"threshold"`
+	// Weights of the components of the score. Missing weights default to the documented defaults.
+	Weights *HealthScoreWeights `json:"weights,omitempty"`
+}
+
+// HealthScoreWeights captures the weights of the components of the health score.
+type HealthScoreWeights struct {
+	// Restart is the weight of every restart of a container. It defaults to 1.
+	Restart *float64 `json:"restart,omitempty"`
+	// BackoffMinute is the weight of every minute a container has been in CrashLoopBackOff. It defaults to 2.
+	BackoffMinute *float64 `json:"backoffMinute,omitempty"`
+	// ReadinessGate is the weight of every readiness gate of the pod which is not true. It defaults to 5.
+	ReadinessGate *float64 `json:"readinessGate,omitempty"`
+}
+
+// LeaseReference references a Lease which needs to be renewed within the given maximum age.
+type LeaseReference struct {
+	Name   string          `json:"name"`
+	MaxAge metav1.Duration `json:"maxAge"`
+}
+
+// HealthCheckReference references a custom resource whose field at the given dot-separated path, e.g.
+// status.health, has the given value while the dependency is ready.
+type HealthCheckReference struct {
+	APIVersion string `json:"apiVersion"`
+	Resource   string `json:"resource"`
+	Name       string `json:"name"`
+	ReadyField string `json:"readyField"`
+	ReadyValue string `json:"readyValue"`
+}
+
+// ContainerStateSource is the state of a container status which is inspected.
+type ContainerStateSource string
+
+const (
+	// ContainerStateCurrent is the current state of the container.
+	ContainerStateCurrent ContainerStateSource = "State"
+	// ContainerStateLastTermination is the state of the previous run of the container.
+	ContainerStateLastTermination ContainerStateSource = "LastTerminationState"
+)
+
+// RestartRule matches pods controlled by an owner of the given kind with a container waiting
+// for one of the given reasons, e.g. CrashLoopBackOff or ImagePullBackOff.
+type RestartRule struct {
+	// OwnerKind is the kind of the direct controller of the pod, e.g. ReplicaSet for the pods of a Deployment or
+	// Job for the pods of a CronJob. An empty kind matches all pods.
+	OwnerKind      string   `json:"ownerKind,omitempty"`
+	RestartReasons []string `json:"restartReasons"`
+}
+
+// PodPredicate matches pods in the given phase with all the given conditions for at least the given duration.
+type PodPredicate struct {
+	// Phase of the pod. An empty phase matches all pods.
+	Phase v1.PodPhase `json:"phase,omitempty"`
+	// Conditions which are all required to be in the given state.
+	Conditions []PodConditionRequirement `json:"conditions,omitempty"`
+	// For is the minimum duration the required conditions have been in their state or, if no conditions are
+	// required, the minimum age of the pod.
+	For *metav1.Duration `json:"for,omitempty"`
+}
+
+// PodConditionRequirement requires a pod condition to have the given status and, if given, reason.
+type PodConditionRequirement struct {
+	Type   v1.PodConditionType `json:"type"`
+	Status v1.ConditionStatus  `json:"status"`
+	Reason string              `json:"reason,omitempty"`
+}
+
+// DependantResource struct captures the details needed to identify a dependant resource (typically a
+// custom resource) whose controller has to be triggered to reconcile once the service becomes ready.
+type DependantResource struct {
+	APIVersion string `json:"apiVersion"`
+	Resource   string `json:"resource"`
+	Name       string `json:"name"`
 }
@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+// The outcomes of the decisions recorded in the audit log.
+const (
+	AuditOutcomeSucceeded   = "Succeeded"
+	AuditOutcomeFailed      = "Failed"
+	AuditOutcomeDryRun      = "DryRun"
+	AuditOutcomeQuietHours  = "QuietHours"
+	AuditOutcomeObserveOnly = "ObserveOnly"
+	AuditOutcomeCircuitOpen = "CircuitOpen"
+	// AuditOutcomeRetryBudgetExhausted is the outcome of a deletion deferred to the next reconcile, as the retry
+	// budget of the current one is exhausted.
+	AuditOutcomeRetryBudgetExhausted = "RetryBudgetExhausted"
+	// AuditOutcomeNamespaceNotAllowed is the outcome of a deletion skipped, as the namespace is no longer reconciled.
+	AuditOutcomeNamespaceNotAllowed = "NamespaceNotAllowed"
+)
+
+// AuditRecord is a single decision of the controller on a dependant pod.
+type AuditRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Actor     string    `json:"actor"`
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod"`
+	Owner     string    `json:"owner,omitempty"`
+	Service   string    `json:"service"`
+	Reason    string    `json:"reason"`
+	Action    string    `json:"action"`
+	Outcome   string    `json:"outcome"`
+}
+
+// AuditLog writes the decisions of the controller as JSON lines. The file is rotated to <path>.1 once it would
+// exceed its maximum size.
+type AuditLog struct {
+	mux     sync.Mutex
+	path    string
+	maxSize int64
+	w       io.Writer
+	file    *os.File
+	size    int64
+}
+
+// NewAuditLog opens the audit log at the given path for appending. The records are written to stdout if the path
+// is empty or "-". A maxSize of 0 does not limit the size of the file.
+func NewAuditLog(path string, maxSize int64) (*AuditLog, error) {
+	if path == "" || path == "-" {
+		return &AuditLog{w: os.Stdout}, nil
+	}
+	l := &AuditLog{path: path, maxSize: maxSize}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *AuditLog) open() error {
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.w, l.size = f, f, fi.Size()
+	return nil
+}
+
+// Write appends the record as a single line and flushes it to the disk.
+func (l *AuditLog) Write(record *AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.file != nil && l.maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.w.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if l.file != nil {
+		return l.file.Sync()
+	}
+	return nil
+}
+
+func (l *AuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+// Close closes the audit log file, if any.
+func (l *AuditLog) Close() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// audit records the decision on the pod in the audit log, if configured. Failures are only logged.
+func (c *Controller) audit(ctx context.Context, po *v1.Pod, service, reason string, action api.Action, outcome string) {
+	if c.AuditLog == nil {
+		return
+	}
+	record := &AuditRecord{
+		Timestamp: c.clock.Now().UTC(),
+		Actor:     c.FieldManager,
+		Namespace: po.Namespace,
+		Pod:       po.Name,
+		Service:   service,
+		Reason:    reason,
+		Action:    string(action),
+		Outcome:   outcome,
+	}
+	if owner, err := c.getTopLevelOwner(ctx, po); err == nil && owner != nil {
+		record.Owner = owner.Kind + "/" + owner.Name
+	}
+	if err := c.AuditLog.Write(record); err != nil {
+		klog.Errorf("Error writing the audit record for pod %s/%s: %s", po.Namespace, po.Name, err)
+	}
+}
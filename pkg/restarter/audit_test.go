@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func readAuditRecords(t *testing.T, path string) []AuditRecord {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("error opening audit log: %v", err)
+	}
+	defer f.Close()
+	var records []AuditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("malformed audit line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestDecisionsAreAudited(t *testing.T) {
+	path := filepath.Join(newTempDir(t), "audit.jsonl")
+	auditLog, err := NewAuditLog(path, 0)
+	if err != nil {
+		t.Fatalf("error opening audit log: %v", err)
+	}
+	defer auditLog.Close()
+	isController := true
+	pod := newPodInCrashloop("pod-0", nil)
+	pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "etcd", Controller: &isController}}
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	dryRun := true
+	c := &Controller{
+		clientset:    fake.NewSimpleClientset(pod),
+		clock:        clock.NewFakeClock(now),
+		FieldManager: defaultFieldManager,
+		AuditLog:     auditLog,
+	}
+
+	if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+		t.Fatalf("error deleting pod: %v", err)
+	}
+	if err := c.deletePod(pod, "kube-apiserver"); err == nil {
+		t.Fatalf("Expected an error deleting the pod again")
+	}
+	c.serviceDependants = &api.ServiceDependants{Options: api.Options{DryRun: &dryRun}}
+	if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+		t.Fatalf("error deleting pod in dry run: %v", err)
+	}
+
+	records := readAuditRecords(t, path)
+	outcomes := []string{AuditOutcomeSucceeded, AuditOutcomeFailed, AuditOutcomeDryRun}
+	if len(records) != len(outcomes) {
+		t.Fatalf("Expected %d audit records but got %d", len(outcomes), len(records))
+	}
+	for i, r := range records {
+		expected := AuditRecord{
+			Timestamp: now,
+			Actor:     "dependency-watchdog",
+			Namespace: "default",
+			Pod:       "pod-0",
+			Owner:     "StatefulSet/etcd",
+			Service:   "kube-apiserver",
+			Reason:    ReasonCrashLoopBackOff,
+			Action:    "delete",
+			Outcome:   outcomes[i],
+		}
+		r.Timestamp = r.Timestamp.UTC()
+		if r != expected {
+			t.Errorf("Expected audit record %+v but got %+v", expected, r)
+		}
+	}
+}
+
+func TestAuditLogRotation(t *testing.T) {
+	record := &AuditRecord{Pod: "pod-0", Reason: ReasonCrashLoopBackOff, Outcome: AuditOutcomeSucceeded}
+	line, err := json.Marshal(record)
+	if err != nil {
+		t.Fatalf("error encoding audit record: %v", err)
+	}
+	maxSize := 2 * int64(len(line)+1)
+	path := filepath.Join(newTempDir(t), "audit.jsonl")
+	auditLog, err := NewAuditLog(path, maxSize)
+	if err != nil {
+		t.Fatalf("error opening audit log: %v", err)
+	}
+	defer auditLog.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := auditLog.Write(record); err != nil {
+			t.Fatalf("error writing audit record: %v", err)
+		}
+	}
+	if rotated, current := len(readAuditRecords(t, path+".1")), len(readAuditRecords(t, path)); rotated != 2 || current != 1 {
+		t.Errorf("Expected 2 rotated and 1 current audit records but got %d and %d", rotated, current)
+	}
+}
@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog"
+)
+
+// recycleBudgets counts the pods recycled per top-level owner during the reconcile of a service, so that the
+// availability floor of the owner is honoured per reconcile. Reconciles are identified by the <namespace>/<service> key.
+type recycleBudgets struct {
+	mux         sync.Mutex
+	reconciles  map[string]map[string]int
+	generations map[string]uint64
+}
+
+func newRecycleBudgets() *recycleBudgets {
+	return &recycleBudgets{reconciles: make(map[string]map[string]int), generations: make(map[string]uint64)}
+}
+
+// start resets the recycled pods of the reconcile of the given generation identified by key.
+func (b *recycleBudgets) start(key string, generation uint64) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.reconciles[key] = make(map[string]int)
+	b.generations[key] = generation
+}
+
+// take consumes one pod of the budget of the owner. It returns false if the budget is exhausted.
+func (b *recycleBudgets) take(key string, owner *v1.ObjectReference, budget int) bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	recycled, ok := b.reconciles[key]
+	if !ok {
+		recycled = make(map[string]int)
+		b.reconciles[key] = recycled
+	}
+	ownerKey := owner.Kind + "/" + owner.Name
+	if recycled[ownerKey] >= budget {
+		return false
+	}
+	recycled[ownerKey]++
+	return true
+}
+
+// release returns one pod to the budget of the owner once its replacement is available.
+func (b *recycleBudgets) release(key string, owner *v1.ObjectReference) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	ownerKey := owner.Kind + "/" + owner.Name
+	if recycled := b.reconciles[key]; recycled[ownerKey] > 0 {
+		recycled[ownerKey]--
+	}
+}
+
+// finish forgets the recycled pods of the reconcile of the given generation identified by key, unless it was
+// superseded by another reconcile of the service.
+func (b *recycleBudgets) finish(key string, generation uint64) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.generations[key] != generation {
+		return
+	}
+	delete(b.reconciles, key)
+	delete(b.generations, key)
+}
+
+// GetMaxUnavailableReplicas returns the number of the desired replicas which may be recycled in a single reconcile
+// according to the availability floor of the dependant pods.
+func GetMaxUnavailableReplicas(depPods *api.DependantPods, replicas int32) int {
+	maxUnavailable := int(replicas)
+	if depPods.MinHealthyReplicas != nil && int(replicas-*depPods.MinHealthyReplicas) < maxUnavailable {
+		maxUnavailable = int(replicas - *depPods.MinHealthyReplicas)
+	}
+	if depPods.MaxUnavailableFraction != nil {
+		if n := int(math.Floor(float64(replicas) * *depPods.MaxUnavailableFraction)); n < maxUnavailable {
+			maxUnavailable = n
+		}
+	}
+	if maxUnavailable < 0 {
+		return 0
+	}
+	return maxUnavailable
+}
+
+// getMaxDeletionsPerOwner returns the number of pods of a single owner which may be recycled in a single reconcile.
+func getMaxDeletionsPerOwner(depPods *api.DependantPods) int {
+	if depPods.MaxConcurrentDeletionsPerOwner == nil {
+		return defaultMaxDeletionsPerOwner
+	}
+	return int(*depPods.MaxConcurrentDeletionsPerOwner)
+}
+
+// reserveRecycleBudget checks if the pod may be recycled without exceeding the concurrent deletions per owner or
+// violating the availability floor of its top-level owner and, if so, accounts for it in the current reconcile of
+// the service.
+func (c *Controller) reserveRecycleBudget(ctx context.Context, po *v1.Pod, service string, depPods *api.DependantPods) (bool, error) {
+	if c.recycleBudgets == nil {
+		return true, nil
+	}
+	owner, err := c.getTopLevelOwner(ctx, po)
+	if err != nil {
+		return false, err
+	}
+	if owner == nil {
+		return true, nil
+	}
+	budget := getMaxDeletionsPerOwner(depPods)
+	if depPods.MinHealthyReplicas != nil || depPods.MaxUnavailableFraction != nil {
+		replicas, err := c.getDesiredReplicas(owner)
+		if err != nil {
+			return false, err
+		}
+		if maxUnavailable := GetMaxUnavailableReplicas(depPods, replicas); maxUnavailable < budget {
+			if !c.recycleBudgets.take(po.Namespace+"/"+service, owner, maxUnavailable) {
+				klog.Infof("Recycling pod %s would violate the availability floor of %s %s with %d desired replicas. Skipping pod deletion.",
+					po.Name, owner.Kind, owner.Name, replicas)
+				return false, nil
+			}
+			return true, nil
+		}
+	}
+	if !c.recycleBudgets.take(po.Namespace+"/"+service, owner, budget) {
+		klog.Infof("Recycling pod %s would exceed the %d concurrent deletions of %s %s. Skipping pod deletion.",
+			po.Name, budget, owner.Kind, owner.Name)
+		return false, nil
+	}
+	return true, nil
+}
+
+// releaseRecycleBudget returns the recycled pod to the budget of its top-level owner in the current reconcile of
+// the service once its replacement is available.
+func (c *Controller) releaseRecycleBudget(ctx context.Context, po *v1.Pod, service string) error {
+	if c.recycleBudgets == nil {
+		return nil
+	}
+	owner, err := c.getTopLevelOwner(ctx, po)
+	if err != nil || owner == nil {
+		return err
+	}
+	c.recycleBudgets.release(po.Namespace+"/"+service, owner)
+	return nil
+}
+
+// getDesiredReplicas returns the desired replica count of the owner from its spec.
+func (c *Controller) getDesiredReplicas(owner *v1.ObjectReference) (int32, error) {
+	if c.dynamicClient == nil {
+		return 0, fmt.Errorf("no dynamic client configured to get %s", owner.Name)
+	}
+	gvr, err := getOwnerGVR(owner)
+	if err != nil {
+		return 0, err
+	}
+	obj, err := c.dynamicClient.Resource(gvr).Namespace(owner.Namespace).Get(owner.Name, metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("error getting %s %s: %v", owner.Kind, owner.Name, err)
+	}
+	replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		// The API server defaults the replicas to one.
+		return 1, nil
+	}
+	return int32(replicas), nil
+}
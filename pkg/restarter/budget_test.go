@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetMaxUnavailableReplicas(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+	float64Ptr := func(f float64) *float64 { return &f }
+	tests := []struct {
+		depPods  api.DependantPods
+		replicas int32
+		expected int
+	}{
+		{api.DependantPods{MaxUnavailableFraction: float64Ptr(0.4)}, 5, 2},
+		{api.DependantPods{MaxUnavailableFraction: float64Ptr(0.4)}, 1, 0},
+		{api.DependantPods{MinHealthyReplicas: int32Ptr(2)}, 5, 3},
+		{api.DependantPods{MinHealthyReplicas: int32Ptr(6)}, 5, 0},
+		{api.DependantPods{MinHealthyReplicas: int32Ptr(2), MaxUnavailableFraction: float64Ptr(0.4)}, 5, 2},
+		{api.DependantPods{MinHealthyReplicas: int32Ptr(4), MaxUnavailableFraction: float64Ptr(0.4)}, 5, 1},
+	}
+	for _, tc := range tests {
+		if actual := GetMaxUnavailableReplicas(&tc.depPods, tc.replicas); actual != tc.expected {
+			t.Errorf("Expected %d unavailable replicas of %d for %+v but got %d", tc.expected, tc.replicas, tc.depPods, actual)
+		}
+	}
+}
+
+func TestMaxUnavailableFractionBoundsRecyclingPerReconcile(t *testing.T) {
+	isController := true
+	deployment := &unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+	deployment.SetNamespace(metav1.NamespaceDefault)
+	deployment.SetName("kube-controller-manager")
+	if err := unstructured.SetNestedField(deployment.Object, int64(5), "spec", "replicas"); err != nil {
+		t.Fatalf("error setting replicas: %v", err)
+	}
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       metav1.NamespaceDefault,
+			Name:            "kube-controller-manager-5d8f",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "kube-controller-manager", Controller: &isController}},
+		},
+	}
+	labels := map[string]string{"role": "controlplane"}
+	objects := []runtime.Object{rs}
+	for i := 0; i < 5; i++ {
+		pod := newPodInCrashloop(fmt.Sprintf("pod-%d", i), labels)
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: rs.Name, Controller: &isController}}
+		objects = append(objects, pod)
+	}
+	maxUnavailable := 0.4
+	maxDeletions := int32(5)
+	depPods := &api.DependantPods{
+		Selector:                       &metav1.LabelSelector{MatchLabels: labels},
+		MaxUnavailableFraction:         &maxUnavailable,
+		MaxConcurrentDeletionsPerOwner: &maxDeletions,
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	client := fake.NewSimpleClientset(append(objects, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))...)
+	c := &Controller{
+		clientset:      client,
+		dynamicClient:  dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment),
+		recycleBudgets: newRecycleBudgets(),
+	}
+
+	listPods := func() []v1.Pod {
+		pl, err := client.CoreV1().Pods(metav1.NamespaceDefault).List(metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("error listing pods: %v", err)
+		}
+		return pl.Items
+	}
+	for reconcile, expected := range []int{3, 1} {
+		generation := nextReconcileGeneration()
+		c.recycleBudgets.start("default/kube-apiserver", generation)
+		for _, pod := range listPods() {
+			if err := c.processPod(context.TODO(), &pod, "kube-apiserver", depPods, selector); err != nil {
+				t.Fatalf("reconcile %d: error processing pod %s: %v", reconcile, pod.Name, err)
+			}
+		}
+		c.recycleBudgets.finish("default/kube-apiserver", generation)
+		if remaining := len(listPods()); remaining != expected {
+			t.Errorf("reconcile %d: expected %d remaining pods but got %d", reconcile, expected, remaining)
+		}
+	}
+}
+
+func TestMaxConcurrentDeletionsPerOwner(t *testing.T) {
+	labels := map[string]string{"role": "etcd"}
+	objects := []runtime.Object{newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)}
+	for i := 0; i < 5; i++ {
+		pod := newPodInCrashloop(fmt.Sprintf("etcd-%d", i), labels)
+		pod.OwnerReferences = newControllerRef("apps/v1", "StatefulSet", "etcd")
+		objects = append(objects, pod)
+	}
+	pod := newPodInCrashloop("kcm-0", labels)
+	pod.OwnerReferences = newControllerRef("apps/v1", "StatefulSet", "kube-controller-manager")
+	objects = append(objects, pod)
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	client := fake.NewSimpleClientset(objects...)
+	c := &Controller{clientset: client, recycleBudgets: newRecycleBudgets()}
+
+	listPods := func() []v1.Pod {
+		pl, err := client.CoreV1().Pods(metav1.NamespaceDefault).List(metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			t.Fatalf("error listing pods: %v", err)
+		}
+		return pl.Items
+	}
+	// Every reconcile deletes one pod of each owner, i.e. one of the etcd pods and, in the first one, the
+	// pod of the other owner.
+	for reconcile, expected := range []int{4, 3, 2} {
+		generation := nextReconcileGeneration()
+		c.recycleBudgets.start("default/kube-apiserver", generation)
+		for _, pod := range listPods() {
+			if err := c.processPod(context.TODO(), &pod, "kube-apiserver", depPods, selector); err != nil {
+				t.Fatalf("reconcile %d: error processing pod %s: %v", reconcile, pod.Name, err)
+			}
+		}
+		c.recycleBudgets.finish("default/kube-apiserver", generation)
+		if remaining := len(listPods()); remaining != expected {
+			t.Errorf("reconcile %d: expected %d remaining pods but got %d", reconcile, expected, remaining)
+		}
+	}
+}
+
+func TestOverlappingReconcilesKeepTheirState(t *testing.T) {
+	key := "default/kube-apiserver"
+	owner := &v1.ObjectReference{Kind: "StatefulSet", Name: "etcd"}
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	budgets := newRecycleBudgets()
+	events := newOwnerEvents()
+	retries := newRetryBudgets()
+	cooldowns := newServiceCooldowns()
+	summaries := newReconcileSummaries()
+	cooldowns.record(key, now)
+	srv := api.Service{ActionCooldown: &metav1.Duration{Duration: time.Hour}}
+
+	// The first reconcile is superseded by the second one and finishes late.
+	superseded, active := nextReconcileGeneration(), nextReconcileGeneration()
+	budgets.start(key, superseded)
+	events.start(key, superseded)
+	events.add(key, owner, "etcd-0")
+	retries.start(key, superseded)
+	cooldowns.start(key, superseded)
+	summaries.start(metav1.NamespaceDefault, "kube-apiserver", superseded, 1, now)
+
+	budgets.start(key, active)
+	events.start(key, active)
+	retries.start(key, active)
+	cooldowns.start(key, active)
+	summaries.start(metav1.NamespaceDefault, "kube-apiserver", active, 1, now)
+	if !budgets.take(key, owner, 1) || !retries.take(key, 1) {
+		t.Fatalf("Expected the budgets of the active reconcile to be available")
+	}
+
+	budgets.finish(key, superseded)
+	if evs := events.finish(key, superseded); len(evs) != 0 {
+		t.Errorf("Expected no events for the superseded reconcile but got %d", len(evs))
+	}
+	retries.finish(key, superseded)
+	cooldowns.finish(key, superseded)
+	if _, ok := summaries.finish(metav1.NamespaceDefault, "kube-apiserver", superseded, now); ok {
+		t.Errorf("Expected no summary for the superseded reconcile")
+	}
+
+	if budgets.take(key, owner, 1) {
+		t.Errorf("Expected the recycle budget of the active reconcile to be kept")
+	}
+	if !retries.exhausted(key, 1) {
+		t.Errorf("Expected the retry budget of the active reconcile to be kept")
+	}
+	if _, ok := cooldowns.coolingDownUntil(key, srv.ActionCooldown, now); !ok {
+		t.Errorf("Expected the cooldown of the active reconcile to be kept")
+	}
+	events.add(key, owner, "etcd-1")
+	if evs := events.finish(key, active); len(evs) != 1 || len(evs[0].pods) != 2 {
+		t.Errorf("Expected the active reconcile to record the events of both reconciles but got %v", evs)
+	}
+	if _, ok := summaries.finish(metav1.NamespaceDefault, "kube-apiserver", active, now); !ok {
+		t.Errorf("Expected a summary for the active reconcile")
+	}
+}
@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+)
+
+// ReloadCandidateConfig replaces the candidate config with the one returned by load. The candidate config is
+// verified like the active config on startup and kept if load fails or it is invalid.
+func (c *Controller) ReloadCandidateConfig(load func() (*api.ServiceDependants, error)) error {
+	deps, err := load()
+	if err == nil {
+		err = CheckServiceDependants(deps)
+	}
+	if err != nil {
+		return fmt.Errorf("error loading candidate config: %v", err)
+	}
+	c.configLock.Lock()
+	c.CandidateConfig = deps
+	c.configLock.Unlock()
+	return nil
+}
+
+func (c *Controller) getCandidateConfig() *api.ServiceDependants {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+	return c.CandidateConfig
+}
+
+// compareCandidateDecision compares the decision of the active config on the dependant pod of the service with the
+// decision the candidate config would take on the same pod. Differences are logged and counted but never acted upon.
+func (c *Controller) compareCandidateDecision(ctx context.Context, po *v1.Pod, service string, active *Decision) {
+	candidate := c.getCandidateConfig()
+	if candidate == nil {
+		return
+	}
+	decision, err := c.evaluateDecision(ctx, candidate, po, service)
+	if err != nil {
+		klog.Errorf("Error evaluating the decision of the candidate config on pod %s/%s: %s", po.Namespace, po.Name, err)
+		return
+	}
+	if isSameDecision(active, decision) {
+		return
+	}
+	candidateDecisionDiffTotal.With(prometheus.Labels{labelNamespace: po.Namespace, labelService: service}).Inc()
+	klog.Infof("Candidate config decides on pod %s/%s: %s, active config: %s",
+		po.Namespace, po.Name, describeDecision(decision), describeDecision(active))
+}
+
+// compareCandidateGroupDecision compares the decisions on the dependant pod of the service whose pods are recycled
+// together. The active config takes no decision on the pod itself, so the pod is checked for the comparison.
+func (c *Controller) compareCandidateGroupDecision(ctx context.Context, deps *api.ServiceDependants, po *v1.Pod, service string, depPods *api.DependantPods, selector labels.Selector) {
+	if c.getCandidateConfig() == nil {
+		return
+	}
+	check, err := c.checkPod(ctx, deps, po, service, depPods, selector)
+	if err != nil {
+		klog.Errorf("Error evaluating the decision of the active config on pod %s/%s: %s", po.Namespace, po.Name, err)
+		return
+	}
+	c.compareCandidateDecision(ctx, po, service, c.getCheckedDecision(deps, po, depPods, check))
+}
+
+// compareCandidateSelections compares the decisions on the pods of the dependants of the service in the candidate
+// config which none of the dependants of the service in the active config selects, so that they are never compared
+// while the active config processes its pods.
+func (c *Controller) compareCandidateSelections(ctx context.Context, namespace, service string) {
+	candidate := c.getCandidateConfig()
+	if candidate == nil {
+		return
+	}
+	active := c.getServiceDependants()
+	srv := candidate.Services[service]
+	compared := sets.NewString()
+	for i := range srv.Dependants {
+		depPods := &srv.Dependants[i]
+		selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+		if err != nil {
+			klog.Errorf("Error converting label selector of candidate dependant %s: %s", depPods.Name, err)
+			continue
+		}
+		pl, err := c.clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			klog.Errorf("Error listing pods of candidate dependant %s: %s", depPods.Name, err)
+			continue
+		}
+		for _, po := range selectDependantPods(pl.Items, depPods) {
+			if compared.Has(po.Name) {
+				continue
+			}
+			compared.Insert(po.Name)
+			if selected, _, _ := getSelectingDependant(active, &po, service); selected != nil {
+				continue
+			}
+			c.compareCandidateDecision(ctx, &po, service, nil)
+		}
+	}
+}
+
+// isSameDecision checks if the decisions are the same. Skipping a pod is the same as not selecting it.
+func isSameDecision(a, b *Decision) bool {
+	switch {
+	case a == nil && b == nil:
+		return true
+	case a == nil:
+		return b.Action == DecisionActionSkipped
+	case b == nil:
+		return a.Action == DecisionActionSkipped
+	}
+	return *a == *b
+}
+
+func describeDecision(d *Decision) string {
+	if d == nil {
+		return "not selected"
+	}
+	return d.Action + " (" + d.Reason + ")"
+}
+
+// getSelectingDependant returns the first dependant of the service in deps selecting the pod, if any, and its selector.
+func getSelectingDependant(deps *api.ServiceDependants, po *v1.Pod, service string) (*api.DependantPods, labels.Selector, error) {
+	if deps == nil {
+		return nil, nil, nil
+	}
+	srv := deps.Services[service]
+	for i := range srv.Dependants {
+		depPods := &srv.Dependants[i]
+		selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error converting label selector of dependant %s: %v", depPods.Name, err)
+		}
+		if selector.Matches(labels.Set(po.Labels)) && isDependantPod(po, depPods) {
+			return depPods, selector, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// evaluateDecision evaluates the decision deps would take on the dependant pod of the service without acting on it.
+// It takes the decision of decidePod, assuming the deletions are due right away and not limited by the budgets of the
+// reconcile. It returns nil if no dependant of the service in deps selects the pod.
+func (c *Controller) evaluateDecision(ctx context.Context, deps *api.ServiceDependants, po *v1.Pod, service string) (*Decision, error) {
+	depPods, selector, err := getSelectingDependant(deps, po, service)
+	if err != nil || depPods == nil {
+		return nil, err
+	}
+	if skip, _ := c.checkService(deps, po.Namespace, service); skip != nil {
+		return skip, nil
+	}
+	check, err := c.checkPod(ctx, deps, po, service, depPods, selector)
+	if err != nil {
+		return nil, err
+	}
+	return c.getCheckedDecision(deps, po, depPods, check), nil
+}
@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCandidateConfigIsOnlyCompared(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	selector := &metav1.LabelSelector{MatchLabels: labels}
+	active := api.DependantPods{
+		Selector:     selector,
+		RestartRules: []api.RestartRule{{RestartReasons: []string{"ImagePullBackOff"}}},
+	}
+	candidate := &api.ServiceDependants{
+		Namespace: metav1.NamespaceDefault,
+		Services: map[string]api.Service{
+			"kube-apiserver": {Dependants: []api.DependantPods{{Selector: selector}}},
+		},
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	pod := newPodInCrashloop("pod-0", labels)
+	client := fake.NewSimpleClientset(pod)
+	c := &Controller{
+		clientset:       client,
+		clock:           clock.NewFakeClock(time.Now()),
+		CandidateConfig: candidate,
+		serviceDependants: &api.ServiceDependants{
+			Namespace: metav1.NamespaceDefault,
+			Services:  map[string]api.Service{"kube-apiserver": {Dependants: []api.DependantPods{active}}},
+		},
+	}
+
+	diffs := candidateDecisionDiffTotal.With(prometheus.Labels{labelNamespace: "default", labelService: "kube-apiserver"})
+	before := testutil.ToFloat64(diffs)
+	if err := c.processPod(context.TODO(), pod, "kube-apiserver", &active, s); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	if got := testutil.ToFloat64(diffs); got != before+1 {
+		t.Errorf("Expected the decision diff to be counted but got %v", got-before)
+	}
+	// The comparison reuses the pod read to decide on it.
+	gets := 0
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "get" && action.GetResource().Resource == "pods" {
+			gets++
+		}
+	}
+	if gets != 1 {
+		t.Errorf("Expected the pod to be read once but got %d reads", gets)
+	}
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected the pod not to be deleted by the candidate config but got: %v", err)
+	}
+
+	// Matching decisions are not counted.
+	c.CandidateConfig = &api.ServiceDependants{}
+	if err := c.processPod(context.TODO(), pod, "kube-apiserver", &active, s); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	if got := testutil.ToFloat64(diffs); got != before+1 {
+		t.Errorf("Expected matching decisions not to be counted but got %v", got-before)
+	}
+}
+
+func TestCandidateDecisionComparesTheGuards(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	selector := &metav1.LabelSelector{MatchLabels: labels}
+	newConfig := func(srv api.Service) *api.ServiceDependants {
+		srv.Dependants = []api.DependantPods{{Selector: selector}}
+		return &api.ServiceDependants{Namespace: metav1.NamespaceDefault, Services: map[string]api.Service{"kube-apiserver": srv}}
+	}
+	pod := newPodInCrashloop("pod-0", labels)
+	fakeClock := clock.NewFakeClock(time.Now())
+	c := &Controller{
+		clientset:         fake.NewSimpleClientset(pod),
+		clock:             fakeClock,
+		serviceCooldowns:  newServiceCooldowns(),
+		serviceDependants: newConfig(api.Service{}),
+		CandidateConfig:   newConfig(api.Service{ActionCooldown: &metav1.Duration{Duration: time.Minute}}),
+	}
+	c.serviceCooldowns.record("default/kube-apiserver", fakeClock.Now())
+
+	got, err := c.evaluateDecision(context.TODO(), c.CandidateConfig, pod, "kube-apiserver")
+	if err != nil {
+		t.Fatalf("error evaluating decision: %v", err)
+	}
+	if *got != *skipped(DecisionReasonCooldown) {
+		t.Errorf("Expected the candidate config to cool down but got %+v", got)
+	}
+	diffs := candidateDecisionDiffTotal.With(prometheus.Labels{labelNamespace: "default", labelService: "kube-apiserver"})
+	before := testutil.ToFloat64(diffs)
+	active, err := c.evaluateDecision(context.TODO(), c.serviceDependants, pod, "kube-apiserver")
+	if err != nil {
+		t.Fatalf("error evaluating decision: %v", err)
+	}
+	c.compareCandidateDecision(context.TODO(), pod, "kube-apiserver", active)
+	if got := testutil.ToFloat64(diffs); got != before+1 {
+		t.Errorf("Expected the differing cooldown to be counted but got %v", got-before)
+	}
+}
+
+func TestCandidateSelectionsAreCompared(t *testing.T) {
+	selected := map[string]string{"role": "controlplane"}
+	candidateOnly := map[string]string{"role": "addon"}
+	newConfig := func(labels ...map[string]string) *api.ServiceDependants {
+		var dependants []api.DependantPods
+		for _, l := range labels {
+			dependants = append(dependants, api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: l}})
+		}
+		return &api.ServiceDependants{
+			Namespace: metav1.NamespaceDefault,
+			Services:  map[string]api.Service{"kube-apiserver": {Dependants: dependants}},
+		}
+	}
+	c := &Controller{
+		clientset:         fake.NewSimpleClientset(newPodInCrashloop("pod-0", selected), newPodInCrashloop("pod-1", candidateOnly)),
+		clock:             clock.NewFakeClock(time.Now()),
+		serviceDependants: newConfig(selected),
+		CandidateConfig:   newConfig(selected, candidateOnly),
+	}
+
+	diffs := candidateDecisionDiffTotal.With(prometheus.Labels{labelNamespace: "default", labelService: "kube-apiserver"})
+	before := testutil.ToFloat64(diffs)
+	c.compareCandidateSelections(context.TODO(), metav1.NamespaceDefault, "kube-apiserver")
+	if got := testutil.ToFloat64(diffs); got != before+1 {
+		t.Errorf("Expected only the pod selected by the candidate config alone to be compared but got %v diffs", got-before)
+	}
+}
+
+func TestReloadCandidateConfigChecksTheConfig(t *testing.T) {
+	c := &Controller{}
+	valid := &api.ServiceDependants{Namespace: metav1.NamespaceDefault}
+	if err := c.ReloadCandidateConfig(func() (*api.ServiceDependants, error) { return valid, nil }); err != nil {
+		t.Fatalf("error reloading candidate config: %v", err)
+	}
+	invalid := &api.ServiceDependants{Namespace: metav1.NamespaceDefault, Options: api.Options{QuietHours: &api.QuietHours{Timezone: "Mars/Olympus"}}}
+	if err := c.ReloadCandidateConfig(func() (*api.ServiceDependants, error) { return invalid, nil }); err == nil {
+		t.Errorf("Expected the invalid candidate config to be rejected")
+	}
+	if c.getCandidateConfig() != valid {
+		t.Errorf("Expected the valid candidate config to be kept but got %+v", c.getCandidateConfig())
+	}
+}
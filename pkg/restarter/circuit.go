@@ -0,0 +1,37 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+)
+
+// isCircuitOpen checks if too many namespaces are degraded at once according to the circuit breaker, if any.
+func (c *Controller) isCircuitOpen() bool {
+	deps := c.getServiceDependants()
+	if deps == nil || deps.CircuitBreaker == nil || c.flapDetector == nil {
+		return false
+	}
+	degraded, total := c.flapDetector.DegradedNamespaces()
+	return IsCircuitOpen(*deps.CircuitBreaker, degraded, total)
+}
+
+// setCircuitOpenMetric exposes the state of the circuit as a metric.
+func setCircuitOpenMetric(open bool) {
+	if open {
+		circuitOpen.Set(1)
+	} else {
+		circuitOpen.Set(0)
+	}
+}
+
+// IsCircuitOpen checks if the number of degraded namespaces out of the total number of namespaces exceeds one of the
+// thresholds of the circuit breaker.
+func IsCircuitOpen(cb api.CircuitBreaker, degraded, total int) bool {
+	if cb.MaxDegradedNamespaces != nil && degraded > int(*cb.MaxDegradedNamespaces) {
+		return true
+	}
+	return cb.MaxDegradedFraction != nil && total > 0 && float64(degraded)/float64(total) > *cb.MaxDegradedFraction
+}
@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	maxDegraded := int32(2)
+	maxFraction := 0.5
+	deps := &api.ServiceDependants{
+		CircuitBreaker: &api.CircuitBreaker{MaxDegradedNamespaces: &maxDegraded, MaxDegradedFraction: &maxFraction},
+	}
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	c := &Controller{serviceDependants: deps, clock: fakeClock, flapDetector: NewFlapDetector(fakeClock)}
+
+	recordReadiness := func(from, namespaces int, ready bool) {
+		for i := from; i < from+namespaces; i++ {
+			c.flapDetector.RecordReadiness(fmt.Sprintf("shoot-%d/kube-apiserver", i), ready)
+		}
+	}
+	deleteDependantPod := func() bool {
+		pod := newPodInCrashloop("pod-0", nil)
+		c.clientset = fake.NewSimpleClientset(pod)
+		if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+			t.Fatalf("error deleting pod: %v", err)
+		}
+		_, err := c.clientset.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{})
+		return err != nil
+	}
+
+	tests := []struct {
+		name             string
+		ready            int
+		degraded         int
+		expectedOpen     bool
+		expectedDeletion bool
+	}{
+		{"all namespaces ready", 10, 0, false, true},
+		{"few namespaces degraded", 10, 2, false, true},
+		{"too many namespaces degraded", 10, 3, true, false},
+		{"half of the namespaces degraded", 2, 2, false, true},
+		{"majority of namespaces degraded", 1, 2, true, false},
+	}
+	for _, tc := range tests {
+		c.flapDetector = NewFlapDetector(fakeClock)
+		recordReadiness(0, tc.ready, true)
+		recordReadiness(tc.ready, tc.degraded, false)
+
+		if deleted := deleteDependantPod(); deleted != tc.expectedDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expectedDeletion, deleted)
+		}
+		expectedGauge := 0.0
+		if tc.expectedOpen {
+			expectedGauge = 1
+		}
+		if got := testutil.ToFloat64(circuitOpen); got != expectedGauge {
+			t.Errorf("%s: expected circuit gauge %v but got %v", tc.name, expectedGauge, got)
+		}
+	}
+
+	// The degraded namespaces of the last case recover, which closes the circuit again.
+	recordReadiness(1, 2, true)
+	if !deleteDependantPod() {
+		t.Errorf("recovered: expected the pod to be deleted")
+	}
+	if got := testutil.ToFloat64(circuitOpen); got != 0 {
+		t.Errorf("recovered: expected circuit gauge 0 but got %v", got)
+	}
+}
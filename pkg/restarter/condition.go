@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+)
+
+const (
+	// ConditionTypeRecovered is the type of the condition set in the status of the owners of the deleted pods.
+	ConditionTypeRecovered   = "DependencyWatchdogRecovered"
+	conditionReasonRecovered = "DeletedCrashloopingPods"
+)
+
+// reportRecoveredCondition sets the recovered condition in the status of the top-level owner of the pod.
+// It is best-effort, so failures are only logged.
+func (c *Controller) reportRecoveredCondition(ctx context.Context, po *v1.Pod, service string) {
+	owner, err := c.getTopLevelOwner(ctx, po)
+	if err != nil {
+		klog.Errorf("Error resolving the owner of pod %s/%s: %s", po.Namespace, po.Name, err)
+		return
+	}
+	if owner == nil {
+		return
+	}
+	err = c.setOwnerRecoveredCondition(owner, fmt.Sprintf("Deleted pod %s in CrashLoopBackOff after service %s became ready", po.Name, service))
+	if apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+		klog.V(4).Infof("%s %s/%s has no status subresource. Skipping recovered condition.", owner.Kind, owner.Namespace, owner.Name)
+		return
+	}
+	if err != nil {
+		klog.Errorf("Error setting the recovered condition of %s %s/%s: %s", owner.Kind, owner.Namespace, owner.Name, err)
+	}
+}
+
+// setOwnerRecoveredCondition adds or replaces the recovered condition in the status of the owner through its
+// status subresource. The update is retried on conflicts, so that concurrent changes of the status are kept.
+func (c *Controller) setOwnerRecoveredCondition(owner *v1.ObjectReference, message string) error {
+	if c.dynamicClient == nil {
+		return fmt.Errorf("no dynamic client configured to update %s", owner.Name)
+	}
+	gvr, err := getOwnerGVR(owner)
+	if err != nil {
+		return err
+	}
+	client := c.dynamicClient.Resource(gvr).Namespace(owner.Namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := client.Get(owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		conditions, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		if err != nil {
+			return err
+		}
+		condition := map[string]interface{}{
+			"type":               ConditionTypeRecovered,
+			"status":             string(v1.ConditionTrue),
+			"reason":             conditionReasonRecovered,
+			"message":            message,
+			"lastTransitionTime": c.clock.Now().UTC().Format(time.RFC3339),
+		}
+		replaced := false
+		for i := range conditions {
+			if existing, ok := conditions[i].(map[string]interface{}); ok && existing["type"] == ConditionTypeRecovered {
+				conditions[i] = condition
+				replaced = true
+			}
+		}
+		if !replaced {
+			conditions = append(conditions, condition)
+		}
+		if err := unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions"); err != nil {
+			return err
+		}
+		_, err = client.UpdateStatus(obj, metav1.UpdateOptions{FieldManager: c.FieldManager})
+		return err
+	})
+}
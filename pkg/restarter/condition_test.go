@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeletePodReportsRecoveredCondition(t *testing.T) {
+	isController := true
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	deployment := &unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+	deployment.SetNamespace(metav1.NamespaceDefault)
+	deployment.SetName("kube-controller-manager")
+	other := map[string]interface{}{"type": "Available", "status": "True"}
+	if err := unstructured.SetNestedSlice(deployment.Object, []interface{}{other}, "status", "conditions"); err != nil {
+		t.Fatalf("error setting conditions: %v", err)
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment)
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	report := true
+
+	for i := 0; i < 2; i++ {
+		pod := newPodInCrashloop("pod-0", nil)
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "kube-controller-manager", Controller: &isController}}
+		c := &Controller{
+			clientset:         fake.NewSimpleClientset(pod),
+			dynamicClient:     dynamicClient,
+			clock:             clock.NewFakeClock(now),
+			serviceDependants: &api.ServiceDependants{Options: api.Options{ReportRecoveredCondition: &report}},
+		}
+		if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+			t.Fatalf("intervention %d: error deleting pod: %v", i, err)
+		}
+	}
+
+	d, err := dynamicClient.Resource(gvr).Namespace(metav1.NamespaceDefault).Get("kube-controller-manager", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error fetching deployment: %v", err)
+	}
+	conditions, _, err := unstructured.NestedSlice(d.Object, "status", "conditions")
+	if err != nil {
+		t.Fatalf("error reading conditions: %v", err)
+	}
+	if len(conditions) != 2 {
+		t.Fatalf("Expected the recovered condition to be added once besides the existing one but got %v", conditions)
+	}
+	condition := conditions[1].(map[string]interface{})
+	if condition["type"] != ConditionTypeRecovered || condition["status"] != "True" || condition["lastTransitionTime"] != "2021-06-01T12:00:00Z" {
+		t.Errorf("Unexpected recovered condition %v", condition)
+	}
+}
+
+func TestRecoveredConditionFailureDoesNotBlockDeletion(t *testing.T) {
+	isController := true
+	pod := newPodInCrashloop("pod-0", nil)
+	pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "missing", Controller: &isController}}
+	client := fake.NewSimpleClientset(pod)
+	report := true
+	c := &Controller{
+		clientset:         client,
+		dynamicClient:     dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+		clock:             clock.RealClock{},
+		serviceDependants: &api.ServiceDependants{Options: api.Options{ReportRecoveredCondition: &report}},
+	}
+
+	if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+		t.Fatalf("error deleting pod: %v", err)
+	}
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected the pod to be deleted despite the missing owner")
+	}
+}
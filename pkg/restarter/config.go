@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"k8s.io/klog"
+)
+
+const redacted = "REDACTED"
+
+// RedactServiceDependants returns a copy of the service dependants without the secrets, i.e. the webhook secret
+// and everything of the webhook URL but its scheme and host, so that the config can be logged or served.
+func RedactServiceDependants(deps *api.ServiceDependants) *api.ServiceDependants {
+	out := *deps
+	if deps.Webhook != nil {
+		webhook := *deps.Webhook
+		if webhook.Secret != "" {
+			webhook.Secret = redacted
+		}
+		webhook.URL = redactURL(webhook.URL)
+		out.Webhook = &webhook
+	}
+	return &out
+}
+
+// redactURL keeps only the scheme and the host of the URL, as the user info, the path, the query and the fragment
+// may all carry tokens. URLs which cannot be parsed are redacted completely.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		if rawURL == "" {
+			return ""
+		}
+		return redacted
+	}
+	redactedURL := &url.URL{Scheme: u.Scheme, Host: u.Host}
+	if u.User != nil || u.Opaque != "" || u.RawQuery != "" || u.Fragment != "" || (u.Path != "" && u.Path != "/") {
+		redactedURL.Path = "/" + redacted
+	}
+	return redactedURL.String()
+}
+
+// ConfigHandler returns an HTTP handler which serves the active config with the secrets redacted. The config is
+// served as YAML if the request accepts it and as JSON otherwise.
+func (c *Controller) ConfigHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		deps := RedactServiceDependants(c.getServiceDependants())
+		if !acceptsYAML(r) {
+			writeJSON(w, deps)
+			return
+		}
+		data, err := api.Encode(deps)
+		if err != nil {
+			klog.Errorf("Error encoding config: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		if _, err := w.Write([]byte(data)); err != nil {
+			klog.Errorf("Error writing response: %s", err)
+		}
+	})
+}
+
+// acceptsYAML checks if the request accepts YAML.
+func acceptsYAML(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/yaml", "application/x-yaml", "text/yaml":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+const deletionPollInterval = time.Second
+
+// waitForPodDeletion polls the deleted pod until the apiserver confirms its deletion, i.e. the pod is terminating,
+// gone or replaced by a pod of the same name, or the timeout expires. It returns false if the deletion was not
+// confirmed in time.
+func (c *Controller) waitForPodDeletion(po *v1.Pod, service string, timeout time.Duration) bool {
+	deadline := c.clock.Now().Add(timeout)
+	for {
+		current, err := c.clientset.CoreV1().Pods(po.Namespace).Get(po.Name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			return true
+		case err != nil:
+			klog.Errorf("Error getting pod %s/%s to confirm its deletion: %s", po.Namespace, po.Name, err)
+		case current.UID != po.UID || IsPodDeleted(current):
+			return true
+		}
+		if !c.clock.Now().Before(deadline) {
+			klog.Warningf("Deletion of pod %s/%s was not confirmed within %s.", po.Namespace, po.Name, timeout)
+			deletionWaitTimeoutsTotal.With(prometheus.Labels{labelNamespace: po.Namespace, labelService: service}).Inc()
+			return false
+		}
+		select {
+		case <-c.clock.After(deletionPollInterval):
+		case <-c.stopCh:
+			return false
+		}
+	}
+}
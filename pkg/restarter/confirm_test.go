@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestWaitForDeletion(t *testing.T) {
+	deps := &api.ServiceDependants{
+		Options: api.Options{WaitForDeletion: &metav1.Duration{Duration: 5 * time.Second}},
+	}
+	tests := []struct {
+		name             string
+		goneAfterPolls   int
+		expectedPolls    int
+		expectedTimeouts float64
+	}{
+		{"removed after one poll", 1, 2, 0},
+		{"never removed", -1, 6, 1},
+	}
+	for _, tc := range tests {
+		service := "kube-apiserver-" + tc.name
+		pod := newPodInCrashloop("pod-0", nil)
+		client := fake.NewSimpleClientset(pod)
+		// The deletion is accepted, but the pod only disappears once it has been polled as often as configured.
+		client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, nil
+		})
+		polls := 0
+		client.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			polls++
+			if tc.goneAfterPolls >= 0 && polls > tc.goneAfterPolls {
+				return true, nil, apierrors.NewNotFound(v1.Resource("pods"), pod.Name)
+			}
+			return true, pod, nil
+		})
+		fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+		c := &Controller{clientset: client, clock: fakeClock, serviceDependants: deps}
+
+		done := make(chan error)
+		go func() {
+			done <- c.deletePod(pod, service)
+		}()
+		var err error
+	wait:
+		for {
+			select {
+			case err = <-done:
+				break wait
+			default:
+			}
+			if fakeClock.HasWaiters() {
+				fakeClock.Step(deletionPollInterval)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("%s: error deleting pod: %v", tc.name, err)
+		}
+
+		if polls != tc.expectedPolls {
+			t.Errorf("%s: expected %d polls but got %d", tc.name, tc.expectedPolls, polls)
+		}
+		timeouts := testutil.ToFloat64(deletionWaitTimeoutsTotal.With(prometheus.Labels{labelNamespace: pod.Namespace, labelService: service}))
+		if timeouts != tc.expectedTimeouts {
+			t.Errorf("%s: expected %v timeouts but got %v", tc.name, tc.expectedTimeouts, timeouts)
+		}
+	}
+}
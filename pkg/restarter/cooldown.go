@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// serviceCooldowns tracks the last pod deletion per service and the last pod deletion before the active reconcile of
+// the service started. The cooldown of a reconcile is measured from the latter, so that the deletions of a reconcile
+// do not hold off the remaining deletions of the same reconcile. Services are identified by the <namespace>/<service>
+// key. Without tracked cooldowns, i.e. on a nil receiver, no service is cooling down.
+type serviceCooldowns struct {
+	mux           sync.Mutex
+	lastDeletions map[string]time.Time
+	since         map[string]time.Time
+	generations   map[string]uint64
+}
+
+func newServiceCooldowns() *serviceCooldowns {
+	return &serviceCooldowns{
+		lastDeletions: make(map[string]time.Time),
+		since:         make(map[string]time.Time),
+		generations:   make(map[string]uint64),
+	}
+}
+
+// start remembers the last pod deletion before the reconcile of the given generation of the service identified by
+// key started.
+func (s *serviceCooldowns) start(key string, generation uint64) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.since, key)
+	s.generations[key] = generation
+	if last, ok := s.lastDeletions[key]; ok {
+		s.since[key] = last
+	}
+}
+
+// coolingDownUntil returns the end of the given cooldown of the service identified by key if it did not pass at now.
+// The cooldown is measured from the last pod deletion before the active reconcile of the service started or, without
+// an active reconcile, from the last pod deletion.
+func (s *serviceCooldowns) coolingDownUntil(key string, cooldown *metav1.Duration, now time.Time) (time.Time, bool) {
+	if s == nil || cooldown == nil {
+		return time.Time{}, false
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	last, ok := s.lastDeletions[key]
+	if _, active := s.generations[key]; active {
+		last, ok = s.since[key]
+	}
+	if !ok {
+		return time.Time{}, false
+	}
+	until := last.Add(cooldown.Duration)
+	return until, until.After(now)
+}
+
+// record records a pod deletion for the service identified by key at the given time.
+func (s *serviceCooldowns) record(key string, t time.Time) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.lastDeletions[key] = t
+}
+
+// finish ends the reconcile of the given generation of the service identified by key, unless it was superseded by
+// another reconcile of the service.
+func (s *serviceCooldowns) finish(key string, generation uint64) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.generations[key] != generation {
+		return
+	}
+	delete(s.since, key)
+	delete(s.generations, key)
+}
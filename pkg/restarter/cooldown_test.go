@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServiceActionCooldown(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	cooldown := &metav1.Duration{Duration: 5 * time.Minute}
+	deps := &api.ServiceDependants{Services: map[string]api.Service{
+		"kube-apiserver": {ActionCooldown: cooldown},
+		"etcd-main":      {ActionCooldown: cooldown},
+	}}
+	client := fake.NewSimpleClientset(
+		newPodInCrashloop("pod-0", labels),
+		newPodInCrashloop("pod-1", labels),
+		newPodInCrashloop("pod-2", labels),
+		newPodInCrashloop("pod-3", labels),
+		newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil),
+		newEndpoint("etcd-main", metav1.NamespaceDefault, nil),
+	)
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	c := &Controller{clientset: client, clock: fakeClock, serviceDependants: deps, serviceCooldowns: newServiceCooldowns()}
+
+	reconcile := func(service string, pods ...string) {
+		key := metav1.NamespaceDefault + "/" + service
+		c.serviceCooldowns.start(key, 1)
+		defer c.serviceCooldowns.finish(key, 1)
+		for _, name := range pods {
+			pod, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get(name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting pod %s: %v", name, err)
+			}
+			if err := c.processPod(context.TODO(), pod, service, depPods, selector); err != nil {
+				t.Fatalf("error processing pod %s: %v", name, err)
+			}
+		}
+	}
+	isDeleted := func(name string) bool {
+		_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get(name, metav1.GetOptions{})
+		return err != nil
+	}
+
+	// All the pods of the first reconcile are deleted, regardless of the cooldown it starts.
+	reconcile("kube-apiserver", "pod-0", "pod-1")
+	if !isDeleted("pod-0") || !isDeleted("pod-1") {
+		t.Fatalf("Expected the pods of the first reconcile to be deleted")
+	}
+
+	fakeClock.Step(time.Minute)
+	reconcile("kube-apiserver", "pod-2")
+	if isDeleted("pod-2") {
+		t.Errorf("Expected no deletion while the service is cooling down")
+	}
+	reconcile("etcd-main", "pod-3")
+	if !isDeleted("pod-3") {
+		t.Errorf("Expected another service not to be affected by the cooldown")
+	}
+
+	fakeClock.Step(5 * time.Minute)
+	reconcile("kube-apiserver", "pod-2")
+	if !isDeleted("pod-2") {
+		t.Errorf("Expected the deletion once the cooldown is over")
+	}
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"k8s.io/klog"
+)
+
+// DebugDependencyPath is the path prefix of the DebugDependencyHandler, followed by <namespace>/<service>.
+const DebugDependencyPath = "/debug/dependency/"
+
+// ReadinessOverride is the readiness forced for a service through the DebugDependencyHandler.
+type ReadinessOverride struct {
+	Ready bool `json:"ready"`
+}
+
+// readinessOverrides holds the forced readiness of the services, keyed by <namespace>/<service>.
+type readinessOverrides struct {
+	mux   sync.RWMutex
+	ready map[string]bool
+}
+
+func newReadinessOverrides() *readinessOverrides {
+	return &readinessOverrides{ready: make(map[string]bool)}
+}
+
+// get returns the forced readiness of the service identified by key, if any.
+func (o *readinessOverrides) get(key string) (bool, bool) {
+	if o == nil {
+		return false, false
+	}
+	o.mux.RLock()
+	defer o.mux.RUnlock()
+	ready, ok := o.ready[key]
+	return ready, ok
+}
+
+func (o *readinessOverrides) set(key string, ready bool) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.ready[key] = ready
+}
+
+func (o *readinessOverrides) remove(key string) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	delete(o.ready, key)
+}
+
+// getReadinessOverride returns the forced readiness of the service, which is only taken into account if the debug
+// overrides are enabled.
+func (c *Controller) getReadinessOverride(namespace, service string) (bool, bool) {
+	if !c.EnableDebugOverrides {
+		return false, false
+	}
+	return c.readinessOverrides.get(namespace + "/" + service)
+}
+
+// DebugDependencyHandler returns an HTTP handler forcing the readiness of a service for the integration tests of
+// downstream systems, without changing its endpoints. A PUT of a ReadinessOverride to <namespace>/<service> forces
+// the readiness and a DELETE removes it again. The handler is not found unless the debug overrides are enabled.
+func (c *Controller) DebugDependencyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.EnableDebugOverrides || c.readinessOverrides == nil {
+			http.NotFound(w, r)
+			return
+		}
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, DebugDependencyPath), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			http.Error(w, "expected "+DebugDependencyPath+"<namespace>/<service>", http.StatusBadRequest)
+			return
+		}
+		key := parts[0] + "/" + parts[1]
+		switch r.Method {
+		case http.MethodPut:
+			var override ReadinessOverride
+			if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+				http.Error(w, "error decoding readiness override: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			c.readinessOverrides.set(key, override.Ready)
+			klog.Warningf("Forcing the readiness of service %s to %v through the debug override.", key, override.Ready)
+		case http.MethodDelete:
+			c.readinessOverrides.remove(key)
+			klog.Warningf("Removed the debug override of the readiness of service %s.", key)
+		default:
+			w.Header().Set("Allow", http.MethodPut+", "+http.MethodDelete)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if c.workqueue != nil {
+			c.workqueue.Add(key)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
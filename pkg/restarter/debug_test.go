@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func putReadinessOverride(c *Controller, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	c.DebugDependencyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, path, strings.NewReader(body)))
+	return rec
+}
+
+func TestDebugDependencyOverride(t *testing.T) {
+	// The endpoint has a ready address, so the service is ready unless the override forces it not to be.
+	client := fake.NewSimpleClientset(newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+	deps := &api.ServiceDependants{Services: map[string]api.Service{"kube-apiserver": {}}}
+	c := &Controller{clientset: client, serviceDependants: deps, readinessOverrides: newReadinessOverrides(), EnableDebugOverrides: true}
+
+	if rec := putReadinessOverride(c, DebugDependencyPath+"default/kube-apiserver", `{"ready": false}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected the override to be accepted but got %d: %s", rec.Code, rec.Body.String())
+	}
+	if c.isDependencyStillReady(context.TODO(), metav1.NamespaceDefault, "kube-apiserver") {
+		t.Errorf("Expected the override to abort the pod deletions")
+	}
+
+	c.EnableDebugOverrides = false
+	if !c.isDependencyStillReady(context.TODO(), metav1.NamespaceDefault, "kube-apiserver") {
+		t.Errorf("Expected the override to be ignored with the debug overrides disabled")
+	}
+
+	c.EnableDebugOverrides = true
+	rec := httptest.NewRecorder()
+	c.DebugDependencyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, DebugDependencyPath+"default/kube-apiserver", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected the override to be removed but got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !c.isDependencyStillReady(context.TODO(), metav1.NamespaceDefault, "kube-apiserver") {
+		t.Errorf("Expected the endpoints to decide the readiness once the override is removed")
+	}
+}
+
+func TestDebugDependencyHandler(t *testing.T) {
+	c := &Controller{readinessOverrides: newReadinessOverrides()}
+	if rec := putReadinessOverride(c, DebugDependencyPath+"default/kube-apiserver", `{"ready": true}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the handler not to be found with the debug overrides disabled but got %d", rec.Code)
+	}
+	if _, ok := c.readinessOverrides.get("default/kube-apiserver"); ok {
+		t.Errorf("Expected no override to be recorded with the debug overrides disabled")
+	}
+
+	c.EnableDebugOverrides = true
+	tests := []struct {
+		name         string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{"valid", DebugDependencyPath + "default/kube-apiserver", `{"ready": true}`, http.StatusNoContent},
+		{"missing service", DebugDependencyPath + "default", `{"ready": true}`, http.StatusBadRequest},
+		{"nested path", DebugDependencyPath + "default/kube-apiserver/extra", `{"ready": true}`, http.StatusBadRequest},
+		{"invalid body", DebugDependencyPath + "default/kube-apiserver", `ready`, http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		if rec := putReadinessOverride(c, tc.path, tc.body); rec.Code != tc.expectedCode {
+			t.Errorf("%s: expected %d but got %d: %s", tc.name, tc.expectedCode, rec.Code, rec.Body.String())
+		}
+	}
+	if ready, ok := c.getReadinessOverride(metav1.NamespaceDefault, "kube-apiserver"); !ok || !ready {
+		t.Errorf("Expected the service to be forced ready but got %v (%v)", ready, ok)
+	}
+
+	rec := httptest.NewRecorder()
+	c.DebugDependencyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DebugDependencyPath+"default/kube-apiserver", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected %d for a GET but got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// The actions of the decisions on the dependant pods.
+const (
+	DecisionActionRecycled = "recycled"
+	DecisionActionSkipped  = "skipped"
+	DecisionActionObserved = "observed"
+	DecisionActionFailed   = "failed"
+)
+
+// The reasons of the decisions on the dependant pods. They form a fixed vocabulary to bound the cardinality of the
+// decisions metric.
+const (
+	DecisionReasonCrashloop           = "crashloop"
+	DecisionReasonInitCrashloop       = "init_crashloop"
+	DecisionReasonLivenessKilled      = "liveness_killed"
+	DecisionReasonUnschedulable       = "unschedulable"
+	DecisionReasonVolumeMount         = "volume_mount"
+	DecisionReasonUnhealthy           = "unhealthy"
+	DecisionReasonHealthy             = "healthy"
+	DecisionReasonPolicy              = "policy"
+	DecisionReasonIgnored             = "ignored"
+	DecisionReasonDependencyOverlap   = "dependency_overlap"
+	DecisionReasonEvicting            = "evicting"
+	DecisionReasonTerminating         = "terminating"
+	DecisionReasonDependencyNotReady  = "dependency_not_ready"
+	DecisionReasonNotRecovered        = "not_recovered"
+	DecisionReasonGracePeriod         = "grace_period"
+	DecisionReasonCooldown            = "cooldown"
+	DecisionReasonZoneNotReady        = "zone_not_ready"
+	DecisionReasonNodeLocalized       = "node_localized"
+	DecisionReasonHeld                = "held"
+	DecisionReasonPaused              = "paused"
+	DecisionReasonMinAge              = "min_age"
+	DecisionReasonBudget              = "budget"
+	DecisionReasonDryRun              = "dry_run"
+	DecisionReasonQuietHours          = "quiet_hours"
+	DecisionReasonCircuitOpen         = "circuit_open"
+	DecisionReasonRetryBudget         = "retry_budget"
+	DecisionReasonNamespaceNotAllowed = "namespace_not_allowed"
+)
+
+// Decision is the decision on a dependant pod.
+type Decision struct {
+	Action string
+	Reason string
+}
+
+func skipped(reason string) *Decision {
+	return &Decision{Action: DecisionActionSkipped, Reason: reason}
+}
+
+// recordDecision counts the decision in the decisions metric.
+func recordDecision(decision *Decision) {
+	decisionsTotal.WithLabelValues(decision.Action, decision.Reason).Inc()
+}
+
+// getUnhealthyReason returns the reason why the dependant pod is unhealthy.
+func getUnhealthyReason(pod *v1.Pod, depPods *api.DependantPods) string {
+	switch {
+	case IsPodInCrashloopBackoff(pod.Status):
+		return DecisionReasonCrashloop
+	case depPods.RecycleInitCrashloop && IsPodInInitCrashloopBackoff(pod):
+		return DecisionReasonInitCrashloop
+	case depPods.RecycleLivenessKilled && isPodLivenessKilled(pod):
+		return DecisionReasonLivenessKilled
+	case depPods.UnschedulableThreshold != nil && IsPodUnschedulableFor(pod, depPods.UnschedulableThreshold.Duration, metav1.Now().Time):
+		return DecisionReasonUnschedulable
+	case depPods.VolumeMountThreshold != nil && IsPodWaitingForVolumesFor(pod, depPods.VolumeMountThreshold.Duration, metav1.Now().Time):
+		return DecisionReasonVolumeMount
+	}
+	return DecisionReasonUnhealthy
+}
+
+// getNotRecycledReason returns the reason why the recycle policy decided not to recycle the dependant pod.
+func getNotRecycledReason(pod *v1.Pod, depPods *api.DependantPods, depReady bool) string {
+	switch {
+	case !depReady:
+		return DecisionReasonDependencyNotReady
+	case IsPodIgnored(pod):
+		return DecisionReasonIgnored
+	case IsPodBeingEvicted(pod):
+		return DecisionReasonEvicting
+	case IsPodDeleted(pod):
+		return DecisionReasonTerminating
+	case !shouldDeleteDependantPod(pod, depPods):
+		return DecisionReasonHealthy
+	}
+	return DecisionReasonPolicy
+}
+
+// getOutcomeDecision returns the decision on a dependant pod with the given outcome of its deletion.
+func getOutcomeDecision(outcome, unhealthyReason string) *Decision {
+	switch outcome {
+	case AuditOutcomeSucceeded:
+		return &Decision{Action: DecisionActionRecycled, Reason: unhealthyReason}
+	case AuditOutcomeDryRun:
+		return skipped(DecisionReasonDryRun)
+	case AuditOutcomeQuietHours:
+		return skipped(DecisionReasonQuietHours)
+	case AuditOutcomeCircuitOpen:
+		return skipped(DecisionReasonCircuitOpen)
+	case AuditOutcomeRetryBudgetExhausted:
+		return skipped(DecisionReasonRetryBudget)
+	case AuditOutcomeNamespaceNotAllowed:
+		return skipped(DecisionReasonNamespaceNotAllowed)
+	}
+	return &Decision{Action: DecisionActionFailed, Reason: unhealthyReason}
+}
+
+// podCheck is the result of checking a dependant pod against a config.
+type podCheck struct {
+	// skip is the decision to skip the pod, if it is not to be recycled.
+	skip *Decision
+	// recyclable tells if the pod passed the recycle guards and the recycle policy.
+	recyclable bool
+	// reason is the reason of the recycle policy for its verdict.
+	reason string
+	// hold is the hold of the deletion of the pods of the owner of the pod, if it is held.
+	hold *ownerHold
+}
+
+// checkService checks if deps allow deleting the dependant pods of the service at the moment. It returns the decision
+// to skip the pods, if they are to be skipped, and the end of the grace period or the cooldown skipping them.
+func (c *Controller) checkService(deps *api.ServiceDependants, namespace, service string) (*Decision, time.Time) {
+	opts := getNamespaceOptions(deps, namespace)
+	key := namespace + "/" + service
+	var recovered time.Time
+	var ok bool
+	if c.flapDetector != nil {
+		recovered, ok = c.flapDetector.LastRecoveryTime(key)
+	}
+	if isRecoveryEdgeOnly(opts) && (!ok || c.clock.Since(recovered) > getRecoveryActionWindow(opts)) {
+		return skipped(DecisionReasonNotRecovered), time.Time{}
+	}
+	if gracePeriod := getRecoveryGracePeriod(opts); ok && gracePeriod > 0 && c.clock.Since(recovered) < gracePeriod {
+		return skipped(DecisionReasonGracePeriod), recovered.Add(gracePeriod)
+	}
+	if deps == nil || deps.Services[service].ActionCooldown == nil {
+		return nil, time.Time{}
+	}
+	if until, ok := c.serviceCooldowns.coolingDownUntil(key, deps.Services[service].ActionCooldown, c.clock.Now()); ok {
+		return skipped(DecisionReasonCooldown), until
+	}
+	return nil, time.Time{}
+}
+
+// checkPod checks the dependant pod of the service against deps without acting on it or reporting the result. The
+// pod is recycled unless the check skips it.
+func (c *Controller) checkPod(ctx context.Context, deps *api.ServiceDependants, po *v1.Pod, service string, depPods *api.DependantPods, selector labels.Selector) (*podCheck, error) {
+	if IsPodDeleted(po) {
+		return &podCheck{skip: skipped(DecisionReasonTerminating)}, nil
+	}
+	if !depPods.AllowDependencyOverlap {
+		backing, err := c.getDependencyBackingPods(ctx, po.Namespace, service)
+		if err != nil {
+			return nil, err
+		}
+		if isBackingDependency(po, depPods, backing) {
+			return &podCheck{skip: skipped(DecisionReasonDependencyOverlap)}, nil
+		}
+	}
+	depReady, err := c.isDependantDependencyReady(po.Namespace, depPods)
+	if err != nil {
+		return nil, err
+	}
+	recycle, reason, err := c.shouldRecycle(ctx, po, depPods, depReady)
+	if err != nil {
+		return nil, err
+	}
+	if !recycle {
+		return &podCheck{skip: skipped(getNotRecycledReason(po, depPods, depReady)), reason: reason}, nil
+	}
+	check := &podCheck{recyclable: true, reason: reason}
+	if depPods.RequireAllUnhealthy {
+		pl, err := c.clientset.CoreV1().Pods(po.Namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, fmt.Errorf("error listing pods with selector %s: %v", selector.String(), err)
+		}
+		pods := excludeEvictedPods(selectDependantPods(pl.Items, depPods))
+		for i := range pods {
+			recycle, _, err := c.shouldRecycleDependantPod(ctx, &pods[i], depPods, depReady)
+			if err != nil {
+				return nil, err
+			}
+			if !recycle {
+				check.skip = skipped(getNotRecycledReason(&pods[i], depPods, depReady))
+				return check, nil
+			}
+		}
+	}
+	if depPods.ZonePinned {
+		ready, err := c.isReadyEndpointPresentInPodZone(po, service)
+		if err != nil {
+			return nil, err
+		}
+		if !ready {
+			check.skip = skipped(DecisionReasonZoneNotReady)
+			return check, nil
+		}
+	}
+	if depPods.NodeLocalizedCrashloopFraction != nil {
+		localized, err := c.checkCrashloopLocalizedOnNode(po, selector, *depPods.NodeLocalizedCrashloopFraction)
+		if err != nil {
+			return nil, err
+		}
+		if localized {
+			check.skip = skipped(DecisionReasonNodeLocalized)
+			return check, nil
+		}
+	}
+	if depPods.OwnerCrashloopHoldFraction != nil {
+		hold, err := c.getOwnerHold(ctx, po, selector, *depPods.OwnerCrashloopHoldFraction)
+		if err != nil {
+			return nil, err
+		}
+		if hold != nil {
+			check.skip, check.hold = skipped(DecisionReasonHeld), hold
+			return check, nil
+		}
+	}
+	paused, err := IsOwnerPaused(ctx, c.clientset, po)
+	if err != nil {
+		return nil, err
+	}
+	if paused {
+		check.skip = skipped(DecisionReasonPaused)
+		return check, nil
+	}
+	recent, err := c.isOwnerRecentlyChanged(po, getNamespaceOptions(deps, po.Namespace))
+	if err != nil {
+		return nil, err
+	}
+	if recent {
+		check.skip = skipped(DecisionReasonMinAge)
+	}
+	return check, nil
+}
+
+// getCheckedDecision returns the decision deps take on the checked dependant pod, assuming its deletion is due right
+// away and not limited by the budgets of the reconcile.
+func (c *Controller) getCheckedDecision(deps *api.ServiceDependants, po *v1.Pod, depPods *api.DependantPods, check *podCheck) *Decision {
+	if check.skip != nil {
+		return check.skip
+	}
+	return c.getActionDecision(deps, po, depPods)
+}
+
+// getActionDecision returns the decision deps take on the dependant pod which passed its checks, assuming its deletion
+// is due right away and not limited by the budgets of the reconcile.
+func (c *Controller) getActionDecision(deps *api.ServiceDependants, po *v1.Pod, depPods *api.DependantPods) *Decision {
+	opts := getNamespaceOptions(deps, po.Namespace)
+	unhealthyReason := getUnhealthyReason(po, depPods)
+	switch {
+	case depPods.ObserveOnly:
+		return &Decision{Action: DecisionActionObserved, Reason: unhealthyReason}
+	case deps != nil && !c.isNamespaceAllowed(deps, po.Namespace):
+		return getOutcomeDecision(AuditOutcomeNamespaceNotAllowed, unhealthyReason)
+	case isDryRun(opts):
+		return getOutcomeDecision(AuditOutcomeDryRun, unhealthyReason)
+	case opts.QuietHours != nil && InQuietHours(c.clock.Now(), *opts.QuietHours):
+		return getOutcomeDecision(AuditOutcomeQuietHours, unhealthyReason)
+	}
+	return &Decision{Action: DecisionActionRecycled, Reason: unhealthyReason}
+}
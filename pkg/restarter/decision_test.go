@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDecisionsTotal(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	ignored := newPodInCrashloop("ignored", labels)
+	ignored.Annotations = map[string]string{ignoreAnnotationKey: "true"}
+	dryRun := true
+	cooldown := &metav1.Duration{Duration: time.Hour}
+
+	tests := []struct {
+		name           string
+		pod            *v1.Pod
+		deps           *api.ServiceDependants
+		coolingDown    bool
+		expectedAction string
+		expectedReason string
+	}{
+		{"crashloop", newPodInCrashloop("crashloop", labels), &api.ServiceDependants{}, false, DecisionActionRecycled, DecisionReasonCrashloop},
+		{"healthy", newPodHealthy("healthy", labels), &api.ServiceDependants{}, false, DecisionActionSkipped, DecisionReasonHealthy},
+		{"ignored", ignored, &api.ServiceDependants{}, false, DecisionActionSkipped, DecisionReasonIgnored},
+		{"dry run", newPodInCrashloop("dry-run", labels), &api.ServiceDependants{Options: api.Options{DryRun: &dryRun}}, false, DecisionActionSkipped, DecisionReasonDryRun},
+		{"cooldown", newPodInCrashloop("cooldown", labels), &api.ServiceDependants{
+			Services: map[string]api.Service{"kube-apiserver": {ActionCooldown: cooldown}},
+		}, true, DecisionActionSkipped, DecisionReasonCooldown},
+	}
+	for _, tc := range tests {
+		client := fake.NewSimpleClientset(tc.pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		fakeClock := clock.NewFakeClock(time.Now())
+		c := &Controller{clientset: client, clock: fakeClock, serviceDependants: tc.deps, serviceCooldowns: newServiceCooldowns()}
+		if tc.coolingDown {
+			// A reconcile starting right after a deletion of the previous one is cooling down.
+			key := metav1.NamespaceDefault + "/kube-apiserver"
+			c.serviceCooldowns.record(key, fakeClock.Now())
+			c.serviceCooldowns.start(key, 1)
+		}
+
+		counter := decisionsTotal.WithLabelValues(tc.expectedAction, tc.expectedReason)
+		before := testutil.ToFloat64(counter)
+		if err := c.processPod(context.TODO(), tc.pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		if actual := testutil.ToFloat64(counter) - before; actual != 1 {
+			t.Errorf("%s: expected the decision %s/%s to be counted once but got %f", tc.name, tc.expectedAction, tc.expectedReason, actual)
+		}
+	}
+}
+
+func TestGetUnhealthyReason(t *testing.T) {
+	initCrashloop := newPodHealthy("init-crashloop", nil)
+	initCrashloop.Spec.RestartPolicy = v1.RestartPolicyOnFailure
+	initCrashloop.Status.InitContainerStatuses = []v1.ContainerStatus{{
+		Name:  "setup",
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+	}}
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		depPods  *api.DependantPods
+		expected string
+	}{
+		{"crashloop", newPodInCrashloop("crashloop", nil), &api.DependantPods{}, DecisionReasonCrashloop},
+		{"init crashloop", initCrashloop, &api.DependantPods{RecycleInitCrashloop: true}, DecisionReasonInitCrashloop},
+		{"waiting for volumes", newPodWaitingForVolumes("volumes", nil, time.Now().Add(-10*time.Minute)), &api.DependantPods{VolumeMountThreshold: &metav1.Duration{Duration: time.Minute}}, DecisionReasonVolumeMount},
+		{"other", newPodHealthy("other", nil), &api.DependantPods{}, DecisionReasonUnhealthy},
+	}
+	for _, tc := range tests {
+		if actual := getUnhealthyReason(tc.pod, tc.depPods); actual != tc.expected {
+			t.Errorf("%s: expected reason %s but got %s", tc.name, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+)
+
+// ConfigDiff describes the changes between two ServiceDependants. Dependants are identified
+// by <service>/<dependant> and namespaces by the namespaces with overridden options.
+type ConfigDiff struct {
+	AddedNamespaces   []string
+	RemovedNamespaces []string
+	ChangedNamespaces []string
+	AddedServices     []string
+	RemovedServices   []string
+	ChangedServices   []string
+	AddedDependants   []string
+	RemovedDependants []string
+	ChangedDependants []string
+	// ChangedFields are the names of the changed global settings.
+	ChangedFields []string
+}
+
+// DiffServiceDependants returns the changes from the old to the new ServiceDependants.
+func DiffServiceDependants(old, new *api.ServiceDependants) ConfigDiff {
+	var diff ConfigDiff
+
+	for ns, opts := range new.Namespaces {
+		oldOpts, ok := old.Namespaces[ns]
+		switch {
+		case !ok:
+			diff.AddedNamespaces = append(diff.AddedNamespaces, ns)
+		case !reflect.DeepEqual(oldOpts, opts):
+			diff.ChangedNamespaces = append(diff.ChangedNamespaces, ns)
+		}
+	}
+	for ns := range old.Namespaces {
+		if _, ok := new.Namespaces[ns]; !ok {
+			diff.RemovedNamespaces = append(diff.RemovedNamespaces, ns)
+		}
+	}
+
+	for name, srv := range new.Services {
+		oldSrv, ok := old.Services[name]
+		if !ok {
+			diff.AddedServices = append(diff.AddedServices, name)
+			continue
+		}
+		if !reflect.DeepEqual(oldSrv.DependantResources, srv.DependantResources) || !reflect.DeepEqual(oldSrv.OnDependencyLost, srv.OnDependencyLost) ||
+			!reflect.DeepEqual(oldSrv.Readiness, srv.Readiness) || !reflect.DeepEqual(oldSrv.ActionCooldown, srv.ActionCooldown) ||
+			!reflect.DeepEqual(oldSrv.ReadinessAll, srv.ReadinessAll) || !reflect.DeepEqual(oldSrv.ReadyStableFor, srv.ReadyStableFor) ||
+			!reflect.DeepEqual(oldSrv.MaxNotReadyDuration, srv.MaxNotReadyDuration) {
+			diff.ChangedServices = append(diff.ChangedServices, name)
+		}
+		diff.diffDependants(name, oldSrv.Dependants, srv.Dependants)
+	}
+	for name := range old.Services {
+		if _, ok := new.Services[name]; !ok {
+			diff.RemovedServices = append(diff.RemovedServices, name)
+		}
+	}
+
+	diff.ChangedFields = diffFields(reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem())
+
+	for _, list := range [][]string{diff.AddedNamespaces, diff.RemovedNamespaces, diff.ChangedNamespaces, diff.AddedServices,
+		diff.RemovedServices, diff.ChangedServices, diff.AddedDependants, diff.RemovedDependants, diff.ChangedDependants, diff.ChangedFields} {
+		sort.Strings(list)
+	}
+	return diff
+}
+
+func (d *ConfigDiff) diffDependants(service string, old, new []api.DependantPods) {
+	oldDeps := dependantsByName(service, old)
+	newDeps := dependantsByName(service, new)
+	for key, depPods := range newDeps {
+		oldDepPods, ok := oldDeps[key]
+		switch {
+		case !ok:
+			d.AddedDependants = append(d.AddedDependants, key)
+		case !reflect.DeepEqual(oldDepPods, depPods):
+			d.ChangedDependants = append(d.ChangedDependants, key)
+		}
+	}
+	for key := range oldDeps {
+		if _, ok := newDeps[key]; !ok {
+			d.RemovedDependants = append(d.RemovedDependants, key)
+		}
+	}
+}
+
+// dependantsByName identifies the dependants by their name or, if they have none, by their index.
+func dependantsByName(service string, dependants []api.DependantPods) map[string]api.DependantPods {
+	m := make(map[string]api.DependantPods, len(dependants))
+	for i, depPods := range dependants {
+		name := depPods.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		m[service+"/"+name] = depPods
+	}
+	return m
+}
+
+// diffFields returns the json names of the fields other than the services and namespaces differing between
+// old and new. Embedded structs are compared field by field.
+func diffFields(old, new reflect.Value) []string {
+	var changed []string
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		if field.Name == "Services" || field.Name == "Namespaces" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			changed = append(changed, diffFields(old.Field(i), new.Field(i))...)
+			continue
+		}
+		if !reflect.DeepEqual(old.Field(i).Interface(), new.Field(i).Interface()) {
+			changed = append(changed, strings.Split(field.Tag.Get("json"), ",")[0])
+		}
+	}
+	return changed
+}
+
+// IsEmpty returns true if there are no changes.
+func (d ConfigDiff) IsEmpty() bool {
+	return reflect.DeepEqual(d, ConfigDiff{})
+}
+
+func (d ConfigDiff) String() string {
+	if d.IsEmpty() {
+		return "no changes"
+	}
+	var parts []string
+	add := func(what string, items []string) {
+		if len(items) > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", what, strings.Join(items, ", ")))
+		}
+	}
+	add("added namespaces", d.AddedNamespaces)
+	add("removed namespaces", d.RemovedNamespaces)
+	add("changed namespaces", d.ChangedNamespaces)
+	add("added services", d.AddedServices)
+	add("removed services", d.RemovedServices)
+	add("changed services", d.ChangedServices)
+	add("added dependants", d.AddedDependants)
+	add("removed dependants", d.RemovedDependants)
+	add("changed dependants", d.ChangedDependants)
+	add("changed settings", d.ChangedFields)
+	return strings.Join(parts, "; ")
+}
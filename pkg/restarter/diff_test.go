@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+)
+
+func TestDiffServiceDependants(t *testing.T) {
+	old, err := api.Decode([]byte(`namespace: ""
+dryRun: true
+namespaces:
+  shoot--dev:
+    dryRun: false
+  shoot--prod:
+    dryRun: false
+services:
+  kube-apiserver:
+    dependantPods:
+    - name: controller-manager
+      selector:
+        matchLabels:
+          role: controller-manager
+    - name: scheduler
+      selector:
+        matchLabels:
+          role: scheduler
+  etcd:
+    dependantPods: []
+`))
+	if err != nil {
+		t.Fatalf("error decoding old config: %v", err)
+	}
+	new, err := api.Decode([]byte(`namespace: ""
+dryRun: false
+namespaces:
+  shoot--dev:
+    dryRun: true
+  shoot--qa:
+    dryRun: false
+services:
+  kube-apiserver:
+    dependantPods:
+    - name: controller-manager
+      requireAllUnhealthy: true
+      selector:
+        matchLabels:
+          role: controller-manager
+    - name: machine-controller-manager
+      selector:
+        matchLabels:
+          role: machine-controller-manager
+  vpn:
+    dependantPods: []
+`))
+	if err != nil {
+		t.Fatalf("error decoding new config: %v", err)
+	}
+
+	expected := ConfigDiff{
+		AddedNamespaces:   []string{"shoot--qa"},
+		RemovedNamespaces: []string{"shoot--prod"},
+		ChangedNamespaces: []string{"shoot--dev"},
+		AddedServices:     []string{"vpn"},
+		RemovedServices:   []string{"etcd"},
+		AddedDependants:   []string{"kube-apiserver/machine-controller-manager"},
+		RemovedDependants: []string{"kube-apiserver/scheduler"},
+		ChangedDependants: []string{"kube-apiserver/controller-manager"},
+		ChangedFields:     []string{"dryRun"},
+	}
+	if diff := DiffServiceDependants(old, new); !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected diff %+v but got %+v", expected, diff)
+	}
+	if diff := DiffServiceDependants(old, old); !diff.IsEmpty() {
+		t.Errorf("Expected no changes but got %s", diff)
+	}
+}
@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// DryRunReport summarizes the pod deletions which would have been performed in dry run, so that the caps and
+// cooldowns can be tuned before the deletions are enforced.
+type DryRunReport struct {
+	// Since is the time the first deletion was skipped in dry run.
+	Since   time.Time           `json:"since,omitempty"`
+	Entries []DryRunReportEntry `json:"entries"`
+}
+
+// DryRunReportEntry summarizes the deletions skipped in dry run for the dependant pods of a service for a reason.
+// The intervals are the durations between consecutive skipped deletions, which only exist for at least two of them.
+type DryRunReportEntry struct {
+	Namespace           string    `json:"namespace"`
+	Service             string    `json:"service"`
+	Reason              string    `json:"reason"`
+	Count               int       `json:"count"`
+	First               time.Time `json:"first"`
+	Last                time.Time `json:"last"`
+	MinIntervalSeconds  float64   `json:"minIntervalSeconds,omitempty"`
+	MeanIntervalSeconds float64   `json:"meanIntervalSeconds,omitempty"`
+	MaxIntervalSeconds  float64   `json:"maxIntervalSeconds,omitempty"`
+}
+
+// dryRunReport accumulates the deletions skipped in dry run, keyed by <namespace>/<service>/<reason>.
+type dryRunReport struct {
+	mux     sync.Mutex
+	entries map[string]*DryRunReportEntry
+}
+
+func newDryRunReport() *dryRunReport {
+	return &dryRunReport{entries: make(map[string]*DryRunReportEntry)}
+}
+
+// record accumulates a deletion of a dependant pod of the service skipped at now for the reason.
+func (r *dryRunReport) record(namespace, service, reason string, now time.Time) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	key := namespace + "/" + service + "/" + reason
+	entry, ok := r.entries[key]
+	if !ok {
+		r.entries[key] = &DryRunReportEntry{Namespace: namespace, Service: service, Reason: reason, Count: 1, First: now, Last: now}
+		return
+	}
+	interval := now.Sub(entry.Last).Seconds()
+	if entry.Count == 1 || interval < entry.MinIntervalSeconds {
+		entry.MinIntervalSeconds = interval
+	}
+	if interval > entry.MaxIntervalSeconds {
+		entry.MaxIntervalSeconds = interval
+	}
+	entry.Count++
+	entry.Last = now
+	entry.MeanIntervalSeconds = entry.Last.Sub(entry.First).Seconds() / float64(entry.Count-1)
+}
+
+// report returns the accumulated report with the entries sorted by namespace, service and reason.
+func (r *dryRunReport) report() DryRunReport {
+	report := DryRunReport{Entries: []DryRunReportEntry{}}
+	if r == nil {
+		return report
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for _, entry := range r.entries {
+		report.Entries = append(report.Entries, *entry)
+		if report.Since.IsZero() || entry.First.Before(report.Since) {
+			report.Since = entry.First
+		}
+	}
+	sort.Slice(report.Entries, func(i, j int) bool {
+		a, b := report.Entries[i], report.Entries[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Reason < b.Reason
+	})
+	return report
+}
+
+// recordDryRun accumulates the deletion of the pod skipped in dry run in the dry run report.
+func (c *Controller) recordDryRun(namespace, service, reason string) {
+	if c.dryRunReport == nil {
+		return
+	}
+	c.dryRunReport.record(namespace, service, reason, c.clock.Now().UTC())
+}
+
+// logDryRunReport logs the entries of the dry run report, if any, e.g. on shutdown.
+func (c *Controller) logDryRunReport() {
+	report := c.dryRunReport.report()
+	if len(report.Entries) == 0 {
+		return
+	}
+	klog.Infof("Dry run report since %s:", report.Since.Format(time.RFC3339))
+	for _, e := range report.Entries {
+		klog.Infof("Dry run: namespace=%s service=%s reason=%s count=%d first=%s last=%s minInterval=%.0fs meanInterval=%.0fs maxInterval=%.0fs",
+			e.Namespace, e.Service, e.Reason, e.Count, e.First.Format(time.RFC3339), e.Last.Format(time.RFC3339),
+			e.MinIntervalSeconds, e.MeanIntervalSeconds, e.MaxIntervalSeconds)
+	}
+}
+
+// DryRunReportHandler returns an HTTP handler which serves the report of the pod deletions skipped in dry run as JSON.
+func (c *Controller) DryRunReportHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, c.dryRunReport.report())
+	})
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDryRunReport(t *testing.T) {
+	dryRun := true
+	deps := &api.ServiceDependants{Options: api.Options{DryRun: &dryRun}}
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	fakeClock := clock.NewFakeClock(start)
+	client := fake.NewSimpleClientset()
+	c := &Controller{clientset: client, clock: fakeClock, serviceDependants: deps, dryRunReport: newDryRunReport()}
+
+	// The deletions which would have been performed, with the time passed since the previous one.
+	decisions := []struct {
+		after   time.Duration
+		service string
+		reason  string
+	}{
+		{0, "kube-apiserver", ReasonCrashLoopBackOff},
+		{time.Minute, "kube-apiserver", ReasonCrashLoopBackOff},
+		{time.Minute, "etcd-main", reasonAllPodsUnhealthy},
+		{3 * time.Minute, "kube-apiserver", ReasonCrashLoopBackOff},
+	}
+	for i, d := range decisions {
+		fakeClock.Step(d.after)
+		pod := newPodInCrashloop("pod-"+strconv.Itoa(i), nil)
+		if _, err := client.CoreV1().Pods(pod.Namespace).Create(pod); err != nil {
+			t.Fatalf("error creating pod: %v", err)
+		}
+		if err := c.deletePodForReason(pod, d.service, d.reason, nil); err != nil {
+			t.Fatalf("error deleting pod: %v", err)
+		}
+		if _, err := client.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{}); err != nil {
+			t.Fatalf("Expected pod %s not to be deleted in dry run but got %v", pod.Name, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	c.DryRunReportHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dryrun-report", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 but got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report DryRunReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("error decoding report: %v", err)
+	}
+	expected := DryRunReport{
+		Since: start,
+		Entries: []DryRunReportEntry{
+			{Namespace: "default", Service: "etcd-main", Reason: reasonAllPodsUnhealthy, Count: 1,
+				First: start.Add(2 * time.Minute), Last: start.Add(2 * time.Minute)},
+			{Namespace: "default", Service: "kube-apiserver", Reason: ReasonCrashLoopBackOff, Count: 3,
+				First: start, Last: start.Add(5 * time.Minute), MinIntervalSeconds: 60, MeanIntervalSeconds: 150, MaxIntervalSeconds: 240},
+		},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected the report %+v but got %+v", expected, report)
+	}
+
+	sink, restore := captureLogs(t)
+	defer restore()
+	c.logDryRunReport()
+	if logs := sink.String(); !strings.Contains(logs, "namespace=default service=kube-apiserver reason=CrashLoopBackOff count=3") {
+		t.Errorf("Expected the report to be logged but got:\n%s", logs)
+	}
+}
+
+func TestDryRunReportEmpty(t *testing.T) {
+	c := &Controller{}
+	rec := httptest.NewRecorder()
+	c.DryRunReportHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dryrun-report", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"entries":[]`) {
+		t.Errorf("Expected an empty report but got %d: %s", rec.Code, rec.Body.String())
+	}
+}
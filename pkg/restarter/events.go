@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+const (
+	eventReasonPodDeleted          = "DeletedCrashloopingPod"
+	eventReasonDependencyRecovered = "DependencyRecovered"
+)
+
+// ownerEvents aggregates the deleted pods per top-level owner during the reconcile of a service, so that
+// every owner gets a single event per reconcile. Reconciles are identified by the <namespace>/<service> key.
+type ownerEvents struct {
+	mux         sync.Mutex
+	reconciles  map[string]map[string]*ownerEvent
+	generations map[string]uint64
+}
+
+type ownerEvent struct {
+	owner *v1.ObjectReference
+	pods  []string
+}
+
+func newOwnerEvents() *ownerEvents {
+	return &ownerEvents{reconciles: make(map[string]map[string]*ownerEvent), generations: make(map[string]uint64)}
+}
+
+// start begins aggregating the owner events of the reconcile of the given generation identified by key. The events
+// aggregated by a superseded reconcile of the service are taken over, so that they are recorded once it finishes.
+func (e *ownerEvents) start(key string, generation uint64) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if _, ok := e.reconciles[key]; !ok {
+		e.reconciles[key] = make(map[string]*ownerEvent)
+	}
+	e.generations[key] = generation
+}
+
+// add records the deletion of the pod for the owner. It returns false if no reconcile of the key is active.
+func (e *ownerEvents) add(key string, owner *v1.ObjectReference, pod string) bool {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	events, ok := e.reconciles[key]
+	if !ok {
+		return false
+	}
+	ownerKey := owner.Kind + "/" + owner.Name
+	if _, ok := events[ownerKey]; !ok {
+		events[ownerKey] = &ownerEvent{owner: owner}
+	}
+	events[ownerKey].pods = append(events[ownerKey].pods, pod)
+	return true
+}
+
+// finish stops aggregating the owner events of the reconcile of the given generation identified by key and returns
+// them. Nothing is returned if the reconcile was superseded by another reconcile of the service.
+func (e *ownerEvents) finish(key string, generation uint64) []*ownerEvent {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if e.generations[key] != generation {
+		return nil
+	}
+	delete(e.generations, key)
+	var events []*ownerEvent
+	for _, ev := range e.reconciles[key] {
+		events = append(events, ev)
+	}
+	delete(e.reconciles, key)
+	sort.Slice(events, func(i, j int) bool { return events[i].owner.Name < events[j].owner.Name })
+	return events
+}
+
+// recordPodDeleted records an event for the deleted pod and aggregates it for its top-level owner.
+func (c *Controller) recordPodDeleted(ctx context.Context, po *v1.Pod, service string) {
+	if c.Recorder == nil {
+		return
+	}
+	c.Recorder.Eventf(po, v1.EventTypeNormal, eventReasonPodDeleted, "Deleted pod in CrashLoopBackOff after service %s became ready", service)
+
+	owner, err := c.getTopLevelOwner(ctx, po)
+	if err != nil {
+		klog.Errorf("Error resolving the owner of pod %s/%s: %s", po.Namespace, po.Name, err)
+		return
+	}
+	if owner == nil {
+		return
+	}
+	if !c.ownerEvents.add(po.Namespace+"/"+service, owner, po.Name) {
+		c.recordOwnerEvent(&ownerEvent{owner: owner, pods: []string{po.Name}}, service)
+	}
+}
+
+// observeReadiness records the readiness of the service with the flap detector. If the service recovered, it logs
+// and records an event for its endpoints naming the readiness predicates which reported it as ready.
+func (c *Controller) observeReadiness(ep *v1.Endpoints, srv api.Service, ready bool) {
+	if !c.flapDetector.RecordReadiness(ep.Namespace+"/"+ep.Name, ready) {
+		return
+	}
+	predicates := c.describeServiceReadiness(ep, srv)
+	klog.Infof("Dependency recovered: namespace=%s service=%s predicates=%s", ep.Namespace, ep.Name, predicates)
+	if c.Recorder == nil {
+		return
+	}
+	c.Recorder.Eventf(ep, v1.EventTypeNormal, eventReasonDependencyRecovered, "Service %s recovered as reported by %s", ep.Name, predicates)
+}
+
+// recordOwnerEvents records the aggregated events of the finished reconcile of the service.
+func (c *Controller) recordOwnerEvents(namespace, service string, generation uint64) {
+	for _, ev := range c.ownerEvents.finish(namespace+"/"+service, generation) {
+		c.recordOwnerEvent(ev, service)
+	}
+}
+
+func (c *Controller) recordOwnerEvent(ev *ownerEvent, service string) {
+	c.Recorder.Eventf(ev.owner, v1.EventTypeNormal, eventReasonPodDeleted, "Deleted %d pod(s) in CrashLoopBackOff after service %s became ready: %s",
+		len(ev.pods), service, strings.Join(ev.pods, ", "))
+}
+
+// getTopLevelOwner returns the reference of the controller of the pod, following a ReplicaSet to its Deployment.
+// It returns nil if the pod is not controlled by any owner.
+func (c *Controller) getTopLevelOwner(ctx context.Context, po *v1.Pod) (*v1.ObjectReference, error) {
+	owner := ControllerOwnerRef(po)
+	if owner == nil {
+		return nil, nil
+	}
+	if owner.Kind == "ReplicaSet" {
+		deployment, err := getReplicaSetOwner(ctx, c.clientset, po.Namespace, owner.Name)
+		if err != nil {
+			return nil, err
+		}
+		if deployment != nil && deployment.Kind == "Deployment" {
+			owner = deployment
+		}
+	}
+	return &v1.ObjectReference{
+		APIVersion: owner.APIVersion,
+		Kind:       owner.Kind,
+		Namespace:  po.Namespace,
+		Name:       owner.Name,
+		UID:        owner.UID,
+	}, nil
+}
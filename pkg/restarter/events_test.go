@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+type recordedEvent struct {
+	object  runtime.Object
+	message string
+}
+
+// eventRecorder records the events together with the objects they are recorded for.
+type eventRecorder struct {
+	record.EventRecorder
+	mux    sync.Mutex
+	events []recordedEvent
+}
+
+func (r *eventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.events = append(r.events, recordedEvent{object: object, message: fmt.Sprintf(messageFmt, args...)})
+}
+
+func TestOwnerEventsAreAggregatedPerReconcile(t *testing.T) {
+	isController := true
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       metav1.NamespaceDefault,
+			Name:            "kube-controller-manager-5d8f",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "kube-controller-manager", Controller: &isController}},
+		},
+	}
+	var pods []*v1.Pod
+	for _, name := range []string{"pod-0", "pod-1"} {
+		pod := newPodInCrashloop(name, nil)
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: rs.Name, Controller: &isController}}
+		pods = append(pods, pod)
+	}
+	recorder := &eventRecorder{}
+	c := &Controller{
+		clientset:   fake.NewSimpleClientset(rs, pods[0], pods[1]),
+		ownerEvents: newOwnerEvents(),
+		Recorder:    recorder,
+	}
+
+	c.ownerEvents.start("default/kube-apiserver", 1)
+	for _, pod := range pods {
+		if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+			t.Fatalf("error deleting pod: %v", err)
+		}
+	}
+	c.recordOwnerEvents(metav1.NamespaceDefault, "kube-apiserver", 1)
+
+	var podEvents, ownerEvents []recordedEvent
+	for _, ev := range recorder.events {
+		switch obj := ev.object.(type) {
+		case *v1.Pod:
+			podEvents = append(podEvents, ev)
+		case *v1.ObjectReference:
+			if obj.Kind != "Deployment" || obj.Name != "kube-controller-manager" {
+				t.Errorf("Expected the owner event to be recorded for the deployment but got %s %s", obj.Kind, obj.Name)
+			}
+			ownerEvents = append(ownerEvents, ev)
+		default:
+			t.Errorf("Unexpected event for %T", ev.object)
+		}
+	}
+	if len(podEvents) != 2 {
+		t.Errorf("Expected an event for each of the 2 pods but got %d", len(podEvents))
+	}
+	if len(ownerEvents) != 1 {
+		t.Fatalf("Expected a single aggregated event for the deployment but got %d", len(ownerEvents))
+	}
+	if !strings.Contains(ownerEvents[0].message, "pod-0, pod-1") {
+		t.Errorf("Expected the aggregated event to list both pods but got %q", ownerEvents[0].message)
+	}
+}
+
+func TestRecoveryEventNamesReadinessPredicates(t *testing.T) {
+	minReadyAddresses := int32(2)
+	srv := api.Service{
+		Readiness:    &api.Readiness{Predicate: api.ReadinessMinReadyAddresses, MinReadyAddresses: &minReadyAddresses},
+		ReadinessAll: []api.Readiness{{Predicate: api.ReadinessNamedPortReady, PortName: "https"}},
+	}
+	recorder := &eventRecorder{}
+	c := &Controller{clientset: fake.NewSimpleClientset(), flapDetector: NewFlapDetector(clock.RealClock{}), Recorder: recorder}
+
+	for _, addresses := range []int{3, 1, 3, 3} {
+		ep := newEndpointWithPorts(addresses, "https")
+		ready, err := c.isServiceReady(ep, srv)
+		if err != nil {
+			t.Fatalf("error evaluating the readiness: %v", err)
+		}
+		c.observeReadiness(ep, srv, ready)
+	}
+
+	// Neither the first observation nor unchanged readiness is a recovery.
+	if len(recorder.events) != 1 {
+		t.Fatalf("Expected a single recovery event but got %d", len(recorder.events))
+	}
+	ev := recorder.events[0]
+	if _, ok := ev.object.(*v1.Endpoints); !ok {
+		t.Errorf("Expected the recovery event to be recorded for the endpoints but got %T", ev.object)
+	}
+	expected := "Service kube-apiserver recovered as reported by MinReadyAddresses (3 ready addresses), NamedPortReady (port https)"
+	if ev.message != expected {
+		t.Errorf("Expected the event message %q but got %q", expected, ev.message)
+	}
+}
+
+func TestRecoveryEventNamesDebugOverride(t *testing.T) {
+	recorder := &eventRecorder{}
+	c := &Controller{
+		clientset:            fake.NewSimpleClientset(),
+		flapDetector:         NewFlapDetector(clock.RealClock{}),
+		Recorder:             recorder,
+		EnableDebugOverrides: true,
+		readinessOverrides:   newReadinessOverrides(),
+	}
+	ep := newEndpointWithPorts(0)
+	c.observeReadiness(ep, api.Service{}, false)
+	c.readinessOverrides.set(metav1.NamespaceDefault+"/kube-apiserver", true)
+	c.observeReadiness(ep, api.Service{}, true)
+
+	if len(recorder.events) != 1 || !strings.HasSuffix(recorder.events[0].message, "reported by debug override") {
+		t.Errorf("Expected a recovery event naming the debug override but got %+v", recorder.events)
+	}
+}
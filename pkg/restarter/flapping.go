@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/tools/cache"
+)
+
+// maxTrackedTransitions bounds the number of readiness transitions remembered per endpoint.
+const maxTrackedTransitions = 64
+
+// FlapDetector tracks the readiness transitions of endpoints to detect flapping.
+// Endpoints are identified by their <namespace>/<name> key.
+type FlapDetector struct {
+	clock       clock.Clock
+	mux         sync.Mutex
+	ready       map[string]bool
+	transitions map[string][]time.Time
+	recoveries  map[string]time.Time
+	losses      map[string]time.Time
+}
+
+// NewFlapDetector returns a new FlapDetector using the given clock.
+func NewFlapDetector(clock clock.Clock) *FlapDetector {
+	return &FlapDetector{
+		clock:       clock,
+		ready:       make(map[string]bool),
+		transitions: make(map[string][]time.Time),
+		recoveries:  make(map[string]time.Time),
+		losses:      make(map[string]time.Time),
+	}
+}
+
+// RecordReadiness records the observed readiness of the endpoint identified by key. It returns true if the
+// endpoint recovered, i.e. transitioned to ready. The first observation of an endpoint is not counted as a transition.
+func (d *FlapDetector) RecordReadiness(key string, ready bool) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	old, ok := d.ready[key]
+	d.ready[key] = ready
+	if !ok || old == ready {
+		return false
+	}
+
+	now := d.clock.Now()
+	if ready {
+		d.recoveries[key] = now
+	} else {
+		d.losses[key] = now
+	}
+	transitions := append(d.transitions[key], now)
+	if len(transitions) > maxTrackedTransitions {
+		transitions = transitions[len(transitions)-maxTrackedTransitions:]
+	}
+	d.transitions[key] = transitions
+
+	if namespace, name, err := cache.SplitMetaNamespaceKey(key); err == nil {
+		endpointFlapsTotal.With(prometheus.Labels{labelNamespace: namespace, labelService: name}).Inc()
+	}
+	return ready
+}
+
+// IsEndpointFlapping returns true if the endpoint identified by the <namespace>/<name> key
+// transitioned between ready and not-ready at least threshold times within the given window.
+func (d *FlapDetector) IsEndpointFlapping(service string, window time.Duration, threshold int) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	since := d.clock.Now().Add(-window)
+	count := 0
+	for _, t := range d.transitions[service] {
+		if t.After(since) {
+			count++
+		}
+	}
+	return count >= threshold
+}
+
+// LastRecoveryTime returns the time of the last observed transition of the endpoint identified
+// by the <namespace>/<name> key from not-ready to ready.
+func (d *FlapDetector) LastRecoveryTime(key string) (time.Time, bool) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	t, ok := d.recoveries[key]
+	return t, ok
+}
+
+// LastLossTime returns the time of the last observed transition of the endpoint identified
+// by the <namespace>/<name> key from ready to not-ready.
+func (d *FlapDetector) LastLossTime(key string) (time.Time, bool) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	t, ok := d.losses[key]
+	return t, ok
+}
+
+// LastTransitionTime returns the time of the last observed readiness transition of the endpoint
+// identified by the <namespace>/<name> key.
+func (d *FlapDetector) LastTransitionTime(key string) (time.Time, bool) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	transitions := d.transitions[key]
+	if len(transitions) == 0 {
+		return time.Time{}, false
+	}
+	return transitions[len(transitions)-1], true
+}
+
+// DegradedNamespaces returns the number of namespaces with an endpoint whose last observed readiness is not-ready
+// and the number of namespaces with any observed endpoint.
+func (d *FlapDetector) DegradedNamespaces() (degraded, total int) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	namespaces := make(map[string]bool)
+	for key, ready := range d.ready {
+		namespace, _, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			continue
+		}
+		namespaces[namespace] = namespaces[namespace] || !ready
+	}
+	for _, isDegraded := range namespaces {
+		if isDegraded {
+			degraded++
+		}
+	}
+	return degraded, len(namespaces)
+}
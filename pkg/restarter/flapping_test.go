@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+func TestIsEndpointFlapping(t *testing.T) {
+	const key = "flapping/kube-apiserver"
+	fakeClock := clock.NewFakeClock(time.Now())
+	d := NewFlapDetector(fakeClock)
+	flaps := endpointFlapsTotal.With(prometheus.Labels{labelNamespace: "flapping", labelService: "kube-apiserver"})
+
+	// ready -> not-ready -> ready -> not-ready -> ready: 4 transitions within a minute.
+	for i, ready := range []bool{true, false, true, false, true} {
+		d.RecordReadiness(key, ready)
+		if i < 4 {
+			fakeClock.Step(15 * time.Second)
+		}
+	}
+	// Repeated observations of the same state are not transitions.
+	d.RecordReadiness(key, true)
+
+	if got := testutil.ToFloat64(flaps); got != 4 {
+		t.Errorf("Expected 4 flaps to be counted but got %v", got)
+	}
+	if !d.IsEndpointFlapping(key, time.Minute, 4) {
+		t.Errorf("Expected endpoint %s to be flapping with 4 transitions within a minute", key)
+	}
+	if d.IsEndpointFlapping(key, time.Minute, 5) {
+		t.Errorf("Expected endpoint %s not to be flapping below the threshold", key)
+	}
+	if d.IsEndpointFlapping(key, 20*time.Second, 3) {
+		t.Errorf("Expected endpoint %s not to be flapping within a window of 20s", key)
+	}
+
+	fakeClock.Step(2 * time.Minute)
+	if d.IsEndpointFlapping(key, time.Minute, 1) {
+		t.Errorf("Expected endpoint %s not to be flapping once the transitions left the window", key)
+	}
+	if d.IsEndpointFlapping("flapping/unknown", time.Minute, 1) {
+		t.Errorf("Expected unknown endpoint not to be flapping")
+	}
+}
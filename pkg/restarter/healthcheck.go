@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog"
+)
+
+// GetHealthCheckGVR resolves the GroupVersionResource of the custom resource referenced by the health check.
+func GetHealthCheckGVR(hc *api.HealthCheckReference) (schema.GroupVersionResource, error) {
+	gv, err := schema.ParseGroupVersion(hc.APIVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid apiVersion %q for health check %s: %v", hc.APIVersion, hc.Name, err)
+	}
+	if hc.Resource == "" {
+		return schema.GroupVersionResource{}, fmt.Errorf("missing resource for health check %s", hc.Name)
+	}
+	return gv.WithResource(hc.Resource), nil
+}
+
+// IsHealthCheckReady checks if the field of the custom resource at the dot-separated path of the health check has
+// the ready value. Missing fields and fields which are no strings are not ready.
+func IsHealthCheckReady(obj *unstructured.Unstructured, hc *api.HealthCheckReference) bool {
+	value, found, err := unstructured.NestedString(obj.Object, strings.Split(hc.ReadyField, ".")...)
+	if err != nil || !found {
+		return false
+	}
+	return value == hc.ReadyValue
+}
+
+// isHealthCheckReady checks if the custom resource referenced by the health check of the dependant pods, if any,
+// reports the dependency as ready. A missing custom resource is not ready.
+func (c *Controller) isHealthCheckReady(namespace string, depPods *api.DependantPods) (bool, error) {
+	hc := depPods.HealthCheck
+	if hc == nil {
+		return true, nil
+	}
+	if c.dynamicClient == nil {
+		return false, fmt.Errorf("no dynamic client configured to get health check %s", hc.Name)
+	}
+	gvr, err := GetHealthCheckGVR(hc)
+	if err != nil {
+		return false, err
+	}
+	obj, err := c.dynamicClient.Resource(gvr).Namespace(namespace).Get(hc.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		klog.Infof("Health check %s %s/%s does not exist. Treating the dependency as not ready.", gvr.Resource, namespace, hc.Name)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error getting health check %s %s: %v", gvr.Resource, hc.Name, err)
+	}
+	return IsHealthCheckReady(obj, hc), nil
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newHealthCheck(name, health string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "health.gardener.cloud/v1alpha1",
+		"kind":       "HealthCheck",
+		"metadata": map[string]interface{}{
+			"namespace": metav1.NamespaceDefault,
+			"name":      name,
+		},
+	}}
+	if health != "" {
+		_ = unstructured.SetNestedField(obj.Object, health, "status", "health")
+	}
+	return obj
+}
+
+func TestIsHealthCheckReady(t *testing.T) {
+	hc := &api.HealthCheckReference{Name: "etcd", ReadyField: "status.health", ReadyValue: "Healthy"}
+	withNonString := newHealthCheck("etcd", "")
+	_ = unstructured.SetNestedField(withNonString.Object, int64(1), "status", "health")
+	tests := []struct {
+		name     string
+		obj      *unstructured.Unstructured
+		expected bool
+	}{
+		{"ready value", newHealthCheck("etcd", "Healthy"), true},
+		{"other value", newHealthCheck("etcd", "Degraded"), false},
+		{"missing field", newHealthCheck("etcd", ""), false},
+		{"field which is no string", withNonString, false},
+	}
+	for _, tc := range tests {
+		if actual := IsHealthCheckReady(tc.obj, hc); actual != tc.expected {
+			t.Errorf("%s: expected ready %v but got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestHealthCheckDrivesDependencyReadiness(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{
+		Selector: &metav1.LabelSelector{MatchLabels: labels},
+		HealthCheck: &api.HealthCheckReference{
+			APIVersion: "health.gardener.cloud/v1alpha1",
+			Resource:   "healthchecks",
+			Name:       "etcd",
+			ReadyField: "status.health",
+			ReadyValue: "Healthy",
+		},
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	gvr, err := GetHealthCheckGVR(depPods.HealthCheck)
+	if err != nil {
+		t.Fatalf("error resolving health check resource: %v", err)
+	}
+
+	hc := newHealthCheck("etcd", "Degraded")
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), hc)
+	pod := newPodInCrashloop("pod-0", labels)
+	client := fake.NewSimpleClientset(pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+	c := &Controller{clientset: client, dynamicClient: dynamicClient}
+
+	if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); err != nil {
+		t.Fatalf("expected pod to be kept while the health check is not ready but got %v", err)
+	}
+
+	if err := unstructured.SetNestedField(hc.Object, "Healthy", "status", "health"); err != nil {
+		t.Fatalf("error setting health: %v", err)
+	}
+	if _, err := dynamicClient.Resource(gvr).Namespace(metav1.NamespaceDefault).Update(hc, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("error updating health check: %v", err)
+	}
+	if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); err == nil {
+		t.Errorf("expected pod to be deleted once the health check is ready")
+	}
+}
+
+func TestMissingHealthCheckIsNotReady(t *testing.T) {
+	depPods := &api.DependantPods{HealthCheck: &api.HealthCheckReference{
+		APIVersion: "health.gardener.cloud/v1alpha1",
+		Resource:   "healthchecks",
+		Name:       "etcd",
+		ReadyField: "status.health",
+		ReadyValue: "Healthy",
+	}}
+	c := &Controller{dynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())}
+	ready, err := c.isHealthCheckReady(metav1.NamespaceDefault, depPods)
+	if err != nil {
+		t.Fatalf("error checking health check: %v", err)
+	}
+	if ready {
+		t.Errorf("expected missing health check not to be ready")
+	}
+}
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultRestartWeight       = 1.0
+	defaultBackoffMinuteWeight = 2.0
+	defaultReadinessGateWeight = 5.0
+)
+
+// HealthScore returns the health score of the pod with the default weights, i.e. 1 per container restart,
+// 2 per minute a container has been in CrashLoopBackOff and 5 per readiness gate which is not true.
+func HealthScore(pod *v1.Pod, now metav1.Time) float64 {
+	return WeightedHealthScore(pod, api.HealthScoreWeights{}, now)
+}
+
+// WeightedHealthScore returns the health score of the pod with the given weights. Missing weights default to
+// the weights of HealthScore. The time in CrashLoopBackOff is measured since the last termination of the container.
+func WeightedHealthScore(pod *v1.Pod, weights api.HealthScoreWeights, now metav1.Time) float64 {
+	var restarts, backoffMinutes, failedGates float64
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += float64(status.RestartCount)
+		if !isContainerInCrashLoopBackOff(status.State) || status.LastTerminationState.Terminated == nil {
+			continue
+		}
+		if finishedAt := status.LastTerminationState.Terminated.FinishedAt; !finishedAt.IsZero() && finishedAt.Before(&now) {
+			backoffMinutes += now.Sub(finishedAt.Time).Minutes()
+		}
+	}
+	for _, gate := range pod.Spec.ReadinessGates {
+		if !IsPodConditionTrue(pod, gate.ConditionType) {
+			failedGates++
+		}
+	}
+	return restarts*weightOrDefault(weights.Restart, defaultRestartWeight) +
+		backoffMinutes*weightOrDefault(weights.BackoffMinute, defaultBackoffMinuteWeight) +
+		failedGates*weightOrDefault(weights.ReadinessGate, defaultReadinessGateWeight)
+}
+
+func weightOrDefault(weight *float64, defaultWeight float64) float64 {
+	if weight == nil {
+		return defaultWeight
+	}
+	return *weight
+}
+
+// isHealthScoreExceeded checks if the health score of the pod reaches the threshold.
+func isHealthScoreExceeded(pod *v1.Pod, score *api.HealthScore, now metav1.Time) bool {
+	var weights api.HealthScoreWeights
+	if score.Weights != nil {
+		weights = *score.Weights
+	}
+	return WeightedHealthScore(pod, weights, now) >= score.Threshold
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHealthScore(t *testing.T) {
+	now := metav1.Now()
+	const gate v1.PodConditionType = "example.com/ready"
+
+	restarting := newPodHealthy("restarting", nil)
+	restarting.Status.ContainerStatuses[0].RestartCount = 3
+
+	backingOff := newPodInCrashloop("backing-off", nil)
+	backingOff.Status.ContainerStatuses[0].RestartCount = 2
+	backingOff.Status.ContainerStatuses[0].LastTerminationState = v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+		FinishedAt: metav1.NewTime(now.Add(-5 * time.Minute)),
+	}}
+
+	gateFailed := newPodHealthy("gate-failed", nil)
+	gateFailed.Spec.ReadinessGates = []v1.PodReadinessGate{{ConditionType: gate}}
+	gateFailed.Status.Conditions = []v1.PodCondition{{Type: gate, Status: v1.ConditionFalse}}
+
+	gatePassed := newPodHealthy("gate-passed", nil)
+	gatePassed.Spec.ReadinessGates = []v1.PodReadinessGate{{ConditionType: gate}}
+	gatePassed.Status.Conditions = []v1.PodCondition{{Type: gate, Status: v1.ConditionTrue}}
+
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		expected float64
+	}{
+		{"healthy", newPodHealthy("healthy", nil), 0},
+		{"restarting", restarting, 3},
+		{"in backoff", backingOff, 2 + 5*2},
+		{"readiness gate failed", gateFailed, 5},
+		{"readiness gate passed", gatePassed, 0},
+	}
+	for _, tc := range tests {
+		if score := HealthScore(tc.pod, now); score < tc.expected-0.01 || score > tc.expected+0.01 {
+			t.Errorf("%s: expected score %f but got %f", tc.name, tc.expected, score)
+		}
+	}
+
+	restartWeight := 10.0
+	if score := WeightedHealthScore(restarting, api.HealthScoreWeights{Restart: &restartWeight}, now); score != 30 {
+		t.Errorf("Expected the configured restart weight to be applied but got score %f", score)
+	}
+}
+
+func TestHealthScoreThreshold(t *testing.T) {
+	now := metav1.Now()
+	pod := newPodHealthy("pod-0", nil)
+	pod.Status.ContainerStatuses[0].RestartCount = 4
+	tests := []struct {
+		name      string
+		threshold float64
+		expected  bool
+	}{
+		{"below threshold", 5, false},
+		{"at threshold", 4, true},
+		{"above threshold", 3, true},
+	}
+	for _, tc := range tests {
+		depPods := &api.DependantPods{HealthScore: &api.HealthScore{Threshold: tc.threshold}}
+		if actual := isHealthScoreExceeded(pod, depPods.HealthScore, now); actual != tc.expected {
+			t.Errorf("%s: expected %v but got %v", tc.name, tc.expected, actual)
+		}
+		if actual := shouldDeleteDependantPod(pod, depPods); actual != tc.expected {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+// maxRecoveryHistoryEntries bounds the number of recovery actions remembered per owner.
+const maxRecoveryHistoryEntries = 100
+
+// RecoveryHistoryEntry is a recovery action taken on a pod of a top-level owner.
+type RecoveryHistoryEntry struct {
+	Timestamp time.Time  `json:"timestamp"`
+	Kind      string     `json:"kind"`
+	Pod       string     `json:"pod"`
+	Service   string     `json:"service"`
+	Reason    string     `json:"reason"`
+	Action    api.Action `json:"action"`
+}
+
+// recoveryHistory remembers the latest recovery actions per top-level owner, identified by its <namespace>/<name> key.
+type recoveryHistory struct {
+	mux     sync.Mutex
+	entries map[string][]RecoveryHistoryEntry
+}
+
+func newRecoveryHistory() *recoveryHistory {
+	return &recoveryHistory{entries: make(map[string][]RecoveryHistoryEntry)}
+}
+
+// add records the entry for the owner, dropping the oldest entries beyond the bound.
+func (h *recoveryHistory) add(owner string, entry RecoveryHistoryEntry) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	entries := append(h.entries[owner], entry)
+	if len(entries) > maxRecoveryHistoryEntries {
+		entries = entries[len(entries)-maxRecoveryHistoryEntries:]
+	}
+	h.entries[owner] = entries
+}
+
+// get returns the entries of the owner recorded at or after since, oldest first.
+func (h *recoveryHistory) get(owner string, since time.Time) []RecoveryHistoryEntry {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	entries := []RecoveryHistoryEntry{}
+	for _, entry := range h.entries[owner] {
+		if !entry.Timestamp.Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// recordRecoveryHistory records the recovery action taken on the pod in the history of its top-level owner.
+// Pods without an owner are not recorded.
+func (c *Controller) recordRecoveryHistory(ctx context.Context, po *v1.Pod, service, reason string, action api.Action) {
+	if c.recoveryHistory == nil {
+		return
+	}
+	owner, err := c.getTopLevelOwner(ctx, po)
+	if err != nil {
+		klog.Errorf("Error resolving the owner of pod %s/%s: %s", po.Namespace, po.Name, err)
+		return
+	}
+	if owner == nil {
+		return
+	}
+	c.recoveryHistory.add(owner.Namespace+"/"+owner.Name, RecoveryHistoryEntry{
+		Timestamp: c.clock.Now().UTC(),
+		Kind:      owner.Kind,
+		Pod:       po.Name,
+		Service:   service,
+		Reason:    reason,
+		Action:    action,
+	})
+}
+
+// HistoryHandler returns an HTTP handler which serves the recovery history of the owner given by the
+// owner=<namespace>/<name> query parameter as JSON. The optional since=<duration> query parameter restricts the
+// history to the recent recovery actions, e.g. since=24h.
+func (c *Controller) HistoryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		owner := r.URL.Query().Get("owner")
+		if owner == "" {
+			http.Error(w, "missing owner=<namespace>/<name> query parameter", http.StatusBadRequest)
+			return
+		}
+		var since time.Time
+		if s := r.URL.Query().Get("since"); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			since = c.clock.Now().Add(-d)
+		}
+		entries := []RecoveryHistoryEntry{}
+		if c.recoveryHistory != nil {
+			entries = c.recoveryHistory.get(owner, since)
+		}
+		writeJSON(w, entries)
+	})
+}
@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHistoryHandler(t *testing.T) {
+	pod0 := newPodInCrashloop("etcd-0", nil)
+	pod0.OwnerReferences = newControllerRef("apps/v1", "StatefulSet", "etcd")
+	pod1 := newPodInCrashloop("etcd-1", nil)
+	pod1.OwnerReferences = newControllerRef("apps/v1", "StatefulSet", "etcd")
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	c := &Controller{
+		clientset:         fake.NewSimpleClientset(pod0, pod1),
+		clock:             fakeClock,
+		serviceDependants: &api.ServiceDependants{},
+		recoveryHistory:   newRecoveryHistory(),
+	}
+
+	if err := c.deletePod(pod0, "kube-apiserver"); err != nil {
+		t.Fatalf("error deleting pod: %v", err)
+	}
+	fakeClock.Step(25 * time.Hour)
+	if err := c.deletePod(pod1, "kube-apiserver"); err != nil {
+		t.Fatalf("error deleting pod: %v", err)
+	}
+
+	getHistory := func(query string) []RecoveryHistoryEntry {
+		rec := httptest.NewRecorder()
+		c.HistoryHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/history?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200 but got %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var entries []RecoveryHistoryEntry
+		if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("%s: error decoding history: %v", query, err)
+		}
+		return entries
+	}
+
+	entries := getHistory("owner=default/etcd")
+	if len(entries) != 2 || entries[0].Pod != "etcd-0" || entries[1].Pod != "etcd-1" {
+		t.Fatalf("Expected both recoveries in the history but got %+v", entries)
+	}
+	if entries[0].Kind != "StatefulSet" || entries[0].Service != "kube-apiserver" || entries[0].Reason != ReasonCrashLoopBackOff {
+		t.Errorf("Expected the details of the recovery in the history but got %+v", entries[0])
+	}
+	if entries := getHistory("owner=default/etcd&since=24h"); len(entries) != 1 || entries[0].Pod != "etcd-1" {
+		t.Errorf("Expected only the recent recovery in the history but got %+v", entries)
+	}
+	if entries := getHistory("owner=default/other"); len(entries) != 0 {
+		t.Errorf("Expected no history of another owner but got %+v", entries)
+	}
+
+	rec := httptest.NewRecorder()
+	c.HistoryHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/history", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without an owner but got %d", rec.Code)
+	}
+}
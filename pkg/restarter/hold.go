@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+)
+
+const eventReasonOwnerHeld = "HeldCrashloopingPods"
+
+// GetOwnerCrashloopFraction returns the fraction of the pods controlled by the owner with the given uid which are in
+// CrashloopBackoff, and the number of its pods.
+func GetOwnerCrashloopFraction(pods []v1.Pod, owner types.UID) (float64, int) {
+	var crashlooping, total int
+	for i := range pods {
+		if ref := ControllerOwnerRef(&pods[i]); ref == nil || ref.UID != owner {
+			continue
+		}
+		total++
+		if IsPodInCrashloopBackoff(pods[i].Status) {
+			crashlooping++
+		}
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	return float64(crashlooping) / float64(total), total
+}
+
+// ownerHold captures why the deletion of the pods of an owner is held.
+type ownerHold struct {
+	controller   *metav1.OwnerReference
+	owner        *v1.ObjectReference
+	crashlooping float64
+	total        int
+}
+
+// reportOwnerHold reports the hold of the deletion of the pods of the controller of the pod, which holds the deletion
+// of the pod until the hold is acknowledged.
+func (c *Controller) reportOwnerHold(po *v1.Pod, service string, hold *ownerHold) {
+	klog.Warningf("%.0f%% of the %d pods of %s %s/%s are crashlooping. Holding the deletion of pod %s until the hold is acknowledged.",
+		hold.crashlooping*100, hold.total, hold.controller.Kind, po.Namespace, hold.controller.Name, po.Name)
+	ownerCrashloopHoldsTotal.With(prometheus.Labels{labelNamespace: po.Namespace, labelService: service}).Inc()
+	if c.Recorder != nil {
+		c.Recorder.Eventf(hold.owner, v1.EventTypeWarning, eventReasonOwnerHeld,
+			"Holding the deletion of the pods after service %s became ready, as %.0f%% of the %d pods of %s %s are crashlooping. Annotate with %s=true to resume.",
+			service, hold.crashlooping*100, hold.total, hold.controller.Kind, hold.controller.Name, holdAcknowledgedAnnotationKey)
+	}
+}
+
+// getOwnerHold returns the hold of the deletion of the pods of the controller of the pod, if any, without reporting it.
+func (c *Controller) getOwnerHold(ctx context.Context, po *v1.Pod, selector labels.Selector, fraction float64) (*ownerHold, error) {
+	controller := ControllerOwnerRef(po)
+	if controller == nil {
+		return nil, nil
+	}
+	pl, err := c.clientset.CoreV1().Pods(po.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods with selector %s: %v", selector.String(), err)
+	}
+	crashlooping, total := GetOwnerCrashloopFraction(excludeTerminatingPods(pl.Items), controller.UID)
+	if crashlooping <= fraction {
+		return nil, nil
+	}
+	owner, err := c.getTopLevelOwner(ctx, po)
+	if err != nil {
+		return nil, err
+	}
+	acknowledged, err := c.isHoldAcknowledged(owner)
+	if err != nil {
+		return nil, err
+	}
+	if acknowledged {
+		klog.V(4).Infof("Hold of %s %s/%s is acknowledged. Processing pod %s.", owner.Kind, owner.Namespace, owner.Name, po.Name)
+		return nil, nil
+	}
+	return &ownerHold{controller: controller, owner: owner, crashlooping: crashlooping, total: total}, nil
+}
+
+// isHoldAcknowledged checks if the owner is annotated to acknowledge the hold of the deletion of its pods. Owners
+// which cannot be read through the dynamic client are not acknowledged.
+func (c *Controller) isHoldAcknowledged(owner *v1.ObjectReference) (bool, error) {
+	if c.dynamicClient == nil {
+		return false, nil
+	}
+	gvr, err := getOwnerGVR(owner)
+	if err != nil {
+		return false, nil
+	}
+	obj, err := c.dynamicClient.Resource(gvr).Namespace(owner.Namespace).Get(owner.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error getting %s %s: %v", owner.Kind, owner.Name, err)
+	}
+	return obj.GetAnnotations()[holdAcknowledgedAnnotationKey] == "true", nil
+}
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newStatefulSetPods(owner string, uid types.UID, labels map[string]string, healthy, crashlooping int) []*v1.Pod {
+	isController := true
+	var pods []*v1.Pod
+	for i := 0; i < healthy+crashlooping; i++ {
+		name := owner + "-" + strconv.Itoa(i)
+		pod := newPodInCrashloop(name, labels)
+		if i < healthy {
+			pod = newPodHealthy(name, labels)
+		}
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: owner, UID: uid, Controller: &isController}}
+		pods = append(pods, pod)
+	}
+	return pods
+}
+
+func TestGetOwnerCrashloopFraction(t *testing.T) {
+	var pods []v1.Pod
+	for _, pod := range append(newStatefulSetPods("etcd", "etcd-uid", nil, 1, 3), newStatefulSetPods("other", "other-uid", nil, 4, 0)...) {
+		pods = append(pods, *pod)
+	}
+	fraction, total := GetOwnerCrashloopFraction(pods, "etcd-uid")
+	if fraction != 0.75 || total != 4 {
+		t.Errorf("Expected 3 of 4 pods of the owner to be crashlooping but got %f of %d", fraction, total)
+	}
+	if fraction, total := GetOwnerCrashloopFraction(pods, "unknown"); fraction != 0 || total != 0 {
+		t.Errorf("Expected no pods of an unknown owner but got %f of %d", fraction, total)
+	}
+}
+
+func TestOwnerCrashloopHold(t *testing.T) {
+	labels := map[string]string{"app": "etcd"}
+	fraction := 0.5
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}, OwnerCrashloopHoldFraction: &fraction}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	objs := []runtime.Object{newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)}
+	pods := newStatefulSetPods("etcd", "etcd-uid", labels, 1, 2)
+	for _, pod := range pods {
+		objs = append(objs, pod)
+	}
+	sts := &unstructured.Unstructured{}
+	sts.SetAPIVersion("apps/v1")
+	sts.SetKind("StatefulSet")
+	sts.SetNamespace(metav1.NamespaceDefault)
+	sts.SetName("etcd")
+	client := fake.NewSimpleClientset(objs...)
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), sts)
+	recorder := &eventRecorder{}
+	c := &Controller{clientset: client, dynamicClient: dynamicClient, serviceDependants: &api.ServiceDependants{}, ownerEvents: newOwnerEvents(), Recorder: recorder}
+	held := ownerCrashloopHoldsTotal.WithLabelValues(metav1.NamespaceDefault, "kube-apiserver")
+	before := testutil.ToFloat64(held)
+
+	// Two of the three pods of the owner are crashlooping, which exceeds the fraction.
+	crashlooping := pods[1]
+	if err := c.processPod(context.TODO(), crashlooping, "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get(crashlooping.Name, metav1.GetOptions{}); err != nil {
+		t.Fatalf("Expected the deletion of pod %s to be held but got %v", crashlooping.Name, err)
+	}
+	if actual := testutil.ToFloat64(held) - before; actual != 1 {
+		t.Errorf("Expected the hold to be counted once but got %f", actual)
+	}
+	if len(recorder.events) != 1 || !strings.Contains(recorder.events[0].message, holdAcknowledgedAnnotationKey) {
+		t.Fatalf("Expected a single event asking to acknowledge the hold but got %v", recorder.events)
+	}
+	if owner, ok := recorder.events[0].object.(*v1.ObjectReference); !ok || owner.Kind != "StatefulSet" || owner.Name != "etcd" {
+		t.Errorf("Expected the event to be recorded for the statefulset but got %v", recorder.events[0].object)
+	}
+
+	// The operator acknowledges the hold, so that the pod is deleted.
+	sts.SetAnnotations(map[string]string{holdAcknowledgedAnnotationKey: "true"})
+	gvr, err := getOwnerGVR(&v1.ObjectReference{APIVersion: "apps/v1", Kind: "StatefulSet"})
+	if err != nil {
+		t.Fatalf("error getting resource of statefulsets: %v", err)
+	}
+	if _, err := dynamicClient.Resource(gvr).Namespace(metav1.NamespaceDefault).Update(sts, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("error acknowledging the hold: %v", err)
+	}
+	if err := c.processPod(context.TODO(), crashlooping, "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get(crashlooping.Name, metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected pod %s to be deleted once the hold is acknowledged", crashlooping.Name)
+	}
+	if actual := testutil.ToFloat64(held) - before; actual != 1 {
+		t.Errorf("Expected no further hold to be counted but got %f", actual)
+	}
+}
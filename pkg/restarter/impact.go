@@ -0,0 +1,189 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// The reasons for deferring the deletion of a pod in the impact report.
+const (
+	DeferredObserveOnly       = "ObserveOnly"
+	DeferredDryRun            = "DryRun"
+	DeferredQuietHours        = "QuietHours"
+	DeferredAvailabilityFloor = "AvailabilityFloor"
+	DeferredLaterWave         = "LaterWave"
+	DeferredOwnerConcurrency  = "OwnerConcurrency"
+)
+
+// ImpactReport captures the pods which would be deleted if all the configured services recovered right now.
+type ImpactReport struct {
+	Dependants []DependantImpact `json:"dependants"`
+	// DeletionsPerSecond is the current rate of the deletions if they are limited.
+	DeletionsPerSecond *float64 `json:"deletionsPerSecond,omitempty"`
+}
+
+// DependantImpact captures the pods of a dependant of a service in a namespace which would be deleted.
+type DependantImpact struct {
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	Dependant string `json:"dependant"`
+	// Pods would be deleted right away.
+	Pods []string `json:"pods"`
+	// Owners are the top-level owners of the pods, i.e. <kind>/<name>.
+	Owners []string `json:"owners"`
+	// Deferred are the pods which would be deleted without the guards deferring them.
+	Deferred []DeferredPod `json:"deferred"`
+}
+
+// DeferredPod is a pod whose deletion would be deferred for the given reason.
+type DeferredPod struct {
+	Pod    string `json:"pod"`
+	Reason string `json:"reason"`
+}
+
+// EstimateImpact reports the pods which would be deleted if all the configured services found in the cluster recovered
+// right now, applying the guards of the config in simulation mode. It does not make any changes.
+func (c *Controller) EstimateImpact(ctx context.Context) (*ImpactReport, error) {
+	ctx = withReconcileCache(ctx)
+	deps := c.getServiceDependants()
+	epl, err := c.clientset.CoreV1().Endpoints(deps.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing endpoints: %v", err)
+	}
+
+	report := &ImpactReport{Dependants: []DependantImpact{}}
+	if c.deletionLimiter != nil {
+		rate := c.deletionLimiter.Limit()
+		report.DeletionsPerSecond = &rate
+	}
+	for _, ep := range epl.Items {
+		srv, ok := deps.Services[ep.Name]
+		if !ok || !c.isNamespaceAllowed(deps, ep.Namespace) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		opts := getNamespaceOptions(deps, ep.Namespace)
+		for i := range srv.Dependants {
+			impact, err := c.estimateDependantImpact(ctx, ep.Namespace, ep.Name, &srv.Dependants[i], opts)
+			if err != nil {
+				return nil, err
+			}
+			report.Dependants = append(report.Dependants, *impact)
+		}
+	}
+	sort.SliceStable(report.Dependants, func(i, j int) bool {
+		a, b := report.Dependants[i], report.Dependants[j]
+		return a.Namespace < b.Namespace || (a.Namespace == b.Namespace && a.Service < b.Service)
+	})
+	return report, nil
+}
+
+func (c *Controller) estimateDependantImpact(ctx context.Context, namespace, service string, depPods *api.DependantPods, opts api.Options) (*DependantImpact, error) {
+	impact := &DependantImpact{Namespace: namespace, Service: service, Dependant: depPods.Name, Pods: []string{}, Owners: []string{}, Deferred: []DeferredPod{}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("error converting label selector of dependant %s: %v", depPods.Name, err)
+	}
+	pl, err := c.clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods with selector %s: %v", selector.String(), err)
+	}
+	pods := selectDependantPods(pl.Items, depPods)
+	var candidates []v1.Pod
+	if depPods.RequireAllUnhealthy {
+		if AllPodsShouldBeDeleted(excludeEvictedPods(pods), depPods) {
+			candidates = pods
+		}
+	} else {
+		for _, po := range pods {
+			if shouldDeleteDependantPod(&po, depPods) {
+				candidates = append(candidates, po)
+			}
+		}
+	}
+
+	recycled := make(map[string]int)
+	owners := make(map[string]bool)
+	for i := range candidates {
+		po := &candidates[i]
+		reason, err := c.estimateDeferral(ctx, po, i, depPods, opts, recycled)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			impact.Deferred = append(impact.Deferred, DeferredPod{Pod: po.Name, Reason: reason})
+			continue
+		}
+		impact.Pods = append(impact.Pods, po.Name)
+		owner, err := c.getTopLevelOwner(ctx, po)
+		if err != nil {
+			return nil, err
+		}
+		if owner != nil && !owners[owner.Kind+"/"+owner.Name] {
+			owners[owner.Kind+"/"+owner.Name] = true
+			impact.Owners = append(impact.Owners, owner.Kind+"/"+owner.Name)
+		}
+	}
+	sort.Strings(impact.Owners)
+	return impact, nil
+}
+
+// estimateDeferral returns the reason for deferring the deletion of the i-th candidate pod of the dependant pods, if any.
+// The pods counted against the availability floors and the concurrent deletions of their owners are tracked in recycled.
+func (c *Controller) estimateDeferral(ctx context.Context, po *v1.Pod, i int, depPods *api.DependantPods, opts api.Options, recycled map[string]int) (string, error) {
+	switch {
+	case depPods.ObserveOnly:
+		return DeferredObserveOnly, nil
+	case isDryRun(opts):
+		return DeferredDryRun, nil
+	case opts.QuietHours != nil && InQuietHours(c.clock.Now(), *opts.QuietHours):
+		return DeferredQuietHours, nil
+	case depPods.RequireAllUnhealthy && depPods.WaveSize != nil && *depPods.WaveSize > 0 && i >= int(*depPods.WaveSize):
+		return DeferredLaterWave, nil
+	}
+	owner, err := c.getTopLevelOwner(ctx, po)
+	if err != nil || owner == nil {
+		return "", err
+	}
+	ownerKey := owner.Kind + "/" + owner.Name
+	if depPods.MinHealthyReplicas != nil || depPods.MaxUnavailableFraction != nil {
+		replicas, err := c.getDesiredReplicas(owner)
+		if err != nil {
+			return "", err
+		}
+		if recycled[ownerKey] >= GetMaxUnavailableReplicas(depPods, replicas) {
+			return DeferredAvailabilityFloor, nil
+		}
+	}
+	if recycled[ownerKey] >= getMaxDeletionsPerOwner(depPods) {
+		return DeferredOwnerConcurrency, nil
+	}
+	recycled[ownerKey]++
+	return "", nil
+}
+
+// ImpactHandler returns an HTTP handler which serves the estimated impact as JSON.
+func (c *Controller) ImpactHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report, err := c.EstimateImpact(r.Context())
+		if err != nil {
+			klog.Errorf("Error estimating impact: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, report)
+	})
+}
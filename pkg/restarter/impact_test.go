@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEstimateImpact(t *testing.T) {
+	isController := true
+	deployment := &unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+	deployment.SetNamespace(metav1.NamespaceDefault)
+	deployment.SetName("kube-controller-manager")
+	if err := unstructured.SetNestedField(deployment.Object, int64(3), "spec", "replicas"); err != nil {
+		t.Fatalf("error setting replicas: %v", err)
+	}
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       metav1.NamespaceDefault,
+			Name:            "kube-controller-manager-5d8f",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "kube-controller-manager", Controller: &isController}},
+		},
+	}
+	kcmLabels := map[string]string{"role": "kube-controller-manager"}
+	etcdLabels := map[string]string{"role": "etcd"}
+	objects := []runtime.Object{rs, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)}
+	for i := 0; i < 3; i++ {
+		pod := newPodInCrashloop(fmt.Sprintf("kcm-%d", i), kcmLabels)
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: rs.Name, Controller: &isController}}
+		objects = append(objects, pod)
+	}
+	objects = append(objects, newPodInCrashloop("etcd-0", etcdLabels), newPodHealthy("etcd-1", etcdLabels))
+
+	minHealthy := int32(2)
+	c := &Controller{
+		clientset:     fake.NewSimpleClientset(objects...),
+		dynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment),
+		serviceDependants: &api.ServiceDependants{
+			Services: map[string]api.Service{
+				"kube-apiserver": {Dependants: []api.DependantPods{
+					{Name: "kube-controller-manager", Selector: &metav1.LabelSelector{MatchLabels: kcmLabels}, MinHealthyReplicas: &minHealthy},
+					{Name: "etcd", Selector: &metav1.LabelSelector{MatchLabels: etcdLabels}, ObserveOnly: true},
+				}},
+			},
+		},
+	}
+
+	report, err := c.EstimateImpact(context.TODO())
+	if err != nil {
+		t.Fatalf("error estimating impact: %v", err)
+	}
+	expected := &ImpactReport{Dependants: []DependantImpact{
+		{
+			Namespace: "default",
+			Service:   "kube-apiserver",
+			Dependant: "kube-controller-manager",
+			Pods:      []string{"kcm-0"},
+			Owners:    []string{"Deployment/kube-controller-manager"},
+			Deferred:  []DeferredPod{{Pod: "kcm-1", Reason: DeferredAvailabilityFloor}, {Pod: "kcm-2", Reason: DeferredAvailabilityFloor}},
+		},
+		{
+			Namespace: "default",
+			Service:   "kube-apiserver",
+			Dependant: "etcd",
+			Pods:      []string{},
+			Owners:    []string{},
+			Deferred:  []DeferredPod{{Pod: "etcd-0", Reason: DeferredObserveOnly}},
+		},
+	}}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected impact report %+v but got %+v", expected, report)
+	}
+	for _, action := range c.clientset.(*fake.Clientset).Actions() {
+		if action.GetVerb() != "list" && action.GetVerb() != "get" {
+			t.Errorf("Expected no changes but got %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+}
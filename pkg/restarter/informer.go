@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TransformFunc transforms an object before it is stored in the cache of an informer.
+type TransformFunc func(obj runtime.Object) error
+
+// CheckInformer verifies that only known fields are dropped from the cached objects.
+func CheckInformer(deps *api.ServiceDependants) error {
+	if deps.Informer == nil {
+		return nil
+	}
+	for _, field := range deps.Informer.StripFields {
+		switch field {
+		case api.FieldManagedFields, api.FieldLabels, api.FieldOwnerReferences, api.FieldAnnotations:
+		default:
+			return fmt.Errorf("unknown field %q to be dropped from the cached objects", field)
+		}
+	}
+	return nil
+}
+
+// getResyncPeriod returns the configured resync period of the informers or the default one.
+func getResyncPeriod(deps *api.ServiceDependants, defaultResync time.Duration) time.Duration {
+	if deps.Informer == nil || deps.Informer.ResyncPeriod == nil {
+		return defaultResync
+	}
+	return deps.Informer.ResyncPeriod.Duration
+}
+
+// StripFieldsTransform returns a transform which drops the given metadata fields from the objects in place. The
+// annotation of the last change of endpoints is kept, as it is required to detect stale endpoints.
+func StripFieldsTransform(fields []string) TransformFunc {
+	return func(obj runtime.Object) error {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		for _, field := range fields {
+			switch field {
+			case api.FieldManagedFields:
+				accessor.SetManagedFields(nil)
+			case api.FieldLabels:
+				accessor.SetLabels(nil)
+			case api.FieldOwnerReferences:
+				accessor.SetOwnerReferences(nil)
+			case api.FieldAnnotations:
+				var annotations map[string]string
+				if value, ok := accessor.GetAnnotations()[v1.EndpointsLastChangeTriggerTime]; ok {
+					annotations = map[string]string{v1.EndpointsLastChangeTriggerTime: value}
+				}
+				accessor.SetAnnotations(annotations)
+			}
+		}
+		return nil
+	}
+}
+
+// newTransformingListWatch returns a ListWatch which applies the transform to copies of all the listed and watched
+// objects, as the source of the list and watch may share the objects it returns.
+func newTransformingListWatch(lw *cache.ListWatch, transform TransformFunc) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			listed, err := lw.List(options)
+			if err != nil {
+				return nil, err
+			}
+			list := listed.DeepCopyObject()
+			if err := meta.EachListItem(list, transform); err != nil {
+				return nil, err
+			}
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			w, err := lw.Watch(options)
+			if err != nil {
+				return nil, err
+			}
+			return watch.Filter(w, func(ev watch.Event) (watch.Event, bool) {
+				if ev.Type != watch.Error {
+					ev.Object = ev.Object.DeepCopyObject()
+					if err := transform(ev.Object); err != nil {
+						return watch.Event{Type: watch.Error, Object: &metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}}, true
+					}
+				}
+				return ev, true
+			}), nil
+		},
+	}
+}
+
+// newEndpointsInformer returns an informer for the endpoints in the namespace, which drops the configured
+// fields from the cached endpoints.
+func newEndpointsInformer(client kubernetes.Interface, namespace string, resync time.Duration, transform TransformFunc) cache.SharedIndexInformer {
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().Endpoints(namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return client.CoreV1().Endpoints(namespace).Watch(options)
+		},
+	}
+	return cache.NewSharedIndexInformer(newTransformingListWatch(lw, transform), &v1.Endpoints{}, resync,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestCheckInformer(t *testing.T) {
+	tests := []struct {
+		name        string
+		informer    *api.Informer
+		expectedErr bool
+	}{
+		{"no informer", nil, false},
+		{"known fields", &api.Informer{StripFields: []string{api.FieldManagedFields, api.FieldLabels}}, false},
+		{"unknown field", &api.Informer{StripFields: []string{"subsets"}}, true},
+	}
+	for _, tc := range tests {
+		if err := CheckInformer(&api.ServiceDependants{Informer: tc.informer}); (err != nil) != tc.expectedErr {
+			t.Errorf("%s: expected error %v but got %v", tc.name, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestGetResyncPeriod(t *testing.T) {
+	deps := &api.ServiceDependants{}
+	if resync := getResyncPeriod(deps, 30*time.Second); resync != 30*time.Second {
+		t.Errorf("Expected the default resync period but got %s", resync)
+	}
+	deps.Informer = &api.Informer{ResyncPeriod: &metav1.Duration{Duration: 10 * time.Minute}}
+	if resync := getResyncPeriod(deps, 30*time.Second); resync != 10*time.Minute {
+		t.Errorf("Expected the configured resync period but got %s", resync)
+	}
+}
+
+func newEndpointWithMetadata(name string) *v1.Endpoints {
+	ep := newEndpoint(name, metav1.NamespaceDefault, map[string]string{"app": name})
+	ep.Annotations[v1.EndpointsLastChangeTriggerTime] = "2021-06-01T12:00:00Z"
+	ep.Annotations["description"] = "dropped"
+	ep.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kube-controller-manager", Operation: metav1.ManagedFieldsOperationUpdate}}
+	ep.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "Service", Name: name}}
+	return ep
+}
+
+func TestInformerStripsFields(t *testing.T) {
+	deps := &api.ServiceDependants{
+		Namespace: metav1.NamespaceDefault,
+		Informer:  &api.Informer{StripFields: []string{api.FieldManagedFields, api.FieldLabels, api.FieldOwnerReferences, api.FieldAnnotations}},
+	}
+	client := fake.NewSimpleClientset(newEndpointWithMetadata("kube-apiserver"))
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory := NewSharedInformerFactory(client, deps, 0)
+	informer := factory.Core().V1().Endpoints().Informer()
+	lister := factory.Core().V1().Endpoints().Lister()
+	factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
+		t.Fatalf("error waiting for the caches to sync")
+	}
+	if _, err := client.CoreV1().Endpoints(metav1.NamespaceDefault).Create(newEndpointWithMetadata("etcd-main")); err != nil {
+		t.Fatalf("error creating endpoints: %v", err)
+	}
+
+	// Both the listed and the watched endpoints are stripped.
+	for _, name := range []string{"kube-apiserver", "etcd-main"} {
+		var ep *v1.Endpoints
+		if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			var err error
+			ep, err = lister.Endpoints(metav1.NamespaceDefault).Get(name)
+			return err == nil, nil
+		}); err != nil {
+			t.Fatalf("error waiting for endpoints %s in the cache: %v", name, err)
+		}
+		if len(ep.ManagedFields) != 0 || len(ep.Labels) != 0 || len(ep.OwnerReferences) != 0 {
+			t.Errorf("Expected the configured fields to be dropped from the cached endpoints %s but got %v", name, ep.ObjectMeta)
+		}
+		if len(ep.Annotations) != 1 || ep.Annotations[v1.EndpointsLastChangeTriggerTime] == "" {
+			t.Errorf("Expected only the last change annotation to be kept for endpoints %s but got %v", name, ep.Annotations)
+		}
+		if len(ep.Subsets) == 0 {
+			t.Errorf("Expected the subsets of the endpoints %s to be kept", name)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// IsLeaseFresh checks if the lease has been renewed within the given maximum age. Leases which have never been
+// renewed are not fresh.
+func IsLeaseFresh(lease *coordinationv1.Lease, maxAge time.Duration, now metav1.Time) bool {
+	if lease.Spec.RenewTime == nil {
+		return false
+	}
+	return !lease.Spec.RenewTime.Add(maxAge).Before(now.Time)
+}
+
+// isLeaseFresh checks if the lease of the dependant pods, if any, is fresh. A missing lease is not fresh.
+func (c *Controller) isLeaseFresh(namespace string, depPods *api.DependantPods) (bool, error) {
+	if depPods.Lease == nil {
+		return true, nil
+	}
+	lease, err := c.clientset.CoordinationV1().Leases(namespace).Get(depPods.Lease.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		klog.Infof("Lease %s/%s does not exist. Treating the dependency as not ready.", namespace, depPods.Lease.Name)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error getting lease %s: %v", depPods.Lease.Name, err)
+	}
+	return IsLeaseFresh(lease, depPods.Lease.MaxAge.Duration, metav1.NewTime(c.clock.Now())), nil
+}
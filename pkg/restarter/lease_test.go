@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newLease(name string, renewTime *metav1.MicroTime) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: name},
+		Spec:       coordinationv1.LeaseSpec{RenewTime: renewTime},
+	}
+}
+
+func TestIsLeaseFresh(t *testing.T) {
+	now := metav1.NewTime(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	renewedAt := func(d time.Duration) *metav1.MicroTime {
+		t := metav1.NewMicroTime(now.Add(-d))
+		return &t
+	}
+	tests := []struct {
+		name     string
+		lease    *coordinationv1.Lease
+		expected bool
+	}{
+		{"fresh lease", newLease("kcm", renewedAt(10*time.Second)), true},
+		{"stale lease", newLease("kcm", renewedAt(time.Minute)), false},
+		{"lease without renew time", newLease("kcm", nil), false},
+	}
+	for _, tc := range tests {
+		if actual := IsLeaseFresh(tc.lease, 30*time.Second, now); actual != tc.expected {
+			t.Errorf("%s: expected fresh %v but got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestLeaseDrivesDependencyReadiness(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	renewedAt := func(d time.Duration) *metav1.MicroTime {
+		t := metav1.NewMicroTime(fakeClock.Now().Add(-d))
+		return &t
+	}
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{
+		Selector: &metav1.LabelSelector{MatchLabels: labels},
+		Lease:    &api.LeaseReference{Name: "kube-controller-manager", MaxAge: metav1.Duration{Duration: 30 * time.Second}},
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		lease            *coordinationv1.Lease
+		expectedDeletion bool
+	}{
+		{"fresh lease", newLease("kube-controller-manager", renewedAt(10*time.Second)), true},
+		{"stale lease", newLease("kube-controller-manager", renewedAt(time.Minute)), false},
+		{"missing lease", nil, false},
+	}
+	for _, tc := range tests {
+		pod := newPodInCrashloop("pod-0", labels)
+		objects := []runtime.Object{pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)}
+		if tc.lease != nil {
+			objects = append(objects, tc.lease)
+		}
+		client := fake.NewSimpleClientset(objects...)
+		c := &Controller{clientset: client, clock: fakeClock}
+
+		if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{})
+		if deleted := err != nil; deleted != tc.expectedDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expectedDeletion, deleted)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+)
+
+// reconcileDependencyLost takes the configured action on the dependant pods of the service once it has stayed not
+// ready for long enough after being ready, and reverts the action once the service is ready again. The reverting
+// does not depend on the controller having taken the action, so that it also happens once after a restart.
+func (c *Controller) reconcileDependencyLost(namespace, service string, srv api.Service, ready bool) error {
+	lost := srv.OnDependencyLost
+	if lost == nil || len(lost.Annotations) == 0 || isDryRun(getNamespaceOptions(c.getServiceDependants(), namespace)) {
+		return nil
+	}
+	key := namespace + "/" + service
+	if ready {
+		if !c.lostAnnotations.mayBeAnnotated(key) {
+			return nil
+		}
+		if err := c.annotateDependantPods(namespace, srv, lost.Annotations, false); err != nil {
+			return err
+		}
+		c.lostAnnotations.reverted(key)
+		return nil
+	}
+	lostAt, ok := c.flapDetector.LastLossTime(key)
+	if !ok {
+		return nil
+	}
+	if lost.After != nil {
+		if due := lostAt.Add(lost.After.Duration); due.After(c.clock.Now()) {
+			c.requeueAt(namespace, service, due)
+			return nil
+		}
+	}
+	klog.Infof("Service %s/%s was lost at %s. Annotating its dependant pods.", namespace, service, lostAt)
+	c.lostAnnotations.annotated(key)
+	return c.annotateDependantPods(namespace, srv, lost.Annotations, true)
+}
+
+// annotateDependantPods adds the annotations to or removes them from the dependant pods of the service which
+// are not annotated accordingly yet. The dependants which are only observed are left untouched.
+func (c *Controller) annotateDependantPods(namespace string, srv api.Service, annotations map[string]string, add bool) error {
+	patch, err := newAnnotationsPatch(annotations, add)
+	if err != nil {
+		return err
+	}
+	for i := range srv.Dependants {
+		depPods := &srv.Dependants[i]
+		if depPods.ObserveOnly {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+		if err != nil {
+			return fmt.Errorf("error converting label selector of dependant %s: %v", depPods.Name, err)
+		}
+		pl, err := c.clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return fmt.Errorf("error listing pods with selector %s: %v", selector.String(), err)
+		}
+		for _, po := range selectDependantPods(pl.Items, depPods) {
+			if all, any := matchAnnotations(&po, annotations); add && all || !add && !any {
+				continue
+			}
+			if _, err := c.clientset.CoreV1().Pods(namespace).Patch(po.Name, types.MergePatchType, patch); err != nil {
+				return fmt.Errorf("error patching annotations of pod %s: %v", po.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// lostAnnotations remembers the services whose dependant pods are known to carry no annotations of the lost
+// dependency, so that the pods are not listed again on every endpoint event once the service is ready. The services
+// unknown after a restart are checked once.
+type lostAnnotations struct {
+	mux   sync.Mutex
+	clean map[string]bool
+}
+
+func newLostAnnotations() *lostAnnotations {
+	return &lostAnnotations{clean: make(map[string]bool)}
+}
+
+// mayBeAnnotated checks if the dependant pods of the service identified by key may carry the annotations.
+func (l *lostAnnotations) mayBeAnnotated(key string) bool {
+	if l == nil {
+		return true
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return !l.clean[key]
+}
+
+// annotated records that the dependant pods of the service identified by key are about to be annotated.
+func (l *lostAnnotations) annotated(key string) {
+	if l == nil {
+		return
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	delete(l.clean, key)
+}
+
+// reverted records that the annotations were removed from the dependant pods of the service identified by key.
+func (l *lostAnnotations) reverted(key string) {
+	if l == nil {
+		return
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.clean[key] = true
+}
+
+// matchAnnotations checks if the pod has all and if it has any of the annotations.
+func matchAnnotations(po *v1.Pod, annotations map[string]string) (all, any bool) {
+	all = true
+	for key, value := range annotations {
+		if v, ok := po.Annotations[key]; ok && v == value {
+			any = true
+		} else {
+			all = false
+		}
+	}
+	return all, any
+}
+
+func newAnnotationsPatch(annotations map[string]string, add bool) ([]byte, error) {
+	values := make(map[string]interface{}, len(annotations))
+	for key, value := range annotations {
+		if add {
+			values[key] = value
+		} else {
+			values[key] = nil
+		}
+	}
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": values},
+	})
+}
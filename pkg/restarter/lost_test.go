@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDependencyLostAction(t *testing.T) {
+	const key = "default/kube-apiserver"
+	labels := map[string]string{"role": "controlplane"}
+	srv := api.Service{
+		Dependants: []api.DependantPods{{Selector: &metav1.LabelSelector{MatchLabels: labels}}},
+		OnDependencyLost: &api.DependencyLostAction{
+			After:       &metav1.Duration{Duration: time.Minute},
+			Annotations: map[string]string{"dependency-watchdog.gardener.cloud/hold": "true"},
+		},
+	}
+	client := fake.NewSimpleClientset(newPodHealthy("pod-0", labels), newPodHealthy("pod-1", labels), newPodHealthy("other", nil))
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	queue := &recordingQueue{added: make(map[interface{}]time.Duration)}
+	c := &Controller{clientset: client, clock: fakeClock, flapDetector: NewFlapDetector(fakeClock), workqueue: queue}
+	assertHeld := func(step string, expected bool) {
+		for _, name := range []string{"pod-0", "pod-1"} {
+			po, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get(name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("%s: error getting pod: %v", step, err)
+			}
+			if held := po.Annotations["dependency-watchdog.gardener.cloud/hold"] == "true"; held != expected {
+				t.Errorf("%s: expected pod %s to be held %v but got annotations %v", step, name, expected, po.Annotations)
+			}
+		}
+	}
+
+	c.flapDetector.RecordReadiness(key, true)
+	c.flapDetector.RecordReadiness(key, false)
+	if err := c.reconcileDependencyLost(metav1.NamespaceDefault, "kube-apiserver", srv, false); err != nil {
+		t.Fatalf("error reconciling lost dependency: %v", err)
+	}
+	assertHeld("lost recently", false)
+	if delay := queue.added[key]; delay != time.Minute {
+		t.Errorf("Expected the service to be requeued once the action is due but got %v", queue.added)
+	}
+
+	fakeClock.Step(time.Minute)
+	if err := c.reconcileDependencyLost(metav1.NamespaceDefault, "kube-apiserver", srv, false); err != nil {
+		t.Fatalf("error reconciling lost dependency: %v", err)
+	}
+	assertHeld("lost for long enough", true)
+	if other, _ := client.CoreV1().Pods(metav1.NamespaceDefault).Get("other", metav1.GetOptions{}); len(other.Annotations) != 0 {
+		t.Errorf("Expected pods of other workloads not to be annotated but got %v", other.Annotations)
+	}
+
+	c.flapDetector.RecordReadiness(key, true)
+	if err := c.reconcileDependencyLost(metav1.NamespaceDefault, "kube-apiserver", srv, true); err != nil {
+		t.Fatalf("error reconciling recovered dependency: %v", err)
+	}
+	assertHeld("recovered", false)
+}
+
+func TestDependencyLostActionIsRevertedOnlyIfPodsMayBeAnnotated(t *testing.T) {
+	const key = "default/kube-apiserver"
+	labels := map[string]string{"role": "controlplane"}
+	srv := api.Service{
+		Dependants: []api.DependantPods{{Selector: &metav1.LabelSelector{MatchLabels: labels}}},
+		OnDependencyLost: &api.DependencyLostAction{
+			Annotations: map[string]string{"dependency-watchdog.gardener.cloud/hold": "true"},
+		},
+	}
+	client := fake.NewSimpleClientset(newPodHealthy("pod-0", labels))
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	c := &Controller{clientset: client, clock: fakeClock, flapDetector: NewFlapDetector(fakeClock), lostAnnotations: newLostAnnotations()}
+	countLists := func() int {
+		n := 0
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "list" {
+				n++
+			}
+		}
+		return n
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := c.reconcileDependencyLost(metav1.NamespaceDefault, "kube-apiserver", srv, true); err != nil {
+			t.Fatalf("error reconciling ready dependency: %v", err)
+		}
+	}
+	if n := countLists(); n != 1 {
+		t.Errorf("Expected the dependant pods to be checked once after a restart but they were listed %d times", n)
+	}
+
+	c.flapDetector.RecordReadiness(key, true)
+	c.flapDetector.RecordReadiness(key, false)
+	if err := c.reconcileDependencyLost(metav1.NamespaceDefault, "kube-apiserver", srv, false); err != nil {
+		t.Fatalf("error reconciling lost dependency: %v", err)
+	}
+	if err := c.reconcileDependencyLost(metav1.NamespaceDefault, "kube-apiserver", srv, true); err != nil {
+		t.Fatalf("error reconciling recovered dependency: %v", err)
+	}
+	if n := countLists(); n != 3 {
+		t.Errorf("Expected the dependant pods to be checked again after the loss but they were listed %d times in total", n)
+	}
+	if po, _ := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); len(po.Annotations) != 0 {
+		t.Errorf("Expected the annotations to be reverted but got %v", po.Annotations)
+	}
+}
+
+func TestDependencyLostActionIsSkippedWithoutDeletions(t *testing.T) {
+	const key = "default/kube-apiserver"
+	labels := map[string]string{"role": "controlplane"}
+	dryRun := true
+	for _, tc := range []struct {
+		name        string
+		options     api.Options
+		observeOnly bool
+	}{
+		{name: "dry run", options: api.Options{DryRun: &dryRun}},
+		{name: "observe only", observeOnly: true},
+	} {
+		srv := api.Service{
+			Dependants: []api.DependantPods{{Selector: &metav1.LabelSelector{MatchLabels: labels}, ObserveOnly: tc.observeOnly}},
+			OnDependencyLost: &api.DependencyLostAction{
+				Annotations: map[string]string{"dependency-watchdog.gardener.cloud/hold": "true"},
+			},
+		}
+		client := fake.NewSimpleClientset(newPodHealthy("pod-0", labels))
+		fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+		c := &Controller{
+			clientset:         client,
+			clock:             fakeClock,
+			flapDetector:      NewFlapDetector(fakeClock),
+			serviceDependants: &api.ServiceDependants{Options: tc.options},
+		}
+		c.flapDetector.RecordReadiness(key, true)
+		c.flapDetector.RecordReadiness(key, false)
+		if err := c.reconcileDependencyLost(metav1.NamespaceDefault, "kube-apiserver", srv, false); err != nil {
+			t.Fatalf("%s: error reconciling lost dependency: %v", tc.name, err)
+		}
+		if po, _ := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); len(po.Annotations) != 0 {
+			t.Errorf("%s: expected the pod not to be annotated but got %v", tc.name, po.Annotations)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	metricsNamespace = "dependencywatchdog"
+	labelNamespace   = "namespace"
+	labelService     = "service"
+	labelNode        = "node"
+	labelQueue       = "name"
+	labelAction      = "action"
+	labelReason      = "reason"
+)
+
+var (
+	endpointFlapsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "endpoint_flaps_total",
+			Help:      "The accumulated total number of readiness transitions of the watched endpoints.",
+		},
+		[]string{labelNamespace, labelService},
+	)
+
+	nodeLocalizedCrashloopTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "node_localized_crashloop_total",
+			Help:      "The accumulated total number of pod deletions skipped because the CrashloopBackoff was localized on the node.",
+		},
+		[]string{labelNode},
+	)
+
+	candidateDecisionDiffTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "candidate_decision_diff_total",
+			Help:      "The accumulated total number of pod deletion decisions of the candidate config differing from the active config.",
+		},
+		[]string{labelNamespace, labelService},
+	)
+
+	configReloadsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "config_reloads_total",
+			Help:      "The accumulated total number of successful config reloads.",
+		},
+	)
+
+	configReloadErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "config_reload_errors_total",
+			Help:      "The accumulated total number of failed config reloads.",
+		},
+	)
+
+	configLastReloadTimestampSeconds = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "config_last_reload_timestamp_seconds",
+			Help:      "The time of the last successful config reload in seconds since the epoch.",
+		},
+	)
+
+	deletionWarningsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "deletion_warnings_total",
+			Help:      "The accumulated total number of warnings about upcoming pod deletions.",
+		},
+		[]string{labelNamespace, labelService},
+	)
+
+	deletionVetoesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "deletion_vetoes_total",
+			Help:      "The accumulated total number of warned pod deletions vetoed by annotating the pod to be ignored.",
+		},
+		[]string{labelNamespace, labelService},
+	)
+
+	deletionWaitTimeoutsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "deletion_wait_timeouts_total",
+			Help:      "The accumulated total number of pod deletions not confirmed by the apiserver within the configured timeout.",
+		},
+		[]string{labelNamespace, labelService},
+	)
+
+	circuitOpen = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "circuit_open",
+			Help:      "Whether all pod deletions are suppressed because too many namespaces are degraded at once.",
+		},
+	)
+
+	effectiveDeletionRate = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "effective_deletions_per_second",
+			Help:      "The rate of pod deletions currently allowed after adapting to the throttling of the apiserver.",
+		},
+	)
+
+	notReadyTimeoutsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "not_ready_timeouts_total",
+			Help:      "The accumulated total number of services which stayed not ready for longer than their maximum not-ready duration.",
+		},
+		[]string{labelNamespace, labelService},
+	)
+
+	ownerCrashloopHoldsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "owner_crashloop_holds_total",
+			Help:      "The accumulated total number of pod deletions held because too many pods of their owner were crashlooping.",
+		},
+		[]string{labelNamespace, labelService},
+	)
+
+	retryBudgetDeferralsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "retry_budget_deferrals_total",
+			Help:      "The accumulated total number of pod deletions deferred to the next reconcile because the retry budget of the reconcile was exhausted.",
+		},
+		[]string{labelNamespace, labelService},
+	)
+
+	decisionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "decisions_total",
+			Help:      "The accumulated total number of decisions on the dependant pods by action and reason.",
+		},
+		[]string{labelAction, labelReason},
+	)
+
+	workqueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "workqueue_depth",
+			Help:      "The number of items waiting in the workqueue to be reconciled.",
+		},
+		[]string{labelQueue},
+	)
+
+	workqueueAddsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "workqueue_adds_total",
+			Help:      "The accumulated total number of items added to the workqueue.",
+		},
+		[]string{labelQueue},
+	)
+
+	workqueueRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "workqueue_retries_total",
+			Help:      "The accumulated total number of items requeued with a rate limit after a failed reconcile.",
+		},
+		[]string{labelQueue},
+	)
+
+	workqueueDroppedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "workqueue_dropped_total",
+			Help:      "The accumulated total number of items removed from the workqueue without being reconciled because they are invalid.",
+		},
+		[]string{labelQueue},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(endpointFlapsTotal)
+	prometheus.MustRegister(nodeLocalizedCrashloopTotal)
+	prometheus.MustRegister(candidateDecisionDiffTotal)
+	prometheus.MustRegister(configReloadsTotal)
+	prometheus.MustRegister(configReloadErrorsTotal)
+	prometheus.MustRegister(configLastReloadTimestampSeconds)
+	prometheus.MustRegister(deletionWarningsTotal)
+	prometheus.MustRegister(deletionVetoesTotal)
+	prometheus.MustRegister(deletionWaitTimeoutsTotal)
+	prometheus.MustRegister(circuitOpen)
+	prometheus.MustRegister(effectiveDeletionRate)
+	prometheus.MustRegister(notReadyTimeoutsTotal)
+	prometheus.MustRegister(ownerCrashloopHoldsTotal)
+	prometheus.MustRegister(retryBudgetDeferralsTotal)
+	prometheus.MustRegister(decisionsTotal)
+	prometheus.MustRegister(workqueueDepth)
+	prometheus.MustRegister(workqueueAddsTotal)
+	prometheus.MustRegister(workqueueRetriesTotal)
+	prometheus.MustRegister(workqueueDroppedTotal)
+
+	workqueue.SetProvider(workqueueMetricsProvider{})
+}
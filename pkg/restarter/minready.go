@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog"
+)
+
+// minReadySecondsResolver resolves the minReadySeconds of the dependant pods. Unless configured for the dependant
+// pods, they are inherited from the controllers of the pods, e.g. their ReplicaSets or StatefulSets. The values of
+// the controllers are cached for the lifetime of the resolver, i.e. a single reconcile.
+type minReadySecondsResolver struct {
+	c       *Controller
+	depPods *api.DependantPods
+	owners  map[string]int32
+}
+
+func (c *Controller) newMinReadySecondsResolver(depPods *api.DependantPods) *minReadySecondsResolver {
+	return &minReadySecondsResolver{c: c, depPods: depPods, owners: make(map[string]int32)}
+}
+
+// resolve returns the minReadySeconds of the pod. Controllers which cannot be read are treated as not
+// requiring the pods to be ready for a minimum duration.
+func (r *minReadySecondsResolver) resolve(po *v1.Pod) int32 {
+	if r.depPods.MinReadySeconds != nil {
+		return *r.depPods.MinReadySeconds
+	}
+	controller := ControllerOwnerRef(po)
+	if controller == nil || r.c.dynamicClient == nil {
+		return 0
+	}
+	key := controller.Kind + "/" + controller.Name
+	if minReadySeconds, ok := r.owners[key]; ok {
+		return minReadySeconds
+	}
+	minReadySeconds, err := r.c.getOwnerMinReadySeconds(&v1.ObjectReference{
+		APIVersion: controller.APIVersion,
+		Kind:       controller.Kind,
+		Namespace:  po.Namespace,
+		Name:       controller.Name,
+	})
+	if err != nil {
+		klog.Errorf("Error getting minReadySeconds of pod %s/%s: %s", po.Namespace, po.Name, err)
+		return 0
+	}
+	r.owners[key] = minReadySeconds
+	return minReadySeconds
+}
+
+func (c *Controller) getOwnerMinReadySeconds(owner *v1.ObjectReference) (int32, error) {
+	gvr, err := getOwnerGVR(owner)
+	if err != nil {
+		return 0, err
+	}
+	obj, err := c.dynamicClient.Resource(gvr).Namespace(owner.Namespace).Get(owner.Name, metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("error getting %s %s: %v", owner.Kind, owner.Name, err)
+	}
+	minReadySeconds, _, err := unstructured.NestedInt64(obj.Object, "spec", "minReadySeconds")
+	if err != nil {
+		return 0, err
+	}
+	return int32(minReadySeconds), nil
+}
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestMinReadySecondsInheritedFromController(t *testing.T) {
+	rs := &unstructured.Unstructured{}
+	rs.SetAPIVersion("apps/v1")
+	rs.SetKind("ReplicaSet")
+	rs.SetNamespace(metav1.NamespaceDefault)
+	rs.SetName("kube-controller-manager-5d8f")
+	if err := unstructured.SetNestedField(rs.Object, int64(30), "spec", "minReadySeconds"); err != nil {
+		t.Fatalf("error setting minReadySeconds: %v", err)
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), rs)
+	c := &Controller{dynamicClient: dynamicClient}
+
+	now := metav1.Now()
+	newReadyPod := func(name string, readyFor time.Duration) *v1.Pod {
+		pod := newPodHealthy(name, nil)
+		pod.OwnerReferences = newControllerRef("apps/v1", "ReplicaSet", rs.GetName())
+		pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue, LastTransitionTime: metav1.NewTime(now.Add(-readyFor))}}
+		return pod
+	}
+	recentlyReady := newReadyPod("pod-0", 10*time.Second)
+	readyForLong := newReadyPod("pod-1", time.Minute)
+	depPods := &api.DependantPods{}
+	resolver := c.newMinReadySecondsResolver(depPods)
+
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		expected bool
+	}{
+		{"ready for less than the minReadySeconds of the controller", recentlyReady, false},
+		{"ready for longer than the minReadySeconds of the controller", readyForLong, true},
+		{"not controlled", newPodHealthy("pod-2", nil), true},
+	}
+	for _, tc := range tests {
+		if got := isDependantPodAvailable(tc.pod, depPods, resolver.resolve(tc.pod), now); got != tc.expected {
+			t.Errorf("%s: expected available %v but got %v", tc.name, tc.expected, got)
+		}
+	}
+	if gets := len(dynamicClient.Actions()); gets != 1 {
+		t.Errorf("Expected the controller to be read once per reconcile but got %d requests", gets)
+	}
+
+	configured := int32(5)
+	if got := c.newMinReadySecondsResolver(&api.DependantPods{MinReadySeconds: &configured}).resolve(recentlyReady); got != configured {
+		t.Errorf("Expected the configured minReadySeconds %d but got %d", configured, got)
+	}
+}
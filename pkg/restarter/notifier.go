@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"k8s.io/klog"
+)
+
+const (
+	defaultWebhookTimeout = 5 * time.Second
+	// SignatureHeader is the HTTP header carrying the HMAC-SHA256 signature of the webhook payload.
+	SignatureHeader = "X-Dependency-Watchdog-Signature"
+)
+
+// DeletionNotification is the payload posted to the webhook for every pod deleted by the dependency-watchdog.
+type DeletionNotification struct {
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod"`
+	Service   string    `json:"service"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type webhookNotifier struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func newWebhookNotifier(webhook *api.Webhook) *webhookNotifier {
+	n := &webhookNotifier{
+		url:    webhook.URL,
+		client: &http.Client{Timeout: defaultWebhookTimeout},
+	}
+	if webhook.Secret != "" {
+		n.secret = []byte(webhook.Secret)
+	}
+	return n
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of the payload with the given secret
+// as sent in the SignatureHeader.
+func Sign(payload, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notify posts the notification to the webhook. It is best-effort, so failures are only logged.
+func (n *webhookNotifier) notify(notification *DeletionNotification) {
+	if err := n.post(notification); err != nil {
+		klog.Errorf("Error notifying webhook about deletion of pod %s/%s: %s", notification.Namespace, notification.Pod, err)
+	}
+}
+
+func (n *webhookNotifier) post(notification *DeletionNotification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != nil {
+		req.Header.Set(SignatureHeader, Sign(payload, n.secret))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d from webhook", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeletePodNotifiesWebhook(t *testing.T) {
+	const secret = "s3cr3t"
+	type request struct {
+		body      []byte
+		signature string
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- request{body: body, signature: r.Header.Get(SignatureHeader)}
+	}))
+	defer server.Close()
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, reason := range []string{ReasonCrashLoopBackOff, reasonAllPodsUnhealthy} {
+		pod := newPodInCrashloop("pod-0", nil)
+		c := &Controller{
+			clientset: fake.NewSimpleClientset(pod),
+			clock:     clock.NewFakeClock(now),
+			notifier:  newWebhookNotifier(&api.Webhook{URL: server.URL, Secret: secret}),
+		}
+		if err := c.deletePodForReason(pod, "kube-apiserver", reason, nil); err != nil {
+			t.Fatalf("error deleting pod: %v", err)
+		}
+
+		var req request
+		select {
+		case req = <-requests:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("webhook was not notified about the deletion for reason %q", reason)
+		}
+
+		var n DeletionNotification
+		if err := json.Unmarshal(req.body, &n); err != nil {
+			t.Fatalf("error decoding payload: %v", err)
+		}
+		expected := DeletionNotification{Namespace: "default", Pod: "pod-0", Service: "kube-apiserver", Reason: reason, Timestamp: now}
+		if n != expected {
+			t.Errorf("Expected payload %+v but got %+v", expected, n)
+		}
+		if expectedSignature := Sign(req.body, []byte(secret)); req.signature != expectedSignature {
+			t.Errorf("Expected signature %s but got %s", expectedSignature, req.signature)
+		}
+	}
+}
+
+func TestWebhookFailureDoesNotBlockDeletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	n := newWebhookNotifier(&api.Webhook{URL: server.URL})
+	if err := n.post(&DeletionNotification{Pod: "pod-0"}); err == nil {
+		t.Errorf("Expected an error for a failing webhook")
+	}
+
+	pod := newPodInCrashloop("pod-0", nil)
+	c := &Controller{clientset: fake.NewSimpleClientset(pod), clock: clock.RealClock{}, notifier: n}
+	if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+		t.Errorf("Expected deletion to succeed despite the failing webhook but got: %v", err)
+	}
+}
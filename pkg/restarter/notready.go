@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog"
+)
+
+// NotReadyTimeoutHandler takes the alternate action for a service which has been not ready since the given time for
+// longer than its maximum not-ready duration, e.g. scaling its dependants to zero or raising a critical alert.
+type NotReadyTimeoutHandler func(namespace, service string, notReadySince time.Time) error
+
+// notReadyTracker tracks since when the services have been not ready and whether the alternate action was already
+// taken for the current outage. Services are identified by the <namespace>/<service> key. Unlike the readiness
+// transitions, the first observation of a service counts, so that a service which never became ready times out
+// as well. Without a tracker, i.e. on a nil receiver, no service is tracked.
+type notReadyTracker struct {
+	mux   sync.Mutex
+	since map[string]time.Time
+	fired map[string]bool
+}
+
+func newNotReadyTracker() *notReadyTracker {
+	return &notReadyTracker{since: make(map[string]time.Time), fired: make(map[string]bool)}
+}
+
+// observe records the readiness of the service identified by key at now and returns since when it has been not
+// ready, if it is not ready.
+func (t *notReadyTracker) observe(key string, ready bool, now time.Time) (time.Time, bool) {
+	if t == nil {
+		return time.Time{}, false
+	}
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if ready {
+		delete(t.since, key)
+		delete(t.fired, key)
+		return time.Time{}, false
+	}
+	since, ok := t.since[key]
+	if !ok {
+		since = now
+		t.since[key] = since
+	}
+	return since, true
+}
+
+// fire marks the alternate action for the current outage of the service identified by key as taken. It returns
+// false if it was already taken.
+func (t *notReadyTracker) fire(key string) bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.fired[key] {
+		return false
+	}
+	t.fired[key] = true
+	return true
+}
+
+// reconcileNotReadyTimeout takes the alternate action once per outage of the service after it has been not ready
+// for its maximum not-ready duration, instead of waiting for it to recover indefinitely.
+func (c *Controller) reconcileNotReadyTimeout(namespace, service string, srv api.Service, ready bool) error {
+	if srv.MaxNotReadyDuration == nil {
+		return nil
+	}
+	key := namespace + "/" + service
+	since, notReady := c.notReady.observe(key, ready, c.clock.Now())
+	if !notReady {
+		return nil
+	}
+	if due := since.Add(srv.MaxNotReadyDuration.Duration); due.After(c.clock.Now()) {
+		c.requeueAt(namespace, service, due)
+		return nil
+	}
+	if !c.notReady.fire(key) {
+		return nil
+	}
+	klog.Errorf("Service %s/%s has not been ready since %s, longer than %s. Giving up waiting for it.",
+		namespace, service, since.Format(time.RFC3339), srv.MaxNotReadyDuration.Duration)
+	notReadyTimeoutsTotal.With(prometheus.Labels{labelNamespace: namespace, labelService: service}).Inc()
+	if c.NotReadyTimeoutHandler == nil {
+		return nil
+	}
+	return c.NotReadyTimeoutHandler(namespace, service, since)
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+func TestNotReadyTimeout(t *testing.T) {
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	fakeClock := clock.NewFakeClock(start)
+	var fired []time.Time
+	c := &Controller{
+		clock:    fakeClock,
+		notReady: newNotReadyTracker(),
+		NotReadyTimeoutHandler: func(namespace, service string, notReadySince time.Time) error {
+			fired = append(fired, notReadySince)
+			return nil
+		},
+	}
+	srv := api.Service{MaxNotReadyDuration: &metav1.Duration{Duration: 5 * time.Minute}}
+	timeouts := testutil.ToFloat64(notReadyTimeoutsTotal.WithLabelValues(metav1.NamespaceDefault, "etcd-main"))
+
+	observe := func(ready bool) {
+		if err := c.reconcileNotReadyTimeout(metav1.NamespaceDefault, "etcd-main", srv, ready); err != nil {
+			t.Fatalf("error reconciling the not-ready timeout: %v", err)
+		}
+	}
+	steps := []struct {
+		step          time.Duration
+		ready         bool
+		expectedFired int
+	}{
+		{0, false, 0},
+		{4 * time.Minute, false, 0},
+		{time.Minute, false, 1},
+		// The alternate action is taken once per outage.
+		{time.Minute, false, 1},
+		{time.Minute, true, 1},
+		{time.Minute, false, 1},
+		{4 * time.Minute, false, 1},
+		{time.Minute, false, 2},
+	}
+	for i, s := range steps {
+		fakeClock.Step(s.step)
+		observe(s.ready)
+		if len(fired) != s.expectedFired {
+			t.Fatalf("step %d: expected the alternate action to be taken %d times but got %d", i, s.expectedFired, len(fired))
+		}
+	}
+	if !fired[0].Equal(start) {
+		t.Errorf("Expected the first outage to be not ready since %s but got %s", start, fired[0])
+	}
+	if expected := start.Add(8 * time.Minute); !fired[1].Equal(expected) {
+		t.Errorf("Expected the second outage to be not ready since %s but got %s", expected, fired[1])
+	}
+	if actual := testutil.ToFloat64(notReadyTimeoutsTotal.WithLabelValues(metav1.NamespaceDefault, "etcd-main")) - timeouts; actual != 2 {
+		t.Errorf("Expected 2 not-ready timeouts to be counted but got %f", actual)
+	}
+}
+
+func TestNotReadyTimeoutNotConfigured(t *testing.T) {
+	c := &Controller{
+		clock:    clock.NewFakeClock(time.Now()),
+		notReady: newNotReadyTracker(),
+		NotReadyTimeoutHandler: func(namespace, service string, notReadySince time.Time) error {
+			t.Errorf("Expected no alternate action without a maximum not-ready duration")
+			return nil
+		},
+	}
+	if err := c.reconcileNotReadyTimeout(metav1.NamespaceDefault, "etcd-main", api.Service{}, false); err != nil {
+		t.Fatalf("error reconciling the not-ready timeout: %v", err)
+	}
+}
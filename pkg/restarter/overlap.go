@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+)
+
+const eventReasonDependencyOverlap = "DependencyOverlap"
+
+// GetBackingPods returns the <namespace>/<name> keys of the pods backing the ready and the not ready addresses of
+// the endpoints.
+func GetBackingPods(ep *v1.Endpoints) sets.String {
+	pods := sets.NewString()
+	add := func(addresses []v1.EndpointAddress) {
+		for _, address := range addresses {
+			if address.TargetRef == nil || address.TargetRef.Kind != "Pod" {
+				continue
+			}
+			namespace := address.TargetRef.Namespace
+			if namespace == "" {
+				namespace = ep.Namespace
+			}
+			pods.Insert(namespace + "/" + address.TargetRef.Name)
+		}
+	}
+	for _, subset := range ep.Subsets {
+		add(subset.Addresses)
+		add(subset.NotReadyAddresses)
+	}
+	return pods
+}
+
+// getDependencyBackingPods returns the keys of the pods backing the service. A service without endpoints has no
+// backing pods.
+func (c *Controller) getDependencyBackingPods(ctx context.Context, namespace, service string) (sets.String, error) {
+	ep, err := c.getEndpoints(ctx, namespace, service)
+	if apierrors.IsNotFound(err) {
+		return sets.NewString(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting endpoint %s/%s: %v", namespace, service, err)
+	}
+	return GetBackingPods(ep), nil
+}
+
+// isBackingDependency checks if the dependant pod backs the service it depends on, i.e. the selector of the
+// dependant overlaps with the pods of the service. Such pods are never recycled unless the dependant allows it,
+// as recycling them destabilizes the dependency itself.
+func isBackingDependency(po *v1.Pod, depPods *api.DependantPods, backing sets.String) bool {
+	return !depPods.AllowDependencyOverlap && backing.Has(po.Namespace+"/"+po.Name)
+}
+
+// reportBackingDependency reports that the dependant pod is not recycled as it backs the service it depends on.
+func (c *Controller) reportBackingDependency(po *v1.Pod, service string, depPods *api.DependantPods) {
+	klog.Errorf("Pod %s/%s selected by dependant %s backs service %s it depends on. Refusing to recycle it, check the selector of the dependant.",
+		po.Namespace, po.Name, depPods.Name, service)
+	if c.Recorder != nil {
+		c.Recorder.Eventf(po, v1.EventTypeWarning, eventReasonDependencyOverlap,
+			"Not recycling pod backing service %s, which it depends on as dependant %s. Check the selector of the dependant.", service, depPods.Name)
+	}
+}
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newEndpointBackedBy returns the endpoint of the service backed by the given ready pods.
+func newEndpointBackedBy(service string, pods ...string) *v1.Endpoints {
+	ep := newEndpoint(service, metav1.NamespaceDefault, nil)
+	ep.Subsets[0].Addresses = nil
+	for _, pod := range pods {
+		ep.Subsets[0].Addresses = append(ep.Subsets[0].Addresses, v1.EndpointAddress{
+			IP:        "10.1.0.52",
+			TargetRef: &v1.ObjectReference{Kind: "Pod", Namespace: metav1.NamespaceDefault, Name: pod},
+		})
+	}
+	return ep
+}
+
+func TestGetBackingPods(t *testing.T) {
+	ep := newEndpointBackedBy("etcd-main", "etcd-main-0")
+	ep.Subsets[0].NotReadyAddresses = []v1.EndpointAddress{
+		{IP: "10.1.0.53", TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "etcd-main-1"}},
+		{IP: "10.1.0.54"},
+	}
+	expected := []string{"default/etcd-main-0", "default/etcd-main-1"}
+	if actual := GetBackingPods(ep).List(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected the backing pods %v but got %v", expected, actual)
+	}
+}
+
+func TestDependencyOverlap(t *testing.T) {
+	// The selector of the dependant accidentally matches the pods of the dependency as well.
+	labels := map[string]string{"gardener.cloud/role": "controlplane"}
+	selector := &metav1.LabelSelector{MatchLabels: labels}
+
+	tests := []struct {
+		name            string
+		depPods         api.DependantPods
+		expectedDeleted map[string]bool
+	}{
+		{"overlapping", api.DependantPods{Name: "controlplane", Selector: selector},
+			map[string]bool{"etcd-main-0": false, "kube-apiserver-0": true}},
+		{"all unhealthy", api.DependantPods{Name: "controlplane", Selector: selector, RequireAllUnhealthy: true},
+			map[string]bool{"etcd-main-0": false, "kube-apiserver-0": true}},
+		{"overlap allowed", api.DependantPods{Name: "controlplane", Selector: selector, AllowDependencyOverlap: true},
+			map[string]bool{"etcd-main-0": true, "kube-apiserver-0": true}},
+	}
+	for _, tc := range tests {
+		dependency := newPodInCrashloop("etcd-main-0", labels)
+		dependant := newPodInCrashloop("kube-apiserver-0", labels)
+		client := fake.NewSimpleClientset(dependency, dependant, newEndpointBackedBy("etcd-main", dependency.Name))
+		recorder := &eventRecorder{}
+		c := &Controller{clientset: client, serviceDependants: &api.ServiceDependants{}, ownerEvents: newOwnerEvents(), Recorder: recorder}
+		sel, err := metav1.LabelSelectorAsSelector(tc.depPods.Selector)
+		if err != nil {
+			t.Fatalf("error converting label selector: %v", err)
+		}
+
+		for _, pod := range []*v1.Pod{dependency, dependant} {
+			if _, err := client.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{}); err != nil {
+				continue
+			}
+			if err := c.processPod(context.TODO(), pod, "etcd-main", &tc.depPods, sel); err != nil {
+				t.Fatalf("%s: error processing pod %s: %v", tc.name, pod.Name, err)
+			}
+		}
+		for name, expectedDeleted := range tc.expectedDeleted {
+			_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get(name, metav1.GetOptions{})
+			if deleted := err != nil; deleted != expectedDeleted {
+				t.Errorf("%s: expected pod %s to be deleted %v but got %v", tc.name, name, expectedDeleted, deleted)
+			}
+		}
+		var overlapEvents int
+		for _, ev := range recorder.events {
+			if pod, ok := ev.object.(*v1.Pod); ok && pod.Name == dependency.Name && strings.HasPrefix(ev.message, "Not recycling pod backing service") {
+				overlapEvents++
+			}
+		}
+		if expected := !tc.depPods.AllowDependencyOverlap; (overlapEvents > 0) != expected {
+			t.Errorf("%s: expected an event for the overlapping pod %v but got %d", tc.name, expected, overlapEvents)
+		}
+	}
+}
+
+func TestRunPreflightCheckDependencyOverlap(t *testing.T) {
+	labels := map[string]string{"gardener.cloud/role": "controlplane"}
+	deps := &api.ServiceDependants{
+		Namespace: metav1.NamespaceDefault,
+		Services: map[string]api.Service{
+			"etcd-main": {Dependants: []api.DependantPods{
+				{Name: "controlplane", Selector: &metav1.LabelSelector{MatchLabels: labels}},
+			}},
+		},
+	}
+	client := fake.NewSimpleClientset(
+		newPodHealthy("etcd-main-0", labels),
+		newPodHealthy("kube-apiserver-0", labels),
+		newEndpointBackedBy("etcd-main", "etcd-main-0"),
+	)
+	c := &Controller{clientset: client, serviceDependants: deps}
+
+	expected := []string{
+		"selector gardener.cloud/role=controlplane of dependant controlplane of service etcd-main matches pods backing the service: default/etcd-main-0",
+	}
+	if warnings := c.RunPreflightCheck(); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Expected the warnings %v but got %v", expected, warnings)
+	}
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog"
+)
+
+// ownerChangeCacheTTL is the duration for which the time of the last change of an owner is cached.
+const ownerChangeCacheTTL = time.Minute
+
+// ownerChangeCache caches the time of the last change of the owners of the dependant pods, so that the owner is not
+// read again for every pod of a reconcile.
+type ownerChangeCache struct {
+	lock    sync.Mutex
+	entries map[string]ownerChangeEntry
+}
+
+type ownerChangeEntry struct {
+	changedAt time.Time
+	fetchedAt time.Time
+}
+
+func newOwnerChangeCache() *ownerChangeCache {
+	return &ownerChangeCache{entries: make(map[string]ownerChangeEntry)}
+}
+
+// get returns the cached time of the last change of the owner if it was fetched within the TTL. Without a cache,
+// no time is cached.
+func (oc *ownerChangeCache) get(key string, now time.Time) (time.Time, bool) {
+	if oc == nil {
+		return time.Time{}, false
+	}
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	entry, ok := oc.entries[key]
+	if !ok || now.Sub(entry.fetchedAt) > ownerChangeCacheTTL {
+		delete(oc.entries, key)
+		return time.Time{}, false
+	}
+	return entry.changedAt, true
+}
+
+func (oc *ownerChangeCache) set(key string, changedAt, now time.Time) {
+	if oc == nil {
+		return
+	}
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	oc.entries[key] = ownerChangeEntry{changedAt: changedAt, fetchedAt: now}
+}
+
+// OwnerChangedAt returns the time of the last change of the owner, i.e. its creation or the last rollout restart
+// recorded in the annotation of its pod template. A Deployment creates a new ReplicaSet for every update, so the
+// creation of the ReplicaSet controlling a pod reflects the last update of the Deployment as well.
+func OwnerChangedAt(owner *unstructured.Unstructured) time.Time {
+	changedAt := owner.GetCreationTimestamp().Time
+	restartedAt, found, err := unstructured.NestedString(owner.Object, "spec", "template", "metadata", "annotations", restartedAtAnnotationKey)
+	if err != nil || !found {
+		return changedAt
+	}
+	if t, err := time.Parse(time.RFC3339, restartedAt); err == nil && t.After(changedAt) {
+		return t
+	}
+	return changedAt
+}
+
+// isOwnerRecentlyChanged checks if the controller of the pod was created or updated within the configured window,
+// in which case its pods are still churning and recycling them is counterproductive. Owners which no longer exist
+// are treated as not recently changed.
+func (c *Controller) isOwnerRecentlyChanged(po *v1.Pod, opts api.Options) (bool, error) {
+	if opts.RecentOwnerChangeWindow == nil || c.dynamicClient == nil {
+		return false, nil
+	}
+	controller := ControllerOwnerRef(po)
+	if controller == nil {
+		return false, nil
+	}
+	owner := &v1.ObjectReference{APIVersion: controller.APIVersion, Kind: controller.Kind, Namespace: po.Namespace, Name: controller.Name}
+	now := c.clock.Now()
+	key := owner.Namespace + "/" + owner.Kind + "/" + owner.Name
+	changedAt, ok := c.ownerChanges.get(key, now)
+	if !ok {
+		gvr, err := getOwnerGVR(owner)
+		if err != nil {
+			return false, nil
+		}
+		obj, err := c.dynamicClient.Resource(gvr).Namespace(owner.Namespace).Get(owner.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("error getting %s %s: %v", owner.Kind, owner.Name, err)
+		}
+		changedAt = OwnerChangedAt(obj)
+		c.ownerChanges.set(key, changedAt, now)
+	}
+	if now.Sub(changedAt) >= opts.RecentOwnerChangeWindow.Duration {
+		return false, nil
+	}
+	klog.Infof("%s %s/%s of pod %s was changed at %s, within the last %s. Skipping pod deletion.",
+		owner.Kind, owner.Namespace, owner.Name, po.Name, changedAt.Format(time.RFC3339), opts.RecentOwnerChangeWindow.Duration)
+	return true, nil
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newReplicaSetCreatedAt(name string, created time.Time) *unstructured.Unstructured {
+	rs := &unstructured.Unstructured{}
+	rs.SetAPIVersion("apps/v1")
+	rs.SetKind("ReplicaSet")
+	rs.SetNamespace(metav1.NamespaceDefault)
+	rs.SetName(name)
+	rs.SetCreationTimestamp(metav1.NewTime(created))
+	return rs
+}
+
+func TestOwnerChangedAt(t *testing.T) {
+	created := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	owner := newReplicaSetCreatedAt("kube-controller-manager-5d8f", created)
+	if changedAt := OwnerChangedAt(owner); !changedAt.Equal(created) {
+		t.Errorf("Expected the creation of the owner but got %s", changedAt)
+	}
+	restarted := created.Add(time.Hour)
+	if err := unstructured.SetNestedField(owner.Object, restarted.Format(time.RFC3339), "spec", "template", "metadata", "annotations", restartedAtAnnotationKey); err != nil {
+		t.Fatalf("error setting annotation: %v", err)
+	}
+	if changedAt := OwnerChangedAt(owner); !changedAt.Equal(restarted) {
+		t.Errorf("Expected the last rollout restart of the owner but got %s", changedAt)
+	}
+}
+
+func TestRecentlyChangedOwnerGuard(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	isController := true
+	labels := map[string]string{"role": "controlplane"}
+	newOwnedPod := func(name, owner string) *v1.Pod {
+		pod := newPodInCrashloop(name, labels)
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: owner, Controller: &isController}}
+		return pod
+	}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	client := fake.NewSimpleClientset(
+		newOwnedPod("fresh-0", "fresh"),
+		newOwnedPod("fresh-1", "fresh"),
+		newOwnedPod("established-0", "established"),
+		newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil),
+	)
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newReplicaSetCreatedAt("fresh", now.Add(-time.Minute)),
+		newReplicaSetCreatedAt("established", now.Add(-24*time.Hour)),
+	)
+	deps := &api.ServiceDependants{Options: api.Options{RecentOwnerChangeWindow: &metav1.Duration{Duration: 10 * time.Minute}}}
+	c := &Controller{
+		clientset:         client,
+		dynamicClient:     dynamicClient,
+		clock:             clock.NewFakeClock(now),
+		serviceDependants: deps,
+		ownerChanges:      newOwnerChangeCache(),
+	}
+
+	for _, name := range []string{"fresh-0", "fresh-1", "established-0"} {
+		pod, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get(name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error getting pod %s: %v", name, err)
+		}
+		if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("error processing pod %s: %v", name, err)
+		}
+	}
+	for name, expectedDeleted := range map[string]bool{"fresh-0": false, "fresh-1": false, "established-0": true} {
+		_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get(name, metav1.GetOptions{})
+		if deleted := err != nil; deleted != expectedDeleted {
+			t.Errorf("Expected pod %s to be deleted %v but got %v", name, expectedDeleted, deleted)
+		}
+	}
+
+	var reads int
+	for _, action := range dynamicClient.Actions() {
+		if get, ok := action.(k8stesting.GetAction); ok && get.GetResource().Resource == "replicasets" && get.GetName() == "fresh" {
+			reads++
+		}
+	}
+	if reads != 1 {
+		t.Errorf("Expected the owner of both fresh pods to be read once but got %d reads", reads)
+	}
+}
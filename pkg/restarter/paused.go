@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+// IsOwnerPaused checks if the controlling Deployment of the pod is paused or the controlling CronJob of its Job is
+// suspended. No controller reconciles the replacements of the pods of such owners, so recycling them is pointless.
+// Owners which no longer exist are treated as not paused.
+func IsOwnerPaused(ctx context.Context, client kubernetes.Interface, pod *v1.Pod) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	owner := ControllerOwnerRef(pod)
+	if owner == nil {
+		return false, nil
+	}
+	switch owner.Kind {
+	case "ReplicaSet":
+		deployment, err := getReplicaSetOwner(ctx, client, pod.Namespace, owner.Name)
+		if err != nil {
+			return false, err
+		}
+		if deployment == nil || deployment.Kind != "Deployment" {
+			return false, nil
+		}
+		d, err := client.AppsV1().Deployments(pod.Namespace).Get(deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, ignoreNotFound(err, "deployment", deployment.Name)
+		}
+		return d.Spec.Paused, nil
+	case "Job":
+		job, err := client.BatchV1().Jobs(pod.Namespace).Get(owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, ignoreNotFound(err, "job", owner.Name)
+		}
+		cronJob := getControllerOwnerRef(job)
+		if cronJob == nil || cronJob.Kind != "CronJob" {
+			return false, nil
+		}
+		cj, err := client.BatchV1beta1().CronJobs(pod.Namespace).Get(cronJob.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, ignoreNotFound(err, "cronjob", cronJob.Name)
+		}
+		return cj.Spec.Suspend != nil && *cj.Spec.Suspend, nil
+	}
+	return false, nil
+}
+
+func ignoreNotFound(err error, kind, name string) error {
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return fmt.Errorf("error getting %s %s: %v", kind, name, err)
+}
+
+// isOwnerPaused checks if the owner of the pod is paused, logging the reason for skipping the pod if so.
+func (c *Controller) isOwnerPaused(ctx context.Context, po *v1.Pod) (bool, error) {
+	paused, err := IsOwnerPaused(ctx, c.clientset, po)
+	if err != nil {
+		return false, err
+	}
+	if paused {
+		klog.Infof("The owner of pod %s/%s is paused or suspended. Skipping pod deletion.", po.Namespace, po.Name)
+	}
+	return paused, nil
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newControllerRef(apiVersion, kind, name string) []metav1.OwnerReference {
+	isController := true
+	return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name, Controller: &isController}}
+}
+
+func newDeploymentPod(name string, labels map[string]string, paused bool) (*v1.Pod, []runtime.Object) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "kcm", Namespace: metav1.NamespaceDefault},
+		Spec:       appsv1.DeploymentSpec{Paused: paused},
+	}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name:            "kcm-abc",
+		Namespace:       metav1.NamespaceDefault,
+		OwnerReferences: newControllerRef("apps/v1", "Deployment", deployment.Name),
+	}}
+	pod := newPodInCrashloop(name, labels)
+	pod.OwnerReferences = newControllerRef("apps/v1", "ReplicaSet", rs.Name)
+	return pod, []runtime.Object{deployment, rs}
+}
+
+func TestIsOwnerPaused(t *testing.T) {
+	suspend := true
+	cronJob := &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: metav1.NamespaceDefault},
+		Spec:       batchv1beta1.CronJobSpec{Suspend: &suspend},
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:            "backup-123",
+		Namespace:       metav1.NamespaceDefault,
+		OwnerReferences: newControllerRef("batch/v1beta1", "CronJob", cronJob.Name),
+	}}
+	jobPod := newPodInCrashloop("backup-123-xyz", nil)
+	jobPod.OwnerReferences = newControllerRef("batch/v1", "Job", job.Name)
+	orphanPod := newPodInCrashloop("orphan", nil)
+	orphanPod.OwnerReferences = newControllerRef("apps/v1", "ReplicaSet", "missing")
+
+	pausedPod, pausedOwners := newDeploymentPod("pod-0", nil, true)
+	activePod, activeOwners := newDeploymentPod("pod-0", nil, false)
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		objects  []runtime.Object
+		expected bool
+	}{
+		{"paused deployment", pausedPod, pausedOwners, true},
+		{"active deployment", activePod, activeOwners, false},
+		{"suspended cronjob", jobPod, []runtime.Object{cronJob, job}, true},
+		{"missing owner", orphanPod, nil, false},
+		{"no owner", newPodInCrashloop("pod-0", nil), nil, false},
+	}
+	for _, tc := range tests {
+		paused, err := IsOwnerPaused(context.TODO(), fake.NewSimpleClientset(tc.objects...), tc.pod)
+		if err != nil {
+			t.Fatalf("%s: error checking owner: %v", tc.name, err)
+		}
+		if paused != tc.expected {
+			t.Errorf("%s: expected paused %v but got %v", tc.name, tc.expected, paused)
+		}
+	}
+}
+
+func TestPodsOfPausedDeploymentsAreSkipped(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	for _, paused := range []bool{true, false} {
+		pod, owners := newDeploymentPod("pod-0", labels, paused)
+		client := fake.NewSimpleClientset(append(owners, pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))...)
+		c := &Controller{clientset: client}
+
+		if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("paused %v: error processing pod: %v", paused, err)
+		}
+		_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{})
+		if deleted := err != nil; deleted == paused {
+			t.Errorf("paused %v: expected deletion %v but got %v", paused, !paused, deleted)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+var invalidMetricLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// podMetrics counts the unhealthy and deleted dependant pods. Besides the namespace and the service, the metrics
+// carry the values of the configured label keys of the pods, or of their namespaces if the pods lack them.
+type podMetrics struct {
+	labelKeys []string
+	unhealthy *prometheus.CounterVec
+	deleted   *prometheus.CounterVec
+}
+
+// metricLabelName converts the key of a Kubernetes label into a valid metric label name.
+func metricLabelName(key string) string {
+	return "label_" + invalidMetricLabelChars.ReplaceAllString(key, "_")
+}
+
+func newPodMetrics(labelKeys []string) *podMetrics {
+	labelNames := []string{labelNamespace, labelService}
+	for _, key := range labelKeys {
+		labelNames = append(labelNames, metricLabelName(key))
+	}
+	return &podMetrics{
+		labelKeys: labelKeys,
+		unhealthy: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricsNamespace,
+				Name:      "unhealthy_pods_total",
+				Help:      "The accumulated total number of dependant pods found in CrashLoopBackOff or otherwise unhealthy.",
+			},
+			labelNames,
+		),
+		deleted: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricsNamespace,
+				Name:      "deleted_pods_total",
+				Help:      "The accumulated total number of deleted dependant pods.",
+			},
+			labelNames,
+		),
+	}
+}
+
+// register registers the metrics with the default registry. Metrics registered before, e.g. by another
+// controller, are reused. The registry only reports them as already registered if they have the same label names,
+// so metrics with other label names fail the registration.
+func (m *podMetrics) register() error {
+	var err error
+	if m.unhealthy, err = registerCounterVec(m.unhealthy); err != nil {
+		return err
+	}
+	m.deleted, err = registerCounterVec(m.deleted)
+	return err
+}
+
+func registerCounterVec(c *prometheus.CounterVec) (*prometheus.CounterVec, error) {
+	err := prometheus.Register(c)
+	if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		if existing, ok := are.ExistingCollector.(*prometheus.CounterVec); ok {
+			return existing, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// RegisterMetrics registers the metrics of the dependant pods, which carry the configured metric labels, with the
+// default registry.
+func (c *Controller) RegisterMetrics() error {
+	if err := c.podMetrics.register(); err != nil {
+		return fmt.Errorf("error registering the pod metrics: %v", err)
+	}
+	return nil
+}
+
+// podMetricLabels returns the metric labels of the pod of the service. The labels of the namespace of the pod are only
+// read if cluster-scoped operations are allowed.
+func (c *Controller) podMetricLabels(po *v1.Pod, service string) prometheus.Labels {
+	labels := prometheus.Labels{labelNamespace: po.Namespace, labelService: service}
+	var nsLabels map[string]string
+	for _, key := range c.podMetrics.labelKeys {
+		value, ok := po.Labels[key]
+		if !ok && isClusterScoped(c.getServiceDependants()) {
+			if nsLabels == nil {
+				nsLabels = c.getNamespaceLabels(po.Namespace)
+			}
+			value = nsLabels[key]
+		}
+		labels[metricLabelName(key)] = value
+	}
+	return labels
+}
+
+func (c *Controller) getNamespaceLabels(namespace string) map[string]string {
+	ns, err := c.clientset.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Error getting namespace %s for the metric labels: %s", namespace, err)
+		return map[string]string{}
+	}
+	if ns.Labels == nil {
+		return map[string]string{}
+	}
+	return ns.Labels
+}
+
+func (c *Controller) countUnhealthyPod(po *v1.Pod, service string) {
+	if c.podMetrics != nil {
+		c.podMetrics.unhealthy.With(c.podMetricLabels(po, service)).Inc()
+	}
+}
+
+func (c *Controller) countDeletedPod(po *v1.Pod, service string) {
+	if c.podMetrics != nil {
+		c.podMetrics.deleted.With(c.podMetricLabels(po, service)).Inc()
+	}
+}
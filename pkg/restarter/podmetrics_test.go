@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPodMetricsAreEnrichedWithLabels(t *testing.T) {
+	labels := map[string]string{"role": "controlplane", "gardener.cloud/team": "core"}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "controlplane"}}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	pod := newPodInCrashloop("pod-0", labels)
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceDefault, Labels: map[string]string{"tier": "gold"}}}
+	c := &Controller{
+		clientset:         fake.NewSimpleClientset(pod, ns, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)),
+		podMetrics:        newPodMetrics([]string{"gardener.cloud/team", "tier", "missing"}),
+		serviceDependants: &api.ServiceDependants{AllNamespaces: true},
+	}
+
+	if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+
+	expected := prometheus.Labels{
+		labelNamespace:              "default",
+		labelService:                "kube-apiserver",
+		"label_gardener_cloud_team": "core",
+		"label_tier":                "gold",
+		"label_missing":             "",
+	}
+	if got := testutil.ToFloat64(c.podMetrics.unhealthy.With(expected)); got != 1 {
+		t.Errorf("Expected one unhealthy pod with labels %v but got %v", expected, got)
+	}
+	if got := testutil.ToFloat64(c.podMetrics.deleted.With(expected)); got != 1 {
+		t.Errorf("Expected one deleted pod with labels %v but got %v", expected, got)
+	}
+}
+
+func TestRegisterMetricsFailsOnOtherLabels(t *testing.T) {
+	registered := &Controller{podMetrics: newPodMetrics([]string{"tier"})}
+	if err := registered.RegisterMetrics(); err != nil {
+		t.Fatalf("error registering metrics: %v", err)
+	}
+	defer prometheus.Unregister(registered.podMetrics.unhealthy)
+	defer prometheus.Unregister(registered.podMetrics.deleted)
+
+	// The metrics with the same labels are reused.
+	same := &Controller{podMetrics: newPodMetrics([]string{"tier"})}
+	if err := same.RegisterMetrics(); err != nil {
+		t.Fatalf("error registering metrics: %v", err)
+	}
+	if same.podMetrics.unhealthy != registered.podMetrics.unhealthy || same.podMetrics.deleted != registered.podMetrics.deleted {
+		t.Errorf("Expected the registered metrics to be reused")
+	}
+
+	other := &Controller{podMetrics: newPodMetrics([]string{"team"})}
+	if err := other.RegisterMetrics(); err == nil {
+		t.Errorf("Expected an error registering the metrics with other labels")
+	}
+}
@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+)
+
+// RecyclePolicy decides if a dependant pod should be recycled. depReady tells if the dependency of the pod is ready.
+// Besides the decision, it returns a human readable reason for it. Policies are only consulted for the pods passing
+// the recycle guards, so that no policy can recycle a pod while its dependency is not ready or against a veto.
+type RecyclePolicy interface {
+	ShouldRecycle(ctx context.Context, pod *v1.Pod, depReady bool) (bool, string, error)
+}
+
+// RecyclePolicyFunc adapts a function to a RecyclePolicy.
+type RecyclePolicyFunc func(ctx context.Context, pod *v1.Pod, depReady bool) (bool, string, error)
+
+// ShouldRecycle calls f(ctx, pod, depReady).
+func (f RecyclePolicyFunc) ShouldRecycle(ctx context.Context, pod *v1.Pod, depReady bool) (bool, string, error) {
+	return f(ctx, pod, depReady)
+}
+
+// DefaultRecyclePolicy is the built-in policy recycling the pods which are unhealthy according to the dependant
+// pods in the context, i.e. in CrashloopBackoff unless restart rules or predicates are configured, once the
+// dependency is ready.
+var DefaultRecyclePolicy RecyclePolicy = RecyclePolicyFunc(func(ctx context.Context, pod *v1.Pod, depReady bool) (bool, string, error) {
+	if !depReady {
+		return false, "dependency is not ready", nil
+	}
+	depPods := DependantPodsFromContext(ctx)
+	if depPods == nil {
+		depPods = &api.DependantPods{}
+	}
+	if !shouldDeleteDependantPod(pod, depPods) {
+		return false, "pod is healthy", nil
+	}
+	return true, "pod is unhealthy", nil
+})
+
+// getRecycleGuardReason returns the human readable reason for not recycling the dependant pod regardless of the
+// recycle policy, if any. The guards are enforced before the policy is consulted.
+func getRecycleGuardReason(pod *v1.Pod, depPods *api.DependantPods, depReady bool) string {
+	switch {
+	case !depReady:
+		return "dependency is not ready"
+	case IsPodIgnored(pod):
+		return "pod is ignored"
+	case IsPodBeingEvicted(pod):
+		return "pod is being evicted"
+	case IsPodDeleted(pod):
+		return "pod is terminating"
+	case isDependantPodVetoed(pod, depPods):
+		return "pod is vetoed"
+	}
+	return ""
+}
+
+// shouldRecycle checks the recycle guards and consults the recycle policy only for the pods passing them.
+func (c *Controller) shouldRecycle(ctx context.Context, po *v1.Pod, depPods *api.DependantPods, depReady bool) (bool, string, error) {
+	if reason := getRecycleGuardReason(po, depPods, depReady); reason != "" {
+		return false, reason, nil
+	}
+	recycle, reason, err := c.recyclePolicy().ShouldRecycle(withDependantPods(ctx, depPods), po, depReady)
+	if err != nil {
+		return false, "", fmt.Errorf("error evaluating recycle policy for pod %s: %v", po.Name, err)
+	}
+	return recycle, reason, nil
+}
+
+type dependantPodsKey struct{}
+
+// withDependantPods returns a context carrying the dependant pods the evaluated pod belongs to.
+func withDependantPods(ctx context.Context, depPods *api.DependantPods) context.Context {
+	return context.WithValue(ctx, dependantPodsKey{}, depPods)
+}
+
+// DependantPodsFromContext returns the dependant pods the pod evaluated by a RecyclePolicy belongs to, if any.
+func DependantPodsFromContext(ctx context.Context) *api.DependantPods {
+	depPods, _ := ctx.Value(dependantPodsKey{}).(*api.DependantPods)
+	return depPods
+}
+
+// AllOf returns a policy recycling a pod only if all the given policies do. The policies are evaluated in
+// order and the evaluation stops at the first policy declining the recycling.
+func AllOf(policies ...RecyclePolicy) RecyclePolicy {
+	return RecyclePolicyFunc(func(ctx context.Context, pod *v1.Pod, depReady bool) (bool, string, error) {
+		var reasons []string
+		for _, p := range policies {
+			ok, reason, err := p.ShouldRecycle(ctx, pod, depReady)
+			if err != nil || !ok {
+				return false, reason, err
+			}
+			reasons = append(reasons, reason)
+		}
+		return true, strings.Join(reasons, ", "), nil
+	})
+}
+
+// AnyOf returns a policy recycling a pod if any of the given policies does. The policies are evaluated in
+// order and the evaluation stops at the first policy recycling the pod.
+func AnyOf(policies ...RecyclePolicy) RecyclePolicy {
+	return RecyclePolicyFunc(func(ctx context.Context, pod *v1.Pod, depReady bool) (bool, string, error) {
+		var reasons []string
+		for _, p := range policies {
+			ok, reason, err := p.ShouldRecycle(ctx, pod, depReady)
+			if err != nil || ok {
+				return ok, reason, err
+			}
+			reasons = append(reasons, reason)
+		}
+		return false, strings.Join(reasons, ", "), nil
+	})
+}
+
+// recyclePolicy returns the configured recycle policy or the default one.
+func (c *Controller) recyclePolicy() RecyclePolicy {
+	if c.RecyclePolicy != nil {
+		return c.RecyclePolicy
+	}
+	return DefaultRecyclePolicy
+}
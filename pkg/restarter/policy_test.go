@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var neverRecycle = RecyclePolicyFunc(func(ctx context.Context, pod *v1.Pod, depReady bool) (bool, string, error) {
+	return false, "never", nil
+})
+
+func TestRecyclePolicies(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		policy           RecyclePolicy
+		expectedDeletion bool
+	}{
+		{"default", nil, true},
+		{"all of default and never", AllOf(DefaultRecyclePolicy, neverRecycle), false},
+		{"any of default and never", AnyOf(neverRecycle, DefaultRecyclePolicy), true},
+		{"never", neverRecycle, false},
+	}
+	for _, tc := range tests {
+		pod := newPodInCrashloop("pod-0", labels)
+		client := fake.NewSimpleClientset(pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		c := &Controller{clientset: client, RecyclePolicy: tc.policy}
+
+		if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{})
+		if deleted := err != nil; deleted != tc.expectedDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expectedDeletion, deleted)
+		}
+	}
+}
+
+func TestDefaultRecyclePolicyRequiresReadyDependency(t *testing.T) {
+	pod := newPodInCrashloop("pod-0", nil)
+	if ok, _, _ := DefaultRecyclePolicy.ShouldRecycle(context.TODO(), pod, false); ok {
+		t.Errorf("Expected no recycling while the dependency is not ready")
+	}
+	if ok, _, _ := DefaultRecyclePolicy.ShouldRecycle(context.TODO(), pod, true); !ok {
+		t.Errorf("Expected the crashlooping pod to be recycled once the dependency is ready")
+	}
+}
+
+func TestCustomRecyclePolicyCannotBypassTheGuards(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	alwaysRecycle := RecyclePolicyFunc(func(ctx context.Context, pod *v1.Pod, depReady bool) (bool, string, error) {
+		return true, "always", nil
+	})
+	ignored := newPodInCrashloop("pod-0", labels)
+	ignored.Annotations = map[string]string{ignoreAnnotationKey: "true"}
+
+	tests := []struct {
+		name    string
+		pod     *v1.Pod
+		depPods *api.DependantPods
+	}{
+		{"ignored pod", ignored, &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}},
+		{"dependency not ready", newPodInCrashloop("pod-0", labels), &api.DependantPods{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Sentinel: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "sentinel"}},
+		}},
+	}
+	for _, tc := range tests {
+		selector, err := metav1.LabelSelectorAsSelector(tc.depPods.Selector)
+		if err != nil {
+			t.Fatalf("error converting label selector: %v", err)
+		}
+		client := fake.NewSimpleClientset(tc.pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		c := &Controller{clientset: client, RecyclePolicy: AnyOf(DefaultRecyclePolicy, alwaysRecycle)}
+
+		if err := c.processPod(context.TODO(), tc.pod, "kube-apiserver", tc.depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); err != nil {
+			t.Errorf("%s: expected the pod not to be recycled by the custom policy but got: %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+)
+
+// RunPreflightCheck checks whether the endpoints of every configured service exist and the selector of every
+// dependant matches any pods in the configured namespace or, if none is configured, in any namespace, but none of
+// the pods backing the service. The findings are logged as warnings and returned. Errors of the checks are logged, as the preflight check never blocks the startup.
+func (c *Controller) RunPreflightCheck() []string {
+	deps := c.getServiceDependants()
+	names := make([]string, 0, len(deps.Services))
+	for name := range deps.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var warnings []string
+	warn := func(format string, args ...interface{}) {
+		warning := fmt.Sprintf(format, args...)
+		klog.Warningf("Preflight check: %s", warning)
+		warnings = append(warnings, warning)
+	}
+	for _, name := range names {
+		backing, err := c.getBackingPodsOfService(deps.Namespace, name)
+		if err != nil {
+			klog.Errorf("Preflight check: error checking the endpoints of service %s: %s", name, err)
+		} else if backing == nil {
+			warn("no endpoints of service %s found", name)
+		}
+		srv := deps.Services[name]
+		for i := range srv.Dependants {
+			depPods := &srv.Dependants[i]
+			selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+			if err != nil {
+				klog.Errorf("Preflight check: error converting label selector of dependant %s of service %s: %s", depPods.Name, name, err)
+				continue
+			}
+			pl, err := c.clientset.CoreV1().Pods(deps.Namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+			if err != nil {
+				klog.Errorf("Preflight check: error listing pods with selector %s: %s", selector.String(), err)
+				continue
+			}
+			selected := selectDependantPods(pl.Items, depPods)
+			if len(selected) == 0 {
+				warn("selector %s of dependant %s of service %s matches no pods", selector.String(), depPods.Name, name)
+			}
+			if overlap := getOverlappingPods(selected, backing); len(overlap) > 0 && !depPods.AllowDependencyOverlap {
+				warn("selector %s of dependant %s of service %s matches pods backing the service: %s", selector.String(), depPods.Name, name, strings.Join(overlap, ", "))
+			}
+		}
+	}
+	return warnings
+}
+
+// getBackingPodsOfService returns the keys of the pods backing the endpoints of the service in the namespace or, if
+// it is empty, in any namespace. It returns nil if no endpoints of the service exist.
+func (c *Controller) getBackingPodsOfService(namespace, service string) (sets.String, error) {
+	epl, err := c.clientset.CoreV1().Endpoints(namespace).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", service).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var backing sets.String
+	for i := range epl.Items {
+		if epl.Items[i].Name != service {
+			continue
+		}
+		if backing == nil {
+			backing = sets.NewString()
+		}
+		backing = backing.Union(GetBackingPods(&epl.Items[i]))
+	}
+	return backing, nil
+}
+
+// getOverlappingPods returns the sorted keys of the pods which are in the backing pods.
+func getOverlappingPods(pods []v1.Pod, backing sets.String) []string {
+	overlap := sets.NewString()
+	for i := range pods {
+		if key := pods[i].Namespace + "/" + pods[i].Name; backing.Has(key) {
+			overlap.Insert(key)
+		}
+	}
+	return overlap.List()
+}
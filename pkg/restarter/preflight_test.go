@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunPreflightCheck(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	deps := &api.ServiceDependants{
+		Namespace:      metav1.NamespaceDefault,
+		PreflightCheck: true,
+		Services: map[string]api.Service{
+			"kube-apiserver": {Dependants: []api.DependantPods{
+				{Name: "controlplane", Selector: &metav1.LabelSelector{MatchLabels: labels}},
+				{Name: "typo", Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "controlplnae"}}},
+			}},
+			"etcd-main": {Dependants: []api.DependantPods{
+				{Name: "controlplane", Selector: &metav1.LabelSelector{MatchLabels: labels}},
+			}},
+		},
+	}
+	client := fake.NewSimpleClientset(
+		newPodHealthy("pod-0", labels),
+		newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil),
+		newEndpoint("etcd-main", "other", nil),
+	)
+	c := &Controller{clientset: client, serviceDependants: deps}
+
+	expected := []string{
+		"no endpoints of service etcd-main found",
+		"selector role=controlplnae of dependant typo of service kube-apiserver matches no pods",
+	}
+	if warnings := c.RunPreflightCheck(); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Expected the warnings %v but got %v", expected, warnings)
+	}
+}
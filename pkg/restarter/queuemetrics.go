@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"k8s.io/client-go/util/workqueue"
+)
+
+// endpointsQueueName is the name of the workqueue of the endpoints in the workqueue metrics.
+const endpointsQueueName = "Endpoints"
+
+// workqueueMetricsProvider provides the depth, adds and retries of the named workqueues, which reveal whether
+// the controller keeps up with the changes of the endpoints. The other workqueue metrics are not exposed.
+type workqueueMetricsProvider struct{}
+
+func (workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return workqueueDepth.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return workqueueAddsTotal.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return noopWorkqueueMetric{}
+}
+
+func (workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return noopWorkqueueMetric{}
+}
+
+func (workqueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopWorkqueueMetric{}
+}
+
+func (workqueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopWorkqueueMetric{}
+}
+
+func (workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return workqueueRetriesTotal.WithLabelValues(name)
+}
+
+type noopWorkqueueMetric struct{}
+
+func (noopWorkqueueMetric) Inc()            {}
+func (noopWorkqueueMetric) Dec()            {}
+func (noopWorkqueueMetric) Set(float64)     {}
+func (noopWorkqueueMetric) Observe(float64) {}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestWorkqueueMetrics(t *testing.T) {
+	const name, n = "test-backlog", 5
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), name)
+	defer queue.ShutDown()
+
+	for i := 0; i < n; i++ {
+		queue.Add(fmt.Sprintf("default/service-%d", i))
+	}
+	// Adding an item which is already waiting does not increase the depth.
+	queue.Add("default/service-0")
+	if depth := testutil.ToFloat64(workqueueDepth.WithLabelValues(name)); depth != n {
+		t.Errorf("Expected a depth of %d but got %f", n, depth)
+	}
+	if adds := testutil.ToFloat64(workqueueAddsTotal.WithLabelValues(name)); adds != n {
+		t.Errorf("Expected %d adds but got %f", n, adds)
+	}
+
+	item, _ := queue.Get()
+	if depth := testutil.ToFloat64(workqueueDepth.WithLabelValues(name)); depth != n-1 {
+		t.Errorf("Expected a depth of %d after processing an item but got %f", n-1, depth)
+	}
+	queue.AddRateLimited(item)
+	queue.Done(item)
+	if retries := testutil.ToFloat64(workqueueRetriesTotal.WithLabelValues(name)); retries != 1 {
+		t.Errorf("Expected 1 retry but got %f", retries)
+	}
+}
+
+func TestInvalidWorkqueueItemDropped(t *testing.T) {
+	c := &Controller{workqueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), endpointsQueueName)}
+	defer c.workqueue.ShutDown()
+	dropped := testutil.ToFloat64(workqueueDroppedTotal.WithLabelValues(endpointsQueueName))
+	c.workqueue.Add(42)
+	c.processNextWorkItem()
+	if actual := testutil.ToFloat64(workqueueDroppedTotal.WithLabelValues(endpointsQueueName)); actual != dropped+1 {
+		t.Errorf("Expected the invalid item to be dropped")
+	}
+}
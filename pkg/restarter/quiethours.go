@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"k8s.io/klog"
+)
+
+// invalidQuietHoursRecheckInterval is the interval after which an invalid schedule is evaluated again. No pods are
+// deleted in the meantime.
+const invalidQuietHoursRecheckInterval = time.Minute
+
+// CheckQuietHours verifies that the time zones and the windows of the quiet hours of the global options and the
+// options of the namespaces are valid.
+func CheckQuietHours(deps *api.ServiceDependants) error {
+	if err := validateQuietHours(deps.QuietHours); err != nil {
+		return fmt.Errorf("invalid quiet hours: %v", err)
+	}
+	for namespace, opts := range deps.Namespaces {
+		if err := validateQuietHours(opts.QuietHours); err != nil {
+			return fmt.Errorf("invalid quiet hours of namespace %s: %v", namespace, err)
+		}
+	}
+	return nil
+}
+
+func validateQuietHours(schedule *api.QuietHours) error {
+	if schedule == nil {
+		return nil
+	}
+	if _, err := loadQuietHoursLocation(*schedule); err != nil {
+		return err
+	}
+	for _, w := range schedule.Windows {
+		if _, _, err := parseTimeWindow(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InQuietHours checks if the given time is within one of the windows of the schedule in its time zone.
+// Invalid schedules are logged and considered quiet.
+func InQuietHours(now time.Time, schedule api.QuietHours) bool {
+	_, ok := QuietHoursEnd(now, schedule)
+	return ok
+}
+
+// QuietHoursEnd returns the end of the quiet hours the given time is within, if any. If several windows contain
+// the time, the latest of their ends is returned. Invalid schedules are logged and considered quiet until they are
+// evaluated again after a recheck interval, so that no pods are deleted by mistake.
+func QuietHoursEnd(now time.Time, schedule api.QuietHours) (time.Time, bool) {
+	loc, err := loadQuietHoursLocation(schedule)
+	if err != nil {
+		klog.Errorf("Invalid quiet hours: %s", err)
+		return now.Add(invalidQuietHoursRecheckInterval), true
+	}
+	now = now.In(loc)
+	minute := now.Hour()*60 + now.Minute()
+	var (
+		end time.Time
+		in  bool
+	)
+	for _, w := range schedule.Windows {
+		start, windowEnd, err := parseTimeWindow(w)
+		if err != nil {
+			klog.Errorf("Invalid quiet hours: %s", err)
+			return now.Add(invalidQuietHoursRecheckInterval), true
+		}
+		day := now.Day()
+		switch {
+		case start <= windowEnd && minute >= start && minute < windowEnd:
+		case start > windowEnd && minute < windowEnd:
+		case start > windowEnd && minute >= start:
+			// The window spans midnight and ends tomorrow.
+			day++
+		default:
+			continue
+		}
+		t := time.Date(now.Year(), now.Month(), day, windowEnd/60, windowEnd%60, 0, 0, loc)
+		if !in || t.After(end) {
+			end = t
+		}
+		in = true
+	}
+	return end, in
+}
+
+// loadQuietHoursLocation returns the time zone of the schedule, which defaults to UTC.
+func loadQuietHoursLocation(schedule api.QuietHours) (*time.Location, error) {
+	if schedule.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", schedule.Timezone, err)
+	}
+	return loc, nil
+}
+
+// parseTimeWindow parses the start and the end of the window into the minutes since midnight.
+func parseTimeWindow(w api.TimeWindow) (start, end int, err error) {
+	if start, err = parseMinuteOfDay(w.Start); err != nil {
+		return 0, 0, fmt.Errorf("invalid window %s-%s: %v", w.Start, w.End, err)
+	}
+	if end, err = parseMinuteOfDay(w.End); err != nil {
+		return 0, 0, fmt.Errorf("invalid window %s-%s: %v", w.Start, w.End, err)
+	}
+	return start, end, nil
+}
+
+// parseMinuteOfDay parses a time of the day in the format HH:MM into the minutes since midnight.
+func parseMinuteOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %v", value, err)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+)
+
+func TestInQuietHours(t *testing.T) {
+	business := api.QuietHours{Timezone: "Europe/Berlin", Windows: []api.TimeWindow{{Start: "09:00", End: "17:00"}}}
+	overnight := api.QuietHours{Windows: []api.TimeWindow{{Start: "22:00", End: "06:00"}}}
+	invalid := api.QuietHours{Windows: []api.TimeWindow{{Start: "9am", End: "17:00"}}}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		schedule api.QuietHours
+		expected bool
+	}{
+		// Berlin is at UTC+2 in June.
+		{"inside window", time.Date(2021, 6, 1, 7, 0, 0, 0, time.UTC), business, true},
+		{"before window", time.Date(2021, 6, 1, 6, 59, 0, 0, time.UTC), business, false},
+		{"end of window is exclusive", time.Date(2021, 6, 1, 15, 0, 0, 0, time.UTC), business, false},
+		{"before midnight", time.Date(2021, 6, 1, 23, 0, 0, 0, time.UTC), overnight, true},
+		{"after midnight", time.Date(2021, 6, 1, 5, 59, 0, 0, time.UTC), overnight, true},
+		{"outside window spanning midnight", time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC), overnight, false},
+		{"invalid window", time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC), invalid, true},
+		{"invalid timezone", time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC), api.QuietHours{Timezone: "Mars/Olympus", Windows: business.Windows}, true},
+	}
+	for _, tc := range tests {
+		if actual := InQuietHours(tc.now, tc.schedule); actual != tc.expected {
+			t.Errorf("%s: expected %v but got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	business := api.QuietHours{Timezone: "Europe/Berlin", Windows: []api.TimeWindow{{Start: "09:00", End: "17:00"}}}
+	overnight := api.QuietHours{Windows: []api.TimeWindow{{Start: "22:00", End: "06:00"}, {Start: "05:00", End: "07:00"}}}
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		schedule    api.QuietHours
+		expectedEnd time.Time
+		expectedOK  bool
+	}{
+		{"inside window", time.Date(2021, 6, 1, 7, 0, 0, 0, time.UTC), business, time.Date(2021, 6, 1, 15, 0, 0, 0, time.UTC), true},
+		{"outside window", time.Date(2021, 6, 1, 16, 0, 0, 0, time.UTC), business, time.Time{}, false},
+		{"before midnight", time.Date(2021, 6, 1, 23, 0, 0, 0, time.UTC), overnight, time.Date(2021, 6, 2, 6, 0, 0, 0, time.UTC), true},
+		{"overlapping windows", time.Date(2021, 6, 1, 5, 30, 0, 0, time.UTC), overnight, time.Date(2021, 6, 1, 7, 0, 0, 0, time.UTC), true},
+		{"invalid timezone", time.Date(2021, 6, 1, 16, 0, 0, 0, time.UTC), api.QuietHours{Timezone: "Mars/Olympus"}, time.Date(2021, 6, 1, 16, 1, 0, 0, time.UTC), true},
+	}
+	for _, tc := range tests {
+		end, ok := QuietHoursEnd(tc.now, tc.schedule)
+		if ok != tc.expectedOK || !end.Equal(tc.expectedEnd) {
+			t.Errorf("%s: expected end %s (%v) but got %s (%v)", tc.name, tc.expectedEnd, tc.expectedOK, end, ok)
+		}
+	}
+}
+
+func TestCheckQuietHours(t *testing.T) {
+	valid := &api.QuietHours{Timezone: "Europe/Berlin", Windows: []api.TimeWindow{{Start: "22:00", End: "06:00"}}}
+	tests := []struct {
+		name      string
+		deps      *api.ServiceDependants
+		expectErr bool
+	}{
+		{"no quiet hours", &api.ServiceDependants{}, false},
+		{"valid quiet hours", &api.ServiceDependants{Options: api.Options{QuietHours: valid}}, false},
+		{"invalid timezone", &api.ServiceDependants{Options: api.Options{QuietHours: &api.QuietHours{Timezone: "Mars/Olympus"}}}, true},
+		{"invalid window of a namespace", &api.ServiceDependants{Namespaces: map[string]api.Options{
+			"shoot--a": {QuietHours: &api.QuietHours{Windows: []api.TimeWindow{{Start: "22:00", End: "6am"}}}},
+		}}, true},
+	}
+	for _, tc := range tests {
+		if err := CheckQuietHours(tc.deps); (err != nil) != tc.expectErr {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/klog"
+)
+
+// permission is an action on a resource the controller needs to be allowed to perform.
+type permission struct {
+	group       string
+	resource    string
+	subresource string
+	verb        string
+}
+
+func (p permission) String() string {
+	resource := p.resource
+	if p.subresource != "" {
+		resource += "/" + p.subresource
+	}
+	if p.group == "" {
+		return p.verb + " " + resource
+	}
+	return p.verb + " " + resource + "." + p.group
+}
+
+// requiredPermissions are the permissions without which the controller cannot recycle any pods.
+var requiredPermissions = []permission{
+	{resource: "endpoints", verb: "get"},
+	{resource: "endpoints", verb: "list"},
+	{resource: "endpoints", verb: "watch"},
+	{resource: "pods", verb: "get"},
+	{resource: "pods", verb: "list"},
+	{resource: "pods", verb: "watch"},
+	{resource: "pods", verb: "delete"},
+}
+
+// ownerResources are the resources of the pod owners whose recovery count is annotated and whose rollout is restarted.
+var ownerResources = []string{"deployments", "statefulsets", "replicasets"}
+
+// optionalPermissions returns the permissions only needed by some of the configured features.
+func (c *Controller) optionalPermissions() []permission {
+	deps := c.getServiceDependants()
+	perms := []permission{
+		{group: "discovery.k8s.io", resource: "endpointslices", verb: "list"},
+		{group: "batch", resource: "jobs", verb: "get"},
+		{group: "batch", resource: "cronjobs", verb: "get"},
+		{group: "coordination.k8s.io", resource: "leases", verb: "get"},
+	}
+	for _, resource := range ownerResources {
+		perms = append(perms,
+			permission{group: "apps", resource: resource, verb: "get"},
+			permission{group: "apps", resource: resource, verb: "patch"},
+		)
+	}
+	if anyNamespaceOptions(deps, func(opts api.Options) bool { return getAction(opts) == api.ActionEvict }) {
+		perms = append(perms, permission{resource: "pods", subresource: "eviction", verb: "create"})
+	}
+	if anyNamespaceOptions(deps, isRecoveredConditionReported) {
+		for _, resource := range ownerResources {
+			perms = append(perms, permission{group: "apps", resource: resource, subresource: "status", verb: "update"})
+		}
+	}
+	if isClusterScoped(deps) && len(deps.MetricLabels) > 0 {
+		perms = append(perms, permission{resource: "namespaces", verb: "get"})
+	}
+	zonePinned := false
+	for _, srv := range deps.Services {
+		if srv.OnDependencyLost != nil {
+			perms = append(perms, permission{resource: "pods", verb: "patch"})
+		}
+		for i := range srv.Dependants {
+			zonePinned = zonePinned || srv.Dependants[i].ZonePinned
+		}
+		for i := range srv.DependantResources {
+			gvr, err := GetDependantResourceGVR(&srv.DependantResources[i])
+			if err != nil {
+				continue
+			}
+			perms = append(perms, permission{group: gvr.Group, resource: gvr.Resource, verb: "patch"})
+		}
+		for i := range srv.Dependants {
+			if srv.Dependants[i].HealthCheck == nil {
+				continue
+			}
+			gvr, err := GetHealthCheckGVR(srv.Dependants[i].HealthCheck)
+			if err != nil {
+				continue
+			}
+			perms = append(perms, permission{group: gvr.Group, resource: gvr.Resource, verb: "get"})
+		}
+	}
+	if zonePinned {
+		perms = append(perms, permission{resource: "nodes", verb: "get"})
+	}
+	return perms
+}
+
+// anyNamespaceOptions checks if the global options or the options of any namespace override match.
+func anyNamespaceOptions(deps *api.ServiceDependants, match func(api.Options) bool) bool {
+	if match(deps.Options) {
+		return true
+	}
+	for namespace := range deps.Namespaces {
+		if match(getNamespaceOptions(deps, namespace)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPermissions reviews if the controller is allowed to perform the actions it needs in the configured namespace.
+// Missing permissions are logged. If RequireRBAC is configured, missing required permissions are returned as an error.
+func (c *Controller) CheckPermissions() error {
+	deps := c.getServiceDependants()
+	var missing []string
+	for _, p := range requiredPermissions {
+		allowed, err := c.isAllowed(deps.Namespace, p)
+		if err != nil {
+			klog.Errorf("Error reviewing the permission to %s: %s", p, err)
+			continue
+		}
+		if !allowed {
+			klog.Errorf("Missing permission to %s in namespace %q. Pods will not be recycled.", p, deps.Namespace)
+			missing = append(missing, p.String())
+		}
+	}
+	for _, p := range c.optionalPermissions() {
+		allowed, err := c.isAllowed(deps.Namespace, p)
+		if err != nil {
+			klog.Errorf("Error reviewing the permission to %s: %s", p, err)
+			continue
+		}
+		if !allowed {
+			klog.Warningf("Missing permission to %s in namespace %q. Features depending on it will fail.", p, deps.Namespace)
+		}
+	}
+	if len(missing) > 0 && deps.RequireRBAC {
+		return fmt.Errorf("missing permissions: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (c *Controller) isAllowed(namespace string, p permission) (bool, error) {
+	review, err := c.clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(&authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Group:       p.group,
+				Resource:    p.resource,
+				Subresource: p.subresource,
+				Verb:        p.verb,
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newAccessReviewClientset returns a clientset allowing all the reviewed actions except the denied ones.
+func newAccessReviewClientset(denied ...permission) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = true
+		for _, p := range denied {
+			if p.group == attrs.Group && p.resource == attrs.Resource && p.subresource == attrs.Subresource && p.verb == attrs.Verb {
+				review.Status.Allowed = false
+			}
+		}
+		return true, review, nil
+	})
+	return client
+}
+
+func TestCheckPermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		denied      []permission
+		requireRBAC bool
+		expectErr   bool
+	}{
+		{"all allowed", nil, true, false},
+		{"delete pods denied", []permission{{resource: "pods", verb: "delete"}}, true, true},
+		{"delete pods denied without requiring rbac", []permission{{resource: "pods", verb: "delete"}}, false, false},
+		{"optional permission denied", []permission{{group: "discovery.k8s.io", resource: "endpointslices", verb: "list"}}, true, false},
+	}
+	for _, tc := range tests {
+		c := &Controller{
+			clientset:         newAccessReviewClientset(tc.denied...),
+			serviceDependants: &api.ServiceDependants{RequireRBAC: tc.requireRBAC},
+		}
+		if err := c.CheckPermissions(); (err != nil) != tc.expectErr {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestOptionalPermissionsFollowConfiguredFeatures(t *testing.T) {
+	evict := api.ActionEvict
+	reportRecovered := true
+	gated := []permission{
+		{resource: "pods", subresource: "eviction", verb: "create"},
+		{group: "apps", resource: "deployments", subresource: "status", verb: "update"},
+		{resource: "namespaces", verb: "get"},
+		{resource: "nodes", verb: "get"},
+	}
+	hasPermission := func(perms []permission, p permission) bool {
+		for _, perm := range perms {
+			if perm == p {
+				return true
+			}
+		}
+		return false
+	}
+
+	c := &Controller{serviceDependants: &api.ServiceDependants{
+		Services: map[string]api.Service{"kube-apiserver": {Dependants: []api.DependantPods{{Name: "kube-controller-manager"}}}},
+	}}
+	perms := c.optionalPermissions()
+	for _, p := range gated {
+		if hasPermission(perms, p) {
+			t.Errorf("Expected no permission to %s without the features needing it", p)
+		}
+	}
+	if !hasPermission(perms, permission{group: "apps", resource: "statefulsets", verb: "patch"}) {
+		t.Errorf("Expected the permission to patch the owners for the recovery count but got %v", perms)
+	}
+
+	c = &Controller{serviceDependants: &api.ServiceDependants{
+		AllNamespaces: true,
+		MetricLabels:  []string{"project"},
+		Namespaces: map[string]api.Options{
+			"shoot--a": {Action: &evict},
+			"shoot--b": {ReportRecoveredCondition: &reportRecovered},
+		},
+		Services: map[string]api.Service{"kube-apiserver": {Dependants: []api.DependantPods{{Name: "kube-controller-manager", ZonePinned: true}}}},
+	}}
+	perms = c.optionalPermissions()
+	for _, p := range gated {
+		if !hasPermission(perms, p) {
+			t.Errorf("Expected the permission to %s for the configured features but got %v", p, perms)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReadinessPredicate decides if a dependency is ready.
+type ReadinessPredicate interface {
+	IsReady(dep *Dependency) (bool, error)
+}
+
+// ReadinessPredicateFunc adapts a function to a ReadinessPredicate.
+type ReadinessPredicateFunc func(dep *Dependency) (bool, error)
+
+// IsReady calls f(dep).
+func (f ReadinessPredicateFunc) IsReady(dep *Dependency) (bool, error) {
+	return f(dep)
+}
+
+// ReadinessPredicateFactory returns the readiness predicate for the given parameters of a service.
+type ReadinessPredicateFactory func(readiness api.Readiness) (ReadinessPredicate, error)
+
+// Dependency gives the readiness predicates access to the endpoints of a service. The endpoint slices of the
+// service are only listed once a predicate asks for them.
+type Dependency struct {
+	Endpoints  *v1.Endpoints
+	listSlices func() ([]discoveryv1beta1.EndpointSlice, error)
+	slices     []discoveryv1beta1.EndpointSlice
+	listed     bool
+}
+
+// NewDependency returns the dependency with the given endpoints and endpoint slices.
+func NewDependency(ep *v1.Endpoints, slices []discoveryv1beta1.EndpointSlice) *Dependency {
+	return &Dependency{Endpoints: ep, slices: slices, listed: true}
+}
+
+// EndpointSlices returns the endpoint slices of the service.
+func (d *Dependency) EndpointSlices() ([]discoveryv1beta1.EndpointSlice, error) {
+	if !d.listed {
+		slices, err := d.listSlices()
+		if err != nil {
+			return nil, err
+		}
+		d.slices, d.listed = slices, true
+	}
+	return d.slices, nil
+}
+
+// AnyReadyAddress is the default readiness predicate requiring any ready address of the service.
+var AnyReadyAddress ReadinessPredicate = ReadinessPredicateFunc(func(dep *Dependency) (bool, error) {
+	return IsReadyEndpointPresentInSubsets(dep.Endpoints.Subsets), nil
+})
+
+// MinReadyAddresses returns a readiness predicate requiring at least min ready addresses of the service.
+func MinReadyAddresses(min int) ReadinessPredicate {
+	return ReadinessPredicateFunc(func(dep *Dependency) (bool, error) {
+		return countReadyAddresses(dep.Endpoints.Subsets) >= min, nil
+	})
+}
+
+// NamedPortReady returns a readiness predicate requiring a ready address serving the port with the given name.
+func NamedPortReady(name string) ReadinessPredicate {
+	return ReadinessPredicateFunc(func(dep *Dependency) (bool, error) {
+		for _, subset := range dep.Endpoints.Subsets {
+			if len(subset.Addresses) == 0 {
+				continue
+			}
+			for _, port := range subset.Ports {
+				if port.Name == name {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	})
+}
+
+// AllZonesReady is a readiness predicate requiring a ready endpoint in every zone with an endpoint of the service.
+// Endpoints in an unknown zone are not taken into account. Without any known zone, any ready address is required.
+var AllZonesReady ReadinessPredicate = ReadinessPredicateFunc(func(dep *Dependency) (bool, error) {
+	slices, err := dep.EndpointSlices()
+	if err != nil {
+		return false, err
+	}
+	zones := make(map[string]bool)
+	for _, slice := range slices {
+		for _, ep := range slice.Endpoints {
+			if zone := getZone(ep.Topology); zone != "" {
+				zones[zone] = zones[zone] || ep.Conditions.Ready == nil || *ep.Conditions.Ready
+			}
+		}
+	}
+	if len(zones) == 0 {
+		return IsReadyEndpointPresentInSubsets(dep.Endpoints.Subsets), nil
+	}
+	for _, ready := range zones {
+		if !ready {
+			return false, nil
+		}
+	}
+	return true, nil
+})
+
+// builtinReadinessPredicates are the readiness predicates which can be selected by name without registering them.
+var builtinReadinessPredicates = map[string]ReadinessPredicateFactory{
+	api.ReadinessAnyReadyAddress: func(api.Readiness) (ReadinessPredicate, error) {
+		return AnyReadyAddress, nil
+	},
+	api.ReadinessMinReadyAddresses: func(r api.Readiness) (ReadinessPredicate, error) {
+		if r.MinReadyAddresses == nil {
+			return nil, fmt.Errorf("predicate %s requires minReadyAddresses", r.Predicate)
+		}
+		return MinReadyAddresses(int(*r.MinReadyAddresses)), nil
+	},
+	api.ReadinessNamedPortReady: func(r api.Readiness) (ReadinessPredicate, error) {
+		if r.PortName == "" {
+			return nil, fmt.Errorf("predicate %s requires portName", r.Predicate)
+		}
+		return NamedPortReady(r.PortName), nil
+	},
+	api.ReadinessAllZonesReady: func(api.Readiness) (ReadinessPredicate, error) {
+		return AllZonesReady, nil
+	},
+}
+
+// The default costs of evaluating the readiness predicates. The built-in predicates not listed read only the
+// endpoints at hand.
+const (
+	sliceReadinessCost  = 1
+	customReadinessCost = 10
+)
+
+var builtinReadinessCosts = map[string]int32{
+	api.ReadinessAllZonesReady: sliceReadinessCost,
+}
+
+// AllReady returns a readiness predicate requiring all the given predicates to be ready. The predicates are
+// evaluated in order and the evaluation stops at the first predicate which is not ready.
+func AllReady(predicates ...ReadinessPredicate) ReadinessPredicate {
+	return ReadinessPredicateFunc(func(dep *Dependency) (bool, error) {
+		for _, p := range predicates {
+			ready, err := p.IsReady(dep)
+			if err != nil || !ready {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+// getReadinessPredicate returns the readiness predicate with the given name and parameters, looking up the custom
+// readiness predicates before the built-in ones, and the cost of evaluating it.
+func (c *Controller) getReadinessPredicate(readiness api.Readiness) (ReadinessPredicate, int32, error) {
+	cost := int32(customReadinessCost)
+	factory, ok := c.ReadinessPredicates[readiness.Predicate]
+	if !ok {
+		factory, ok = builtinReadinessPredicates[readiness.Predicate]
+		cost = builtinReadinessCosts[readiness.Predicate]
+	}
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown readiness predicate %q", readiness.Predicate)
+	}
+	if readiness.Cost != nil {
+		cost = *readiness.Cost
+	}
+	predicate, err := factory(readiness)
+	return predicate, cost, err
+}
+
+// getServiceReadiness returns the readiness predicates selected by the service, defaulting to any ready address.
+func getServiceReadiness(srv api.Service) []api.Readiness {
+	readiness := []api.Readiness{{Predicate: api.ReadinessAnyReadyAddress}}
+	if srv.Readiness != nil {
+		readiness[0] = *srv.Readiness
+	}
+	return append(readiness, srv.ReadinessAll...)
+}
+
+// getServiceReadinessPredicate returns the predicate requiring all the readiness predicates selected by the service
+// to be ready, evaluating them cheapest first. The service defaults to having any ready address.
+func (c *Controller) getServiceReadinessPredicate(srv api.Service) (ReadinessPredicate, error) {
+	readiness := getServiceReadiness(srv)
+	type costedPredicate struct {
+		predicate ReadinessPredicate
+		cost      int32
+	}
+	costed := make([]costedPredicate, len(readiness))
+	for i, r := range readiness {
+		predicate, cost, err := c.getReadinessPredicate(r)
+		if err != nil {
+			return nil, err
+		}
+		costed[i] = costedPredicate{predicate, cost}
+	}
+	if len(costed) == 1 {
+		return costed[0].predicate, nil
+	}
+	sort.SliceStable(costed, func(i, j int) bool {
+		return costed[i].cost < costed[j].cost
+	})
+	predicates := make([]ReadinessPredicate, len(costed))
+	for i := range costed {
+		predicates[i] = costed[i].predicate
+	}
+	return AllReady(predicates...), nil
+}
+
+// isServiceReady evaluates the readiness of the service like evaluateServiceReadiness. If configured, the readiness
+// predicates need to hold for a stability window, which is tracked across the calls.
+func (c *Controller) isServiceReady(ep *v1.Endpoints, srv api.Service) (bool, error) {
+	if ready, ok := c.getReadinessOverride(ep.Namespace, ep.Name); ok {
+		return ready, nil
+	}
+	ready, err := c.evaluateServiceReadiness(ep, srv)
+	if err != nil || srv.ReadyStableFor == nil {
+		return ready, err
+	}
+	return c.isReadyStable(ep.Namespace, ep.Name, ready, srv.ReadyStableFor.Duration), nil
+}
+
+// evaluateServiceReadiness evaluates the readiness predicates of the service against its endpoints unless its
+// readiness is forced through the debug overrides. It does not track the stability of the readiness.
+func (c *Controller) evaluateServiceReadiness(ep *v1.Endpoints, srv api.Service) (bool, error) {
+	if ready, ok := c.getReadinessOverride(ep.Namespace, ep.Name); ok {
+		return ready, nil
+	}
+	predicate, err := c.getServiceReadinessPredicate(srv)
+	if err != nil {
+		return false, err
+	}
+	return predicate.IsReady(&Dependency{
+		Endpoints: ep,
+		listSlices: func() ([]discoveryv1beta1.EndpointSlice, error) {
+			sl, err := c.clientset.DiscoveryV1beta1().EndpointSlices(ep.Namespace).List(metav1.ListOptions{
+				LabelSelector: discoveryv1beta1.LabelServiceName + "=" + ep.Name,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error listing endpoint slices of service %s: %v", ep.Name, err)
+			}
+			return sl.Items, nil
+		},
+	})
+}
+
+// DescribeReadiness names the readiness predicate together with its salient detail about the endpoints, e.g. the
+// number of ready addresses. Custom predicates are only named.
+func DescribeReadiness(readiness api.Readiness, ep *v1.Endpoints) string {
+	switch readiness.Predicate {
+	case api.ReadinessAnyReadyAddress, api.ReadinessMinReadyAddresses, api.ReadinessAllZonesReady:
+		return fmt.Sprintf("%s (%d ready addresses)", readiness.Predicate, countReadyAddresses(ep.Subsets))
+	case api.ReadinessNamedPortReady:
+		return fmt.Sprintf("%s (port %s)", readiness.Predicate, readiness.PortName)
+	}
+	return readiness.Predicate
+}
+
+// describeServiceReadiness names what reported the service as ready: the debug override of its readiness or all of
+// its readiness predicates, as they all need to hold, and the stability window if configured.
+func (c *Controller) describeServiceReadiness(ep *v1.Endpoints, srv api.Service) string {
+	if _, ok := c.getReadinessOverride(ep.Namespace, ep.Name); ok {
+		return "debug override"
+	}
+	readiness := getServiceReadiness(srv)
+	descriptions := make([]string, len(readiness))
+	for i := range readiness {
+		descriptions[i] = DescribeReadiness(readiness[i], ep)
+	}
+	if srv.ReadyStableFor != nil {
+		descriptions = append(descriptions, fmt.Sprintf("stable for %s", srv.ReadyStableFor.Duration))
+	}
+	return strings.Join(descriptions, ", ")
+}
@@ -0,0 +1,192 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newEndpointWithPorts(addresses int, ports ...string) *v1.Endpoints {
+	ep := newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)
+	ep.Subsets[0].Addresses = make([]v1.EndpointAddress, addresses)
+	for _, port := range ports {
+		ep.Subsets[0].Ports = append(ep.Subsets[0].Ports, v1.EndpointPort{Name: port, Port: 443})
+	}
+	return ep
+}
+
+func TestBuiltinReadinessPredicates(t *testing.T) {
+	minReadyAddresses := int32(2)
+	tests := []struct {
+		name      string
+		readiness *api.Readiness
+		ep        *v1.Endpoints
+		expected  bool
+	}{
+		{"default with a ready address", nil, newEndpointWithPorts(1), true},
+		{"default without a ready address", nil, newEndpointWithPorts(0), false},
+		{"any ready address", &api.Readiness{Predicate: api.ReadinessAnyReadyAddress}, newEndpointWithPorts(1), true},
+		{"enough ready addresses", &api.Readiness{Predicate: api.ReadinessMinReadyAddresses, MinReadyAddresses: &minReadyAddresses}, newEndpointWithPorts(2), true},
+		{"too few ready addresses", &api.Readiness{Predicate: api.ReadinessMinReadyAddresses, MinReadyAddresses: &minReadyAddresses}, newEndpointWithPorts(1), false},
+		{"named port ready", &api.Readiness{Predicate: api.ReadinessNamedPortReady, PortName: "https"}, newEndpointWithPorts(1, "metrics", "https"), true},
+		{"named port missing", &api.Readiness{Predicate: api.ReadinessNamedPortReady, PortName: "https"}, newEndpointWithPorts(1, "metrics"), false},
+		{"named port without a ready address", &api.Readiness{Predicate: api.ReadinessNamedPortReady, PortName: "https"}, newEndpointWithPorts(0, "https"), false},
+	}
+	for _, tc := range tests {
+		c := &Controller{clientset: fake.NewSimpleClientset()}
+		ready, err := c.isServiceReady(tc.ep, api.Service{Readiness: tc.readiness})
+		if err != nil {
+			t.Fatalf("%s: error evaluating readiness: %v", tc.name, err)
+		}
+		if ready != tc.expected {
+			t.Errorf("%s: expected ready %v but got %v", tc.name, tc.expected, ready)
+		}
+	}
+}
+
+func TestAllZonesReady(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints []discoveryv1beta1.Endpoint
+		expected  bool
+	}{
+		{"all zones ready", []discoveryv1beta1.Endpoint{newZoneEndpoint("zone-a", true), newZoneEndpoint("zone-b", true), newZoneEndpoint("zone-b", false)}, true},
+		{"one zone not ready", []discoveryv1beta1.Endpoint{newZoneEndpoint("zone-a", true), newZoneEndpoint("zone-b", false)}, false},
+		{"no zones", nil, true},
+	}
+	readiness := &api.Readiness{Predicate: api.ReadinessAllZonesReady}
+	for _, tc := range tests {
+		c := &Controller{clientset: fake.NewSimpleClientset(newEndpointSlice("kube-apiserver", tc.endpoints...))}
+		ready, err := c.isServiceReady(newEndpointWithPorts(1), api.Service{Readiness: readiness})
+		if err != nil {
+			t.Fatalf("%s: error evaluating readiness: %v", tc.name, err)
+		}
+		if ready != tc.expected {
+			t.Errorf("%s: expected ready %v but got %v", tc.name, tc.expected, ready)
+		}
+	}
+}
+
+func TestCustomReadinessPredicate(t *testing.T) {
+	// The custom predicate requires a ready address of a pod rather than of an external IP.
+	podBacked := ReadinessPredicateFunc(func(dep *Dependency) (bool, error) {
+		for _, subset := range dep.Endpoints.Subsets {
+			for _, address := range subset.Addresses {
+				if address.TargetRef != nil && address.TargetRef.Kind == "Pod" {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	})
+	c := &Controller{
+		clientset: fake.NewSimpleClientset(),
+		ReadinessPredicates: map[string]ReadinessPredicateFactory{
+			"PodBacked": func(api.Readiness) (ReadinessPredicate, error) { return podBacked, nil },
+		},
+	}
+	srv := api.Service{Readiness: &api.Readiness{Predicate: "PodBacked"}}
+
+	ep := newEndpointWithPorts(1)
+	if ready, err := c.isServiceReady(ep, srv); err != nil || ready {
+		t.Errorf("Expected an endpoint without pods not to be ready but got %v (%v)", ready, err)
+	}
+	ep.Subsets[0].Addresses[0].TargetRef = &v1.ObjectReference{Kind: "Pod", Name: "kube-apiserver-0"}
+	if ready, err := c.isServiceReady(ep, srv); err != nil || !ready {
+		t.Errorf("Expected an endpoint with a pod to be ready but got %v (%v)", ready, err)
+	}
+
+	if _, err := c.isServiceReady(ep, api.Service{Readiness: &api.Readiness{Predicate: "Unknown"}}); err == nil {
+		t.Errorf("Expected an error for an unknown readiness predicate")
+	}
+	if _, err := c.isServiceReady(ep, api.Service{Readiness: &api.Readiness{Predicate: api.ReadinessMinReadyAddresses}}); err == nil {
+		t.Errorf("Expected an error for a readiness predicate missing its parameters")
+	}
+}
+
+func TestReadinessPredicateOrder(t *testing.T) {
+	// The probe stands in for an expensive predicate, e.g. an HTTP request to the service.
+	probes := 0
+	probe := ReadinessPredicateFunc(func(dep *Dependency) (bool, error) {
+		probes++
+		return true, nil
+	})
+	c := &Controller{
+		clientset: fake.NewSimpleClientset(),
+		ReadinessPredicates: map[string]ReadinessPredicateFactory{
+			"Probe": func(api.Readiness) (ReadinessPredicate, error) { return probe, nil },
+		},
+	}
+	minReadyAddresses := int32(2)
+	cheap := int32(0)
+
+	tests := []struct {
+		name           string
+		srv            api.Service
+		ep             *v1.Endpoints
+		expectedReady  bool
+		expectedProbes int
+	}{
+		{"probe after the default predicate which is not ready", api.Service{
+			ReadinessAll: []api.Readiness{{Predicate: "Probe"}},
+		}, newEndpointWithPorts(0), false, 0},
+		{"probe after the default predicate which is ready", api.Service{
+			ReadinessAll: []api.Readiness{{Predicate: "Probe"}},
+		}, newEndpointWithPorts(1), true, 1},
+		{"probe ordered after a cheaper predicate which is not ready", api.Service{
+			Readiness: &api.Readiness{Predicate: "Probe"},
+			ReadinessAll: []api.Readiness{
+				{Predicate: api.ReadinessMinReadyAddresses, MinReadyAddresses: &minReadyAddresses},
+			},
+		}, newEndpointWithPorts(1), false, 0},
+		{"probe configured as cheap", api.Service{
+			Readiness: &api.Readiness{Predicate: "Probe", Cost: &cheap},
+			ReadinessAll: []api.Readiness{
+				{Predicate: api.ReadinessMinReadyAddresses, MinReadyAddresses: &minReadyAddresses},
+			},
+		}, newEndpointWithPorts(1), false, 1},
+	}
+	for _, tc := range tests {
+		probes = 0
+		ready, err := c.isServiceReady(tc.ep, tc.srv)
+		if err != nil {
+			t.Fatalf("%s: error evaluating the readiness: %v", tc.name, err)
+		}
+		if ready != tc.expectedReady {
+			t.Errorf("%s: expected ready %v but got %v", tc.name, tc.expectedReady, ready)
+		}
+		if probes != tc.expectedProbes {
+			t.Errorf("%s: expected %d probes but got %d", tc.name, tc.expectedProbes, probes)
+		}
+	}
+
+	if _, err := c.isServiceReady(newEndpointWithPorts(1), api.Service{ReadinessAll: []api.Readiness{{Predicate: "Unknown"}}}); err == nil {
+		t.Errorf("Expected an error for an unknown readiness predicate")
+	}
+}
+
+func TestDescribeReadiness(t *testing.T) {
+	ep := newEndpointWithPorts(2, "https")
+	tests := []struct {
+		readiness api.Readiness
+		expected  string
+	}{
+		{api.Readiness{Predicate: api.ReadinessAnyReadyAddress}, "AnyReadyAddress (2 ready addresses)"},
+		{api.Readiness{Predicate: api.ReadinessAllZonesReady}, "AllZonesReady (2 ready addresses)"},
+		{api.Readiness{Predicate: api.ReadinessNamedPortReady, PortName: "https"}, "NamedPortReady (port https)"},
+		{api.Readiness{Predicate: "EtcdQuorum"}, "EtcdQuorum"},
+	}
+	for _, tc := range tests {
+		if actual := DescribeReadiness(tc.readiness, ep); actual != tc.expected {
+			t.Errorf("Expected %q but got %q", tc.expected, actual)
+		}
+	}
+}
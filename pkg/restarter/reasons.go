@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// The canonical reasons a container may be waiting for, as reported by the kubelet.
+const (
+	// ReasonCrashLoopBackOff is the reason of a container waiting to be restarted after it terminated repeatedly.
+	ReasonCrashLoopBackOff = "CrashLoopBackOff"
+	// ReasonImagePullBackOff is the reason of a container waiting to retry pulling its image.
+	ReasonImagePullBackOff = "ImagePullBackOff"
+	// ReasonErrImagePull is the reason of a container whose image could not be pulled.
+	ReasonErrImagePull = "ErrImagePull"
+	// ReasonInvalidImageName is the reason of a container whose image name could not be parsed.
+	ReasonInvalidImageName = "InvalidImageName"
+	// ReasonCreateContainerConfigError is the reason of a container whose config could not be generated, e.g.
+	// because a referenced config map or secret is missing.
+	ReasonCreateContainerConfigError = "CreateContainerConfigError"
+	// ReasonCreateContainerError is the reason of a container which could not be created by the runtime.
+	ReasonCreateContainerError = "CreateContainerError"
+	// ReasonRunContainerError is the reason of a container which could not be started by the runtime.
+	ReasonRunContainerError = "RunContainerError"
+	// ReasonContainerCreating is the reason of a container which is being created, e.g. while the volumes of its
+	// pod are mounted.
+	ReasonContainerCreating = "ContainerCreating"
+	// ReasonPodInitializing is the reason of a container waiting for the init containers of its pod.
+	ReasonPodInitializing = "PodInitializing"
+)
+
+var restartReasons = sets.NewString(
+	ReasonCrashLoopBackOff,
+	ReasonImagePullBackOff,
+	ReasonErrImagePull,
+	ReasonInvalidImageName,
+	ReasonCreateContainerConfigError,
+	ReasonCreateContainerError,
+	ReasonRunContainerError,
+	ReasonContainerCreating,
+	ReasonPodInitializing,
+)
+
+// RestartReasons returns the sorted registry of the reasons a container may be waiting for which the restart rules
+// and the restart reason thresholds accept.
+func RestartReasons() []string {
+	return restartReasons.List()
+}
+
+// IsKnownRestartReason checks if the reason is in the registry of the restart reasons.
+func IsKnownRestartReason(reason string) bool {
+	return restartReasons.Has(reason)
+}
+
+// CheckRestartReasons verifies that the restart rules and the restart reason thresholds of the dependants only refer
+// to known restart reasons, revealing typos in the config, unless unknown reasons are allowed.
+func CheckRestartReasons(deps *api.ServiceDependants) error {
+	if deps.AllowUnknownReasons {
+		return nil
+	}
+	for name, srv := range deps.Services {
+		for _, depPods := range srv.Dependants {
+			for _, rule := range depPods.RestartRules {
+				for _, reason := range rule.RestartReasons {
+					if !IsKnownRestartReason(reason) {
+						return fmt.Errorf("unknown restart reason %q in the restart rules of dependant %s of service %s", reason, depPods.Name, name)
+					}
+				}
+			}
+			for reason := range depPods.RestartReasonThresholds {
+				if !IsKnownRestartReason(reason) {
+					return fmt.Errorf("unknown restart reason %q in the restart reason thresholds of dependant %s of service %s", reason, depPods.Name, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// topLevelOwnerKinds maps the kinds of the owners which never control pods directly to the kinds of the controllers
+// of their pods.
+var topLevelOwnerKinds = map[string]string{
+	"Deployment": "ReplicaSet",
+	"CronJob":    "Job",
+}
+
+// CheckRestartRuleOwnerKinds verifies that the restart rules of the dependants only refer to the kinds of the direct
+// controllers of the pods, as the rules are matched against them and not against the top-level owners.
+func CheckRestartRuleOwnerKinds(deps *api.ServiceDependants) error {
+	for name, srv := range deps.Services {
+		for _, depPods := range srv.Dependants {
+			for _, rule := range depPods.RestartRules {
+				if kind, ok := topLevelOwnerKinds[rule.OwnerKind]; ok {
+					return fmt.Errorf("owner kind %s in the restart rules of dependant %s of service %s never controls pods directly, use %s instead",
+						rule.OwnerKind, depPods.Name, name, kind)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+)
+
+func TestRestartReasons(t *testing.T) {
+	expected := []string{
+		"ContainerCreating",
+		"CrashLoopBackOff",
+		"CreateContainerConfigError",
+		"CreateContainerError",
+		"ErrImagePull",
+		"ImagePullBackOff",
+		"InvalidImageName",
+		"PodInitializing",
+		"RunContainerError",
+	}
+	if actual := RestartReasons(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected the restart reasons %v but got %v", expected, actual)
+	}
+	if !IsKnownRestartReason(ReasonImagePullBackOff) {
+		t.Errorf("Expected %s to be a known restart reason", ReasonImagePullBackOff)
+	}
+	if IsKnownRestartReason("CrashloopBackoff") {
+		t.Errorf("Expected a misspelled reason not to be known")
+	}
+}
+
+func TestCheckRestartReasons(t *testing.T) {
+	withDependant := func(depPods api.DependantPods) map[string]api.Service {
+		return map[string]api.Service{"kube-apiserver": {Dependants: []api.DependantPods{depPods}}}
+	}
+	known := withDependant(api.DependantPods{
+		Name:                    "controlplane",
+		RestartRules:            []api.RestartRule{{OwnerKind: "ReplicaSet", RestartReasons: []string{ReasonCrashLoopBackOff, ReasonErrImagePull}}},
+		RestartReasonThresholds: map[string]int32{ReasonImagePullBackOff: 0},
+	})
+	unknownRule := withDependant(api.DependantPods{
+		Name:         "controlplane",
+		RestartRules: []api.RestartRule{{RestartReasons: []string{"CrashloopBackoff"}}},
+	})
+	unknownThreshold := withDependant(api.DependantPods{
+		Name:                    "controlplane",
+		RestartReasonThresholds: map[string]int32{"OOMKilled": 1},
+	})
+
+	tests := []struct {
+		name        string
+		deps        *api.ServiceDependants
+		expectedErr bool
+	}{
+		{"known reasons", &api.ServiceDependants{Services: known}, false},
+		{"unknown reason of a restart rule", &api.ServiceDependants{Services: unknownRule}, true},
+		{"unknown reason of a threshold", &api.ServiceDependants{Services: unknownThreshold}, true},
+		{"unknown reasons allowed", &api.ServiceDependants{Services: unknownThreshold, AllowUnknownReasons: true}, false},
+	}
+	for _, tc := range tests {
+		if err := CheckRestartReasons(tc.deps); (err != nil) != tc.expectedErr {
+			t.Errorf("%s: expected error %v but got %v", tc.name, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestCheckRestartRuleOwnerKinds(t *testing.T) {
+	withOwnerKind := func(kind string) *api.ServiceDependants {
+		return &api.ServiceDependants{Services: map[string]api.Service{"kube-apiserver": {Dependants: []api.DependantPods{{
+			Name:         "controlplane",
+			RestartRules: []api.RestartRule{{OwnerKind: kind, RestartReasons: []string{ReasonCrashLoopBackOff}}},
+		}}}}}
+	}
+
+	tests := []struct {
+		kind        string
+		expectedErr bool
+	}{
+		{"", false},
+		{"ReplicaSet", false},
+		{"StatefulSet", false},
+		{"Job", false},
+		{"Deployment", true},
+		{"CronJob", true},
+	}
+	for _, tc := range tests {
+		if err := CheckRestartRuleOwnerKinds(withOwnerKind(tc.kind)); (err != nil) != tc.expectedErr {
+			t.Errorf("%q: expected error %v but got %v", tc.kind, tc.expectedErr, err)
+		}
+	}
+}
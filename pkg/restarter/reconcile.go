@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// reconcileCacheEndpointsMaxAge bounds the age of the endpoints reused within a reconcile. The pods processed at
+// the same time share a single read, while the pods processed later during the watch see the current endpoints.
+const reconcileCacheEndpointsMaxAge = time.Second
+
+var lastReconcileGeneration uint64
+
+// nextReconcileGeneration returns a new generation identifying a single reconcile of a service. The state tracked per
+// reconcile is keyed by <namespace>/<service> and guarded by the generation, so that a superseded reconcile finishing
+// late does not drop the state of the reconcile of the same service which replaced it.
+func nextReconcileGeneration() uint64 {
+	return atomic.AddUint64(&lastReconcileGeneration, 1)
+}
+
+// reconcileCache memoizes the objects read during a single reconcile, so that they are not read again for every
+// dependant pod. Endpoints are reused for a bounded time only, while the owners resolved from the ReplicaSets are
+// reused for the whole reconcile.
+type reconcileCache struct {
+	mux       sync.Mutex
+	endpoints map[string]cachedEndpoints
+	owners    map[string]*metav1.OwnerReference
+}
+
+type cachedEndpoints struct {
+	ep     *v1.Endpoints
+	readAt time.Time
+}
+
+type reconcileCacheKey struct{}
+
+// withReconcileCache returns a context carrying a new cache of the reconcile.
+func withReconcileCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reconcileCacheKey{}, &reconcileCache{
+		endpoints: make(map[string]cachedEndpoints),
+		owners:    make(map[string]*metav1.OwnerReference),
+	})
+}
+
+// reconcileCacheFromContext returns the cache of the reconcile or nil outside of a reconcile.
+func reconcileCacheFromContext(ctx context.Context) *reconcileCache {
+	cache, _ := ctx.Value(reconcileCacheKey{}).(*reconcileCache)
+	return cache
+}
+
+// storeEndpoints remembers the endpoints of the service read at the given time. Missing endpoints are stored as nil.
+func (rc *reconcileCache) storeEndpoints(namespace, service string, ep *v1.Endpoints, readAt time.Time) {
+	rc.mux.Lock()
+	defer rc.mux.Unlock()
+	rc.endpoints[namespace+"/"+service] = cachedEndpoints{ep: ep, readAt: readAt}
+}
+
+func (rc *reconcileCache) loadEndpoints(namespace, service string, now time.Time) (*v1.Endpoints, bool) {
+	rc.mux.Lock()
+	defer rc.mux.Unlock()
+	cached, ok := rc.endpoints[namespace+"/"+service]
+	if !ok || now.Sub(cached.readAt) >= reconcileCacheEndpointsMaxAge {
+		return nil, false
+	}
+	return cached.ep, true
+}
+
+// getEndpoints returns the endpoints of the service, reusing the ones read recently within the reconcile of the
+// context, if any. A NotFound error is returned for missing endpoints.
+func (c *Controller) getEndpoints(ctx context.Context, namespace, service string) (*v1.Endpoints, error) {
+	if rc := reconcileCacheFromContext(ctx); rc != nil {
+		if ep, ok := rc.loadEndpoints(namespace, service, c.clock.Now()); ok {
+			if ep == nil {
+				return nil, apierrors.NewNotFound(v1.Resource("endpoints"), service)
+			}
+			return ep, nil
+		}
+	}
+	return c.readEndpoints(ctx, namespace, service)
+}
+
+// readEndpoints reads the current endpoints of the service and remembers them in the cache of the reconcile of the
+// context, if any.
+func (c *Controller) readEndpoints(ctx context.Context, namespace, service string) (*v1.Endpoints, error) {
+	ep, err := c.clientset.CoreV1().Endpoints(namespace).Get(service, metav1.GetOptions{})
+	rc := reconcileCacheFromContext(ctx)
+	if rc == nil {
+		return ep, err
+	}
+	switch {
+	case err == nil:
+		rc.storeEndpoints(namespace, service, ep, c.clock.Now())
+	case apierrors.IsNotFound(err):
+		rc.storeEndpoints(namespace, service, nil, c.clock.Now())
+	}
+	return ep, err
+}
+
+// getReplicaSetOwner returns the controller of the ReplicaSet, reusing the one resolved within the reconcile of the
+// context, if any. It returns nil if the ReplicaSet is not controlled by any owner or does not exist.
+func getReplicaSetOwner(ctx context.Context, client kubernetes.Interface, namespace, name string) (*metav1.OwnerReference, error) {
+	rc := reconcileCacheFromContext(ctx)
+	key := namespace + "/" + name
+	if rc != nil {
+		rc.mux.Lock()
+		owner, ok := rc.owners[key]
+		rc.mux.Unlock()
+		if ok {
+			return owner, nil
+		}
+	}
+	rs, err := client.AppsV1().ReplicaSets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("error getting replicaset %s: %v", name, err)
+	}
+	var owner *metav1.OwnerReference
+	if err == nil {
+		owner = getControllerOwnerRef(rs)
+	}
+	if rc != nil {
+		rc.mux.Lock()
+		rc.owners[key] = owner
+		rc.mux.Unlock()
+	}
+	return owner, nil
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReconcileCacheSharesReadsBetweenPods(t *testing.T) {
+	isController := true
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{Name: "controlplane", Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       metav1.NamespaceDefault,
+			Name:            "kube-controller-manager-5d8f",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "kube-controller-manager", Controller: &isController}},
+		},
+	}
+	objects := []runtime.Object{rs, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)}
+	for _, name := range []string{"pod-0", "pod-1", "pod-2"} {
+		pod := newPodInCrashloop(name, labels)
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: rs.Name, Controller: &isController}}
+		objects = append(objects, pod)
+	}
+	client := fake.NewSimpleClientset(objects...)
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	c := &Controller{clientset: client, clock: fakeClock}
+	countGets := func(resource string) int {
+		n := 0
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "get" && action.GetResource().Resource == resource {
+				n++
+			}
+		}
+		return n
+	}
+
+	ctx := withReconcileCache(context.TODO())
+	for _, obj := range objects[2:] {
+		if err := c.processPod(ctx, obj.(*v1.Pod), "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("error processing pod: %v", err)
+		}
+	}
+	if n := countGets("replicasets"); n != 1 {
+		t.Errorf("Expected the replicaset shared by the pods to be read once but got %d reads", n)
+	}
+	// Right before every deletion, the endpoints are read again to make sure the dependency is still ready.
+	if n := countGets("endpoints"); n != 4 {
+		t.Errorf("Expected the endpoints to be read once besides the readiness check of every pod but got %d reads", n)
+	}
+
+	before := countGets("endpoints")
+	if _, err := c.getEndpoints(ctx, metav1.NamespaceDefault, "kube-apiserver"); err != nil {
+		t.Fatalf("error getting endpoints: %v", err)
+	}
+	fakeClock.Step(reconcileCacheEndpointsMaxAge)
+	if _, err := c.getEndpoints(ctx, metav1.NamespaceDefault, "kube-apiserver"); err != nil {
+		t.Fatalf("error getting endpoints: %v", err)
+	}
+	if n := countGets("endpoints") - before; n != 1 {
+		t.Errorf("Expected the endpoints to be read again only once the cached ones are too old but got %d reads", n)
+	}
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+)
+
+// incrementRecoveryCount increments the recovery count annotation of the top-level owner of the pod.
+// It is best-effort, so failures are only logged.
+func (c *Controller) incrementRecoveryCount(ctx context.Context, po *v1.Pod) {
+	owner, err := c.getTopLevelOwner(ctx, po)
+	if err != nil {
+		klog.Errorf("Error resolving the owner of pod %s/%s: %s", po.Namespace, po.Name, err)
+		return
+	}
+	if owner == nil {
+		return
+	}
+	if err := c.incrementOwnerRecoveryCount(owner); err != nil {
+		klog.Errorf("Error incrementing the recovery count of %s %s/%s: %s", owner.Kind, owner.Namespace, owner.Name, err)
+	}
+}
+
+// incrementOwnerRecoveryCount patches the recovery count annotation of the owner. The patch is guarded by the
+// resource version of the owner and retried on conflicts, so that concurrent increments are not lost.
+func (c *Controller) incrementOwnerRecoveryCount(owner *v1.ObjectReference) error {
+	if c.dynamicClient == nil {
+		return fmt.Errorf("no dynamic client configured to patch %s", owner.Name)
+	}
+	gvr, err := getOwnerGVR(owner)
+	if err != nil {
+		return err
+	}
+	client := c.dynamicClient.Resource(gvr).Namespace(owner.Namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := client.Get(owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		// A missing or malformed count starts over at zero.
+		count, _ := strconv.Atoi(obj.GetAnnotations()[recoveryCountAnnotationKey])
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"resourceVersion": obj.GetResourceVersion(),
+				"annotations": map[string]string{
+					recoveryCountAnnotationKey: strconv.Itoa(count + 1),
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = client.Patch(owner.Name, types.MergePatchType, patch, c.patchOptions())
+		return err
+	})
+}
+
+// getOwnerGVR derives the GroupVersionResource of the owner from its kind, which holds for the built-in workloads.
+func getOwnerGVR(owner *v1.ObjectReference) (schema.GroupVersionResource, error) {
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	return gv.WithResource(strings.ToLower(owner.Kind) + "s"), nil
+}
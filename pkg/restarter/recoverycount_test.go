@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"errors"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDeletePodIncrementsOwnerRecoveryCount(t *testing.T) {
+	isController := true
+	deployment := &unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+	deployment.SetNamespace(metav1.NamespaceDefault)
+	deployment.SetName("kube-controller-manager")
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment)
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	for i, expected := range []string{"1", "2"} {
+		pod := newPodInCrashloop("pod-0", nil)
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "kube-controller-manager", Controller: &isController}}
+		c := &Controller{clientset: fake.NewSimpleClientset(pod), dynamicClient: dynamicClient}
+		if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+			t.Fatalf("intervention %d: error deleting pod: %v", i, err)
+		}
+
+		d, err := dynamicClient.Resource(gvr).Namespace(metav1.NamespaceDefault).Get("kube-controller-manager", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error fetching deployment: %v", err)
+		}
+		if count := d.GetAnnotations()[recoveryCountAnnotationKey]; count != expected {
+			t.Errorf("intervention %d: expected recovery count %s but got %q", i, expected, count)
+		}
+	}
+}
+
+func TestRecoveryCountFailureDoesNotBlockDeletion(t *testing.T) {
+	isController := true
+	pod := newPodInCrashloop("pod-0", nil)
+	pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "missing", Controller: &isController}}
+	client := fake.NewSimpleClientset(pod)
+	c := &Controller{clientset: client, dynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())}
+
+	if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+		t.Fatalf("error deleting pod: %v", err)
+	}
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected the pod to be deleted despite the missing owner")
+	}
+}
+
+func TestFailedDeletionDoesNotIncrementRecoveryCount(t *testing.T) {
+	isController := true
+	deployment := &unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+	deployment.SetNamespace(metav1.NamespaceDefault)
+	deployment.SetName("kube-controller-manager")
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment)
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	pod := newPodInCrashloop("pod-0", nil)
+	pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "kube-controller-manager", Controller: &isController}}
+	client := fake.NewSimpleClientset(pod)
+	client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewInternalError(errors.New("deletion failed"))
+	})
+	c := &Controller{clientset: client, dynamicClient: dynamicClient}
+	if err := c.deletePod(pod, "kube-apiserver"); err == nil {
+		t.Fatalf("Expected the deletion to fail")
+	}
+
+	d, err := dynamicClient.Resource(gvr).Namespace(metav1.NamespaceDefault).Get("kube-controller-manager", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error fetching deployment: %v", err)
+	}
+	if count, ok := d.GetAnnotations()[recoveryCountAnnotationKey]; ok {
+		t.Errorf("Expected no recovery count after a failed deletion but got %q", count)
+	}
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"k8s.io/klog"
+)
+
+// CheckServiceDependants verifies the config like on startup.
+func CheckServiceDependants(deps *api.ServiceDependants) error {
+	for _, check := range []func(*api.ServiceDependants) error{CheckScope, CheckInformer, CheckRestartReasons, CheckRestartRuleOwnerKinds, CheckQuietHours, CheckDeletionPropagation} {
+		if err := check(deps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReloadServiceDependants replaces the active configuration with the one returned by load.
+// The active configuration is kept if load fails or the configuration is invalid. Changing the
+// namespace is rejected, as it requires a restart. The other settings only applied on startup
+// keep their active values with a warning if they are changed.
+func (c *Controller) ReloadServiceDependants(load func() (*api.ServiceDependants, error)) error {
+	active := c.getServiceDependants()
+	deps, err := load()
+	if err == nil && deps.Namespace != active.Namespace {
+		err = fmt.Errorf("changing the namespace from %q to %q requires a restart", active.Namespace, deps.Namespace)
+	}
+	if err == nil {
+		err = CheckServiceDependants(deps)
+	}
+	if err != nil {
+		configReloadErrorsTotal.Inc()
+		return fmt.Errorf("error reloading config: %v", err)
+	}
+	keepStartupSettings(active, deps)
+
+	c.configLock.Lock()
+	diff := DiffServiceDependants(c.serviceDependants, deps)
+	c.serviceDependants = deps
+	c.configLock.Unlock()
+	configReloadsTotal.Inc()
+	configLastReloadTimestampSeconds.Set(float64(c.clock.Now().Unix()))
+	klog.Infof("Reloaded config: %s", diff)
+	return nil
+}
+
+// keepStartupSettings sets the settings of deps which are only applied on startup to their active values.
+func keepStartupSettings(active, deps *api.ServiceDependants) {
+	keep := func(name string, activeValue, value interface{}, restore func()) {
+		if !reflect.DeepEqual(activeValue, value) {
+			klog.Warningf("Changing %s requires a restart. Keeping the active value.", name)
+			restore()
+		}
+	}
+	keep("deletionsPerSecond", active.DeletionsPerSecond, deps.DeletionsPerSecond, func() { deps.DeletionsPerSecond = active.DeletionsPerSecond })
+	keep("metricLabels", active.MetricLabels, deps.MetricLabels, func() { deps.MetricLabels = active.MetricLabels })
+	keep("webhook", active.Webhook, deps.Webhook, func() { deps.Webhook = active.Webhook })
+	keep("concurrentReconciles", active.ConcurrentReconciles, deps.ConcurrentReconciles, func() { deps.ConcurrentReconciles = active.ConcurrentReconciles })
+	keep("informer", active.Informer, deps.Informer, func() { deps.Informer = active.Informer })
+}
+
+func (c *Controller) getServiceDependants() *api.ServiceDependants {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+	return c.serviceDependants
+}
+
+// getService returns the active config of the service. Unknown services have an empty config.
+func (c *Controller) getService(name string) api.Service {
+	deps := c.getServiceDependants()
+	if deps == nil {
+		return api.Service{}
+	}
+	return deps.Services[name]
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+func TestReloadServiceDependants(t *testing.T) {
+	dir := newTempDir(t)
+	valid := writeConfigFile(t, dir, "valid.yaml", yamlConfig)
+	invalid := writeConfigFile(t, dir, "invalid.yaml", "services: [")
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := &Controller{
+		serviceDependants: &api.ServiceDependants{Namespace: "default"},
+		clock:             clock.NewFakeClock(now),
+	}
+	load := func(file string) func() (*api.ServiceDependants, error) {
+		return func() (*api.ServiceDependants, error) { return LoadServiceDependants(file) }
+	}
+
+	reloads, errors := testutil.ToFloat64(configReloadsTotal), testutil.ToFloat64(configReloadErrorsTotal)
+	if err := c.ReloadServiceDependants(load(valid)); err != nil {
+		t.Fatalf("error reloading config: %v", err)
+	}
+	if got := testutil.ToFloat64(configReloadsTotal); got != reloads+1 {
+		t.Errorf("Expected %v successful reloads but got %v", reloads+1, got)
+	}
+	if got := testutil.ToFloat64(configLastReloadTimestampSeconds); got != float64(now.Unix()) {
+		t.Errorf("Expected last reload timestamp %v but got %v", now.Unix(), got)
+	}
+	active := c.getServiceDependants()
+	if _, ok := active.Services["kube-apiserver"]; !ok {
+		t.Fatalf("Expected the reloaded config to be active but got %+v", active)
+	}
+
+	if err := c.ReloadServiceDependants(load(invalid)); err == nil {
+		t.Errorf("Expected an error reloading an invalid config")
+	}
+	if err := c.ReloadServiceDependants(load(filepath.Join(dir, "missing.yaml"))); err == nil {
+		t.Errorf("Expected an error reloading a missing config")
+	}
+	invalidQuietHours := func() (*api.ServiceDependants, error) {
+		deps, err := LoadServiceDependants(valid)
+		if err == nil {
+			deps.QuietHours = &api.QuietHours{Timezone: "Mars/Olympus"}
+		}
+		return deps, err
+	}
+	if err := c.ReloadServiceDependants(invalidQuietHours); err == nil {
+		t.Errorf("Expected an error reloading a config with invalid quiet hours")
+	}
+	if got := testutil.ToFloat64(configReloadErrorsTotal); got != errors+3 {
+		t.Errorf("Expected %v failed reloads but got %v", errors+3, got)
+	}
+	if got := testutil.ToFloat64(configReloadsTotal); got != reloads+1 {
+		t.Errorf("Expected failed reloads not to be counted as successful but got %v", got)
+	}
+	if c.getServiceDependants() != active {
+		t.Errorf("Expected the active config to be kept after a failed reload")
+	}
+}
+
+func TestReloadServiceDependantsKeepsStartupSettings(t *testing.T) {
+	dir := newTempDir(t)
+	valid := writeConfigFile(t, dir, "valid.yaml", yamlConfig)
+	deletionsPerSecond := 5.0
+	concurrentReconciles := int32(2)
+	active := &api.ServiceDependants{
+		Namespace:            "default",
+		DeletionsPerSecond:   &deletionsPerSecond,
+		ConcurrentReconciles: &concurrentReconciles,
+		MetricLabels:         []string{"project"},
+		Webhook:              &api.Webhook{URL: "https://example.com/hook"},
+		Informer:             &api.Informer{StripFields: []string{api.FieldManagedFields}},
+	}
+	c := &Controller{serviceDependants: active, clock: clock.NewFakeClock(time.Now())}
+
+	if err := c.ReloadServiceDependants(func() (*api.ServiceDependants, error) { return LoadServiceDependants(valid) }); err != nil {
+		t.Fatalf("error reloading config: %v", err)
+	}
+	deps := c.getServiceDependants()
+	if _, ok := deps.Services["kube-apiserver"]; !ok {
+		t.Errorf("Expected the reloaded services to be active but got %+v", deps.Services)
+	}
+	if deps.DeletionsPerSecond != active.DeletionsPerSecond || deps.ConcurrentReconciles != active.ConcurrentReconciles ||
+		len(deps.MetricLabels) != 1 || deps.Webhook != active.Webhook || deps.Informer != active.Informer {
+		t.Errorf("Expected the settings only applied on startup to be kept but got %+v", deps)
+	}
+
+	reloaded := deps
+	for name, load := range map[string]func() (*api.ServiceDependants, error){
+		"all namespaces": func() (*api.ServiceDependants, error) {
+			deps, err := LoadServiceDependants(valid)
+			if err == nil {
+				deps.AllNamespaces = true
+			}
+			return deps, err
+		},
+		"unknown informer field": func() (*api.ServiceDependants, error) {
+			deps, err := LoadServiceDependants(valid)
+			if err == nil {
+				deps.Informer = &api.Informer{StripFields: []string{"spec"}}
+			}
+			return deps, err
+		},
+	} {
+		if err := c.ReloadServiceDependants(load); err == nil {
+			t.Errorf("%s: expected an error reloading the config", name)
+		}
+		if c.getServiceDependants() != reloaded {
+			t.Errorf("%s: expected the active config to be kept after a failed reload", name)
+		}
+	}
+}
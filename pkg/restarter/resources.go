@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+)
+
+// GetDependantResourceGVR resolves the GroupVersionResource of the given dependant resource.
+func GetDependantResourceGVR(depRes *api.DependantResource) (schema.GroupVersionResource, error) {
+	gv, err := schema.ParseGroupVersion(depRes.APIVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid apiVersion %q for dependant resource %s: %v", depRes.APIVersion, depRes.Name, err)
+	}
+	if depRes.Resource == "" {
+		return schema.GroupVersionResource{}, fmt.Errorf("missing resource for dependant resource %s", depRes.Name)
+	}
+	return gv.WithResource(depRes.Resource), nil
+}
+
+// reconcileDependantResources requests a reconciliation of all the dependant resources of the given service.
+// Errors are logged per resource so that one misconfigured resource does not block the others. It returns
+// the number of requested resources and the error of the context if it is done before all are requested.
+// The requests are issued one after the other by a worker, which stops once the context is done. As the clients
+// do not take the context, the request in flight by then completes in the background.
+func (c *Controller) reconcileDependantResources(ctx context.Context, namespace string, srv api.Service) (int, error) {
+	// The results are buffered, so that the worker never blocks on them once the context is done.
+	requested := make(chan struct{}, len(srv.DependantResources))
+	go func() {
+		for i := range srv.DependantResources {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := c.requestReconcile(namespace, &srv.DependantResources[i]); err != nil {
+				klog.Errorf("Error requesting reconcile of dependant resource: %s", err)
+			}
+			requested <- struct{}{}
+		}
+	}()
+	for i := range srv.DependantResources {
+		select {
+		case <-requested:
+		case <-ctx.Done():
+			return i, ctx.Err()
+		}
+	}
+	return len(srv.DependantResources), nil
+}
+
+// requestReconcile annotates the dependant resource with the current time which triggers
+// its controller to reconcile it.
+func (c *Controller) requestReconcile(namespace string, depRes *api.DependantResource) error {
+	if c.dynamicClient == nil {
+		return fmt.Errorf("no dynamic client configured to patch dependant resource %s", depRes.Name)
+	}
+	gvr, err := GetDependantResourceGVR(depRes)
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				reconcileRequestedAtAnnotationKey: time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("Requesting reconcile of %s %s/%s", gvr.String(), namespace, depRes.Name)
+	_, err = c.dynamicClient.Resource(gvr).Namespace(namespace).Patch(depRes.Name, types.MergePatchType, patch, c.patchOptions())
+	if apierrors.IsNotFound(err) {
+		klog.Warningf("Dependant resource %s %s/%s not found. Skipping reconcile request.", gvr.String(), namespace, depRes.Name)
+		return nil
+	}
+	return err
+}
+
+func (c *Controller) patchOptions() metav1.PatchOptions {
+	return metav1.PatchOptions{FieldManager: c.FieldManager}
+}
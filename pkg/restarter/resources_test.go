@@ -0,0 +1,193 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newWidget(name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("example.gardener.cloud/v1")
+	u.SetKind("Widget")
+	u.SetNamespace(metav1.NamespaceDefault)
+	u.SetName(name)
+	return u
+}
+
+func TestRequestReconcileAnnotatesDependantResource(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newWidget("widget-0"))
+	c := &Controller{dynamicClient: client}
+
+	n, err := c.reconcileDependantResources(context.TODO(), metav1.NamespaceDefault, api.Service{
+		DependantResources: []api.DependantResource{
+			{APIVersion: "example.gardener.cloud/v1", Resource: "widgets", Name: "widget-0"},
+			// Unresolvable and missing resources must not prevent the others from being patched.
+			{APIVersion: "example.gardener.cloud/v1/invalid", Resource: "widgets", Name: "widget-1"},
+			{APIVersion: "example.gardener.cloud/v1", Resource: "widgets", Name: "widget-2"},
+		},
+	})
+	if n != 3 || err != nil {
+		t.Errorf("Expected all 3 dependant resources to be requested but got %d: %v", n, err)
+	}
+
+	gvr := schema.GroupVersionResource{Group: "example.gardener.cloud", Version: "v1", Resource: "widgets"}
+	w, err := client.Resource(gvr).Namespace(metav1.NamespaceDefault).Get("widget-0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error fetching widget: %v", err)
+	}
+	if _, ok := w.GetAnnotations()[reconcileRequestedAtAnnotationKey]; !ok {
+		t.Errorf("Expected annotation %s on the dependant resource but got %v", reconcileRequestedAtAnnotationKey, w.GetAnnotations())
+	}
+}
+
+func TestGetDependantResourceGVR(t *testing.T) {
+	tests := []struct {
+		depRes    api.DependantResource
+		expectErr bool
+	}{
+		{api.DependantResource{APIVersion: "example.gardener.cloud/v1", Resource: "widgets"}, false},
+		{api.DependantResource{APIVersion: "v1", Resource: "configmaps"}, false},
+		{api.DependantResource{APIVersion: "a/b/c", Resource: "widgets"}, true},
+		{api.DependantResource{APIVersion: "example.gardener.cloud/v1"}, true},
+	}
+	for _, tc := range tests {
+		_, err := GetDependantResourceGVR(&tc.depRes)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("Unexpected error for %v: %v", tc.depRes, err)
+		}
+	}
+}
+
+// recordingDynamicClient records the options of the patch requests made through it.
+type recordingDynamicClient struct {
+	dynamic.Interface
+	patchOptions []metav1.PatchOptions
+}
+
+type recordingResourceClient struct {
+	dynamic.NamespaceableResourceInterface
+	recorder *recordingDynamicClient
+}
+
+type recordingNamespacedResourceClient struct {
+	dynamic.ResourceInterface
+	recorder *recordingDynamicClient
+}
+
+func (r *recordingDynamicClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &recordingResourceClient{r.Interface.Resource(gvr), r}
+}
+
+func (r *recordingResourceClient) Namespace(ns string) dynamic.ResourceInterface {
+	return &recordingNamespacedResourceClient{r.NamespaceableResourceInterface.Namespace(ns), r.recorder}
+}
+
+func (r *recordingNamespacedResourceClient) Patch(name string, pt types.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.recorder.patchOptions = append(r.recorder.patchOptions, options)
+	return r.ResourceInterface.Patch(name, pt, data, options, subresources...)
+}
+
+func TestRequestReconcileUsesFieldManager(t *testing.T) {
+	client := &recordingDynamicClient{Interface: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newWidget("widget-0"))}
+	c := &Controller{dynamicClient: client, FieldManager: defaultFieldManager}
+
+	if err := c.requestReconcile(metav1.NamespaceDefault, &api.DependantResource{APIVersion: "example.gardener.cloud/v1", Resource: "widgets", Name: "widget-0"}); err != nil {
+		t.Fatalf("error requesting reconcile: %v", err)
+	}
+	if len(client.patchOptions) != 1 || client.patchOptions[0].FieldManager != "dependency-watchdog" {
+		t.Errorf("Expected one patch with field manager dependency-watchdog but got %v", client.patchOptions)
+	}
+}
+
+func TestReconcileDependantResourcesReturnsOnDeadline(t *testing.T) {
+	unblock := make(chan struct{})
+	var (
+		mux     sync.Mutex
+		patched []string
+	)
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), newWidget("widget-0"), newWidget("widget-1"), newWidget("widget-2"))
+	client.PrependReactor("patch", "widgets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.PatchAction).GetName()
+		if name == "widget-1" {
+			<-unblock
+		}
+		mux.Lock()
+		patched = append(patched, name)
+		mux.Unlock()
+		return false, nil, nil
+	})
+	c := &Controller{dynamicClient: client}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	n, err := c.reconcileDependantResources(ctx, metav1.NamespaceDefault, api.Service{
+		DependantResources: []api.DependantResource{
+			{APIVersion: "example.gardener.cloud/v1", Resource: "widgets", Name: "widget-0"},
+			{APIVersion: "example.gardener.cloud/v1", Resource: "widgets", Name: "widget-1"},
+			{APIVersion: "example.gardener.cloud/v1", Resource: "widgets", Name: "widget-2"},
+		},
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected the reconcile to end on the deadline but got %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected the first dependant resource to be requested before the deadline but got %d", n)
+	}
+
+	// The request in flight completes, but no further resources are requested after the deadline.
+	close(unblock)
+	time.Sleep(100 * time.Millisecond)
+	mux.Lock()
+	defer mux.Unlock()
+	if expected := []string{"widget-0", "widget-1"}; !reflect.DeepEqual(patched, expected) {
+		t.Errorf("Expected the requests %v but got %v", expected, patched)
+	}
+}
+
+func TestReconcileTimeoutBoundsTheReconcile(t *testing.T) {
+	deps := &api.ServiceDependants{
+		Namespace: metav1.NamespaceDefault,
+		Options:   api.Options{ReconcileTimeout: &metav1.Duration{Duration: 100 * time.Millisecond}},
+		Services:  map[string]api.Service{"kube-apiserver": {}},
+	}
+	client := fake.NewSimpleClientset(newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c := NewController(client, nil, informers.NewSharedInformerFactory(client, 0), deps, watchDuration, stopCh)
+	tracer := &recordingTracer{}
+	c.Tracer = tracer
+	go c.Multicontext.Start(stopCh)
+
+	if err := c.processEndpoint(context.TODO(), "default/kube-apiserver"); err != nil {
+		t.Fatalf("error processing endpoint: %v", err)
+	}
+	// The reconcile ends after the reconcile timeout instead of the much longer watch duration.
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		tracer.mux.Lock()
+		defer tracer.mux.Unlock()
+		return len(tracer.spans) == 1 && tracer.spans[0].ended, nil
+	})
+	if err != nil {
+		t.Errorf("Expected the reconcile to end after the reconcile timeout")
+	}
+}
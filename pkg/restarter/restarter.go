@@ -7,12 +7,17 @@ package restarter
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
@@ -20,6 +25,7 @@ import (
 
 	"github.com/gardener/dependency-watchdog/pkg/multicontext"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -29,24 +35,51 @@ import (
 
 // NewController initializes a new K8s dependency-watchdog controller with restarter.
 func NewController(clientset kubernetes.Interface,
+	dynamicClient dynamic.Interface,
 	sharedInformerFactory informers.SharedInformerFactory,
 	serviceDependants *api.ServiceDependants,
 	watchDuration time.Duration,
 	stopCh <-chan struct{}) *Controller {
 	c := &Controller{
 		clientset:         clientset,
+		dynamicClient:     dynamicClient,
 		informerFactory:   sharedInformerFactory,
 		endpointInformer:  sharedInformerFactory.Core().V1().Endpoints().Informer(),
 		endpointLister:    sharedInformerFactory.Core().V1().Endpoints().Lister(),
-		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Endpoints"),
+		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), endpointsQueueName),
 		stopCh:            stopCh,
 		serviceDependants: serviceDependants,
 		watchDuration:     watchDuration,
+		clock:             clock.RealClock{},
+		ownerChanges:      newOwnerChangeCache(),
+		reconcileSlots:    make(chan struct{}, getConcurrentReconciles(serviceDependants)),
+		reconcileTrigger:  make(chan struct{}, 1),
 		Multicontext:      multicontext.New(),
+		FieldManager:      defaultFieldManager,
 		LeaderElection: componentbaseconfigv1alpha1.LeaderElectionConfiguration{
 			ResourceLock: resourcelock.LeasesResourceLock,
 		},
 	}
+	c.flapDetector = NewFlapDetector(c.clock)
+	c.endpointsChanges = newEndpointsChangeTracker()
+	c.ownerEvents = newOwnerEvents()
+	c.recycleBudgets = newRecycleBudgets()
+	c.serviceCooldowns = newServiceCooldowns()
+	c.notReady = newNotReadyTracker()
+	c.readyStability = newReadyStabilityTracker()
+	c.reconcileSummaries = newReconcileSummaries()
+	c.dryRunReport = newDryRunReport()
+	c.retryBudgets = newRetryBudgets()
+	c.lostAnnotations = newLostAnnotations()
+	c.recoveryHistory = newRecoveryHistory()
+	c.readinessOverrides = newReadinessOverrides()
+	c.podMetrics = newPodMetrics(serviceDependants.MetricLabels)
+	if serviceDependants.DeletionsPerSecond != nil {
+		c.deletionLimiter = NewAdaptiveLimiter(*serviceDependants.DeletionsPerSecond, c.clock)
+	}
+	if serviceDependants.Webhook != nil {
+		c.notifier = newWebhookNotifier(serviceDependants.Webhook)
+	}
 	componentbaseconfigv1alpha1.RecommendedDefaultLeaderElectionConfiguration(&c.LeaderElection)
 	c.endpointInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: c.enqueueEndpoint,
@@ -81,13 +114,15 @@ func (c *Controller) enqueueEndpoint(obj interface{}) {
 		return
 	}
 
-	// Skip resources from other namespaces if namespace is specified explicitly in the configuration.
-	if c.serviceDependants.Namespace != "" && c.serviceDependants.Namespace != namespace {
+	deps := c.getServiceDependants()
+	// Skip resources from other namespaces if namespace is specified explicitly in the configuration
+	// and from excluded namespaces otherwise, as well as from namespaces not in the allowlist.
+	if !c.isNamespaceAllowed(deps, namespace) {
 		return
 	}
 
 	// Skip if the resource is not found in the services configured as to be watched.
-	if _, ok := c.serviceDependants.Services[name]; !ok {
+	if _, ok := deps.Services[name]; !ok {
 		return
 	}
 
@@ -116,6 +151,8 @@ func (c *Controller) Run(threadiness int) error {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
+	go c.handleReconcileTriggers()
+
 	klog.Info("Starting workers")
 	// Launch workers to process VPA resources
 	for i := 0; i < threadiness; i++ {
@@ -125,6 +162,7 @@ func (c *Controller) Run(threadiness int) error {
 	klog.Info("Started workers")
 	<-c.stopCh
 	klog.Info("Shutting down workers")
+	c.logDryRunReport()
 
 	return nil
 }
@@ -153,6 +191,7 @@ func (c *Controller) processNextWorkItem() bool {
 		if key, ok = obj.(string); !ok {
 
 			c.workqueue.Forget(obj)
+			workqueueDroppedTotal.WithLabelValues(endpointsQueueName).Inc()
 			utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v", obj))
 			return nil
 		}
@@ -179,35 +218,59 @@ func (c *Controller) processNextWorkItem() bool {
 func (c *Controller) processEndpoint(ctx context.Context, key string) error {
 	namespace, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
+		workqueueDroppedTotal.WithLabelValues(endpointsQueueName).Inc()
 		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
 		return nil
 	}
-	if c.serviceDependants.Namespace != "" && namespace != c.serviceDependants.Namespace {
+	deps := c.getServiceDependants()
+	if !c.isNamespaceAllowed(deps, namespace) {
 		return nil
 	}
 
 	ep, err := c.clientset.CoreV1().Endpoints(namespace).Get(name, metav1.GetOptions{})
 	if err != nil {
-		// The endpoint resource may no longer exist, in which case we stop
-		// processing.
+		// The endpoint resource may not exist (yet), in which case the service is not ready.
 		if apierrors.IsNotFound(err) {
-			utilruntime.HandleError(fmt.Errorf("endpoint '%s' in work queue no longer exists", key))
+			klog.V(4).Infof("Endpoint %s does not exist. Treating the service as not ready.", key)
 			// Cancel any existing context to pro-actively avoid shooting pods accidentally.
 			c.ContextCh <- &multicontext.ContextMessage{
 				Key:      key,
 				CancelFn: nil,
 			}
+			if isDependencyRequiredToExist(getNamespaceOptions(deps, namespace)) {
+				return fmt.Errorf("endpoint %s does not exist", key)
+			}
 			return nil
 		}
 		return err
 	}
-	srv, ok := c.serviceDependants.Services[name]
+	srv, ok := deps.Services[name]
 	if !ok {
 		return nil
 	}
 	klog.Infof("Processing endpoint: %s", key)
-	if !IsReadyEndpointPresentInSubsets(ep.Subsets) {
-		klog.Infof("Endpoint %s does not have any endpoint subset. Skipping pod terminations.", ep.Name)
+	// The readiness is evaluated once per service and shared by all of its dependants.
+	ready, err := c.isServiceReady(ep, srv)
+	if err != nil {
+		return fmt.Errorf("error evaluating the readiness of endpoint %s: %v", key, err)
+	}
+	c.observeReadiness(ep, srv, ready)
+	setCircuitOpenMetric(c.isCircuitOpen())
+	if err := c.reconcileDependencyLost(namespace, name, srv, ready); err != nil {
+		klog.Errorf("Error reconciling the lost dependency %s: %s", key, err)
+	}
+	if err := c.reconcileNotReadyTimeout(namespace, name, srv, ready); err != nil {
+		klog.Errorf("Error taking the alternate action for the not ready dependency %s: %s", key, err)
+	}
+	now := c.clock.Now()
+	lastChange := GetEndpointsLastChangeTime(ep, c.endpointsChanges.observe(key, ep, now))
+	if state := getDependencyState(ready, lastChange, getMaxEndpointsStaleness(getNamespaceOptions(deps, namespace)), now); state != DependencyReady {
+		if state == DependencyUnknown {
+			klog.Infof("Endpoint %s has not changed since %s. Its readiness is unknown. Skipping pod terminations.", ep.Name, lastChange)
+		} else {
+			klog.Infof("Endpoint %s does not have any endpoint subset. Skipping pod terminations.", ep.Name)
+		}
+		logReconcileSummary(deps, ReconcileSummary{Namespace: namespace, Service: name, Dependants: len(srv.Dependants)})
 		// Cancel any existing context to pro-actively avoid shooting pods accidentally.
 		c.ContextCh <- &multicontext.ContextMessage{
 			Key:      key,
@@ -217,16 +280,41 @@ func (c *Controller) processEndpoint(ctx context.Context, key string) error {
 	}
 
 	go func() {
-		klog.Infof("Watching for pods in CrashLoopBackOff for a period of %s", c.watchDuration.String())
-		ctx, cancelFn := context.WithTimeout(ctx, c.watchDuration)
+		timeout := getReconcileTimeout(getNamespaceOptions(c.getServiceDependants(), namespace), c.watchDuration)
+		klog.Infof("Watching for pods in CrashLoopBackOff for a period of %s", timeout.String())
+		ctx, cancelFn := context.WithTimeout(ctx, timeout)
 		defer cancelFn()
+		ctx = withReconcileCache(ctx)
+		reconcileCacheFromContext(ctx).storeEndpoints(namespace, name, ep, now)
+		ctx, span := c.tracer().Start(ctx, spanReconcile,
+			Attribute{Key: "namespace", Value: namespace},
+			Attribute{Key: "service", Value: name},
+		)
+		defer span.End()
 
 		c.ContextCh <- &multicontext.ContextMessage{
 			Key:      key,
 			CancelFn: cancelFn,
 		}
 
-		c.shootPodsIfNecessary(ctx, namespace, srv)
+		generation := nextReconcileGeneration()
+		c.reconcileSummaries.start(namespace, name, generation, len(srv.Dependants), c.clock.Now())
+		defer c.finishReconcileSummary(namespace, name, generation)
+		c.ownerEvents.start(key, generation)
+		defer c.recordOwnerEvents(namespace, name, generation)
+		c.recycleBudgets.start(key, generation)
+		defer c.recycleBudgets.finish(key, generation)
+		c.retryBudgets.start(key, generation)
+		defer c.finishRetryBudget(namespace, name, generation)
+		c.serviceCooldowns.start(key, generation)
+		defer c.serviceCooldowns.finish(key, generation)
+
+		if n, err := c.reconcileDependantResources(ctx, namespace, srv); err != nil {
+			klog.Errorf("Reconcile of service %s/%s ended after requesting the reconcile of %d of %d dependant resources: %s",
+				namespace, name, n, len(srv.DependantResources), err)
+		}
+		c.compareCandidateSelections(ctx, namespace, name)
+		c.shootPodsIfNecessary(ctx, namespace, name, srv)
 		select {
 		case <-ctx.Done():
 			c.ContextCh <- &multicontext.ContextMessage{
@@ -241,10 +329,34 @@ func (c *Controller) processEndpoint(ctx context.Context, key string) error {
 	return nil
 }
 
-func (c *Controller) shootPodsIfNecessary(ctx context.Context, namespace string, srv api.Service) error {
+// withReconcileSlot runs fn once one of the reconcile slots is available, bounding the number of services whose
+// dependant pods are decided on and deleted at the same time. The slot is only held while fn runs, not while the
+// reconcile waits for its pods to become unhealthy. It returns false without running fn if the reconcile ended,
+// e.g. because the service became not ready again, or the controller is stopped while waiting for a slot.
+func (c *Controller) withReconcileSlot(ctx context.Context, fn func()) bool {
+	if c.reconcileSlots == nil {
+		fn()
+		return true
+	}
+	select {
+	case c.reconcileSlots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	case <-c.stopCh:
+		return false
+	}
+	defer func() { <-c.reconcileSlots }()
+	if ctx.Err() != nil {
+		return false
+	}
+	fn()
+	return true
+}
+
+func (c *Controller) shootPodsIfNecessary(ctx context.Context, namespace, service string, srv api.Service) error {
 	for _, dependantPod := range srv.Dependants {
 		go func(depPods api.DependantPods) {
-			err := c.shootDependentPodsIfNecessary(ctx, namespace, &depPods)
+			err := c.shootDependentPodsIfNecessary(ctx, namespace, service, &depPods)
 			if err != nil {
 				klog.Errorf("Error processing dependents pods: %s", err)
 			}
@@ -253,7 +365,7 @@ func (c *Controller) shootPodsIfNecessary(ctx context.Context, namespace string,
 	return nil
 }
 
-func (c *Controller) shootDependentPodsIfNecessary(ctx context.Context, namespace string, depPods *api.DependantPods) error {
+func (c *Controller) shootDependentPodsIfNecessary(ctx context.Context, namespace, service string, depPods *api.DependantPods) error {
 	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
 	if err != nil {
 		return fmt.Errorf("error converting label selector to selector %s", depPods.Selector.String())
@@ -270,6 +382,7 @@ func (c *Controller) shootDependentPodsIfNecessary(ctx context.Context, namespac
 
 			defer w.Stop()
 
+			filter := newPodEventFilter(func(pod *v1.Pod) bool { return isDependantPodUnhealthy(pod, depPods) })
 			for {
 				select {
 				case <-ctx.Done():
@@ -283,13 +396,36 @@ func (c *Controller) shootDependentPodsIfNecessary(ctx context.Context, namespac
 						klog.Infof("Received error from watch channel. Will restart the watch with selector: %s", selector.String())
 						return true, nil
 					}
+					if ev.Type == watch.Deleted {
+						if pod, ok := ev.Object.(*v1.Pod); ok {
+							filter.forget(pod)
+						}
+						continue
+					}
 					if ev.Type != watch.Added && ev.Type != watch.Modified {
 						klog.Infof("Skipping event type: %s", ev.Type)
 						continue
 					}
 					switch pod := ev.Object.(type) {
 					case *v1.Pod:
-						err := c.processPod(ctx, pod)
+						if IsPodDeleted(pod) {
+							filter.forget(pod)
+							klog.V(5).Infof("Skipping event %s for pod %s as it is already terminating", ev.Type, pod.Name)
+							continue
+						}
+						if !isDependantPod(pod, depPods) {
+							klog.V(5).Infof("Skipping event %s for pod %s as it does not use service account %s", ev.Type, pod.Name, depPods.ServiceAccountName)
+							continue
+						}
+						if !filter.shouldProcess(ev.Type, pod) {
+							klog.V(5).Infof("Skipping event %s for pod %s as it did not become unhealthy", ev.Type, pod.Name)
+							continue
+						}
+						var err error
+						if !c.withReconcileSlot(ctx, func() { err = c.processPod(ctx, pod, service, depPods, selector) }) {
+							klog.V(4).Infof("Reconcile of service %s/%s ended while waiting for a reconcile slot. Skipping pod %s.", namespace, service, pod.Name)
+							continue
+						}
 						if err != nil {
 							klog.Errorf("error processing pod %s: %v", pod.Name, err.Error())
 						}
@@ -305,15 +441,487 @@ func (c *Controller) shootDependentPodsIfNecessary(ctx context.Context, namespac
 	}
 }
 
-func (c *Controller) processPod(ctx context.Context, pod *v1.Pod) error {
+// processPod decides on the dependant pod of the service and counts the decision in the decisions metric, which is
+// the single site where the decisions on the candidate pods are counted.
+func (c *Controller) processPod(ctx context.Context, pod *v1.Pod, service string, depPods *api.DependantPods, selector labels.Selector) error {
+	decision, err := c.decidePod(ctx, pod, service, depPods, selector)
+	if decision != nil {
+		recordDecision(decision)
+		c.reconcileSummaries.add(pod.Namespace, service, decision)
+	}
+	return err
+}
+
+// decidePod decides on the dependant pod of the service and recycles it if it should be. It returns the decision
+// unless the pods are recycled together because all of them need to be unhealthy. The decision of the active config
+// is compared with the one of the candidate config before it is acted upon.
+func (c *Controller) decidePod(ctx context.Context, pod *v1.Pod, service string, depPods *api.DependantPods, selector labels.Selector) (*Decision, error) {
+	deps := c.getServiceDependants()
+	skip, until := c.checkService(deps, pod.Namespace, service)
+	if skip != nil && skip.Reason == DecisionReasonGracePeriod {
+		if !c.waitForRecoveryGracePeriod(ctx, pod.Namespace, service, until) {
+			return skip, nil
+		}
+		deps = c.getServiceDependants()
+		skip, until = c.checkService(deps, pod.Namespace, service)
+	}
+	if skip != nil {
+		switch skip.Reason {
+		case DecisionReasonNotRecovered:
+			klog.V(4).Infof("Service %s/%s did not recover recently. Skipping pod %s.", pod.Namespace, service, pod.Name)
+		case DecisionReasonCooldown:
+			klog.V(4).Infof("Service %s/%s is cooling down after its last pod deletions. Skipping pod %s.", pod.Namespace, service, pod.Name)
+			c.requeueAt(pod.Namespace, service, until)
+		}
+		c.compareCandidateDecision(ctx, pod, service, skip)
+		return skip, nil
+	}
+	if depPods.RequireAllUnhealthy {
+		c.compareCandidateGroupDecision(ctx, deps, pod, service, depPods, selector)
+		return nil, c.deletePodsIfAllUnhealthy(ctx, pod.Namespace, service, depPods, selector)
+	}
+
 	// Validate pod status again before shoot it out.
 	po, err := c.clientset.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("error getting pod %s", pod.Name)
+		return nil, fmt.Errorf("error getting pod %s", pod.Name)
+	}
+	check, err := c.checkPod(ctx, deps, po, service, depPods, selector)
+	if err != nil {
+		return nil, err
+	}
+	c.compareCandidateDecision(ctx, po, service, c.getCheckedDecision(deps, po, depPods, check))
+	if check.recyclable {
+		c.countUnhealthyPod(po, service)
+	}
+	if check.skip != nil {
+		c.reportSkippedPod(po, service, depPods, selector, check)
+		return check.skip, nil
+	}
+	unhealthyReason := getUnhealthyReason(po, depPods)
+	if depPods.ObserveOnly {
+		c.observePod(ctx, po, service, check.reason, depPods)
+		return &Decision{Action: DecisionActionObserved, Reason: unhealthyReason}, nil
+	}
+	due := c.warnBeforeDelete(ctx, []v1.Pod{*po}, service, depPods)
+	if len(due) == 0 {
+		return skipped(DecisionReasonIgnored), nil
+	}
+	po = &due[0]
+	if !c.isDependencyStillReady(ctx, po.Namespace, service) {
+		return skipped(DecisionReasonDependencyNotReady), nil
+	}
+	reserved, err := c.reserveRecycleBudget(ctx, po, service, depPods)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		return skipped(DecisionReasonBudget), nil
+	}
+	outcome, err := c.deletePodInSpan(ctx, po, service, check.reason, depPods)
+	return getOutcomeDecision(outcome, unhealthyReason), err
+}
+
+// reportSkippedPod reports why the check skipped the dependant pod of the service.
+func (c *Controller) reportSkippedPod(po *v1.Pod, service string, depPods *api.DependantPods, selector labels.Selector, check *podCheck) {
+	switch {
+	case check.skip.Reason == DecisionReasonTerminating:
+		klog.V(4).Infof("Skipping pod %s as it is already terminating", po.Name)
+	case check.skip.Reason == DecisionReasonDependencyOverlap:
+		c.reportBackingDependency(po, service, depPods)
+	case !check.recyclable:
+		klog.V(4).Infof("Not recycling pod %s: %s", po.Name, check.reason)
+		c.requeueWhenEligible(po, service, depPods)
+	case check.skip.Reason == DecisionReasonZoneNotReady:
+		klog.Infof("Service %s has no ready endpoint in the zone of pod %s. Skipping pod deletion.", service, po.Name)
+	case check.skip.Reason == DecisionReasonNodeLocalized:
+		nodeLocalizedCrashloopTotal.With(prometheus.Labels{labelNode: po.Spec.NodeName}).Inc()
+		klog.Warningf("Pods with selector %s are crashlooping mostly on node %s. Skipping deletion of pod %s. The node might need attention.", selector.String(), po.Spec.NodeName, po.Name)
+	case check.hold != nil:
+		c.reportOwnerHold(po, service, check.hold)
+	case check.skip.Reason == DecisionReasonPaused:
+		klog.Infof("The owner of pod %s/%s is paused or suspended. Skipping pod deletion.", po.Namespace, po.Name)
+	}
+}
+
+// requeueWhenEligible requeues the service for the time at which the skipped pod becomes eligible for a deletion,
+// if it is going to, instead of waiting for the next change of the endpoints of the service.
+func (c *Controller) requeueWhenEligible(po *v1.Pod, service string, depPods *api.DependantPods) {
+	if c.workqueue == nil {
+		return
+	}
+	if at, ok := NextEligibleTime(po, depPods, c.clock.Now()); ok {
+		c.requeueAt(po.Namespace, service, at)
+	}
+}
+
+// requeueAt requeues the endpoints of the service at the given time.
+func (c *Controller) requeueAt(namespace, service string, at time.Time) {
+	if c.workqueue == nil {
+		return
+	}
+	delay := at.Sub(c.clock.Now())
+	klog.V(4).Infof("Requeuing service %s/%s in %s.", namespace, service, delay)
+	c.workqueue.AddAfter(namespace+"/"+service, delay)
+}
+
+// waitForRecoveryGracePeriod waits until the recovery grace period of the service ends, giving the dependant pods the
+// chance to recover on their own. It returns false if the reconcile ended in the meantime.
+func (c *Controller) waitForRecoveryGracePeriod(ctx context.Context, namespace, service string, until time.Time) bool {
+	remaining := until.Sub(c.clock.Now())
+	if remaining <= 0 {
+		return true
+	}
+	klog.V(4).Infof("Service %s/%s recovered recently. Waiting %s before deleting its dependant pods.", namespace, service, remaining)
+	select {
+	case <-c.clock.After(remaining):
+		return true
+	case <-ctx.Done():
+		return false
+	case <-c.stopCh:
+		return false
+	}
+}
+
+// checkCrashloopLocalizedOnNode checks if the CrashloopBackoff of the dependant pods is localized on the node of the
+// given pod.
+func (c *Controller) checkCrashloopLocalizedOnNode(po *v1.Pod, selector labels.Selector, fraction float64) (bool, error) {
+	pl, err := c.clientset.CoreV1().Pods(po.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("error listing pods with selector %s: %v", selector.String(), err)
+	}
+	for _, node := range GetNodesWithLocalizedCrashloops(excludeTerminatingPods(pl.Items), fraction) {
+		if node == po.Spec.NodeName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// podEventFilter remembers the last seen state of the pods received from a watch
+// to avoid processing every status update of a pod.
+type podEventFilter struct {
+	lastSeen  map[types.UID]*v1.Pod
+	unhealthy func(*v1.Pod) bool
+}
+
+// newPodEventFilter returns a filter for the pods becoming unhealthy. Pods in CrashLoopBackOff are
+// considered unhealthy if unhealthy is nil.
+func newPodEventFilter(unhealthy func(*v1.Pod) bool) *podEventFilter {
+	if unhealthy == nil {
+		unhealthy = func(pod *v1.Pod) bool { return IsPodInCrashloopBackoff(pod.Status) }
 	}
-	if !ShouldDeletePod(po) {
+	return &podEventFilter{
+		lastSeen:  make(map[types.UID]*v1.Pod),
+		unhealthy: unhealthy,
+	}
+}
+
+// shouldProcess returns true for newly added pods and for pods whose update
+// transitions them into being unhealthy.
+func (f *podEventFilter) shouldProcess(eventType watch.EventType, pod *v1.Pod) bool {
+	old, ok := f.lastSeen[pod.UID]
+	f.lastSeen[pod.UID] = pod
+	if eventType != watch.Modified || !ok {
+		return true
+	}
+	return !f.unhealthy(old) && f.unhealthy(pod)
+}
+
+// forget drops the last seen state of the pod once it is deleted or terminating, as it is not processed anymore.
+func (f *podEventFilter) forget(pod *v1.Pod) {
+	delete(f.lastSeen, pod.UID)
+}
+
+// deletePodsIfAllUnhealthy deletes all the pods matching the selector but only if every one of them should be deleted.
+func (c *Controller) deletePodsIfAllUnhealthy(ctx context.Context, namespace, service string, depPods *api.DependantPods, selector labels.Selector) error {
+	pl, err := c.clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("error listing pods with selector %s: %v", selector.String(), err)
+	}
+	// Pods which are already being evicted are on their way out like the terminating ones.
+	pods := excludeEvictedPods(selectDependantPods(pl.Items, depPods))
+	depReady, err := c.isDependantDependencyReady(namespace, depPods)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
 		return nil
 	}
-	klog.Infof("Deleting pod: %v", po.Name)
-	return c.clientset.CoreV1().Pods(po.Namespace).Delete(po.Name, &metav1.DeleteOptions{})
+	for i := range pods {
+		recycle, reason, err := c.shouldRecycleDependantPod(ctx, &pods[i], depPods, depReady)
+		if err != nil {
+			return err
+		}
+		if !recycle {
+			klog.V(4).Infof("Not all pods with selector %s should be recycled, pod %s is not: %s. Skipping pod deletions.", selector.String(), pods[i].Name, reason)
+			return nil
+		}
+	}
+	opts := getNamespaceOptions(c.getServiceDependants(), namespace)
+	backing, err := c.getDependencyBackingPods(ctx, namespace, service)
+	if err != nil {
+		return err
+	}
+	var active []v1.Pod
+	for i := range pods {
+		if isBackingDependency(&pods[i], depPods, backing) {
+			c.reportBackingDependency(&pods[i], service, depPods)
+			continue
+		}
+		paused, err := c.isOwnerPaused(ctx, &pods[i])
+		if err != nil {
+			return err
+		}
+		recent, err := c.isOwnerRecentlyChanged(&pods[i], opts)
+		if err != nil {
+			return err
+		}
+		if !paused && !recent {
+			active = append(active, pods[i])
+		}
+	}
+	if pods = c.warnBeforeDelete(ctx, active, service, depPods); len(pods) == 0 {
+		return nil
+	}
+	if depPods.OrderByPriority {
+		sortPodsByPriority(pods)
+	}
+	return c.deletePodsInWaves(ctx, pods, service, depPods, selector)
+}
+
+// isDependantDependencyReady checks the sentinel, the lease and the health check of the dependant pods, if any, which
+// reflect the health of the dependency in addition to the endpoints of the service.
+func (c *Controller) isDependantDependencyReady(namespace string, depPods *api.DependantPods) (bool, error) {
+	ready, err := c.isSentinelAvailable(namespace, depPods)
+	if err != nil || !ready {
+		return false, err
+	}
+	if ready, err = c.isLeaseFresh(namespace, depPods); err != nil || !ready {
+		return false, err
+	}
+	return c.isHealthCheckReady(namespace, depPods)
+}
+
+// shouldRecycleDependantPod checks if the pod is unhealthy according to the dependant pods and the recycle policy
+// recycles it, as required for all the pods which are recycled together.
+func (c *Controller) shouldRecycleDependantPod(ctx context.Context, po *v1.Pod, depPods *api.DependantPods, depReady bool) (bool, string, error) {
+	if !shouldDeleteDependantPod(po, depPods) {
+		return false, getNotRecycledReason(po, depPods, depReady), nil
+	}
+	return c.shouldRecycle(ctx, po, depPods, depReady)
+}
+
+// isDependencyStillReady re-reads the endpoints of the service right before pods are deleted, so that no pods
+// are recycled into a dependency which became not ready again in the meantime. The cancellation of the reconcile
+// may lag behind, so the endpoints cached by the reconcile are not trusted here, but refreshed. The service already
+// passed its stability window when the reconcile started, so only its readiness predicates are evaluated again.
+func (c *Controller) isDependencyStillReady(ctx context.Context, namespace, service string) bool {
+	ep, err := c.readEndpoints(ctx, namespace, service)
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("Error re-reading endpoint %s/%s: %s. Aborting the remaining pod deletions.", namespace, service, err)
+		return false
+	}
+	if err != nil {
+		klog.Infof("Service %s/%s is no longer ready. Aborting the remaining pod deletions.", namespace, service)
+		return false
+	}
+	ready, err := c.evaluateServiceReadiness(ep, c.getService(service))
+	if err != nil {
+		klog.Errorf("Error evaluating the readiness of service %s/%s: %s. Aborting the remaining pod deletions.", namespace, service, err)
+		return false
+	}
+	if !ready {
+		klog.Infof("Service %s/%s is no longer ready. Aborting the remaining pod deletions.", namespace, service)
+		return false
+	}
+	return true
+}
+
+// isSentinelAvailable checks if one of the sentinel pods of the dependant pods is available. The pods are only
+// processed while the endpoints of the dependency are ready, so without a sentinel the dependency is ready. Sentinel
+// pods whose readiness is unknown are only treated as available if configured.
+func (c *Controller) isSentinelAvailable(namespace string, depPods *api.DependantPods) (bool, error) {
+	if depPods.Sentinel == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Sentinel)
+	if err != nil {
+		return false, fmt.Errorf("error converting sentinel selector %s: %v", depPods.Sentinel.String(), err)
+	}
+	pl, err := c.clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return false, fmt.Errorf("error listing sentinel pods with selector %s: %v", selector.String(), err)
+	}
+	now := metav1.Now()
+	for _, po := range excludeTerminatingPods(pl.Items) {
+		switch PodReadyState(&po) {
+		case ReadyStateTrue:
+			if IsPodAvailable(&po, 0, now) {
+				return true, nil
+			}
+		case ReadyStateUnknown:
+			if depPods.SentinelUnknownAvailable {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// deletePod deletes the given dependant pod of the service and notifies the configured webhook about it.
+func (c *Controller) deletePod(po *v1.Pod, service string) error {
+	return c.deletePodForReason(po, service, ReasonCrashLoopBackOff, nil)
+}
+
+// observePod records the pod which would be deleted for the given reason without deleting it.
+func (c *Controller) observePod(ctx context.Context, po *v1.Pod, service, reason string, depPods *api.DependantPods) {
+	klog.Infof("Observe only: skipping deletion of pod %s/%s", po.Namespace, po.Name)
+	opts := getNamespaceOptions(c.getServiceDependants(), po.Namespace)
+	c.audit(ctx, po, service, reason, getPodAction(po, opts, depPods), AuditOutcomeObserveOnly)
+}
+
+// deletePodForReason deletes the given dependant pod of the service for the given reason and records the
+// decision in the audit log. The dependant pods the pod belongs to are optional.
+func (c *Controller) deletePodForReason(po *v1.Pod, service, reason string, depPods *api.DependantPods) error {
+	_, err := c.recyclePodForReason(context.Background(), po, service, reason, depPods)
+	return err
+}
+
+// recyclePodForReason deletes the given dependant pod of the service like deletePodForReason and returns the
+// outcome recorded in the audit log. Waiting for the deletion rate ends with the given context.
+func (c *Controller) recyclePodForReason(ctx context.Context, po *v1.Pod, service, reason string, depPods *api.DependantPods) (string, error) {
+	deps := c.getServiceDependants()
+	opts := getNamespaceOptions(deps, po.Namespace)
+	action := getPodAction(po, opts, depPods)
+	// The config or the allowlist may have changed since the reconcile started.
+	if deps != nil && !c.isNamespaceAllowed(deps, po.Namespace) {
+		klog.Infof("Namespace not allowed: skipping deletion of pod %s/%s", po.Namespace, po.Name)
+		c.audit(ctx, po, service, reason, action, AuditOutcomeNamespaceNotAllowed)
+		return AuditOutcomeNamespaceNotAllowed, nil
+	}
+	if isDryRun(opts) {
+		klog.Infof("Dry run: skipping deletion of pod %s/%s", po.Namespace, po.Name)
+		c.audit(ctx, po, service, reason, action, AuditOutcomeDryRun)
+		c.recordDryRun(po.Namespace, service, reason)
+		return AuditOutcomeDryRun, nil
+	}
+	if opts.QuietHours != nil {
+		if end, ok := QuietHoursEnd(c.clock.Now(), *opts.QuietHours); ok {
+			klog.Infof("Quiet hours: skipping deletion of pod %s/%s", po.Namespace, po.Name)
+			c.audit(ctx, po, service, reason, action, AuditOutcomeQuietHours)
+			c.requeueAt(po.Namespace, service, end)
+			return AuditOutcomeQuietHours, nil
+		}
+	}
+	open := c.isCircuitOpen()
+	setCircuitOpenMetric(open)
+	if open {
+		klog.Infof("Circuit open: skipping deletion of pod %s/%s", po.Namespace, po.Name)
+		c.audit(ctx, po, service, reason, action, AuditOutcomeCircuitOpen)
+		return AuditOutcomeCircuitOpen, nil
+	}
+	if c.isRetryBudgetExhausted(po.Namespace, service, opts) {
+		klog.Infof("Retry budget exhausted: deferring deletion of pod %s/%s to the next reconcile", po.Namespace, po.Name)
+		c.audit(ctx, po, service, reason, action, AuditOutcomeRetryBudgetExhausted)
+		c.deferForRetryBudget(po.Namespace, service)
+		return AuditOutcomeRetryBudgetExhausted, nil
+	}
+	propagation, err := getDeletionPropagation(opts)
+	if err != nil {
+		return AuditOutcomeFailed, err
+	}
+	if opts.DependencySnapshotAnnotation != nil {
+		c.annotateDependencySnapshot(ctx, po, service, *opts.DependencySnapshotAnnotation)
+	}
+	if c.deletionLimiter != nil {
+		if err := c.deletionLimiter.Wait(ctx); err != nil {
+			return AuditOutcomeFailed, err
+		}
+	}
+	err = c.retryOnConflict(po.Namespace, service, opts, func() error { return c.recyclePod(ctx, po, action, propagation) })
+	if c.deletionLimiter != nil {
+		c.deletionLimiter.Observe(action, err)
+	}
+	if err == errRetryBudgetExhausted {
+		c.audit(ctx, po, service, reason, action, AuditOutcomeRetryBudgetExhausted)
+		c.deferForRetryBudget(po.Namespace, service)
+		return AuditOutcomeRetryBudgetExhausted, nil
+	}
+	if err != nil {
+		c.audit(ctx, po, service, reason, action, AuditOutcomeFailed)
+		return AuditOutcomeFailed, err
+	}
+	c.audit(ctx, po, service, reason, action, AuditOutcomeSucceeded)
+	c.incrementRecoveryCount(ctx, po)
+	c.recordRecoveryHistory(ctx, po, service, reason, action)
+	if c.serviceCooldowns != nil {
+		c.serviceCooldowns.record(po.Namespace+"/"+service, c.clock.Now())
+	}
+	if opts.WaitForDeletion != nil && action != api.ActionRollout {
+		c.waitForPodDeletion(po, service, opts.WaitForDeletion.Duration)
+	}
+	c.countDeletedPod(po, service)
+	c.recordPodDeleted(ctx, po, service)
+	if isRecoveredConditionReported(opts) {
+		c.reportRecoveredCondition(ctx, po, service)
+	}
+	if c.notifier != nil {
+		go c.notifier.notify(&DeletionNotification{
+			Namespace: po.Namespace,
+			Pod:       po.Name,
+			Service:   service,
+			Reason:    reason,
+			Timestamp: c.clock.Now().UTC(),
+		})
+	}
+	return AuditOutcomeSucceeded, nil
+}
+
+// selectDependantPods returns the pods matching the service account of the dependant pods, if any, which are not
+// already terminating.
+func selectDependantPods(pods []v1.Pod, depPods *api.DependantPods) []v1.Pod {
+	var selected []v1.Pod
+	for _, po := range excludeTerminatingPods(pods) {
+		if isDependantPod(&po, depPods) {
+			selected = append(selected, po)
+		}
+	}
+	return selected
+}
+
+// sortPodsByPriority sorts the pods by ascending priority, keeping the order of the pods of the same priority.
+func sortPodsByPriority(pods []v1.Pod) {
+	sort.SliceStable(pods, func(i, j int) bool { return GetPodPriority(&pods[i]) < GetPodPriority(&pods[j]) })
+}
+
+// excludeEvictedPods returns the pods which are not already being evicted by other controllers.
+func excludeEvictedPods(pods []v1.Pod) []v1.Pod {
+	var live []v1.Pod
+	for i := range pods {
+		if IsPodBeingEvicted(&pods[i]) {
+			klog.V(4).Infof("Skipping pod %s as it is already being evicted", pods[i].Name)
+			continue
+		}
+		live = append(live, pods[i])
+	}
+	return live
+}
+
+// excludeTerminatingPods returns the pods which are not already terminating, so that they
+// are neither deleted again nor taken into account for further decisions.
+func excludeTerminatingPods(pods []v1.Pod) []v1.Pod {
+	var live []v1.Pod
+	for i := range pods {
+		if IsPodDeleted(&pods[i]) {
+			klog.V(4).Infof("Skipping pod %s as it is already terminating", pods[i].Name)
+			continue
+		}
+		live = append(live, pods[i])
+	}
+	return live
 }
@@ -5,20 +5,30 @@
 package restarter
 
 import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/informers"
 	kubeclient "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	test "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 )
 
 var (
@@ -173,7 +183,7 @@ func (f *fixture) newController(deps *api.ServiceDependants, stopCh chan struct{
 		informers.WithNamespace(deps.Namespace),
 		informers.WithTweakListOptions(func(options *metav1.ListOptions) {}))
 
-	c := NewController(f.client, informers, deps, watchDuration, stopCh)
+	c := NewController(f.client, nil, informers, deps, watchDuration, stopCh)
 	for _, d := range f.endpoints {
 		informers.Apps().V1().Deployments().Informer().GetIndexer().Add(d)
 	}
@@ -332,3 +342,903 @@ func TestDeletePodTransitioningToCrashloopBackoff(t *testing.T) {
 		t.Errorf("Pod in CrashloopBackoff not deleted by the dependency-watchdog. Expected 0 pods but got %d", len(pl.Items))
 	}
 }
+
+func TestPodEventFilterProcessesOnlyCrashloopTransitions(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	healthy := newPodHealthy("pod-0", labels)
+	healthy.UID = "uid-0"
+	crashing := newPodInCrashloop("pod-0", labels)
+	crashing.UID = "uid-0"
+	crashingAgain := crashing.DeepCopy()
+	crashingAgain.Status.ContainerStatuses[0].RestartCount = 5
+
+	f := newPodEventFilter(nil)
+	events := []struct {
+		eventType     watch.EventType
+		pod           *v1.Pod
+		expectProcess bool
+	}{
+		{watch.Added, healthy, true},
+		{watch.Modified, healthy, false},
+		{watch.Modified, crashing, true},
+		{watch.Modified, crashingAgain, false},
+		{watch.Modified, healthy, false},
+		{watch.Modified, crashing, true},
+		{watch.Added, crashingAgain, true},
+	}
+	for i, ev := range events {
+		if got := f.shouldProcess(ev.eventType, ev.pod); got != ev.expectProcess {
+			t.Errorf("Event %d (%s): expected process=%v but got %v", i, ev.eventType, ev.expectProcess, got)
+		}
+	}
+
+	f.forget(crashing)
+	if len(f.lastSeen) != 0 {
+		t.Errorf("Expected the deleted pod to be forgotten but got %d pods", len(f.lastSeen))
+	}
+}
+
+func TestReconcileSlotsBoundConcurrency(t *testing.T) {
+	const (
+		namespaces  = 10
+		concurrency = 2
+	)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c := NewController(fake.NewSimpleClientset(), nil, informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0),
+		&api.ServiceDependants{ConcurrentReconciles: func(i int32) *int32 { return &i }(concurrency)}, watchDuration, stopCh)
+
+	var (
+		wg         sync.WaitGroup
+		active     int32
+		maxActive  int32
+		reconciled int32
+	)
+	for i := 0; i < namespaces; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.withReconcileSlot(context.TODO(), func() {
+				n := atomic.AddInt32(&active, 1)
+				for {
+					m := atomic.LoadInt32(&maxActive)
+					if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt32(&reconciled, 1)
+				atomic.AddInt32(&active, -1)
+			})
+		}()
+	}
+	wg.Wait()
+
+	if reconciled != namespaces {
+		t.Errorf("Expected %d reconciles but got %d", namespaces, reconciled)
+	}
+	if maxActive > concurrency {
+		t.Errorf("Expected at most %d concurrent reconciles but got %d", concurrency, maxActive)
+	}
+}
+
+// waitForPodWatches waits until the pods were watched at least the given number of times.
+func waitForPodWatches(t *testing.T, client *fake.Clientset, count int) {
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		watches := 0
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "watch" && action.GetResource().Resource == "pods" {
+				watches++
+			}
+		}
+		return watches >= count, nil
+	}); err != nil {
+		t.Fatalf("error waiting for %d pod watches: %v", count, err)
+	}
+}
+
+func newSlotTestController(client *fake.Clientset, services []string, stopCh <-chan struct{}) *Controller {
+	concurrency := int32(1)
+	deps := &api.ServiceDependants{Namespace: metav1.NamespaceDefault, ConcurrentReconciles: &concurrency, Services: map[string]api.Service{}}
+	for _, service := range services {
+		deps.Services[service] = api.Service{Dependants: []api.DependantPods{
+			{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"dependency": service}}},
+		}}
+	}
+	c := NewController(client, nil, informers.NewSharedInformerFactory(client, 0), deps, time.Hour, stopCh)
+	go c.Multicontext.Start(stopCh)
+	return c
+}
+
+func TestReconcileSlotIsOnlyHeldWhileProcessingPods(t *testing.T) {
+	services := []string{"kube-apiserver", "etcd-main"}
+	client := fake.NewSimpleClientset(newEndpoint(services[0], metav1.NamespaceDefault, nil), newEndpoint(services[1], metav1.NamespaceDefault, nil))
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c := newSlotTestController(client, services, stopCh)
+
+	// With a single slot, the reconcile of the second service does not wait for the watch of the first one to end.
+	for _, service := range services {
+		if err := c.processEndpoint(context.TODO(), metav1.NamespaceDefault+"/"+service); err != nil {
+			t.Fatalf("error processing endpoint %s: %v", service, err)
+		}
+	}
+	waitForPodWatches(t, client, len(services))
+	for _, service := range services {
+		if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Create(newPodInCrashloop(service+"-dependant", map[string]string{"dependency": service})); err != nil {
+			t.Fatalf("error creating pod: %v", err)
+		}
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		pl, err := client.CoreV1().Pods(metav1.NamespaceDefault).List(metav1.ListOptions{})
+		return err == nil && len(pl.Items) == 0, nil
+	}); err != nil {
+		t.Errorf("Expected the dependant pods of both services to be deleted while their reconciles are active")
+	}
+}
+
+func TestReconcileWaitingForSlotEndsWhenServiceIsNotReady(t *testing.T) {
+	const service = "kube-apiserver"
+	ep := newEndpoint(service, metav1.NamespaceDefault, nil)
+	client := fake.NewSimpleClientset(ep)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c := newSlotTestController(client, []string{service}, stopCh)
+
+	// The only slot is taken by another service while this service becomes ready and not ready again.
+	c.reconcileSlots <- struct{}{}
+	if err := c.processEndpoint(context.TODO(), metav1.NamespaceDefault+"/"+service); err != nil {
+		t.Fatalf("error processing endpoint: %v", err)
+	}
+	waitForPodWatches(t, client, 1)
+	ep.Subsets = nil
+	if _, err := client.CoreV1().Endpoints(metav1.NamespaceDefault).Update(ep); err != nil {
+		t.Fatalf("error updating endpoint: %v", err)
+	}
+	if err := c.processEndpoint(context.TODO(), metav1.NamespaceDefault+"/"+service); err != nil {
+		t.Fatalf("error processing endpoint: %v", err)
+	}
+	<-c.reconcileSlots
+
+	waitForPodWatches(t, client, 1)
+	pod := newPodInCrashloop("pod-0", map[string]string{"dependency": service})
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Create(pod); err != nil {
+		t.Fatalf("error creating pod: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get(pod.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected no pod to be deleted once the service is no longer ready but got %v", err)
+	}
+}
+
+func TestRequireAllUnhealthy(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{
+		Name:                "controlplane",
+		Selector:            &metav1.LabelSelector{MatchLabels: labels},
+		RequireAllUnhealthy: true,
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		pods          []runtime.Object
+		expectedCount int
+	}{
+		{"all unhealthy", []runtime.Object{newPodInCrashloop("pod-0", labels), newPodInCrashloop("pod-1", labels)}, 0},
+		{"one healthy", []runtime.Object{newPodInCrashloop("pod-0", labels), newPodHealthy("pod-1", labels)}, 2},
+	}
+	for _, tc := range tests {
+		client := fake.NewSimpleClientset(append(tc.pods, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))...)
+		c := &Controller{clientset: client}
+		if err := c.processPod(context.TODO(), tc.pods[0].(*v1.Pod), "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		pl, err := client.CoreV1().Pods(metav1.NamespaceDefault).List(metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("%s: error fetching pods: %v", tc.name, err)
+		}
+		if len(pl.Items) != tc.expectedCount {
+			t.Errorf("%s: expected %d pods but got %d", tc.name, tc.expectedCount, len(pl.Items))
+		}
+	}
+}
+
+func TestRequireAllUnhealthyHonoursDependantPods(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	ignored := newPodInCrashloop("pod-1", labels)
+	ignored.Annotations = map[string]string{ignoreAnnotationKey: "true"}
+	debugged := newPodInCrashloop("pod-1", labels)
+	debugged.Spec.EphemeralContainers = []v1.EphemeralContainer{{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debugger"}}}
+	evicted := newPodInCrashloop("pod-1", labels)
+	evicted.Status.Conditions = append(evicted.Status.Conditions, v1.PodCondition{Type: podDisruptionTarget, Status: v1.ConditionTrue})
+	declineAll := RecyclePolicyFunc(func(context.Context, *v1.Pod, bool) (bool, string, error) {
+		return false, "declined", nil
+	})
+
+	tests := []struct {
+		name             string
+		pod              *v1.Pod
+		skipDebuggedPods bool
+		policy           RecyclePolicy
+		expectedDeleted  []string
+	}{
+		{"ignored pod", ignored, false, nil, nil},
+		{"debugged pod", debugged, true, nil, nil},
+		{"declined by the recycle policy", newPodInCrashloop("pod-1", labels), false, declineAll, nil},
+		{"pod being evicted", evicted, false, nil, []string{"pod-0"}},
+	}
+	for _, tc := range tests {
+		depPods := &api.DependantPods{
+			Selector:            &metav1.LabelSelector{MatchLabels: labels},
+			RequireAllUnhealthy: true,
+			SkipDebuggedPods:    tc.skipDebuggedPods,
+		}
+		selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+		if err != nil {
+			t.Fatalf("error converting label selector: %v", err)
+		}
+		pod := newPodInCrashloop("pod-0", labels)
+		client := fake.NewSimpleClientset(pod, tc.pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		c := &Controller{clientset: client, RecyclePolicy: tc.policy}
+		if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		var deleted []string
+		for _, action := range client.Actions() {
+			if da, ok := action.(test.DeleteAction); ok && action.GetVerb() == "delete" {
+				deleted = append(deleted, da.GetName())
+			}
+		}
+		if !reflect.DeepEqual(deleted, tc.expectedDeleted) {
+			t.Errorf("%s: expected the deleted pods %v but got %v", tc.name, tc.expectedDeleted, deleted)
+		}
+	}
+}
+
+func TestRequireAllUnhealthyReevaluatesPodsBeforeDeletion(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}, RequireAllUnhealthy: true}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	pod := newPodInCrashloop("pod-0", labels)
+	client := fake.NewSimpleClientset(pod, newPodInCrashloop("pod-1", labels), newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+	// The remaining pod recovers once the first pod is deleted.
+	client.PrependReactor("delete", "pods", func(action test.Action) (bool, runtime.Object, error) {
+		if action.(test.DeleteAction).GetName() == "pod-0" {
+			return false, nil, client.Tracker().Update(schemaPods, newPodHealthy("pod-1", labels), metav1.NamespaceDefault)
+		}
+		return false, nil, nil
+	})
+	c := &Controller{clientset: client}
+	if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-1", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected the recovered pod not to be deleted but got %v", err)
+	}
+}
+
+func TestTerminatingPodsAreExcluded(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{
+		Selector:            &metav1.LabelSelector{MatchLabels: labels},
+		RequireAllUnhealthy: true,
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	now := metav1.Now()
+	terminatingCrashing := newPodInCrashloop("pod-t0", labels)
+	terminatingCrashing.DeletionTimestamp = &now
+	// A terminating healthy pod must not prevent the deletion of the crashlooping ones either.
+	terminatingHealthy := newPodHealthy("pod-t1", labels)
+	terminatingHealthy.DeletionTimestamp = &now
+	live0 := newPodInCrashloop("pod-0", labels)
+	live1 := newPodInCrashloop("pod-1", labels)
+
+	client := fake.NewSimpleClientset(terminatingCrashing, terminatingHealthy, live0, live1, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+	c := &Controller{clientset: client}
+	if err := c.processPod(context.TODO(), live0, "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+
+	var deleted []string
+	for _, action := range client.Actions() {
+		if da, ok := action.(test.DeleteAction); ok && action.GetVerb() == "delete" {
+			deleted = append(deleted, da.GetName())
+		}
+	}
+	if len(deleted) != 2 || deleted[0] != "pod-0" || deleted[1] != "pod-1" {
+		t.Errorf("Expected only the live pods to be deleted but got %v", deleted)
+	}
+}
+
+func TestNodeLocalizedCrashloops(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	onNode := func(p *v1.Pod, node string) *v1.Pod {
+		p.Spec.NodeName = node
+		return p
+	}
+	fraction := 0.6
+	depPods := &api.DependantPods{
+		Selector:                       &metav1.LabelSelector{MatchLabels: labels},
+		NodeLocalizedCrashloopFraction: &fraction,
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		pods           []*v1.Pod
+		expectedNodes  []string
+		expectDeletion bool
+	}{
+		{
+			name: "concentrated on one node",
+			pods: []*v1.Pod{
+				onNode(newPodInCrashloop("pod-0", labels), "node-a"),
+				onNode(newPodInCrashloop("pod-1", labels), "node-a"),
+				onNode(newPodHealthy("pod-2", labels), "node-b"),
+			},
+			expectedNodes:  []string{"node-a"},
+			expectDeletion: false,
+		},
+		{
+			name: "spread out",
+			pods: []*v1.Pod{
+				onNode(newPodInCrashloop("pod-0", labels), "node-a"),
+				onNode(newPodInCrashloop("pod-1", labels), "node-b"),
+				onNode(newPodInCrashloop("pod-2", labels), "node-c"),
+			},
+			expectDeletion: true,
+		},
+	}
+	for _, tc := range tests {
+		var (
+			pods    []v1.Pod
+			objects []runtime.Object
+		)
+		for _, p := range tc.pods {
+			pods = append(pods, *p)
+			objects = append(objects, p)
+		}
+		nodes := GetNodesWithLocalizedCrashloops(pods, fraction)
+		if len(nodes) != len(tc.expectedNodes) || (len(nodes) > 0 && nodes[0] != tc.expectedNodes[0]) {
+			t.Errorf("%s: expected nodes %v but got %v", tc.name, tc.expectedNodes, nodes)
+		}
+
+		client := fake.NewSimpleClientset(append(objects, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))...)
+		c := &Controller{clientset: client}
+		before := testutil.ToFloat64(nodeLocalizedCrashloopTotal.With(prometheus.Labels{labelNode: "node-a"}))
+		if err := c.processPod(context.TODO(), tc.pods[0], "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{})
+		if deleted := err != nil; deleted != tc.expectDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expectDeletion, deleted)
+		}
+		after := testutil.ToFloat64(nodeLocalizedCrashloopTotal.With(prometheus.Labels{labelNode: "node-a"}))
+		if counted := after > before; counted == tc.expectDeletion {
+			t.Errorf("%s: expected node localized crashloop to be counted %v", tc.name, !tc.expectDeletion)
+		}
+	}
+}
+
+func TestDeletePodInDryRun(t *testing.T) {
+	dryRun, enforce := true, false
+	deps := &api.ServiceDependants{
+		Options:    api.Options{DryRun: &dryRun},
+		Namespaces: map[string]api.Options{"enforced": {DryRun: &enforce}},
+	}
+	dryRunPod := newPodInCrashloop("pod-0", nil)
+	enforcedPod := newPodInCrashloop("pod-1", nil)
+	enforcedPod.Namespace = "enforced"
+	client := fake.NewSimpleClientset(dryRunPod, enforcedPod)
+	c := &Controller{clientset: client, serviceDependants: deps}
+
+	for _, pod := range []*v1.Pod{dryRunPod, enforcedPod} {
+		if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+			t.Fatalf("error deleting pod: %v", err)
+		}
+	}
+	if _, err := client.CoreV1().Pods(dryRunPod.Namespace).Get(dryRunPod.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected pod %s not to be deleted in dry run but got: %v", dryRunPod.Name, err)
+	}
+	if _, err := client.CoreV1().Pods(enforcedPod.Namespace).Get(enforcedPod.Name, metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected pod %s to be deleted", enforcedPod.Name)
+	}
+}
+
+func TestEndpointReadinessEvaluatedOncePerService(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	selector := &metav1.LabelSelector{MatchLabels: labels}
+	deps := &api.ServiceDependants{
+		Namespace: metav1.NamespaceDefault,
+		Services: map[string]api.Service{
+			"kube-apiserver": {
+				Dependants: []api.DependantPods{
+					{Name: "controller-manager", Selector: selector},
+					{Name: "scheduler", Selector: selector},
+					{Name: "machine-controller-manager", Selector: selector},
+				},
+			},
+		},
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	client := fake.NewSimpleClientset(newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+	watches := make(chan struct{}, 3)
+	client.PrependWatchReactor("pods", func(action test.Action) (bool, watch.Interface, error) {
+		watches <- struct{}{}
+		return true, watch.NewFake(), nil
+	})
+	c := NewController(client, nil, informers.NewSharedInformerFactory(client, 0), deps, watchDuration, stopCh)
+	go c.Start(stopCh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.processEndpoint(ctx, "default/kube-apiserver"); err != nil {
+		t.Fatalf("error processing endpoint: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-watches:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected the pods of all 3 dependants to be watched but got %d", i)
+		}
+	}
+
+	var reads int
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "get" && action.GetResource().Resource == "endpoints" {
+			reads++
+		}
+	}
+	if reads != 1 {
+		t.Errorf("Expected the endpoint to be read once for all the dependants but got %d reads", reads)
+	}
+}
+
+func TestRecoveryEdgeOnly(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	edgeOnly := true
+	deps := &api.ServiceDependants{
+		Options: api.Options{
+			RecoveryEdgeOnly:     &edgeOnly,
+			RecoveryActionWindow: &metav1.Duration{Duration: time.Minute},
+		},
+	}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		sinceRecovery    time.Duration
+		expectedDeletion bool
+	}{
+		{"crashlooping during the window", 30 * time.Second, true},
+		{"crashlooping well after the recovery", 10 * time.Minute, false},
+	}
+	for _, tc := range tests {
+		fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+		pod := newPodInCrashloop("pod-0", labels)
+		client := fake.NewSimpleClientset(pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		c := &Controller{clientset: client, clock: fakeClock, flapDetector: NewFlapDetector(fakeClock), serviceDependants: deps}
+		c.flapDetector.RecordReadiness("default/kube-apiserver", false)
+		c.flapDetector.RecordReadiness("default/kube-apiserver", true)
+		fakeClock.Step(tc.sinceRecovery)
+
+		if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{})
+		if deleted := err != nil; deleted != tc.expectedDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expectedDeletion, deleted)
+		}
+	}
+
+	// Without an observed recovery, pods are not deleted at all.
+	fakeClock := clock.NewFakeClock(time.Now())
+	pod := newPodInCrashloop("pod-0", labels)
+	client := fake.NewSimpleClientset(pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+	c := &Controller{clientset: client, clock: fakeClock, flapDetector: NewFlapDetector(fakeClock), serviceDependants: deps}
+	c.flapDetector.RecordReadiness("default/kube-apiserver", true)
+	if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected pod not to be deleted without an observed recovery but got: %v", err)
+	}
+}
+
+func TestMissingEndpoint(t *testing.T) {
+	required := true
+	tests := []struct {
+		name        string
+		opts        api.Options
+		expectError bool
+	}{
+		{"treated as not ready", api.Options{}, false},
+		{"required to exist", api.Options{RequireDependencyExists: &required}, true},
+	}
+	for _, tc := range tests {
+		stopCh := make(chan struct{})
+		client := fake.NewSimpleClientset()
+		deps := &api.ServiceDependants{
+			Namespace: metav1.NamespaceDefault,
+			Services:  map[string]api.Service{"kube-apiserver": {}},
+			Options:   tc.opts,
+		}
+		c := NewController(client, nil, informers.NewSharedInformerFactory(client, 0), deps, watchDuration, stopCh)
+		go c.Start(stopCh)
+
+		err := c.processEndpoint(context.TODO(), "default/kube-apiserver")
+		if (err != nil) != tc.expectError {
+			t.Errorf("%s: expected error %v but got: %v", tc.name, tc.expectError, err)
+		}
+		close(stopCh)
+	}
+}
+
+func TestRecoveryGracePeriod(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	deps := &api.ServiceDependants{
+		Options: api.Options{RecoveryGracePeriod: &metav1.Duration{Duration: time.Minute}},
+	}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		recovers         bool
+		expectedDeletion bool
+	}{
+		{"recovers within the grace period", true, false},
+		{"stays crashlooping", false, true},
+	}
+	for _, tc := range tests {
+		fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+		pod := newPodInCrashloop("pod-0", labels)
+		client := fake.NewSimpleClientset(pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		c := &Controller{clientset: client, clock: fakeClock, flapDetector: NewFlapDetector(fakeClock), serviceDependants: deps}
+		c.flapDetector.RecordReadiness("default/kube-apiserver", false)
+		c.flapDetector.RecordReadiness("default/kube-apiserver", true)
+
+		done := make(chan error)
+		go func() {
+			done <- c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector)
+		}()
+		for !fakeClock.HasWaiters() {
+			time.Sleep(time.Millisecond)
+		}
+		if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); err != nil {
+			t.Fatalf("%s: expected the pod not to be deleted during the grace period", tc.name)
+		}
+		if tc.recovers {
+			if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Update(newPodHealthy("pod-0", labels)); err != nil {
+				t.Fatalf("%s: error updating pod: %v", tc.name, err)
+			}
+		}
+		fakeClock.Step(time.Minute)
+		if err := <-done; err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+
+		_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{})
+		if deleted := err != nil; deleted != tc.expectedDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expectedDeletion, deleted)
+		}
+	}
+}
+
+// deleteRecordingClientset records the options of the pod deletions made through it.
+type deleteRecordingClientset struct {
+	kubeclient.Interface
+	deleteOptions []*metav1.DeleteOptions
+}
+
+type deleteRecordingCoreV1 struct {
+	corev1.CoreV1Interface
+	recorder *deleteRecordingClientset
+}
+
+type deleteRecordingPods struct {
+	corev1.PodInterface
+	recorder *deleteRecordingClientset
+}
+
+func (r *deleteRecordingClientset) CoreV1() corev1.CoreV1Interface {
+	return &deleteRecordingCoreV1{r.Interface.CoreV1(), r}
+}
+
+func (r *deleteRecordingCoreV1) Pods(namespace string) corev1.PodInterface {
+	return &deleteRecordingPods{r.CoreV1Interface.Pods(namespace), r.recorder}
+}
+
+func (r *deleteRecordingPods) Delete(name string, options *metav1.DeleteOptions) error {
+	r.recorder.deleteOptions = append(r.recorder.deleteOptions, options)
+	return r.PodInterface.Delete(name, options)
+}
+
+func TestDeletionPropagation(t *testing.T) {
+	foreground, invalid := metav1.DeletePropagationForeground, metav1.DeletionPropagation("Invalid")
+	tests := []struct {
+		name        string
+		propagation *metav1.DeletionPropagation
+		expectError bool
+	}{
+		{"default", nil, false},
+		{"foreground", &foreground, false},
+		{"invalid", &invalid, true},
+	}
+	for _, tc := range tests {
+		client := &deleteRecordingClientset{Interface: fake.NewSimpleClientset(newPodInCrashloop("pod-0", nil))}
+		c := &Controller{clientset: client, serviceDependants: &api.ServiceDependants{Options: api.Options{DeletionPropagation: tc.propagation}}}
+
+		err := c.deletePod(newPodInCrashloop("pod-0", nil), "kube-apiserver")
+		if (err != nil) != tc.expectError {
+			t.Fatalf("%s: expected error %v but got: %v", tc.name, tc.expectError, err)
+		}
+		if tc.expectError {
+			if len(client.deleteOptions) != 0 {
+				t.Errorf("%s: expected no deletion but got %d", tc.name, len(client.deleteOptions))
+			}
+			continue
+		}
+		if len(client.deleteOptions) != 1 {
+			t.Fatalf("%s: expected one deletion but got %d", tc.name, len(client.deleteOptions))
+		}
+		if got := client.deleteOptions[0].PropagationPolicy; !reflect.DeepEqual(got, tc.propagation) {
+			t.Errorf("%s: expected propagation policy %v but got %v", tc.name, tc.propagation, got)
+		}
+	}
+
+	for _, tc := range tests {
+		deps := &api.ServiceDependants{Options: api.Options{DeletionPropagation: tc.propagation}}
+		if err := CheckDeletionPropagation(deps); (err != nil) != tc.expectError {
+			t.Errorf("%s: expected the check to fail %v but got: %v", tc.name, tc.expectError, err)
+		}
+		deps = &api.ServiceDependants{Namespaces: map[string]api.Options{"shoot--dev": {DeletionPropagation: tc.propagation}}}
+		if err := CheckDeletionPropagation(deps); (err != nil) != tc.expectError {
+			t.Errorf("%s: expected the check of the namespace to fail %v but got: %v", tc.name, tc.expectError, err)
+		}
+	}
+}
+
+func TestSentinelDrivesDependencyReadiness(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	sentinelLabels := map[string]string{"role": "sentinel"}
+	depPods := &api.DependantPods{
+		Selector: &metav1.LabelSelector{MatchLabels: labels},
+		Sentinel: &metav1.LabelSelector{MatchLabels: sentinelLabels},
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+
+	for _, sentinelReady := range []v1.ConditionStatus{v1.ConditionTrue, v1.ConditionFalse} {
+		pod := newPodInCrashloop("pod-0", labels)
+		sentinel := newPodHealthy("sentinel-0", sentinelLabels)
+		sentinel.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: sentinelReady}}
+		client := fake.NewSimpleClientset(pod, sentinel, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		c := &Controller{clientset: client}
+
+		if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("sentinel ready %s: error processing pod: %v", sentinelReady, err)
+		}
+		_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{})
+		if deleted, expected := err != nil, sentinelReady == v1.ConditionTrue; deleted != expected {
+			t.Errorf("sentinel ready %s: expected deletion %v but got %v", sentinelReady, expected, deleted)
+		}
+	}
+}
+
+func TestSentinelWithUnknownReadiness(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	sentinelLabels := map[string]string{"role": "sentinel"}
+	tests := []struct {
+		name             string
+		conditions       []v1.PodCondition
+		unknownAvailable bool
+		expectedDeletion bool
+	}{
+		{"ready", []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}, false, true},
+		{"not ready", []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionFalse}}, true, false},
+		{"unknown treated as unavailable", []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionUnknown}}, false, false},
+		{"unknown treated as available", []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionUnknown}}, true, true},
+		{"no ready condition treated as available", nil, true, true},
+	}
+	for _, tc := range tests {
+		depPods := &api.DependantPods{
+			Selector:                 &metav1.LabelSelector{MatchLabels: labels},
+			Sentinel:                 &metav1.LabelSelector{MatchLabels: sentinelLabels},
+			SentinelUnknownAvailable: tc.unknownAvailable,
+		}
+		selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+		if err != nil {
+			t.Fatalf("error converting label selector: %v", err)
+		}
+		pod := newPodInCrashloop("pod-0", labels)
+		sentinel := newPodHealthy("sentinel-0", sentinelLabels)
+		sentinel.Status.Conditions = tc.conditions
+		client := fake.NewSimpleClientset(pod, sentinel, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		c := &Controller{clientset: client}
+
+		if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		_, err = client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{})
+		if deleted := err != nil; deleted != tc.expectedDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expectedDeletion, deleted)
+		}
+	}
+}
+
+func TestObserveOnlyDependants(t *testing.T) {
+	enforce := false
+	observedLabels := map[string]string{"role": "observed"}
+	enforcedLabels := map[string]string{"role": "enforced"}
+	observed := newPodInCrashloop("observed-0", observedLabels)
+	enforced := newPodInCrashloop("enforced-0", enforcedLabels)
+	client := fake.NewSimpleClientset(observed, enforced, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+	c := &Controller{
+		clientset:         client,
+		serviceDependants: &api.ServiceDependants{Options: api.Options{DryRun: &enforce}},
+	}
+
+	tests := []struct {
+		pod              *v1.Pod
+		depPods          *api.DependantPods
+		expectedDeletion bool
+	}{
+		{observed, &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: observedLabels}, ObserveOnly: true}, false},
+		{enforced, &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: enforcedLabels}}, true},
+	}
+	for _, tc := range tests {
+		selector, err := metav1.LabelSelectorAsSelector(tc.depPods.Selector)
+		if err != nil {
+			t.Fatalf("error converting label selector: %v", err)
+		}
+		if err := c.processPod(context.TODO(), tc.pod, "kube-apiserver", tc.depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.pod.Name, err)
+		}
+		_, err = client.CoreV1().Pods(metav1.NamespaceDefault).Get(tc.pod.Name, metav1.GetOptions{})
+		if deleted := err != nil; deleted != tc.expectedDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.pod.Name, tc.expectedDeletion, deleted)
+		}
+	}
+}
+
+// recordingQueue records the delayed additions to the work queue.
+type recordingQueue struct {
+	workqueue.RateLimitingInterface
+	added map[interface{}]time.Duration
+}
+
+func (q *recordingQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.added[item] = duration
+}
+
+func TestSkippedDeletionsAreRequeuedWhenEligible(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	// The unhealthiness of the pods is evaluated against the current time.
+	now := time.Now()
+	depPods := &api.DependantPods{
+		Selector:               &metav1.LabelSelector{MatchLabels: labels},
+		UnschedulableThreshold: &metav1.Duration{Duration: 5 * time.Minute},
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	unschedulable := newPod("pod-0", "")
+	unschedulable.Labels = labels
+	unschedulable.Namespace = metav1.NamespaceDefault
+	unschedulable.Status.Phase = v1.PodPending
+	unschedulable.Status.Conditions = []v1.PodCondition{{
+		Type:               v1.PodScheduled,
+		Status:             v1.ConditionFalse,
+		Reason:             v1.PodReasonUnschedulable,
+		LastTransitionTime: metav1.NewTime(now.Add(-2 * time.Minute)),
+	}}
+	quietHours := &api.QuietHours{Windows: []api.TimeWindow{{Start: "22:00", End: "06:00"}}}
+
+	tests := []struct {
+		name          string
+		pod           *v1.Pod
+		now           time.Time
+		opts          api.Options
+		expectedDelay time.Duration
+	}{
+		{"unschedulable for less than the threshold", unschedulable, now, api.Options{}, 3 * time.Minute},
+		{"crashlooping during quiet hours", newPodInCrashloop("pod-0", labels), time.Date(2021, 6, 1, 23, 0, 0, 0, time.UTC), api.Options{QuietHours: quietHours}, 7 * time.Hour},
+	}
+	for _, tc := range tests {
+		queue := &recordingQueue{added: make(map[interface{}]time.Duration)}
+		client := fake.NewSimpleClientset(tc.pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		c := &Controller{
+			clientset:         client,
+			clock:             clock.NewFakeClock(tc.now),
+			workqueue:         queue,
+			serviceDependants: &api.ServiceDependants{Options: tc.opts},
+		}
+
+		if err := c.processPod(context.TODO(), tc.pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); err != nil {
+			t.Errorf("%s: expected pod not to be deleted but got: %v", tc.name, err)
+		}
+		if delay, ok := queue.added["default/kube-apiserver"]; !ok || delay != tc.expectedDelay {
+			t.Errorf("%s: expected the service to be requeued in %s but got %v", tc.name, tc.expectedDelay, queue.added)
+		}
+	}
+}
+
+func TestDependantsMatchedByServiceAccount(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	newPodWithServiceAccount := func(name, sa string) *v1.Pod {
+		pod := newPodInCrashloop(name, labels)
+		pod.Spec.ServiceAccountName = sa
+		return pod
+	}
+	tests := []struct {
+		name                string
+		requireAllUnhealthy bool
+		pods                []*v1.Pod
+		expectedDeleted     []string
+	}{
+		{"matching service account", false, []*v1.Pod{newPodWithServiceAccount("pod-0", "kube-controller-manager")}, []string{"pod-0"}},
+		{"other service account", false, []*v1.Pod{newPodWithServiceAccount("pod-0", "tenant")}, nil},
+		{"all unhealthy ignoring other service accounts", true, []*v1.Pod{
+			newPodWithServiceAccount("pod-0", "kube-controller-manager"),
+			newPodWithServiceAccount("pod-1", "tenant"),
+		}, []string{"pod-0"}},
+	}
+	for _, tc := range tests {
+		depPods := &api.DependantPods{
+			Selector:            &metav1.LabelSelector{MatchLabels: labels},
+			ServiceAccountName:  "kube-controller-manager",
+			RequireAllUnhealthy: tc.requireAllUnhealthy,
+		}
+		selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+		if err != nil {
+			t.Fatalf("error converting label selector: %v", err)
+		}
+		objects := []runtime.Object{newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)}
+		for _, pod := range tc.pods {
+			objects = append(objects, pod)
+		}
+		client := fake.NewSimpleClientset(objects...)
+		c := &Controller{clientset: client}
+
+		if err := c.processPod(context.TODO(), tc.pods[0], "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		var deleted []string
+		for _, action := range client.Actions() {
+			if da, ok := action.(test.DeleteAction); ok && action.GetVerb() == "delete" {
+				deleted = append(deleted, da.GetName())
+			}
+		}
+		if !reflect.DeepEqual(deleted, tc.expectedDeleted) {
+			t.Errorf("%s: expected deleted pods %v but got %v", tc.name, tc.expectedDeleted, deleted)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+)
+
+// errRetryBudgetExhausted is returned instead of retrying a conflicting call once the retry budget of the reconcile
+// is exhausted.
+var errRetryBudgetExhausted = errors.New("retry budget of the reconcile exhausted")
+
+type retryBudget struct {
+	generation uint64
+	retries    int
+	deferred   int
+}
+
+// retryBudgets counts the retries of the conflicting calls and the pods deferred to the next reconcile during the
+// reconcile of a service. Reconciles are identified by the <namespace>/<service> key.
+type retryBudgets struct {
+	mux        sync.Mutex
+	reconciles map[string]*retryBudget
+}
+
+func newRetryBudgets() *retryBudgets {
+	return &retryBudgets{reconciles: make(map[string]*retryBudget)}
+}
+
+// start resets the retries of the reconcile of the given generation identified by key.
+func (b *retryBudgets) start(key string, generation uint64) {
+	if b == nil {
+		return
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.reconciles[key] = &retryBudget{generation: generation}
+}
+
+func (b *retryBudgets) get(key string) *retryBudget {
+	r, ok := b.reconciles[key]
+	if !ok {
+		r = &retryBudget{}
+		b.reconciles[key] = r
+	}
+	return r
+}
+
+// take consumes one retry of the budget. It returns false if the budget is exhausted.
+func (b *retryBudgets) take(key string, budget int) bool {
+	if b == nil {
+		return true
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	r := b.get(key)
+	if r.retries >= budget {
+		return false
+	}
+	r.retries++
+	return true
+}
+
+// exhausted checks if all the retries of the budget are consumed.
+func (b *retryBudgets) exhausted(key string, budget int) bool {
+	if b == nil {
+		return false
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.get(key).retries >= budget
+}
+
+// deferPod counts a pod deferred to the next reconcile because the budget is exhausted.
+func (b *retryBudgets) deferPod(key string) {
+	if b == nil {
+		return
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.get(key).deferred++
+}
+
+// finish forgets the reconcile of the given generation identified by key and returns the number of its deferred
+// pods. Nothing is forgotten if the reconcile was superseded by another reconcile of the service.
+func (b *retryBudgets) finish(key string, generation uint64) int {
+	if b == nil {
+		return 0
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	r, ok := b.reconciles[key]
+	if !ok || r.generation != generation {
+		return 0
+	}
+	delete(b.reconciles, key)
+	return r.deferred
+}
+
+// isRetryBudgetExhausted checks if the configured retry budget of the reconcile of the service is exhausted.
+func (c *Controller) isRetryBudgetExhausted(namespace, service string, opts api.Options) bool {
+	return opts.RetryBudget != nil && c.retryBudgets.exhausted(namespace+"/"+service, int(*opts.RetryBudget))
+}
+
+// retryOnConflict calls fn and retries it on conflicts with the default backoff. Every retry consumes the configured
+// retry budget of the reconcile of the service. Once it is exhausted, errRetryBudgetExhausted is returned instead
+// of retrying again.
+func (c *Controller) retryOnConflict(namespace, service string, opts api.Options, fn func() error) error {
+	exhausted := false
+	err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		if !apierrors.IsConflict(err) {
+			return false
+		}
+		if opts.RetryBudget != nil && !c.retryBudgets.take(namespace+"/"+service, int(*opts.RetryBudget)) {
+			exhausted = true
+			return false
+		}
+		return true
+	}, fn)
+	if exhausted {
+		klog.Warningf("Retry budget of %d of the reconcile of service %s/%s is exhausted: %s", *opts.RetryBudget, namespace, service, err)
+		return errRetryBudgetExhausted
+	}
+	return err
+}
+
+// deferForRetryBudget counts the pod of the service deferred to the next reconcile because the retry budget of
+// the current one is exhausted.
+func (c *Controller) deferForRetryBudget(namespace, service string) {
+	c.retryBudgets.deferPod(namespace + "/" + service)
+	retryBudgetDeferralsTotal.With(prometheus.Labels{labelNamespace: namespace, labelService: service}).Inc()
+}
+
+// finishRetryBudget ends the retry budget of the reconcile of the service. If pods were deferred because the
+// budget was exhausted, the service is requeued so that the next reconcile picks them up with a fresh budget.
+func (c *Controller) finishRetryBudget(namespace, service string, generation uint64) {
+	if deferred := c.retryBudgets.finish(namespace+"/"+service, generation); deferred > 0 {
+		klog.Infof("Deferred %d pods of service %s/%s to the next reconcile as the retry budget was exhausted.", deferred, namespace, service)
+		c.requeueAt(namespace, service, c.clock.Now())
+	}
+}
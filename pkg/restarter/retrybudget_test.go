@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRetryBudgetDefersRemainingPods(t *testing.T) {
+	budget := int32(3)
+	deps := &api.ServiceDependants{Options: api.Options{RetryBudget: &budget}}
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+
+	objects := []runtime.Object{newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)}
+	var pods []*v1.Pod
+	for i := 0; i < 3; i++ {
+		pod := newPodInCrashloop("pod-"+strconv.Itoa(i), labels)
+		objects = append(objects, pod)
+		pods = append(pods, pod)
+	}
+	client := fake.NewSimpleClientset(objects...)
+	deletes := 0
+	client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deletes++
+		name := action.(k8stesting.DeleteAction).GetName()
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "pods"}, name, nil)
+	})
+	c := &Controller{
+		clientset:          client,
+		clock:              clock.NewFakeClock(metav1.Now().Time),
+		serviceDependants:  deps,
+		retryBudgets:       newRetryBudgets(),
+		reconcileSummaries: newReconcileSummaries(),
+	}
+	deferrals := retryBudgetDeferralsTotal.WithLabelValues(metav1.NamespaceDefault, "kube-apiserver")
+	before := testutil.ToFloat64(deferrals)
+
+	key := metav1.NamespaceDefault + "/kube-apiserver"
+	c.retryBudgets.start(key, 1)
+	c.reconcileSummaries.start(metav1.NamespaceDefault, "kube-apiserver", 1, 1, c.clock.Now())
+	for _, pod := range pods {
+		if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("error processing pod %s: %v", pod.Name, err)
+		}
+	}
+
+	// The first pod is deleted once and retried until the budget is exhausted, the others are not tried at all.
+	if expected := 1 + int(budget); deletes != expected {
+		t.Errorf("Expected %d delete calls but got %d", expected, deletes)
+	}
+	summary, ok := c.reconcileSummaries.finish(metav1.NamespaceDefault, "kube-apiserver", 1, c.clock.Now())
+	if !ok {
+		t.Fatalf("Expected a reconcile summary")
+	}
+	if summary.Deferred != 3 || summary.Failed != 0 || summary.Recycled != 0 {
+		t.Errorf("Expected all the pods to be deferred but got %+v", summary)
+	}
+	if deferred := c.retryBudgets.finish(key, 1); deferred != 3 {
+		t.Errorf("Expected 3 deferred pods but got %d", deferred)
+	}
+	if actual := testutil.ToFloat64(deferrals) - before; actual != 3 {
+		t.Errorf("Expected 3 deferrals to be counted but got %v", actual)
+	}
+
+	// The next reconcile starts with a fresh budget.
+	deletes = 0
+	c.retryBudgets.start(key, 2)
+	if err := c.processPod(context.TODO(), pods[1], "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	if expected := 1 + int(budget); deletes != expected {
+		t.Errorf("Expected %d delete calls in the next reconcile but got %d", expected, deletes)
+	}
+}
+
+func TestRetryOnConflictWithoutBudget(t *testing.T) {
+	c := &Controller{retryBudgets: newRetryBudgets()}
+	calls := 0
+	err := c.retryOnConflict(metav1.NamespaceDefault, "kube-apiserver", api.Options{}, func() error {
+		calls++
+		if calls < 3 {
+			return apierrors.NewConflict(schema.GroupResource{Resource: "pods"}, "pod-0", nil)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the call to succeed after retrying but got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls but got %d", calls)
+	}
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+// CheckScope verifies that the config only requires cluster-scoped operations if it reconciles all the namespaces.
+// Otherwise, all the requests of the controller are limited to the configured namespace. Without a configured
+// namespace, all the namespaces are reconciled. Relying on this without enabling allNamespaces is deprecated.
+func CheckScope(deps *api.ServiceDependants) error {
+	if deps.Namespace == "" {
+		if !deps.AllNamespaces {
+			klog.Warningf("No namespace configured: reconciling all the namespaces. This is deprecated, set allNamespaces to reconcile all the namespaces.")
+		}
+		return nil
+	}
+	if deps.AllNamespaces {
+		return fmt.Errorf("allNamespaces conflicts with the configured namespace %q", deps.Namespace)
+	}
+	for name, srv := range deps.Services {
+		for _, depPods := range srv.Dependants {
+			if depPods.ZonePinned {
+				return fmt.Errorf("dependant %s of service %s is zone pinned, which requires reading the nodes and hence all the namespaces", depPods.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// isClusterScoped returns true if the controller is allowed to perform cluster-scoped operations, i.e. if it
+// reconciles all the namespaces.
+func isClusterScoped(deps *api.ServiceDependants) bool {
+	return deps != nil && deps.Namespace == ""
+}
+
+// NewSharedInformerFactory returns a shared informer factory for the controller which lists and watches
+// only in the configured namespace, if any, with the configured resync period. The configured fields are
+// dropped from the cached endpoints.
+func NewSharedInformerFactory(clientset kubernetes.Interface, deps *api.ServiceDependants, defaultResync time.Duration) informers.SharedInformerFactory {
+	var opts []informers.SharedInformerOption
+	if deps.Namespace != "" {
+		opts = append(opts, informers.WithNamespace(deps.Namespace))
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, getResyncPeriod(deps, defaultResync), opts...)
+	if deps.Informer != nil && len(deps.Informer.StripFields) > 0 {
+		transform := StripFieldsTransform(deps.Informer.StripFields)
+		factory.InformerFor(&v1.Endpoints{}, func(client kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+			return newEndpointsInformer(client, deps.Namespace, resync, transform)
+		})
+	}
+	return factory
+}
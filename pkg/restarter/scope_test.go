@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestCheckScope(t *testing.T) {
+	zonePinned := map[string]api.Service{"kube-apiserver": {Dependants: []api.DependantPods{{Name: "controlplane", ZonePinned: true}}}}
+	tests := []struct {
+		name        string
+		deps        *api.ServiceDependants
+		expectedErr bool
+	}{
+		{"namespaced", &api.ServiceDependants{Namespace: "shoot--a"}, false},
+		{"all namespaces enabled", &api.ServiceDependants{AllNamespaces: true}, false},
+		{"all namespaces not enabled", &api.ServiceDependants{}, false},
+		{"all namespaces with a namespace", &api.ServiceDependants{Namespace: "shoot--a", AllNamespaces: true}, true},
+		{"namespaced zone pinned", &api.ServiceDependants{Namespace: "shoot--a", Services: zonePinned}, true},
+		{"all namespaces zone pinned", &api.ServiceDependants{AllNamespaces: true, Services: zonePinned}, false},
+	}
+	for _, tc := range tests {
+		if err := CheckScope(tc.deps); (err != nil) != tc.expectedErr {
+			t.Errorf("%s: expected error %v but got %v", tc.name, tc.expectedErr, err)
+		}
+	}
+
+	// Configs without a namespace keep reconciling all the namespaces without enabling it.
+	if !isClusterScoped(&api.ServiceDependants{}) {
+		t.Errorf("Expected a config without a namespace to reconcile all the namespaces")
+	}
+}
+
+func TestNamespacedModeIssuesNoClusterScopedRequests(t *testing.T) {
+	const namespace = "shoot--a"
+	labels := map[string]string{"role": "controlplane"}
+	depPods := api.DependantPods{Name: "controlplane", Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	deps := &api.ServiceDependants{
+		Namespace:    namespace,
+		MetricLabels: []string{"tier"},
+		Services:     map[string]api.Service{"kube-apiserver": {Dependants: []api.DependantPods{depPods}}},
+	}
+	if err := CheckScope(deps); err != nil {
+		t.Fatalf("error checking scope: %v", err)
+	}
+	pod := newPodInCrashloop("pod-0", labels)
+	pod.Namespace = namespace
+	client := fake.NewSimpleClientset(pod, newEndpoint("kube-apiserver", namespace, nil))
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory := NewSharedInformerFactory(client, deps, 0)
+	c := NewController(client, nil, factory, deps, watchDuration, stopCh)
+	factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, c.hasSynced) {
+		t.Fatalf("error waiting for the caches to sync")
+	}
+
+	if _, err := c.EstimateImpact(context.TODO()); err != nil {
+		t.Fatalf("error estimating impact: %v", err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	if err := c.processPod(context.TODO(), pod, "kube-apiserver", &depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+
+	var podLists int
+	for _, action := range client.Actions() {
+		if action.GetNamespace() != namespace {
+			t.Errorf("Expected only requests in namespace %s but got %s %s in namespace %q", namespace, action.GetVerb(), action.GetResource().Resource, action.GetNamespace())
+		}
+		if action.GetVerb() == "list" && action.GetResource() == v1.SchemeGroupVersion.WithResource("pods") {
+			podLists++
+		}
+	}
+	if podLists == 0 {
+		t.Errorf("Expected the pods to be listed in namespace %s", namespace)
+	}
+}
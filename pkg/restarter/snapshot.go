@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+)
+
+// maxDependencySnapshotSize bounds the size of the serialized snapshot annotated on the owners of the deleted pods.
+const maxDependencySnapshotSize = 1024
+
+// DependencySnapshot is the state of the dependency at the time a dependant pod is deleted.
+type DependencySnapshot struct {
+	Service        string `json:"service"`
+	ReadyAddresses int    `json:"readyAddresses"`
+	// LastTransitionTime is the time of the last readiness transition of the dependency observed by the controller.
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	Pod                string       `json:"pod"`
+	Timestamp          metav1.Time  `json:"timestamp"`
+}
+
+// annotateDependencySnapshot annotates the top-level owner of the pod with the snapshot of the dependency under
+// the given key before the pod is deleted. It is best-effort, so failures are only logged.
+func (c *Controller) annotateDependencySnapshot(ctx context.Context, po *v1.Pod, service, key string) {
+	owner, err := c.getTopLevelOwner(ctx, po)
+	if err != nil {
+		klog.Errorf("Error resolving the owner of pod %s/%s: %s", po.Namespace, po.Name, err)
+		return
+	}
+	if owner == nil {
+		return
+	}
+	snapshot, err := c.newDependencySnapshot(ctx, po, service)
+	if err != nil {
+		klog.Errorf("Error taking the snapshot of the dependency %s/%s: %s", po.Namespace, service, err)
+		return
+	}
+	if err := c.annotateOwner(owner, key, snapshot); err != nil {
+		klog.Errorf("Error annotating %s %s/%s with the snapshot of the dependency: %s", owner.Kind, owner.Namespace, owner.Name, err)
+	}
+}
+
+// newDependencySnapshot serializes the current state of the dependency of the pod. Snapshots exceeding the size
+// bound are rejected.
+func (c *Controller) newDependencySnapshot(ctx context.Context, po *v1.Pod, service string) (string, error) {
+	ep, err := c.getEndpoints(ctx, po.Namespace, service)
+	if err != nil {
+		return "", err
+	}
+	snapshot := DependencySnapshot{
+		Service:   service,
+		Pod:       po.Name,
+		Timestamp: metav1.NewTime(c.clock.Now().UTC()),
+	}
+	for _, subset := range ep.Subsets {
+		snapshot.ReadyAddresses += len(subset.Addresses)
+	}
+	if c.flapDetector != nil {
+		if t, ok := c.flapDetector.LastTransitionTime(po.Namespace + "/" + service); ok {
+			transition := metav1.NewTime(t.UTC())
+			snapshot.LastTransitionTime = &transition
+		}
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxDependencySnapshotSize {
+		return "", fmt.Errorf("snapshot of %d bytes exceeds %d bytes", len(data), maxDependencySnapshotSize)
+	}
+	return string(data), nil
+}
+
+// annotateOwner sets the annotation of the owner to the given value.
+func (c *Controller) annotateOwner(owner *v1.ObjectReference, key, value string) error {
+	if c.dynamicClient == nil {
+		return fmt.Errorf("no dynamic client configured to patch %s", owner.Name)
+	}
+	gvr, err := getOwnerGVR(owner)
+	if err != nil {
+		return err
+	}
+	patch, err := newAnnotationsPatch(map[string]string{key: value}, true)
+	if err != nil {
+		return err
+	}
+	_, err = c.dynamicClient.Resource(gvr).Namespace(owner.Namespace).Patch(owner.Name, types.MergePatchType, patch, c.patchOptions())
+	return err
+}
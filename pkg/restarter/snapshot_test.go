@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeletePodAnnotatesDependencySnapshot(t *testing.T) {
+	annotationKey := "example.com/dependency-snapshot"
+	deployment := &unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+	deployment.SetNamespace(metav1.NamespaceDefault)
+	deployment.SetName("kube-controller-manager")
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment)
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	pod := newPodInCrashloop("pod-0", nil)
+	pod.OwnerReferences = newControllerRef("apps/v1", "Deployment", "kube-controller-manager")
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	c := &Controller{
+		clientset:         fake.NewSimpleClientset(pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)),
+		dynamicClient:     dynamicClient,
+		clock:             fakeClock,
+		flapDetector:      NewFlapDetector(fakeClock),
+		serviceDependants: &api.ServiceDependants{Options: api.Options{DependencySnapshotAnnotation: &annotationKey}},
+	}
+	c.flapDetector.RecordReadiness("default/kube-apiserver", false)
+	fakeClock.Step(time.Minute)
+	c.flapDetector.RecordReadiness("default/kube-apiserver", true)
+	fakeClock.Step(time.Minute)
+
+	if err := c.deletePod(pod, "kube-apiserver"); err != nil {
+		t.Fatalf("error deleting pod: %v", err)
+	}
+	d, err := dynamicClient.Resource(gvr).Namespace(metav1.NamespaceDefault).Get("kube-controller-manager", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error fetching deployment: %v", err)
+	}
+	value, ok := d.GetAnnotations()[annotationKey]
+	if !ok {
+		t.Fatalf("Expected the deployment to be annotated with the dependency snapshot but got %v", d.GetAnnotations())
+	}
+	var snapshot DependencySnapshot
+	if err := json.Unmarshal([]byte(value), &snapshot); err != nil {
+		t.Fatalf("Expected the snapshot to be valid JSON but got %q: %v", value, err)
+	}
+	if snapshot.Service != "kube-apiserver" || snapshot.Pod != "pod-0" || snapshot.ReadyAddresses != 1 {
+		t.Errorf("Expected the snapshot to describe the dependency but got %+v", snapshot)
+	}
+	if snapshot.LastTransitionTime == nil || !snapshot.LastTransitionTime.Equal(&metav1.Time{Time: time.Date(2021, 6, 1, 12, 1, 0, 0, time.UTC)}) {
+		t.Errorf("Expected the snapshot to carry the last readiness transition but got %v", snapshot.LastTransitionTime)
+	}
+	if !snapshot.Timestamp.Equal(&metav1.Time{Time: fakeClock.Now()}) {
+		t.Errorf("Expected the snapshot to be taken at %s but got %s", fakeClock.Now(), snapshot.Timestamp)
+	}
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// readyStabilityTracker tracks since when the readiness predicates of the services have been holding
+// continuously. Services are identified by the <namespace>/<service> key.
+type readyStabilityTracker struct {
+	mux   sync.Mutex
+	since map[string]time.Time
+}
+
+func newReadyStabilityTracker() *readyStabilityTracker {
+	return &readyStabilityTracker{since: make(map[string]time.Time)}
+}
+
+// observe records the readiness of the service identified by key at now and returns since when it has been ready,
+// if it is ready. Without a tracker, the service is treated as ready forever.
+func (t *readyStabilityTracker) observe(key string, ready bool, now time.Time) (time.Time, bool) {
+	if !ready {
+		if t != nil {
+			t.mux.Lock()
+			delete(t.since, key)
+			t.mux.Unlock()
+		}
+		return time.Time{}, false
+	}
+	if t == nil {
+		return time.Time{}, true
+	}
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	since, ok := t.since[key]
+	if !ok {
+		since = now
+		t.since[key] = since
+	}
+	return since, true
+}
+
+// isReadyStable checks if the readiness predicates of the service have been holding for at least the given window,
+// so that a service which satisfies them only momentarily, e.g. with a single ready address during its rollout, is
+// not treated as recovered. The service is requeued for the end of the window otherwise.
+func (c *Controller) isReadyStable(namespace, service string, ready bool, window time.Duration) bool {
+	since, ready := c.readyStability.observe(namespace+"/"+service, ready, c.clock.Now())
+	if !ready {
+		return false
+	}
+	if stable := since.Add(window); stable.After(c.clock.Now()) {
+		klog.Infof("Service %s/%s has been ready since %s, less than %s. Treating it as not ready yet.",
+			namespace, service, since.Format(time.RFC3339), window)
+		c.requeueAt(namespace, service, stable)
+		return false
+	}
+	return true
+}
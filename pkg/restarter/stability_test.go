@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReadyStableFor(t *testing.T) {
+	minReadyAddresses := int32(2)
+	srv := api.Service{
+		Readiness:      &api.Readiness{Predicate: api.ReadinessMinReadyAddresses, MinReadyAddresses: &minReadyAddresses},
+		ReadyStableFor: &metav1.Duration{Duration: time.Minute},
+	}
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	c := &Controller{clientset: fake.NewSimpleClientset(), clock: fakeClock, readyStability: newReadyStabilityTracker()}
+
+	// The dependency rolls out, ramping up its ready addresses while one of them is briefly replaced.
+	steps := []struct {
+		name      string
+		after     time.Duration
+		addresses int
+		expected  bool
+	}{
+		{"no ready address", 0, 0, false},
+		{"a single ready address", 10 * time.Second, 1, false},
+		{"minimum ready addresses reached", 10 * time.Second, 2, false},
+		{"minimum ready addresses within the window", 40 * time.Second, 2, false},
+		{"back to a single ready address", 10 * time.Second, 1, false},
+		{"minimum ready addresses reached again", 10 * time.Second, 2, false},
+		{"minimum ready addresses shortly before the end of the window", 59 * time.Second, 3, false},
+		{"minimum ready addresses stable for the window", time.Second, 2, true},
+		{"minimum ready addresses stable beyond the window", time.Hour, 2, true},
+	}
+	for _, step := range steps {
+		fakeClock.Step(step.after)
+		ready, err := c.isServiceReady(newEndpointWithPorts(step.addresses), srv)
+		if err != nil {
+			t.Fatalf("%s: error evaluating the readiness: %v", step.name, err)
+		}
+		if ready != step.expected {
+			t.Errorf("%s: expected ready %v but got %v", step.name, step.expected, ready)
+		}
+	}
+
+	// Without a stability window, the minimum ready addresses suffice immediately.
+	srv.ReadyStableFor = nil
+	c.readyStability = newReadyStabilityTracker()
+	if ready, err := c.isServiceReady(newEndpointWithPorts(2), srv); err != nil || !ready {
+		t.Errorf("Expected the service to be ready without a stability window but got %v (%v)", ready, err)
+	}
+}
+
+func TestReadinessRecheckKeepsTheStabilityWindow(t *testing.T) {
+	srv := api.Service{ReadyStableFor: &metav1.Duration{Duration: time.Minute}}
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	c := &Controller{
+		clientset:         fake.NewSimpleClientset(newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)),
+		clock:             fakeClock,
+		readyStability:    newReadyStabilityTracker(),
+		serviceDependants: &api.ServiceDependants{Services: map[string]api.Service{"kube-apiserver": srv}},
+	}
+
+	// The re-check before the deletions neither restarts nor awaits the stability window.
+	if !c.isDependencyStillReady(context.TODO(), metav1.NamespaceDefault, "kube-apiserver") {
+		t.Errorf("Expected the ready service to still be ready")
+	}
+	if len(c.readyStability.since) != 0 {
+		t.Errorf("Expected the re-check not to track the stability but got %v", c.readyStability.since)
+	}
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// DependencyState describes the readiness of a dependency as seen by the dependency-watchdog.
+type DependencyState string
+
+const (
+	// DependencyReady means that the dependency has ready endpoints.
+	DependencyReady DependencyState = "Ready"
+	// DependencyNotReady means that the dependency has no ready endpoints.
+	DependencyNotReady DependencyState = "NotReady"
+	// DependencyUnknown means that the readiness of the dependency cannot be trusted,
+	// e.g. because its Endpoints object has not been updated for too long.
+	DependencyUnknown DependencyState = "Unknown"
+)
+
+// GetDependencyState returns the state of the dependency backed by the given Endpoints object.
+// If maxStaleness is positive and the Endpoints object did not change since longer than maxStaleness,
+// the state is DependencyUnknown.
+func GetDependencyState(ep *v1.Endpoints, lastChange time.Time, maxStaleness time.Duration, now time.Time) DependencyState {
+	return getDependencyState(IsReadyEndpointPresentInSubsets(ep.Subsets), lastChange, maxStaleness, now)
+}
+
+// getDependencyState returns the state of the dependency whose endpoints were evaluated to the given readiness.
+func getDependencyState(ready bool, lastChange time.Time, maxStaleness time.Duration, now time.Time) DependencyState {
+	if maxStaleness > 0 && now.Sub(lastChange) > maxStaleness {
+		return DependencyUnknown
+	}
+	if ready {
+		return DependencyReady
+	}
+	return DependencyNotReady
+}
+
+// GetEndpointsLastChangeTime returns the last change trigger time annotated on the Endpoints object
+// by the endpoints controller. If the annotation is missing or invalid, lastSeen is returned.
+func GetEndpointsLastChangeTime(ep *v1.Endpoints, lastSeen time.Time) time.Time {
+	if value, ok := ep.Annotations[v1.EndpointsLastChangeTriggerTime]; ok {
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t
+		}
+	}
+	return lastSeen
+}
+
+// endpointsChangeTracker remembers when the controller observed the last change of the Endpoints objects.
+type endpointsChangeTracker struct {
+	mux      sync.Mutex
+	versions map[string]observedVersion
+}
+
+type observedVersion struct {
+	resourceVersion string
+	observedAt      time.Time
+}
+
+func newEndpointsChangeTracker() *endpointsChangeTracker {
+	return &endpointsChangeTracker{
+		versions: make(map[string]observedVersion),
+	}
+}
+
+// observe records the resource version of the Endpoints object identified by key and returns
+// the time at which the current resource version was first observed.
+func (t *endpointsChangeTracker) observe(key string, ep *v1.Endpoints, now time.Time) time.Time {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	if v, ok := t.versions[key]; ok && v.resourceVersion == ep.ResourceVersion {
+		return v.observedAt
+	}
+	t.versions[key] = observedVersion{resourceVersion: ep.ResourceVersion, observedAt: now}
+	return now
+}
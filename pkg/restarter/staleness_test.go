@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetDependencyState(t *testing.T) {
+	now := time.Now()
+	ready := newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)
+	notReady := newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)
+	notReady.Subsets = nil
+
+	tests := []struct {
+		name         string
+		ep           *v1.Endpoints
+		lastChange   time.Time
+		maxStaleness time.Duration
+		expected     DependencyState
+	}{
+		{"fresh ready", ready, now.Add(-time.Minute), time.Hour, DependencyReady},
+		{"fresh not ready", notReady, now.Add(-time.Minute), time.Hour, DependencyNotReady},
+		{"stale ready", ready, now.Add(-2 * time.Hour), time.Hour, DependencyUnknown},
+		{"stale not ready", notReady, now.Add(-2 * time.Hour), time.Hour, DependencyUnknown},
+		{"staleness guard disabled", ready, now.Add(-2 * time.Hour), 0, DependencyReady},
+	}
+	for _, tc := range tests {
+		if got := GetDependencyState(tc.ep, tc.lastChange, tc.maxStaleness, now); got != tc.expected {
+			t.Errorf("%s: expected %s but got %s", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestEndpointsLastChangeTime(t *testing.T) {
+	start := time.Now()
+	tracker := newEndpointsChangeTracker()
+	ep := newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)
+	ep.ResourceVersion = "1"
+
+	if got := tracker.observe("default/kube-apiserver", ep, start); !got.Equal(start) {
+		t.Errorf("Expected first observation at %s but got %s", start, got)
+	}
+	if got := tracker.observe("default/kube-apiserver", ep, start.Add(time.Hour)); !got.Equal(start) {
+		t.Errorf("Expected unchanged endpoints to keep the observation time %s but got %s", start, got)
+	}
+	ep.ResourceVersion = "2"
+	if got := tracker.observe("default/kube-apiserver", ep, start.Add(2*time.Hour)); !got.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("Expected changed endpoints to be observed at %s but got %s", start.Add(2*time.Hour), got)
+	}
+
+	triggerTime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	ep.Annotations[v1.EndpointsLastChangeTriggerTime] = triggerTime.Format(time.RFC3339Nano)
+	if got := GetEndpointsLastChangeTime(ep, start); !got.Equal(triggerTime) {
+		t.Errorf("Expected the annotated trigger time %s but got %s", triggerTime, got)
+	}
+	ep.Annotations[v1.EndpointsLastChangeTriggerTime] = "invalid"
+	if got := GetEndpointsLastChangeTime(ep, start); !got.Equal(start) {
+		t.Errorf("Expected the last seen time %s for an invalid annotation but got %s", start, got)
+	}
+}
+
+func TestLongStableReadyServiceWithFreshUpdateIsReady(t *testing.T) {
+	start := time.Now()
+	tracker := newEndpointsChangeTracker()
+	ep := newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)
+	ep.ResourceVersion = "1"
+	tracker.observe("default/kube-apiserver", ep, start)
+
+	// The service stays ready for longer than the max staleness, but its endpoints were just updated.
+	now := start.Add(2 * time.Hour)
+	ep.ResourceVersion = "2"
+	lastChange := GetEndpointsLastChangeTime(ep, tracker.observe("default/kube-apiserver", ep, now))
+	if got := GetDependencyState(ep, lastChange, time.Hour, now); got != DependencyReady {
+		t.Errorf("Expected the freshly updated ready service to be ready but got %s", got)
+	}
+}
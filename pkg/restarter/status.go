@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+)
+
+// StatusSnapshot captures the readiness of the configured services and their crashlooping dependant pods.
+type StatusSnapshot struct {
+	Services []ServiceStatus `json:"services"`
+}
+
+// ServiceStatus captures the readiness of a service in a namespace and its crashlooping dependant pods.
+type ServiceStatus struct {
+	Namespace        string   `json:"namespace"`
+	Service          string   `json:"service"`
+	Ready            bool     `json:"ready"`
+	CrashloopingPods []string `json:"crashloopingPods"`
+}
+
+// DependenciesReport captures the readiness of the configured services as cached by the controller.
+type DependenciesReport struct {
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// DependencyStatus captures the readiness of a service in a namespace.
+type DependencyStatus struct {
+	Namespace      string `json:"namespace"`
+	Service        string `json:"service"`
+	Ready          bool   `json:"ready"`
+	ReadyAddresses int    `json:"readyAddresses"`
+	// LastTransitionTime is the time of the last readiness transition observed by the controller, if any.
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// Dependencies returns the readiness of all the configured services in the namespaces the controller reconciles from
+// the informer cache. It does not make any changes.
+func (c *Controller) Dependencies() (*DependenciesReport, error) {
+	deps := c.getServiceDependants()
+	eps, err := c.listServiceEndpoints(deps, func(namespace string) ([]*v1.Endpoints, error) {
+		if namespace != "" {
+			return c.endpointLister.Endpoints(namespace).List(labels.Everything())
+		}
+		return c.endpointLister.List(labels.Everything())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DependenciesReport{Dependencies: []DependencyStatus{}}
+	for _, ep := range eps {
+		ready, err := c.evaluateServiceReadiness(ep, deps.Services[ep.Name])
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating the readiness of service %s/%s: %v", ep.Namespace, ep.Name, err)
+		}
+		status := DependencyStatus{
+			Namespace:      ep.Namespace,
+			Service:        ep.Name,
+			Ready:          ready,
+			ReadyAddresses: countReadyAddresses(ep.Subsets),
+		}
+		if t, ok := c.flapDetector.LastTransitionTime(ep.Namespace + "/" + ep.Name); ok {
+			status.LastTransitionTime = &metav1.Time{Time: t}
+		}
+		report.Dependencies = append(report.Dependencies, status)
+	}
+	sort.Slice(report.Dependencies, func(i, j int) bool {
+		a, b := report.Dependencies[i], report.Dependencies[j]
+		return a.Namespace < b.Namespace || (a.Namespace == b.Namespace && a.Service < b.Service)
+	})
+	return report, nil
+}
+
+func countReadyAddresses(subsets []v1.EndpointSubset) int {
+	count := 0
+	for _, subset := range subsets {
+		count += len(subset.Addresses)
+	}
+	return count
+}
+
+// DependenciesHandler returns an HTTP handler which serves the readiness of the configured services as JSON.
+func (c *Controller) DependenciesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		report, err := c.Dependencies()
+		if err != nil {
+			klog.Errorf("Error reporting dependencies: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, report)
+	})
+}
+
+// Snapshot returns the current status of all the configured services found in the namespaces the controller
+// reconciles. It does not make any changes.
+func (c *Controller) Snapshot(ctx context.Context) (*StatusSnapshot, error) {
+	deps := c.getServiceDependants()
+	eps, err := c.listServiceEndpoints(deps, func(namespace string) ([]*v1.Endpoints, error) {
+		epl, err := c.clientset.CoreV1().Endpoints(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		eps := make([]*v1.Endpoints, len(epl.Items))
+		for i := range epl.Items {
+			eps[i] = &epl.Items[i]
+		}
+		return eps, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &StatusSnapshot{Services: []ServiceStatus{}}
+	for _, ep := range eps {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		srv := deps.Services[ep.Name]
+		ready, err := c.evaluateServiceReadiness(ep, srv)
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating the readiness of service %s/%s: %v", ep.Namespace, ep.Name, err)
+		}
+		status := ServiceStatus{
+			Namespace:        ep.Namespace,
+			Service:          ep.Name,
+			Ready:            ready,
+			CrashloopingPods: []string{},
+		}
+		seen := make(map[string]bool)
+		for _, depPods := range srv.Dependants {
+			selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("error converting label selector of dependant %s: %v", depPods.Name, err)
+			}
+			pl, err := c.clientset.CoreV1().Pods(ep.Namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+			if err != nil {
+				return nil, fmt.Errorf("error listing pods with selector %s: %v", selector.String(), err)
+			}
+			for _, po := range selectDependantPods(pl.Items, &depPods) {
+				if IsPodInCrashloopBackoff(po.Status) && !seen[po.Name] {
+					seen[po.Name] = true
+					status.CrashloopingPods = append(status.CrashloopingPods, po.Name)
+				}
+			}
+		}
+		sort.Strings(status.CrashloopingPods)
+		snapshot.Services = append(snapshot.Services, status)
+	}
+	sort.Slice(snapshot.Services, func(i, j int) bool {
+		a, b := snapshot.Services[i], snapshot.Services[j]
+		return a.Namespace < b.Namespace || (a.Namespace == b.Namespace && a.Service < b.Service)
+	})
+	return snapshot, nil
+}
+
+// listServiceEndpoints lists the endpoints of the configured services in the namespaces the controller reconciles with
+// the given list function, which lists the endpoints in the given namespace or, if empty, in all the namespaces.
+func (c *Controller) listServiceEndpoints(deps *api.ServiceDependants, list func(namespace string) ([]*v1.Endpoints, error)) ([]*v1.Endpoints, error) {
+	eps, err := list(deps.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error listing endpoints: %v", err)
+	}
+	var selected []*v1.Endpoints
+	for _, ep := range eps {
+		if _, ok := deps.Services[ep.Name]; ok && c.isNamespaceAllowed(deps, ep.Namespace) {
+			selected = append(selected, ep)
+		}
+	}
+	return selected, nil
+}
+
+// StatusHandler returns an HTTP handler which serves the current status snapshot as JSON.
+func (c *Controller) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot, err := c.Snapshot(r.Context())
+		if err != nil {
+			klog.Errorf("Error taking status snapshot: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, snapshot)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		klog.Errorf("Error writing response: %s", err)
+	}
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSnapshot(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	selector := &metav1.LabelSelector{MatchLabels: labels}
+	minReadyAddresses := int32(2)
+	deps := &api.ServiceDependants{
+		AllNamespaces: true,
+		Services: map[string]api.Service{
+			"kube-apiserver": {Dependants: []api.DependantPods{{Name: "controlplane", Selector: selector}}},
+			"etcd":           {Dependants: []api.DependantPods{{Name: "controlplane", Selector: selector}}},
+			"etcd-events": {Readiness: &api.Readiness{
+				Predicate:         api.ReadinessMinReadyAddresses,
+				MinReadyAddresses: &minReadyAddresses,
+			}},
+		},
+	}
+	notReady := newEndpoint("etcd", metav1.NamespaceDefault, nil)
+	notReady.Subsets = nil
+	terminating := newPodInCrashloop("pod-t", labels)
+	terminating.DeletionTimestamp = &metav1.Time{}
+	client := fake.NewSimpleClientset(
+		newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil),
+		notReady,
+		newEndpoint("unrelated", metav1.NamespaceDefault, nil),
+		newEndpoint("etcd-events", metav1.NamespaceDefault, nil),
+		// The excluded namespaces are not reconciled.
+		newEndpoint("kube-apiserver", metav1.NamespaceSystem, nil),
+		newPodInCrashloop("pod-1", labels),
+		newPodInCrashloop("pod-0", labels),
+		newPodHealthy("pod-h", labels),
+		terminating,
+	)
+	c := &Controller{clientset: client, serviceDependants: deps}
+
+	snapshot, err := c.Snapshot(context.TODO())
+	if err != nil {
+		t.Fatalf("error taking snapshot: %v", err)
+	}
+	expected := &StatusSnapshot{Services: []ServiceStatus{
+		{Namespace: "default", Service: "etcd", Ready: false, CrashloopingPods: []string{"pod-0", "pod-1"}},
+		{Namespace: "default", Service: "etcd-events", Ready: false, CrashloopingPods: []string{}},
+		{Namespace: "default", Service: "kube-apiserver", Ready: true, CrashloopingPods: []string{"pod-0", "pod-1"}},
+	}}
+	if !reflect.DeepEqual(snapshot, expected) {
+		t.Errorf("Expected snapshot %+v but got %+v", expected, snapshot)
+	}
+	for _, action := range client.Actions() {
+		if action.GetVerb() != "list" {
+			t.Errorf("Expected the snapshot to only list resources but got %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	c.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d but got %d", http.StatusOK, rec.Code)
+	}
+	var served StatusSnapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil {
+		t.Fatalf("error decoding served snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(&served, expected) {
+		t.Errorf("Expected served snapshot %+v but got %+v", expected, served)
+	}
+}
+
+func TestDependenciesHandler(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	minReadyAddresses := int32(2)
+	deps := &api.ServiceDependants{
+		AllNamespaces: true,
+		Services: map[string]api.Service{
+			"kube-apiserver": {},
+			"etcd":           {},
+			"etcd-events": {Readiness: &api.Readiness{
+				Predicate:         api.ReadinessMinReadyAddresses,
+				MinReadyAddresses: &minReadyAddresses,
+			}},
+		},
+	}
+	client := fake.NewSimpleClientset()
+	c := NewController(client, nil, informers.NewSharedInformerFactory(client, 0), deps, watchDuration, stopCh)
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	c.flapDetector = NewFlapDetector(fakeClock)
+
+	notReady := newEndpoint("etcd", "shoot--dev", nil)
+	notReady.Subsets[0].NotReadyAddresses, notReady.Subsets[0].Addresses = notReady.Subsets[0].Addresses, nil
+	c.endpointInformer.GetIndexer().Add(newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+	c.endpointInformer.GetIndexer().Add(notReady)
+	c.endpointInformer.GetIndexer().Add(newEndpoint("unrelated", metav1.NamespaceDefault, nil))
+	c.endpointInformer.GetIndexer().Add(newEndpoint("etcd-events", metav1.NamespaceDefault, nil))
+	// The excluded namespaces are not reconciled.
+	c.endpointInformer.GetIndexer().Add(newEndpoint("kube-apiserver", metav1.NamespaceSystem, nil))
+	c.flapDetector.RecordReadiness("shoot--dev/etcd", true)
+	c.flapDetector.RecordReadiness("shoot--dev/etcd", false)
+
+	rec := httptest.NewRecorder()
+	c.DependenciesHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dependencies", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d but got %d", http.StatusOK, rec.Code)
+	}
+	var report DependenciesReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("error decoding report: %v", err)
+	}
+	// Times are decoded in the local time zone.
+	transition := metav1.NewTime(fakeClock.Now().Local())
+	expected := DependenciesReport{Dependencies: []DependencyStatus{
+		{Namespace: "default", Service: "etcd-events", Ready: false, ReadyAddresses: 1},
+		{Namespace: "default", Service: "kube-apiserver", Ready: true, ReadyAddresses: 1},
+		{Namespace: "shoot--dev", Service: "etcd", Ready: false, ReadyAddresses: 0, LastTransitionTime: &transition},
+	}}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected report %+v but got %+v", expected, report)
+	}
+	if len(client.Actions()) != 0 {
+		t.Errorf("Expected the report to be served from the cache but got %d requests", len(client.Actions()))
+	}
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"k8s.io/klog"
+)
+
+// ReconcileSummary summarizes the decisions on the dependant pods during the reconcile of a service.
+type ReconcileSummary struct {
+	Namespace  string
+	Service    string
+	Ready      bool
+	Dependants int
+	// Candidates is the number of the unhealthy dependant pods which were decided on.
+	Candidates int
+	Recycled   int
+	Observed   int
+	// Deferred is the number of the candidates which were skipped, e.g. because of a cooldown or a budget.
+	Deferred int
+	Failed   int
+	Duration time.Duration
+}
+
+type reconcileSummary struct {
+	summary    ReconcileSummary
+	started    time.Time
+	generation uint64
+}
+
+// reconcileSummaries aggregates the decisions during the reconciles of the services. Reconciles are identified by
+// the <namespace>/<service> key.
+type reconcileSummaries struct {
+	mux        sync.Mutex
+	reconciles map[string]*reconcileSummary
+}
+
+func newReconcileSummaries() *reconcileSummaries {
+	return &reconcileSummaries{reconciles: make(map[string]*reconcileSummary)}
+}
+
+// start begins aggregating the decisions of the reconcile of the given generation of the service.
+func (s *reconcileSummaries) start(namespace, service string, generation uint64, dependants int, now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.reconciles[namespace+"/"+service] = &reconcileSummary{
+		summary:    ReconcileSummary{Namespace: namespace, Service: service, Ready: true, Dependants: dependants},
+		started:    now,
+		generation: generation,
+	}
+}
+
+// add records the decision on a dependant pod for the active reconcile of the service, if any.
+func (s *reconcileSummaries) add(namespace, service string, decision *Decision) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	r, ok := s.reconciles[namespace+"/"+service]
+	if !ok || decision.Action == DecisionActionSkipped && decision.Reason == DecisionReasonHealthy {
+		return
+	}
+	r.summary.Candidates++
+	switch decision.Action {
+	case DecisionActionRecycled:
+		r.summary.Recycled++
+	case DecisionActionObserved:
+		r.summary.Observed++
+	case DecisionActionSkipped:
+		r.summary.Deferred++
+	case DecisionActionFailed:
+		r.summary.Failed++
+	}
+}
+
+// finish stops aggregating the decisions of the reconcile of the given generation of the service and returns its
+// summary. There is no summary if the reconcile was superseded by another reconcile of the service.
+func (s *reconcileSummaries) finish(namespace, service string, generation uint64, now time.Time) (ReconcileSummary, bool) {
+	if s == nil {
+		return ReconcileSummary{}, false
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	key := namespace + "/" + service
+	r, ok := s.reconciles[key]
+	if !ok || r.generation != generation {
+		return ReconcileSummary{}, false
+	}
+	delete(s.reconciles, key)
+	r.summary.Duration = now.Sub(r.started)
+	return r.summary, true
+}
+
+// logReconcileSummary logs the summary of the reconcile as a single line at the configured verbosity.
+func logReconcileSummary(deps *api.ServiceDependants, s ReconcileSummary) {
+	var verbosity klog.Level
+	if deps.ReconcileSummaryVerbosity != nil {
+		verbosity = klog.Level(*deps.ReconcileSummaryVerbosity)
+	}
+	klog.V(verbosity).Infof("Reconcile summary: namespace=%s service=%s ready=%t dependants=%d candidates=%d recycled=%d observed=%d deferred=%d failed=%d duration=%s",
+		s.Namespace, s.Service, s.Ready, s.Dependants, s.Candidates, s.Recycled, s.Observed, s.Deferred, s.Failed, s.Duration)
+}
+
+// finishReconcileSummary logs the summary of the finished reconcile of the service.
+func (c *Controller) finishReconcileSummary(namespace, service string, generation uint64) {
+	if summary, ok := c.reconcileSummaries.finish(namespace, service, generation, c.clock.Now()); ok {
+		logReconcileSummary(c.getServiceDependants(), summary)
+	}
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog"
+)
+
+// logSink captures the log lines, which may be written concurrently by the goroutines of other tests.
+type logSink struct {
+	mux sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *logSink) Write(p []byte) (int, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *logSink) String() string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.buf.String()
+}
+
+// captureLogs redirects the logs to the returned sink until the returned function is called.
+func captureLogs(t *testing.T) (*logSink, func()) {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	if err := fs.Set("logtostderr", "false"); err != nil {
+		t.Fatalf("error redirecting logs: %v", err)
+	}
+	sink := &logSink{}
+	klog.SetOutput(sink)
+	return sink, func() {
+		if err := fs.Set("logtostderr", "true"); err != nil {
+			t.Fatalf("error restoring logs: %v", err)
+		}
+	}
+}
+
+func TestReconcileSummary(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	ignored := newPodInCrashloop("ignored", labels)
+	ignored.Annotations = map[string]string{ignoreAnnotationKey: "true"}
+	pods := []*v1.Pod{newPodInCrashloop("crashloop-0", labels), newPodInCrashloop("crashloop-1", labels), ignored, newPodHealthy("healthy", labels)}
+	client := fake.NewSimpleClientset(newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+	for _, pod := range pods {
+		if _, err := client.CoreV1().Pods(pod.Namespace).Create(pod); err != nil {
+			t.Fatalf("error creating pod %s: %v", pod.Name, err)
+		}
+	}
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	deps := &api.ServiceDependants{}
+	c := &Controller{clientset: client, clock: fakeClock, serviceDependants: deps, reconcileSummaries: newReconcileSummaries()}
+
+	sink, restore := captureLogs(t)
+	defer restore()
+	c.reconcileSummaries.start(metav1.NamespaceDefault, "kube-apiserver", 1, 2, fakeClock.Now())
+	for _, pod := range pods {
+		if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("error processing pod %s: %v", pod.Name, err)
+		}
+	}
+	fakeClock.Step(30 * time.Second)
+	c.finishReconcileSummary(metav1.NamespaceDefault, "kube-apiserver", 1)
+
+	expected := "Reconcile summary: namespace=default service=kube-apiserver ready=true dependants=2 candidates=3 recycled=2 observed=0 deferred=1 failed=0 duration=30s"
+	if logs := sink.String(); !strings.Contains(logs, expected) {
+		t.Errorf("Expected the summary %q to be logged but got:\n%s", expected, logs)
+	}
+
+	// The summary is not logged at a higher verbosity than the configured one.
+	verbosity := int32(4)
+	deps.ReconcileSummaryVerbosity = &verbosity
+	logReconcileSummary(deps, ReconcileSummary{Namespace: "verbose", Service: "kube-apiserver"})
+	if logs := sink.String(); strings.Contains(logs, "namespace=verbose") {
+		t.Errorf("Expected the summary not to be logged at verbosity %d but got:\n%s", verbosity, logs)
+	}
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"golang.org/x/time/rate"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/klog"
+)
+
+const (
+	// minDeletionRateFraction bounds how far the deletion rate is lowered relative to the configured rate.
+	minDeletionRateFraction = 1.0 / 16
+	// deletionRateRecoveryInterval is the duration without throttling after which the deletion rate is doubled.
+	deletionRateRecoveryInterval = 30 * time.Second
+	// sustainedThrottlingThreshold is the number of consecutively throttled requests after which the deletion rate
+	// is halved.
+	sustainedThrottlingThreshold = 3
+)
+
+// AdaptiveLimiter limits the rate of the deletions. The rate is halved whenever the apiserver throttles several
+// requests in a row with 429 TooManyRequests and doubled again up to the configured rate for every recovery interval
+// without throttling.
+type AdaptiveLimiter struct {
+	clock      clock.Clock
+	mux        sync.Mutex
+	limiter    *rate.Limiter
+	maxRate    rate.Limit
+	lastAdjust time.Time
+	throttled  int
+}
+
+// NewAdaptiveLimiter returns a new AdaptiveLimiter allowing the given number of deletions per second at most.
+func NewAdaptiveLimiter(deletionsPerSecond float64, clock clock.Clock) *AdaptiveLimiter {
+	l := &AdaptiveLimiter{
+		clock:      clock,
+		limiter:    rate.NewLimiter(rate.Limit(deletionsPerSecond), 1),
+		maxRate:    rate.Limit(deletionsPerSecond),
+		lastAdjust: clock.Now(),
+	}
+	effectiveDeletionRate.Set(deletionsPerSecond)
+	return l
+}
+
+// Wait blocks until the next deletion is allowed or the context is done.
+func (l *AdaptiveLimiter) Wait(ctx context.Context) error {
+	l.recover()
+	return l.limiter.Wait(ctx)
+}
+
+// Observe adjusts the rate to the result of the request to the apiserver taking the given action. The eviction API
+// also answers with 429 TooManyRequests if a PodDisruptionBudget refuses the eviction, which is no sign of
+// throttling, so failed evictions do not lower the rate.
+func (l *AdaptiveLimiter) Observe(action api.Action, err error) {
+	if !apierrors.IsTooManyRequests(err) {
+		l.mux.Lock()
+		l.throttled = 0
+		l.mux.Unlock()
+		l.recover()
+		return
+	}
+	if action == api.ActionEvict {
+		return
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.throttled++; l.throttled < sustainedThrottlingThreshold {
+		return
+	}
+	l.throttled = 0
+	limit := l.limiter.Limit() / 2
+	if min := l.maxRate * minDeletionRateFraction; limit < min {
+		limit = min
+	}
+	l.setLimit(limit)
+	klog.Warningf("The apiserver is throttling requests. Lowering the deletion rate to %.2f/s.", float64(limit))
+}
+
+// Limit returns the current rate of deletions per second.
+func (l *AdaptiveLimiter) Limit() float64 {
+	return float64(l.limiter.Limit())
+}
+
+// recover doubles the rate for every recovery interval passed since the last adjustment.
+func (l *AdaptiveLimiter) recover() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	limit := l.limiter.Limit()
+	if limit >= l.maxRate {
+		l.lastAdjust = l.clock.Now()
+		return
+	}
+	for l.clock.Since(l.lastAdjust) >= deletionRateRecoveryInterval && limit < l.maxRate {
+		limit *= 2
+		l.lastAdjust = l.lastAdjust.Add(deletionRateRecoveryInterval)
+	}
+	if limit > l.maxRate {
+		limit = l.maxRate
+	}
+	if limit != l.limiter.Limit() {
+		l.setLimit(limit)
+		klog.Infof("Raising the deletion rate to %.2f/s.", float64(limit))
+	}
+}
+
+func (l *AdaptiveLimiter) setLimit(limit rate.Limit) {
+	l.limiter.SetLimit(limit)
+	l.lastAdjust = l.clock.Now()
+	effectiveDeletionRate.Set(float64(limit))
+}
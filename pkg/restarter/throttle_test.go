@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	l := NewAdaptiveLimiter(8, fakeClock)
+	tooManyRequests := apierrors.NewTooManyRequests("throttled", 1)
+
+	l.Observe(api.ActionDelete, tooManyRequests)
+	l.Observe(api.ActionDelete, nil)
+	l.Observe(api.ActionDelete, tooManyRequests)
+	if l.Limit() != 8 {
+		t.Errorf("Expected the rate not to be lowered on single throttled requests but got %v", l.Limit())
+	}
+	for i := 0; i < sustainedThrottlingThreshold; i++ {
+		l.Observe(api.ActionEvict, tooManyRequests)
+	}
+	if l.Limit() != 8 {
+		t.Errorf("Expected the rate not to be lowered on evictions refused by a disruption budget but got %v", l.Limit())
+	}
+	l.Observe(api.ActionDelete, tooManyRequests)
+	l.Observe(api.ActionDelete, tooManyRequests)
+	if l.Limit() != 4 {
+		t.Errorf("Expected the rate to be halved after sustained throttling but got %v", l.Limit())
+	}
+	for i := 0; i < 10*sustainedThrottlingThreshold; i++ {
+		l.Observe(api.ActionRollout, tooManyRequests)
+	}
+	if l.Limit() != 0.5 {
+		t.Errorf("Expected the rate to be bounded by the minimum rate on sustained throttling but got %v", l.Limit())
+	}
+
+	l.Observe(api.ActionDelete, nil)
+	if l.Limit() != 0.5 {
+		t.Errorf("Expected the rate not to recover before the recovery interval but got %v", l.Limit())
+	}
+	fakeClock.Step(deletionRateRecoveryInterval)
+	l.Observe(api.ActionDelete, nil)
+	if l.Limit() != 1 {
+		t.Errorf("Expected the rate to be doubled after the recovery interval but got %v", l.Limit())
+	}
+	fakeClock.Step(10 * deletionRateRecoveryInterval)
+	l.Observe(api.ActionDelete, nil)
+	if l.Limit() != 8 {
+		t.Errorf("Expected the rate to recover to the configured rate but got %v", l.Limit())
+	}
+}
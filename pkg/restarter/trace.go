@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Tracer starts spans around the reconciles and the pod deletions. Its shape follows the OpenTelemetry tracer,
+// so that an OpenTelemetry tracer provider can be plugged in with a thin adapter.
+type Tracer interface {
+	// Start starts a span with the given name and attributes. The span is the child of the span in the
+	// context, if any, and the returned context carries the new span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+const (
+	spanReconcile = "dependency-watchdog.reconcile"
+	spanDeletePod = "dependency-watchdog.delete-pod"
+)
+
+type noopTracer struct{}
+
+type noopSpan struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopSpan) RecordError(error) {}
+
+func (noopSpan) End() {}
+
+// tracer returns the configured tracer or a no-op one.
+func (c *Controller) tracer() Tracer {
+	if c.Tracer != nil {
+		return c.Tracer
+	}
+	return noopTracer{}
+}
+
+// deletePodInSpan deletes the pod within a span which is a child of the reconcile span in the context. It returns the
+// outcome of the deletion recorded in the audit log.
+func (c *Controller) deletePodInSpan(ctx context.Context, po *v1.Pod, service, reason string, depPods *api.DependantPods) (string, error) {
+	_, span := c.tracer().Start(ctx, spanDeletePod,
+		Attribute{Key: "namespace", Value: po.Namespace},
+		Attribute{Key: "service", Value: service},
+		Attribute{Key: "pod", Value: po.Name},
+		Attribute{Key: "reason", Value: reason},
+	)
+	defer span.End()
+	outcome, err := c.recyclePodForReason(ctx, po, service, reason, depPods)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return outcome, err
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// recordedSpan is a span recorded in memory by the recordingTracer.
+type recordedSpan struct {
+	name   string
+	parent *recordedSpan
+	attrs  []Attribute
+	ended  bool
+}
+
+type recordedSpanKey struct{}
+
+type recordingTracer struct {
+	mux   sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	parent, _ := ctx.Value(recordedSpanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attrs: attrs}
+	r.spans = append(r.spans, span)
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+func (s *recordedSpan) RecordError(error) {}
+
+func (s *recordedSpan) End() { s.ended = true }
+
+func TestDeletionsAreTracedWithinReconcile(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	pod := newPodInCrashloop("pod-0", labels)
+	tracer := &recordingTracer{}
+	c := &Controller{clientset: fake.NewSimpleClientset(pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)), Tracer: tracer}
+
+	ctx, reconcile := tracer.Start(context.TODO(), spanReconcile)
+	if err := c.processPod(ctx, pod, "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	reconcile.End()
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("Expected a reconcile and a deletion span but got %d spans", len(tracer.spans))
+	}
+	deletion := tracer.spans[1]
+	if deletion.name != spanDeletePod || deletion.parent != tracer.spans[0] || !deletion.ended {
+		t.Errorf("Expected an ended deletion span within the reconcile span but got %+v", deletion)
+	}
+	expectedAttrs := []Attribute{
+		{Key: "namespace", Value: "default"},
+		{Key: "service", Value: "kube-apiserver"},
+		{Key: "pod", Value: "pod-0"},
+		{Key: "reason", Value: "pod is unhealthy"},
+	}
+	if !reflect.DeepEqual(deletion.attrs, expectedAttrs) {
+		t.Errorf("Expected attributes %v but got %v", expectedAttrs, deletion.attrs)
+	}
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"net/http"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+// TriggerReconcile requests an immediate reconcile of all the configured services.
+// It does not block and triggers arriving while a previous one is still pending are coalesced.
+func (c *Controller) TriggerReconcile() {
+	select {
+	case c.reconcileTrigger <- struct{}{}:
+		klog.Info("Triggered an immediate reconcile of all services")
+	default:
+		klog.V(4).Info("An immediate reconcile of all services is already pending")
+	}
+}
+
+// ReconcileHandler returns an HTTP handler which triggers an immediate reconcile of all the
+// configured services on POST requests.
+func (c *Controller) ReconcileHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		c.TriggerReconcile()
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// handleReconcileTriggers enqueues all the configured services whenever a reconcile is triggered
+// until the stop channel is closed.
+func (c *Controller) handleReconcileTriggers() {
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-c.reconcileTrigger:
+			c.enqueueAllEndpoints()
+		}
+	}
+}
+
+// enqueueAllEndpoints puts the endpoints of all the configured services onto the work queue
+// bypassing the rate limiter.
+func (c *Controller) enqueueAllEndpoints() {
+	var (
+		eps  []*v1.Endpoints
+		err  error
+		deps = c.getServiceDependants()
+	)
+	if deps.Namespace != "" {
+		eps, err = c.endpointLister.Endpoints(deps.Namespace).List(labels.Everything())
+	} else {
+		eps, err = c.endpointLister.List(labels.Everything())
+	}
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, ep := range eps {
+		if _, ok := deps.Services[ep.Name]; !ok {
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(ep)
+		if err != nil {
+			utilruntime.HandleError(err)
+			continue
+		}
+		c.workqueue.Add(key)
+	}
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTriggerReconcileEnqueuesServices(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	deps, err := api.Decode([]byte(dep))
+	if err != nil {
+		t.Fatalf("error decoding file: %v", err)
+	}
+	client := fake.NewSimpleClientset()
+	c := NewController(client, nil, informers.NewSharedInformerFactory(client, 0), deps, watchDuration, stopCh)
+	c.endpointInformer.GetIndexer().Add(newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+	c.endpointInformer.GetIndexer().Add(newEndpoint("unrelated", metav1.NamespaceDefault, nil))
+
+	rec := httptest.NewRecorder()
+	c.ReconcileHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reconcile", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d for GET but got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+
+	// Concurrent triggers are coalesced.
+	for i := 0; i < 3; i++ {
+		rec = httptest.NewRecorder()
+		c.ReconcileHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reconcile", nil))
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("Expected status %d for POST but got %d", http.StatusAccepted, rec.Code)
+		}
+	}
+	if len(c.reconcileTrigger) != 1 {
+		t.Errorf("Expected exactly one pending trigger but got %d", len(c.reconcileTrigger))
+	}
+
+	go c.handleReconcileTriggers()
+	err = wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return c.workqueue.Len() == 1, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the configured service to be enqueued promptly but the queue has %d items", c.workqueue.Len())
+	}
+	key, _ := c.workqueue.Get()
+	if key != "default/kube-apiserver" {
+		t.Errorf("Expected default/kube-apiserver to be enqueued but got %v", key)
+	}
+}
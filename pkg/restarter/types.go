@@ -5,34 +5,106 @@
 package restarter
 
 import (
+	"sync"
 	"time"
 
 	"github.com/gardener/dependency-watchdog/pkg/multicontext"
 	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	listerv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	componentbaseconfig "k8s.io/component-base/config/v1alpha1"
 )
 
+var defaultExcludedNamespaces = []string{metav1.NamespaceSystem, metav1.NamespacePublic, v1.NamespaceNodeLease}
+
 const (
-	crashLoopBackOff = "CrashLoopBackOff"
+	// terminatedWithError is the reason of containers which terminated with a non-zero exit code, including the
+	// containers killed by the kubelet.
+	terminatedWithError = "Error"
+	// podDisruptionTarget is the type of the condition of pods which are about to be deleted because of a disruption.
+	podDisruptionTarget v1.PodConditionType = "DisruptionTarget"
+
+	defaultConcurrentReconciles = 2
+	defaultMaxDeletionsPerOwner = 1
+	defaultFieldManager         = "dependency-watchdog"
+	defaultRecoveryActionWindow = time.Minute
+	defaultServiceAccountName   = "default"
+
+	reconcileRequestedAtAnnotationKey = "dependency-watchdog.gardener.cloud/reconcile-requested-at"
+	recoveryCountAnnotationKey        = "dependency-watchdog.gardener.cloud/recovery-count"
+	actionAnnotationKey               = "dependency-watchdog.gardener.cloud/action"
+	ignoreAnnotationKey               = "dependency-watchdog.gardener.cloud/ignore"
+	holdAcknowledgedAnnotationKey     = "dependency-watchdog.gardener.cloud/hold-acknowledged"
+	restartedAtAnnotationKey          = "kubectl.kubernetes.io/restartedAt"
 )
 
 // Controller looks at ServiceDependants and reconciles the dependantPods once the service becomes available.
 type Controller struct {
-	clientset         kubernetes.Interface
-	informerFactory   informers.SharedInformerFactory
-	endpointInformer  cache.SharedIndexInformer
-	endpointLister    listerv1.EndpointsLister
-	workqueue         workqueue.RateLimitingInterface
-	hasSynced         cache.InformerSynced
-	stopCh            <-chan struct{}
-	serviceDependants *api.ServiceDependants
-	watchDuration     time.Duration
+	clientset          kubernetes.Interface
+	dynamicClient      dynamic.Interface
+	informerFactory    informers.SharedInformerFactory
+	endpointInformer   cache.SharedIndexInformer
+	endpointLister     listerv1.EndpointsLister
+	workqueue          workqueue.RateLimitingInterface
+	hasSynced          cache.InformerSynced
+	stopCh             <-chan struct{}
+	serviceDependants  *api.ServiceDependants
+	configLock         sync.RWMutex
+	watchDuration      time.Duration
+	clock              clock.Clock
+	ownerChanges       *ownerChangeCache
+	flapDetector       *FlapDetector
+	endpointsChanges   *endpointsChangeTracker
+	reconcileSlots     chan struct{}
+	reconcileTrigger   chan struct{}
+	notifier           *webhookNotifier
+	ownerEvents        *ownerEvents
+	recycleBudgets     *recycleBudgets
+	serviceCooldowns   *serviceCooldowns
+	notReady           *notReadyTracker
+	deletionLimiter    *AdaptiveLimiter
+	podMetrics         *podMetrics
+	recoveryHistory    *recoveryHistory
+	readinessOverrides *readinessOverrides
+	readyStability     *readyStabilityTracker
+	reconcileSummaries *reconcileSummaries
+	dryRunReport       *dryRunReport
+	retryBudgets       *retryBudgets
+	lostAnnotations    *lostAnnotations
 	// LeaderElection defines the configuration of leader election client.
 	LeaderElection componentbaseconfig.LeaderElectionConfiguration
+	// Recorder records events for the deleted pods and their owners if set.
+	Recorder record.EventRecorder
+	// CandidateConfig is evaluated in addition to the active config if set. The differences in the decisions
+	// are reported without acting on them. It is replaced through ReloadCandidateConfig once the controller runs.
+	CandidateConfig *api.ServiceDependants
+	// RecyclePolicy decides which dependant pods are recycled. The DefaultRecyclePolicy is used if it is not set.
+	// Custom policies can be chained with the default one using AllOf and AnyOf.
+	RecyclePolicy RecyclePolicy
+	// ReadinessPredicates are the custom readiness predicates the services can select by name besides the built-in
+	// ones. They take precedence over the built-in predicates of the same name.
+	ReadinessPredicates map[string]ReadinessPredicateFactory
+	// Tracer traces the reconciles and the pod deletions if set.
+	Tracer Tracer
+	// AuditLog records the decisions on the dependant pods if set.
+	AuditLog *AuditLog
+	// FieldManager is the name of the manager recorded for the changes made by the controller.
+	FieldManager string
+	// NamespaceAllowlist restricts the reconciled namespaces independently of the config if set.
+	NamespaceAllowlist *NamespaceAllowlist
+	// NotReadyTimeoutHandler takes the alternate action for the services which stay not ready for longer than their
+	// maximum not-ready duration if set. Otherwise, the timeout is only logged and counted.
+	NotReadyTimeoutHandler NotReadyTimeoutHandler
+	// EnableDebugOverrides takes the readiness forced through the DebugDependencyHandler into account, e.g. for the
+	// integration tests of downstream systems. It must never be enabled in production.
+	EnableDebugOverrides bool
 	*multicontext.Multicontext
 }
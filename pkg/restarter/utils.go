@@ -5,21 +5,195 @@
 package restarter
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
 )
 
+// Format describes the serialization format of a config-file.
+type Format int
+
+const (
+	// FormatAuto accepts YAML as well as JSON config-files and decodes them leniently.
+	FormatAuto Format = iota
+	// FormatYAML decodes the config-file as YAML ignoring unknown fields.
+	FormatYAML
+	// FormatJSON decodes the config-file as JSON rejecting unknown fields.
+	FormatJSON
+)
+
+// LoadOptions configures how the ServiceDependants are loaded from a config-file.
+type LoadOptions struct {
+	// Format is the format of the config-file. Defaults to FormatAuto.
+	Format Format
+	// Template renders the config-file as a text/template before decoding it. The environment
+	// variables and the Values can be referenced by their name, e.g. {{ .NAMESPACE_PREFIX }}.
+	Template bool
+	// Values are made available to the template in addition to the environment variables,
+	// taking precedence over them.
+	Values map[string]string
+}
+
 // LoadServiceDependants creates the ServiceDependants from a config-file.
 func LoadServiceDependants(file string) (*api.ServiceDependants, error) {
+	return LoadServiceDependantsWithOptions(file, LoadOptions{})
+}
+
+// LoadServiceDependantsWithOptions creates the ServiceDependants from a config-file
+// using the given load options.
+func LoadServiceDependantsWithOptions(file string, opts LoadOptions) (*api.ServiceDependants, error) {
 	data, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
-	return api.Decode(data)
+	if opts.Template {
+		if data, err = renderConfigTemplate(data, opts.Values); err != nil {
+			return nil, err
+		}
+	}
+	return decodeServiceDependants(data, opts)
+}
+
+// renderConfigTemplate renders the config as a text/template with the environment variables
+// and the given values. Referencing a missing key is an error.
+func renderConfigTemplate(data []byte, values map[string]string) ([]byte, error) {
+	tmpl, err := template.New("config").Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config template: %v", err)
+	}
+
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if i := strings.Index(kv, "="); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	for k, v := range values {
+		env[k] = v
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, env); err != nil {
+		return nil, fmt.Errorf("error rendering config template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// LoadServiceDependantsFromDir creates the ServiceDependants by merging all the YAML and JSON
+// config-files in the given directory. Services configured in more than one file as well as
+// conflicting settings result in an error.
+func LoadServiceDependantsFromDir(dir string) (*api.ServiceDependants, error) {
+	return LoadServiceDependantsFromDirWithOptions(dir, LoadOptions{})
+}
+
+// LoadServiceDependantsFromDirWithOptions creates the ServiceDependants like LoadServiceDependantsFromDir
+// loading each config-file using the given load options.
+func LoadServiceDependantsFromDirWithOptions(dir string, opts LoadOptions) (*api.ServiceDependants, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &api.ServiceDependants{Services: make(map[string]api.Service)}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		switch filepath.Ext(f.Name()) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		deps, err := LoadServiceDependantsWithOptions(filepath.Join(dir, f.Name()), opts)
+		if err != nil {
+			return nil, fmt.Errorf("error loading config-file %s: %v", f.Name(), err)
+		}
+		if err := mergeServiceDependants(merged, deps); err != nil {
+			return nil, fmt.Errorf("error merging config-file %s: %v", f.Name(), err)
+		}
+	}
+	return merged, nil
+}
+
+// mergeServiceDependants merges src into dst. Settings other than the services may be configured
+// in more than one config-file only if they have the same value. The overrides of the same namespace
+// are merged unless they set the same option to different values.
+func mergeServiceDependants(dst, src *api.ServiceDependants) error {
+	for name, srv := range src.Services {
+		if _, ok := dst.Services[name]; ok {
+			return fmt.Errorf("service %s is configured more than once", name)
+		}
+		dst.Services[name] = srv
+	}
+	for namespace, opts := range src.Namespaces {
+		if dst.Namespaces == nil {
+			dst.Namespaces = make(map[string]api.Options)
+		}
+		existing, ok := dst.Namespaces[namespace]
+		if !ok {
+			dst.Namespaces[namespace] = opts
+			continue
+		}
+		check := existing
+		if err := mergeFields(reflect.ValueOf(&check).Elem(), reflect.ValueOf(opts)); err != nil {
+			return fmt.Errorf("namespace %s: %v", namespace, err)
+		}
+		dst.Namespaces[namespace] = MergeOptions(existing, opts)
+	}
+
+	return mergeFields(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem())
+}
+
+// mergeFields sets the zero fields of dst to the corresponding fields of src. Embedded structs
+// are merged field by field.
+func mergeFields(dv, sv reflect.Value) error {
+	for i := 0; i < dv.NumField(); i++ {
+		field := dv.Type().Field(i)
+		if field.Name == "Services" || field.Name == "Namespaces" {
+			continue
+		}
+		df, sf := dv.Field(i), sv.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := mergeFields(df, sf); err != nil {
+				return err
+			}
+			continue
+		}
+		if sf.IsZero() {
+			continue
+		}
+		if df.IsZero() {
+			df.Set(sf)
+			continue
+		}
+		if !reflect.DeepEqual(df.Interface(), sf.Interface()) {
+			return fmt.Errorf("conflicting values for %s", strings.Split(field.Tag.Get("json"), ",")[0])
+		}
+	}
+	return nil
+}
+
+func decodeServiceDependants(data []byte, opts LoadOptions) (*api.ServiceDependants, error) {
+	switch opts.Format {
+	case FormatAuto, FormatYAML:
+		// YAML is a superset of JSON, so JSON content is decoded leniently as well.
+		return api.Decode(data)
+	case FormatJSON:
+		return api.DecodeStrictJSON(data)
+	default:
+		return nil, fmt.Errorf("unsupported config format: %d", opts.Format)
+	}
 }
 
 // IsPodAvailable returns true if a pod is available; false otherwise.
@@ -28,11 +202,18 @@ func LoadServiceDependants(file string) (*api.ServiceDependants, error) {
 // 1. minReadySeconds == 0, or
 // 2. LastTransitionTime (is set) + minReadySeconds < current time
 func IsPodAvailable(pod *v1.Pod, minReadySeconds int32, now metav1.Time) bool {
-	if !IsPodReady(pod) {
+	return IsPodAvailableByCondition(pod, v1.PodReady, minReadySeconds, now)
+}
+
+// IsPodAvailableByCondition returns true if a pod is available like IsPodAvailable, but evaluates the
+// readiness of the pod by the given condition type instead of PodReady. An empty type stands for PodReady.
+func IsPodAvailableByCondition(pod *v1.Pod, conditionType v1.PodConditionType, minReadySeconds int32, now metav1.Time) bool {
+	conditionType = readyConditionTypeOrDefault(conditionType)
+	if !IsPodConditionReady(pod, conditionType) {
 		return false
 	}
 
-	c := GetPodReadyCondition(pod.Status)
+	_, c := GetPodCondition(&pod.Status, conditionType)
 	minReadySecondsDuration := time.Duration(minReadySeconds) * time.Second
 	if minReadySeconds == 0 || !c.LastTransitionTime.IsZero() && c.LastTransitionTime.Add(minReadySecondsDuration).Before(now.Time) {
 		return true
@@ -40,11 +221,81 @@ func IsPodAvailable(pod *v1.Pod, minReadySeconds int32, now metav1.Time) bool {
 	return false
 }
 
+// IsPodAvailableStrict returns true if a pod is available like IsPodAvailable, but does not trust a
+// LastTransitionTime of the Ready condition more than allowFutureSkew after now. Such a pod is
+// not considered available as the clocks are probably skewed.
+func IsPodAvailableStrict(pod *v1.Pod, minReadySeconds int32, now metav1.Time, allowFutureSkew time.Duration) bool {
+	if !IsPodReady(pod) {
+		return false
+	}
+
+	c := GetPodReadyCondition(pod.Status)
+	if c.LastTransitionTime.Time.After(now.Add(allowFutureSkew)) {
+		klog.Warningf("Ready condition of pod %s/%s transitioned at %s which is in the future. The clocks might be skewed.", pod.Namespace, pod.Name, c.LastTransitionTime)
+		return false
+	}
+	return IsPodAvailable(pod, minReadySeconds, now)
+}
+
 // IsPodReady returns true if a pod is ready; false otherwise.
 func IsPodReady(pod *v1.Pod) bool {
 	return IsPodReadyConditionTrue(pod.Status)
 }
 
+// ReadyState is the readiness of a pod according to its Ready condition.
+type ReadyState string
+
+const (
+	// ReadyStateTrue is the state of a pod whose Ready condition is true.
+	ReadyStateTrue ReadyState = "True"
+	// ReadyStateFalse is the state of a pod whose Ready condition is false.
+	ReadyStateFalse ReadyState = "False"
+	// ReadyStateUnknown is the state of a pod without a Ready condition or with an unknown one.
+	ReadyStateUnknown ReadyState = "Unknown"
+)
+
+// PodReadyState returns the readiness of the pod, distinguishing a pod which is not ready from a pod whose readiness
+// is not known yet.
+func PodReadyState(pod *v1.Pod) ReadyState {
+	condition := GetPodReadyCondition(pod.Status)
+	switch {
+	case condition == nil || condition.Status == v1.ConditionUnknown:
+		return ReadyStateUnknown
+	case condition.Status == v1.ConditionTrue:
+		return ReadyStateTrue
+	default:
+		return ReadyStateFalse
+	}
+}
+
+// AreAllContainersReady returns true if all the containers of a pod are ready according to its ContainersReady
+// condition. Unlike the Ready condition, it is not affected by the readiness gates of the pod.
+func AreAllContainersReady(pod *v1.Pod) bool {
+	return IsPodConditionTrue(pod, v1.ContainersReady)
+}
+
+// isDependantPodAvailable checks if the dependant pod is available according to the ready condition type of
+// the dependant pods and, if required, all of its containers are ready as well.
+func isDependantPodAvailable(pod *v1.Pod, depPods *api.DependantPods, minReadySeconds int32, now metav1.Time) bool {
+	if depPods.RequireAllContainersReady && !AreAllContainersReady(pod) {
+		return false
+	}
+	return IsPodAvailableByCondition(pod, depPods.ReadyConditionType, minReadySeconds, now)
+}
+
+// IsPodConditionReady returns true if the readiness condition of the given type of a pod is true, e.g. a
+// custom condition signalling the readiness of a workload. An empty type stands for PodReady.
+func IsPodConditionReady(pod *v1.Pod, conditionType v1.PodConditionType) bool {
+	return IsPodConditionTrue(pod, readyConditionTypeOrDefault(conditionType))
+}
+
+func readyConditionTypeOrDefault(conditionType v1.PodConditionType) v1.PodConditionType {
+	if conditionType == "" {
+		return v1.PodReady
+	}
+	return conditionType
+}
+
 // IsPodDeleted returns true if a pod is deleted; false otherwise.
 func IsPodDeleted(pod *v1.Pod) bool {
 	return pod.DeletionTimestamp != nil
@@ -56,6 +307,22 @@ func IsPodReadyConditionTrue(status v1.PodStatus) bool {
 	return condition != nil && condition.Status == v1.ConditionTrue
 }
 
+// IsPodConditionTrue returns true if the pod has the given condition type with status true; false otherwise.
+func IsPodConditionTrue(pod *v1.Pod, conditionType v1.PodConditionType) bool {
+	_, condition := GetPodCondition(&pod.Status, conditionType)
+	return condition != nil && condition.Status == v1.ConditionTrue
+}
+
+// GetPodConditionReason returns the reason of the given condition type of the pod.
+// Returns an empty string if the condition is not present.
+func GetPodConditionReason(pod *v1.Pod, conditionType v1.PodConditionType) string {
+	_, condition := GetPodCondition(&pod.Status, conditionType)
+	if condition == nil {
+		return ""
+	}
+	return condition.Reason
+}
+
 // GetPodReadyCondition extracts the pod ready condition from the given status and returns that.
 // Returns nil if the condition is not present.
 func GetPodReadyCondition(status v1.PodStatus) *v1.PodCondition {
@@ -86,10 +353,99 @@ func GetPodConditionFromList(conditions []v1.PodCondition, conditionType v1.PodC
 	return -1, nil
 }
 
-// ShouldDeletePod checks if the pod is in CrashloopBackoff and decides to delete the pod if its is
-// not already deleted.
-func ShouldDeletePod(pod *v1.Pod) bool {
-	return !IsPodDeleted(pod) && IsPodInCrashloopBackoff(pod.Status)
+// IsPodInTerminalPhase returns true if the pod has either succeeded or failed.
+func IsPodInTerminalPhase(pod *v1.Pod) bool {
+	return pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed
+}
+
+// ControllerOwnerRef returns the owner reference of the pod which is marked as its controller. Pods with several
+// owner references marked as controller are treated as not controlled, so that the owner-based decisions never act
+// on a guessed owner. Owner references which are not marked as controller are never used.
+func ControllerOwnerRef(pod *v1.Pod) *metav1.OwnerReference {
+	return getControllerOwnerRef(pod)
+}
+
+// getControllerOwnerRef returns the owner reference of the object which is marked as its controller, if there is
+// exactly one.
+func getControllerOwnerRef(obj metav1.Object) *metav1.OwnerReference {
+	var controller *metav1.OwnerReference
+	refs := obj.GetOwnerReferences()
+	for i := range refs {
+		if refs[i].Controller == nil || !*refs[i].Controller {
+			continue
+		}
+		if controller != nil {
+			klog.Warningf("%s/%s has multiple controllers %s %s and %s %s. Treating it as not controlled.",
+				obj.GetNamespace(), obj.GetName(), controller.Kind, controller.Name, refs[i].Kind, refs[i].Name)
+			return nil
+		}
+		ref := refs[i]
+		controller = &ref
+	}
+	return controller
+}
+
+// isPodRecreatedByOwner returns true if the pod is controlled by an owner which recreates it
+// after deletion. Completed pods of a Job are not recreated.
+func isPodRecreatedByOwner(pod *v1.Pod) bool {
+	owner := ControllerOwnerRef(pod)
+	return owner != nil && owner.Kind != "Job"
+}
+
+// AllPodsShouldBeDeleted returns true if the given set of pods is not empty and every one of
+// the pods should be deleted according to the dependant pods.
+func AllPodsShouldBeDeleted(pods []v1.Pod, depPods *api.DependantPods) bool {
+	if len(pods) == 0 {
+		return false
+	}
+	for i := range pods {
+		if !shouldDeleteDependantPod(&pods[i], depPods) {
+			return false
+		}
+	}
+	return true
+}
+
+// GroupPodsByNode groups the given pods by the name of the node they are scheduled to.
+func GroupPodsByNode(pods []v1.Pod) map[string][]v1.Pod {
+	groups := make(map[string][]v1.Pod)
+	for i := range pods {
+		groups[pods[i].Spec.NodeName] = append(groups[pods[i].Spec.NodeName], pods[i])
+	}
+	return groups
+}
+
+// GetNodesWithLocalizedCrashloops returns the nodes hosting at least the given fraction of the pods in
+// CrashloopBackoff, provided that more than one pod is crashlooping on such a node and pods on other nodes are fine.
+func GetNodesWithLocalizedCrashloops(pods []v1.Pod, fraction float64) []string {
+	var (
+		crashlooping = make(map[string]int)
+		total        int
+		nodes        []string
+	)
+	for node, nodePods := range GroupPodsByNode(pods) {
+		for i := range nodePods {
+			if IsPodInCrashloopBackoff(nodePods[i].Status) {
+				crashlooping[node]++
+				total++
+			}
+		}
+	}
+	for node, count := range crashlooping {
+		if count > 1 && float64(count) >= fraction*float64(total) && hasHealthyPodsOnOtherNodes(pods, node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func hasHealthyPodsOnOtherNodes(pods []v1.Pod, node string) bool {
+	for i := range pods {
+		if pods[i].Spec.NodeName != node && !IsPodInCrashloopBackoff(pods[i].Status) {
+			return true
+		}
+	}
+	return false
 }
 
 // IsPodInCrashloopBackoff checks if the pod is in CrashloopBackoff from its status fields.
@@ -102,13 +458,359 @@ func IsPodInCrashloopBackoff(status v1.PodStatus) bool {
 	return false
 }
 
+// IsPodInInitCrashloopBackoff checks if the pod with restart policy OnFailure is stuck in its initialization, i.e. one
+// of its init containers is in CrashloopBackoff while none of its main containers is.
+func IsPodInInitCrashloopBackoff(pod *v1.Pod) bool {
+	if pod.Spec.RestartPolicy != v1.RestartPolicyOnFailure || IsPodInCrashloopBackoff(pod.Status) {
+		return false
+	}
+	for _, containerStatus := range pod.Status.InitContainerStatuses {
+		if isContainerInCrashLoopBackOff(containerStatus.State) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetContainerState returns the state of the container status from the given source. Note that a container in
+// CrashLoopBackOff is only visible as waiting in the current state, while the exit code of its last run is only
+// available in the last termination state.
+func GetContainerState(status v1.ContainerStatus, source api.ContainerStateSource) v1.ContainerState {
+	if source == api.ContainerStateLastTermination {
+		return status.LastTerminationState
+	}
+	return status.State
+}
+
+// GetContainerExitCode returns the exit code of the container in the state from the given source, if it terminated.
+func GetContainerExitCode(status v1.ContainerStatus, source api.ContainerStateSource) (int32, bool) {
+	state := GetContainerState(status, source)
+	if state.Terminated == nil {
+		return 0, false
+	}
+	return state.Terminated.ExitCode, true
+}
+
+// HasContainerExitedWith checks if one of the containers terminated with one of the exit codes in the state
+// from the given source.
+func HasContainerExitedWith(status v1.PodStatus, exitCodes []int32, source api.ContainerStateSource) bool {
+	for _, containerStatus := range status.ContainerStatuses {
+		exitCode, ok := GetContainerExitCode(containerStatus, source)
+		if !ok {
+			continue
+		}
+		for _, code := range exitCodes {
+			if exitCode == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getExitCodeSource returns the configured source of the exit codes of the dependant pods.
+func getExitCodeSource(depPods *api.DependantPods) api.ContainerStateSource {
+	if depPods.ExitCodeSource != nil {
+		return *depPods.ExitCodeSource
+	}
+	return api.ContainerStateLastTermination
+}
+
 func isContainerInCrashLoopBackOff(containerState v1.ContainerState) bool {
 	if containerState.Waiting != nil {
-		return containerState.Waiting.Reason == crashLoopBackOff
+		return containerState.Waiting.Reason == ReasonCrashLoopBackOff
 	}
 	return false
 }
 
+// IsContainerLivenessKilled checks if the previous run of the container with the given status was killed by the kubelet
+// because of its failing liveness probe, i.e. the container has a liveness probe and was terminated by SIGKILL or
+// SIGTERM. The kubelet does not record the cause of the kill, so other kills of the container are not distinguished.
+func IsContainerLivenessKilled(pod *v1.Pod, status v1.ContainerStatus) bool {
+	terminated := status.LastTerminationState.Terminated
+	if terminated == nil || terminated.Reason != terminatedWithError || terminated.ExitCode != 137 && terminated.ExitCode != 143 {
+		return false
+	}
+	for _, container := range pod.Spec.Containers {
+		if container.Name == status.Name {
+			return container.LivenessProbe != nil
+		}
+	}
+	return false
+}
+
+// isPodLivenessKilled checks if one of the containers of the pod was killed because of its failing liveness probe.
+func isPodLivenessKilled(pod *v1.Pod) bool {
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if IsContainerLivenessKilled(pod, containerStatus) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesRestartRule returns true and the first of the rules matching the pod. A rule matches if the pod
+// is controlled by an owner of its kind and one of its containers is waiting for one of its reasons. Only the
+// direct controller of the pod is considered, e.g. the ReplicaSet and not the Deployment owning it.
+func MatchesRestartRule(pod *v1.Pod, rules []api.RestartRule) (bool, api.RestartRule) {
+	var ownerKind string
+	if owner := ControllerOwnerRef(pod); owner != nil {
+		ownerKind = owner.Kind
+	}
+	for _, rule := range rules {
+		if rule.OwnerKind != "" && rule.OwnerKind != ownerKind {
+			continue
+		}
+		for _, reason := range rule.RestartReasons {
+			if isContainerWaitingFor(pod.Status, reason) {
+				return true, rule
+			}
+		}
+	}
+	return false, api.RestartRule{}
+}
+
+func isContainerWaitingFor(status v1.PodStatus, reason string) bool {
+	for _, containerStatus := range status.ContainerStatuses {
+		if containerStatus.State.Waiting != nil && containerStatus.State.Waiting.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// ReasonThresholdMet checks if the container is waiting for one of the reasons in thresholds and
+// has been restarted at least as often as the threshold of that reason.
+func ReasonThresholdMet(status v1.ContainerStatus, thresholds map[string]int32) bool {
+	if status.State.Waiting == nil {
+		return false
+	}
+	threshold, ok := thresholds[status.State.Waiting.Reason]
+	return ok && status.RestartCount >= threshold
+}
+
+// ContainerRestartRate returns the number of restarts per minute of the container since the given start time of
+// its pod. The container status does not carry the age of the container, the pod start time stands in for it.
+func ContainerRestartRate(status v1.ContainerStatus, startTime, now metav1.Time) float64 {
+	if startTime.IsZero() || status.RestartCount == 0 {
+		return 0
+	}
+	age := now.Sub(startTime.Time)
+	if age < time.Minute {
+		age = time.Minute
+	}
+	return float64(status.RestartCount) / age.Minutes()
+}
+
+// isRestartRateExceeded checks if one of the containers of the pod restarts at least as often as the given rate.
+func isRestartRateExceeded(pod *v1.Pod, rate float64, now metav1.Time) bool {
+	startTime := pod.CreationTimestamp
+	if pod.Status.StartTime != nil {
+		startTime = *pod.Status.StartTime
+	}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if ContainerRestartRate(containerStatus, startTime, now) >= rate {
+			return true
+		}
+	}
+	return false
+}
+
+// PodMatchesPredicate checks if the pod is in the phase of the predicate and all the conditions of the
+// predicate have been in the required state for at least the duration of the predicate.
+func PodMatchesPredicate(pod *v1.Pod, p api.PodPredicate, now metav1.Time) bool {
+	since, ok := podMatchesPredicateSince(pod, p)
+	return ok && (p.For == nil || !since.Add(p.For.Duration).After(now.Time))
+}
+
+// podMatchesPredicateSince returns the time since which the pod matches the phase and the conditions of the
+// predicate, regardless of its duration.
+func podMatchesPredicateSince(pod *v1.Pod, p api.PodPredicate) (time.Time, bool) {
+	if p.Phase != "" && pod.Status.Phase != p.Phase {
+		return time.Time{}, false
+	}
+	since := pod.CreationTimestamp.Time
+	for i, required := range p.Conditions {
+		_, c := GetPodCondition(&pod.Status, required.Type)
+		if c == nil || c.Status != required.Status || required.Reason != "" && c.Reason != required.Reason {
+			return time.Time{}, false
+		}
+		if i == 0 || c.LastTransitionTime.After(since) {
+			since = c.LastTransitionTime.Time
+		}
+	}
+	return since, true
+}
+
+// IsPodUnschedulable checks if the scheduler failed to find a node for the pod.
+func IsPodUnschedulable(pod *v1.Pod) bool {
+	_, c := GetPodCondition(&pod.Status, v1.PodScheduled)
+	return c != nil && c.Status == v1.ConditionFalse && c.Reason == v1.PodReasonUnschedulable
+}
+
+// IsPodUnschedulableFor checks if the pod has been unschedulable for at least the given duration.
+func IsPodUnschedulableFor(pod *v1.Pod, threshold time.Duration, now time.Time) bool {
+	if !IsPodUnschedulable(pod) {
+		return false
+	}
+	_, c := GetPodCondition(&pod.Status, v1.PodScheduled)
+	return !c.LastTransitionTime.Add(threshold).After(now)
+}
+
+// NextEligibleTime returns the time at which the pod becomes unhealthy according to the dependant pods only by the
+// time passing, i.e. once it has matched a predicate, has been unschedulable or waiting for its volumes for long
+// enough, if it is going to.
+func NextEligibleTime(pod *v1.Pod, depPods *api.DependantPods, now time.Time) (time.Time, bool) {
+	var (
+		next time.Time
+		ok   bool
+	)
+	consider := func(t time.Time) {
+		if t.After(now) && (!ok || t.Before(next)) {
+			next, ok = t, true
+		}
+	}
+	for _, p := range depPods.Predicates {
+		if since, matches := podMatchesPredicateSince(pod, p); matches && p.For != nil {
+			consider(since.Add(p.For.Duration))
+		}
+	}
+	if depPods.UnschedulableThreshold != nil && IsPodUnschedulable(pod) {
+		_, c := GetPodCondition(&pod.Status, v1.PodScheduled)
+		consider(c.LastTransitionTime.Add(depPods.UnschedulableThreshold.Duration))
+	}
+	if depPods.VolumeMountThreshold != nil {
+		if since, ok := podWaitingForVolumesSince(pod); ok {
+			consider(since.Add(depPods.VolumeMountThreshold.Duration))
+		}
+	}
+	return next, ok
+}
+
+// IsPodIgnored checks if the pod is annotated to be ignored by the dependency-watchdog.
+func IsPodIgnored(pod *v1.Pod) bool {
+	return pod.Annotations[ignoreAnnotationKey] == "true"
+}
+
+// IsPodBeingEvicted checks if the pod has a true DisruptionTarget condition, i.e. it is about to be deleted by another
+// controller, e.g. evicted by the descheduler, the cluster autoscaler or because of a taint of its node.
+func IsPodBeingEvicted(pod *v1.Pod) bool {
+	_, condition := GetPodCondition(&pod.Status, podDisruptionTarget)
+	return condition != nil && condition.Status == v1.ConditionTrue
+}
+
+// HasEphemeralContainers checks if ephemeral containers, e.g. for debugging, were attached to the pod.
+func HasEphemeralContainers(pod *v1.Pod) bool {
+	return len(pod.Spec.EphemeralContainers) > 0
+}
+
+// PodUsesServiceAccount checks if the pod runs with the given service account. Pods without a service account
+// run with the default one.
+func PodUsesServiceAccount(pod *v1.Pod, sa string) bool {
+	name := pod.Spec.ServiceAccountName
+	if name == "" {
+		name = defaultServiceAccountName
+	}
+	return name == sa
+}
+
+// PodPriorityClassAllowed checks if the priority class of the pod is one of the allowed ones.
+func PodPriorityClassAllowed(pod *v1.Pod, allowed []string) bool {
+	for _, name := range allowed {
+		if pod.Spec.PriorityClassName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPodPriority returns the priority resolved for the pod by the apiserver. Pods without a priority have the
+// priority 0.
+func GetPodPriority(pod *v1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+// isDependantPod checks if the pod selected by the selector of the dependant pods also uses their service account and
+// one of their priority classes, if any.
+func isDependantPod(pod *v1.Pod, depPods *api.DependantPods) bool {
+	if len(depPods.PriorityClassNames) > 0 && !PodPriorityClassAllowed(pod, depPods.PriorityClassNames) {
+		return false
+	}
+	return depPods.ServiceAccountName == "" || PodUsesServiceAccount(pod, depPods.ServiceAccountName)
+}
+
+// isDependantPodUnhealthy checks if the pod matches the restart rules and reason thresholds of the
+// dependant pods, if any, or is in CrashloopBackoff otherwise. Pods matching one of the predicates of
+// the dependant pods, killed because of their liveness probe, stuck in an init container in CrashloopBackoff
+// reaching the health score threshold or waiting too long for their volumes, if configured, are unhealthy as well.
+// Pods restarting slower than the minimum restart rate are not unhealthy because of their restarts.
+func isDependantPodUnhealthy(pod *v1.Pod, depPods *api.DependantPods) bool {
+	now := metav1.Now()
+	for _, p := range depPods.Predicates {
+		if PodMatchesPredicate(pod, p, now) {
+			return true
+		}
+	}
+	if depPods.UnschedulableThreshold != nil && IsPodUnschedulableFor(pod, depPods.UnschedulableThreshold.Duration, now.Time) {
+		return true
+	}
+	if depPods.VolumeMountThreshold != nil && IsPodWaitingForVolumesFor(pod, depPods.VolumeMountThreshold.Duration, now.Time) {
+		return true
+	}
+	if depPods.RecycleLivenessKilled && isPodLivenessKilled(pod) {
+		return true
+	}
+	if depPods.RecycleInitCrashloop && IsPodInInitCrashloopBackoff(pod) {
+		return true
+	}
+	if depPods.HealthScore != nil && isHealthScoreExceeded(pod, depPods.HealthScore, now) {
+		return true
+	}
+	if depPods.MinRestartRate != nil && !isRestartRateExceeded(pod, *depPods.MinRestartRate, now) {
+		return false
+	}
+	if len(depPods.RestartRules) == 0 && len(depPods.RestartReasonThresholds) == 0 {
+		return IsPodInCrashloopBackoff(pod.Status)
+	}
+	if len(depPods.RestartRules) > 0 {
+		if matches, _ := MatchesRestartRule(pod, depPods.RestartRules); !matches {
+			return false
+		}
+	}
+	if len(depPods.RestartReasonThresholds) == 0 {
+		return true
+	}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if ReasonThresholdMet(containerStatus, depPods.RestartReasonThresholds) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldDeleteDependantPod checks if the pod should be deleted according to the restart rules and reason
+// thresholds of the dependant pods, if any, or because it is in CrashloopBackoff otherwise. Ignored pods, pods
+// being evicted by other controllers, pods with a container which exited with one of the skipped exit codes and, if configured, pods with
+// ephemeral containers are never deleted.
+func shouldDeleteDependantPod(pod *v1.Pod, depPods *api.DependantPods) bool {
+	return !isDependantPodVetoed(pod, depPods) && isDependantPodUnhealthy(pod, depPods)
+}
+
+// isDependantPodVetoed checks if the pod must not be deleted whatever its health, e.g. because an operator
+// ignores it, it is already on its way out or it exited with one of the exit codes to skip.
+func isDependantPodVetoed(pod *v1.Pod, depPods *api.DependantPods) bool {
+	if !isDependantPod(pod, depPods) || IsPodIgnored(pod) || IsPodDeleted(pod) || IsPodBeingEvicted(pod) || IsPodInTerminalPhase(pod) && !isPodRecreatedByOwner(pod) {
+		return true
+	}
+	if len(depPods.SkipExitCodes) > 0 && HasContainerExitedWith(pod.Status, depPods.SkipExitCodes, getExitCodeSource(depPods)) {
+		return true
+	}
+	return depPods.SkipDebuggedPods && HasEphemeralContainers(pod)
+}
+
 // IsReadyEndpointPresentInSubsets checks if the endpoint resource have a subset of ready
 // IP endpoints.
 func IsReadyEndpointPresentInSubsets(subsets []v1.EndpointSubset) bool {
@@ -119,3 +821,167 @@ func IsReadyEndpointPresentInSubsets(subsets []v1.EndpointSubset) bool {
 	}
 	return false
 }
+
+// IsNamespaceExcluded checks if the namespace is one of the exclusions.
+func IsNamespaceExcluded(ns string, exclusions []string) bool {
+	for _, excluded := range exclusions {
+		if ns == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+// isNamespaceReconciled checks if the services in the namespace are reconciled. This is only the case for
+// the configured namespace or, if no namespace is configured, for all the namespaces which are not excluded.
+func isNamespaceReconciled(deps *api.ServiceDependants, namespace string) bool {
+	if deps.Namespace != "" {
+		return namespace == deps.Namespace
+	}
+	exclusions := deps.ExcludedNamespaces
+	if exclusions == nil {
+		exclusions = defaultExcludedNamespaces
+	}
+	return !IsNamespaceExcluded(namespace, exclusions)
+}
+
+func getConcurrentReconciles(deps *api.ServiceDependants) int {
+	if deps.ConcurrentReconciles != nil && *deps.ConcurrentReconciles > 0 {
+		return int(*deps.ConcurrentReconciles)
+	}
+	return defaultConcurrentReconciles
+}
+
+// MergeOptions returns the global options overridden by the options set in override.
+func MergeOptions(global, override api.Options) api.Options {
+	merged := global
+	if override.MaxEndpointsStaleness != nil {
+		merged.MaxEndpointsStaleness = override.MaxEndpointsStaleness
+	}
+	if override.DryRun != nil {
+		merged.DryRun = override.DryRun
+	}
+	if override.RecoveryEdgeOnly != nil {
+		merged.RecoveryEdgeOnly = override.RecoveryEdgeOnly
+	}
+	if override.RecoveryActionWindow != nil {
+		merged.RecoveryActionWindow = override.RecoveryActionWindow
+	}
+	if override.RecoveryGracePeriod != nil {
+		merged.RecoveryGracePeriod = override.RecoveryGracePeriod
+	}
+	if override.DeletionPropagation != nil {
+		merged.DeletionPropagation = override.DeletionPropagation
+	}
+	if override.RequireDependencyExists != nil {
+		merged.RequireDependencyExists = override.RequireDependencyExists
+	}
+	if override.ReportRecoveredCondition != nil {
+		merged.ReportRecoveredCondition = override.ReportRecoveredCondition
+	}
+	if override.Action != nil {
+		merged.Action = override.Action
+	}
+	if override.QuietHours != nil {
+		merged.QuietHours = override.QuietHours
+	}
+	if override.ReconcileTimeout != nil {
+		merged.ReconcileTimeout = override.ReconcileTimeout
+	}
+	if override.WarnBeforeDeleteDelay != nil {
+		merged.WarnBeforeDeleteDelay = override.WarnBeforeDeleteDelay
+	}
+	if override.WaitForDeletion != nil {
+		merged.WaitForDeletion = override.WaitForDeletion
+	}
+	if override.DependencySnapshotAnnotation != nil {
+		merged.DependencySnapshotAnnotation = override.DependencySnapshotAnnotation
+	}
+	if override.RecentOwnerChangeWindow != nil {
+		merged.RecentOwnerChangeWindow = override.RecentOwnerChangeWindow
+	}
+	if override.RetryBudget != nil {
+		merged.RetryBudget = override.RetryBudget
+	}
+	return merged
+}
+
+// getNamespaceOptions returns the options effective for the given namespace.
+func getNamespaceOptions(deps *api.ServiceDependants, namespace string) api.Options {
+	if deps == nil {
+		return api.Options{}
+	}
+	return MergeOptions(deps.Options, deps.Namespaces[namespace])
+}
+
+func getMaxEndpointsStaleness(opts api.Options) time.Duration {
+	if opts.MaxEndpointsStaleness != nil {
+		return opts.MaxEndpointsStaleness.Duration
+	}
+	return 0
+}
+
+func isDryRun(opts api.Options) bool {
+	return opts.DryRun != nil && *opts.DryRun
+}
+
+func isRecoveryEdgeOnly(opts api.Options) bool {
+	return opts.RecoveryEdgeOnly != nil && *opts.RecoveryEdgeOnly
+}
+
+func getRecoveryGracePeriod(opts api.Options) time.Duration {
+	if opts.RecoveryGracePeriod != nil {
+		return opts.RecoveryGracePeriod.Duration
+	}
+	return 0
+}
+
+// CheckDeletionPropagation verifies that the deletion propagation of the global options and the options of the
+// namespaces is supported, so that an unsupported one is revealed on load instead of on the first pod deletion.
+func CheckDeletionPropagation(deps *api.ServiceDependants) error {
+	if _, err := getDeletionPropagation(deps.Options); err != nil {
+		return err
+	}
+	for namespace, opts := range deps.Namespaces {
+		if _, err := getDeletionPropagation(opts); err != nil {
+			return fmt.Errorf("namespace %s: %v", namespace, err)
+		}
+	}
+	return nil
+}
+
+func getDeletionPropagation(opts api.Options) (*metav1.DeletionPropagation, error) {
+	if opts.DeletionPropagation == nil {
+		return nil, nil
+	}
+	switch *opts.DeletionPropagation {
+	case metav1.DeletePropagationBackground, metav1.DeletePropagationForeground, metav1.DeletePropagationOrphan:
+		return opts.DeletionPropagation, nil
+	default:
+		return nil, fmt.Errorf("unsupported deletion propagation %q", *opts.DeletionPropagation)
+	}
+}
+
+func isDependencyRequiredToExist(opts api.Options) bool {
+	return opts.RequireDependencyExists != nil && *opts.RequireDependencyExists
+}
+
+// getReconcileTimeout returns the configured reconcile timeout if it is shorter than the watch duration. The watch
+// duration bounds every reconcile, so that longer reconcile timeouts are capped to it.
+func getReconcileTimeout(opts api.Options, watchDuration time.Duration) time.Duration {
+	if opts.ReconcileTimeout != nil && opts.ReconcileTimeout.Duration < watchDuration {
+		return opts.ReconcileTimeout.Duration
+	}
+	return watchDuration
+}
+
+func isRecoveredConditionReported(opts api.Options) bool {
+	return opts.ReportRecoveredCondition != nil && *opts.ReportRecoveredCondition
+}
+
+func getRecoveryActionWindow(opts api.Options) time.Duration {
+	if opts.RecoveryActionWindow != nil {
+		return opts.RecoveryActionWindow.Duration
+	}
+	return defaultRecoveryActionWindow
+}
@@ -0,0 +1,929 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	yamlConfig = `namespace: default
+services:
+  kube-apiserver:
+    dependantPods:
+    - name: controlplane
+      selector:
+        matchLabels:
+          role: controlplane
+`
+	jsonConfig = `{"namespace":"default","services":{"kube-apiserver":{"dependantPods":[{"name":"controlplane","selector":{"matchLabels":{"role":"controlplane"}}}]}}}`
+
+	jsonConfigWithUnknownField = `{"namespace":"default","unknown":true,"services":{"kube-apiserver":{"dependantPods":[]}}}`
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("error writing config file: %v", err)
+	}
+	return file
+}
+
+func newTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "dwd-config")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	return dir
+}
+
+func TestLoadServiceDependantsWithOptions(t *testing.T) {
+	dir := newTempDir(t)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name      string
+		content   string
+		format    Format
+		expectErr bool
+	}{
+		{"auto yaml", yamlConfig, FormatAuto, false},
+		{"auto json", jsonConfig, FormatAuto, false},
+		{"auto json with unknown field", jsonConfigWithUnknownField, FormatAuto, false},
+		{"yaml", yamlConfig, FormatYAML, false},
+		{"yaml with json content", jsonConfigWithUnknownField, FormatYAML, false},
+		{"json", jsonConfig, FormatJSON, false},
+		{"json with unknown field", jsonConfigWithUnknownField, FormatJSON, true},
+		{"json with yaml content", yamlConfig, FormatJSON, true},
+		{"unsupported format", yamlConfig, Format(42), true},
+	}
+	for _, tc := range tests {
+		file := writeConfigFile(t, dir, "config", tc.content)
+		deps, err := LoadServiceDependantsWithOptions(file, LoadOptions{Format: tc.format})
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error but got none", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if deps.Namespace != "default" {
+			t.Errorf("%s: expected namespace default but got %q", tc.name, deps.Namespace)
+		}
+	}
+}
+
+func TestLoadServiceDependantsFromTemplate(t *testing.T) {
+	dir := newTempDir(t)
+	defer os.RemoveAll(dir)
+
+	const template = `namespace: {{ .NAMESPACE }}
+services:
+  kube-apiserver:
+    dependantPods:
+    - name: {{ .DEPENDANT }}
+      selector:
+        matchLabels:
+          role: controlplane
+`
+	os.Setenv("DEPENDANT", "controlplane")
+	defer os.Unsetenv("DEPENDANT")
+	file := writeConfigFile(t, dir, "config", template)
+
+	deps, err := LoadServiceDependantsWithOptions(file, LoadOptions{Template: true, Values: map[string]string{"NAMESPACE": "shoot--dev"}})
+	if err != nil {
+		t.Fatalf("error loading config template: %v", err)
+	}
+	if deps.Namespace != "shoot--dev" {
+		t.Errorf("Expected namespace shoot--dev from the values but got %q", deps.Namespace)
+	}
+	if name := deps.Services["kube-apiserver"].Dependants[0].Name; name != "controlplane" {
+		t.Errorf("Expected dependant controlplane from the environment but got %q", name)
+	}
+
+	if _, err := LoadServiceDependantsWithOptions(file, LoadOptions{Template: true}); err == nil {
+		t.Errorf("Expected an error for a missing template key")
+	}
+	file = writeConfigFile(t, dir, "invalid", "namespace: {{ .NAMESPACE ")
+	if _, err := LoadServiceDependantsWithOptions(file, LoadOptions{Template: true}); err == nil {
+		t.Errorf("Expected an error for an invalid template")
+	}
+
+	configDir := filepath.Join(dir, "config.d")
+	if err := os.Mkdir(configDir, 0755); err != nil {
+		t.Fatalf("error creating config dir: %v", err)
+	}
+	writeConfigFile(t, configDir, "config.yaml", template)
+	deps, err = LoadServiceDependantsFromDirWithOptions(configDir, LoadOptions{Template: true, Values: map[string]string{"NAMESPACE": "shoot--dev"}})
+	if err != nil {
+		t.Fatalf("error loading config templates from dir: %v", err)
+	}
+	if deps.Namespace != "shoot--dev" {
+		t.Errorf("Expected namespace shoot--dev from the values in the config dir but got %q", deps.Namespace)
+	}
+}
+
+func TestPodConditionHelpers(t *testing.T) {
+	pod := &v1.Pod{
+		Status: v1.PodStatus{
+			Conditions: []v1.PodCondition{
+				{Type: v1.ContainersReady, Status: v1.ConditionTrue},
+				{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: v1.PodReasonUnschedulable},
+			},
+		},
+	}
+	tests := []struct {
+		conditionType  v1.PodConditionType
+		expectedTrue   bool
+		expectedReason string
+	}{
+		{v1.ContainersReady, true, ""},
+		{v1.PodScheduled, false, v1.PodReasonUnschedulable},
+		{v1.PodInitialized, false, ""},
+	}
+	for _, tc := range tests {
+		if got := IsPodConditionTrue(pod, tc.conditionType); got != tc.expectedTrue {
+			t.Errorf("IsPodConditionTrue(%s): expected %v but got %v", tc.conditionType, tc.expectedTrue, got)
+		}
+		if got := GetPodConditionReason(pod, tc.conditionType); got != tc.expectedReason {
+			t.Errorf("GetPodConditionReason(%s): expected %q but got %q", tc.conditionType, tc.expectedReason, got)
+		}
+	}
+}
+
+func TestControllerOwnerRef(t *testing.T) {
+	isController, isNotController := true, false
+	statefulSet := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "etcd", Controller: &isController}
+	replicaSet := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "etcd-abc", Controller: &isController}
+	owner := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "etcd", Controller: &isNotController}
+	tests := []struct {
+		name     string
+		refs     []metav1.OwnerReference
+		expected *metav1.OwnerReference
+	}{
+		{"single controller", []metav1.OwnerReference{owner, statefulSet}, &statefulSet},
+		{"no owner", nil, nil},
+		{"no controller", []metav1.OwnerReference{owner}, nil},
+		{"multiple controllers", []metav1.OwnerReference{statefulSet, replicaSet}, nil},
+	}
+	for _, tc := range tests {
+		pod := newPod("etcd-0", "node-0")
+		pod.OwnerReferences = tc.refs
+		if actual := ControllerOwnerRef(pod); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: expected controller %v but got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestShouldDeletePodInTerminalPhase(t *testing.T) {
+	isController := true
+	tests := []struct {
+		phase          v1.PodPhase
+		ownerKind      string
+		expectedDelete bool
+	}{
+		{v1.PodRunning, "", true},
+		{v1.PodSucceeded, "", false},
+		{v1.PodFailed, "", false},
+		{v1.PodSucceeded, "Job", false},
+		{v1.PodFailed, "Job", false},
+		{v1.PodFailed, "ReplicaSet", true},
+	}
+	for _, tc := range tests {
+		pod := newPodInCrashloop("pod-0", nil)
+		pod.Status.Phase = tc.phase
+		if tc.ownerKind != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: tc.ownerKind, Name: "owner", Controller: &isController}}
+		}
+		if got := IsPodInTerminalPhase(pod); got != (tc.phase != v1.PodRunning) {
+			t.Errorf("IsPodInTerminalPhase(%s): unexpected result %v", tc.phase, got)
+		}
+		if got := shouldDeleteDependantPod(pod, &api.DependantPods{}); got != tc.expectedDelete {
+			t.Errorf("shouldDeleteDependantPod(%s, owner %q): expected %v but got %v", tc.phase, tc.ownerKind, tc.expectedDelete, got)
+		}
+	}
+}
+
+func TestLoadServiceDependantsFromDir(t *testing.T) {
+	const (
+		etcdConfig = `namespace: default
+services:
+  etcd-main-client:
+    dependantPods:
+    - name: apiserver
+      selector:
+        matchLabels:
+          role: apiserver
+`
+		otherNamespaceConfig = `namespace: other
+services:
+  etcd-events-client:
+    dependantPods: []
+`
+	)
+
+	dir := newTempDir(t)
+	defer os.RemoveAll(dir)
+	writeConfigFile(t, dir, "apiserver.yaml", yamlConfig)
+	writeConfigFile(t, dir, "etcd.json", `{"services":{"etcd-main-client":{"dependantPods":[{"name":"apiserver"}]}}}`)
+	writeConfigFile(t, dir, "README.md", "not a config-file")
+
+	deps, err := LoadServiceDependantsFromDir(dir)
+	if err != nil {
+		t.Fatalf("error loading config dir: %v", err)
+	}
+	if deps.Namespace != "default" {
+		t.Errorf("Expected namespace default but got %q", deps.Namespace)
+	}
+	if len(deps.Services) != 2 {
+		t.Errorf("Expected 2 merged services but got %d", len(deps.Services))
+	}
+
+	writeConfigFile(t, dir, "etcd.yaml", etcdConfig)
+	if _, err := LoadServiceDependantsFromDir(dir); err == nil {
+		t.Errorf("Expected an error for a service configured twice")
+	}
+	os.Remove(filepath.Join(dir, "etcd.yaml"))
+
+	writeConfigFile(t, dir, "other.yaml", otherNamespaceConfig)
+	if _, err := LoadServiceDependantsFromDir(dir); err == nil {
+		t.Errorf("Expected an error for conflicting namespaces")
+	}
+	os.Remove(filepath.Join(dir, "other.yaml"))
+
+	writeConfigFile(t, dir, "dryrun.yaml", "namespaces:\n  shoot--dev:\n    dryRun: true\n")
+	writeConfigFile(t, dir, "staleness.yaml", "namespaces:\n  shoot--dev:\n    maxEndpointsStaleness: 1m\n")
+	deps, err = LoadServiceDependantsFromDir(dir)
+	if err != nil {
+		t.Fatalf("error loading config dir with namespace overrides: %v", err)
+	}
+	if opts := deps.Namespaces["shoot--dev"]; !isDryRun(opts) || getMaxEndpointsStaleness(opts) != time.Minute {
+		t.Errorf("Expected the overrides of the namespace to be merged but got %+v", opts)
+	}
+	writeConfigFile(t, dir, "enforce.yaml", "namespaces:\n  shoot--dev:\n    dryRun: false\n")
+	if _, err := LoadServiceDependantsFromDir(dir); err == nil {
+		t.Errorf("Expected an error for conflicting overrides of a namespace")
+	}
+}
+
+func TestMergeOptions(t *testing.T) {
+	dryRun, enforce := true, false
+	global := api.Options{
+		MaxEndpointsStaleness: &metav1.Duration{Duration: time.Minute},
+		DryRun:                &dryRun,
+	}
+
+	merged := MergeOptions(global, api.Options{DryRun: &enforce})
+	if isDryRun(merged) {
+		t.Errorf("Expected the overridden dry run setting to win")
+	}
+	if got := getMaxEndpointsStaleness(merged); got != time.Minute {
+		t.Errorf("Expected max endpoints staleness to be inherited from the global options but got %s", got)
+	}
+
+	merged = MergeOptions(global, api.Options{})
+	if !isDryRun(merged) || getMaxEndpointsStaleness(merged) != time.Minute {
+		t.Errorf("Expected an empty override to keep the global options but got %+v", merged)
+	}
+}
+
+func TestGetNamespaceOptions(t *testing.T) {
+	deps, err := api.Decode([]byte(`namespace: ""
+dryRun: true
+maxEndpointsStaleness: 1m
+namespaces:
+  shoot--enforced:
+    dryRun: false
+services: {}
+`))
+	if err != nil {
+		t.Fatalf("error decoding config: %v", err)
+	}
+	if opts := getNamespaceOptions(deps, "shoot--dev"); !isDryRun(opts) {
+		t.Errorf("Expected namespace without overrides to inherit dry run")
+	}
+	opts := getNamespaceOptions(deps, "shoot--enforced")
+	if isDryRun(opts) {
+		t.Errorf("Expected dry run to be overridden for namespace shoot--enforced")
+	}
+	if got := getMaxEndpointsStaleness(opts); got != time.Minute {
+		t.Errorf("Expected max endpoints staleness to be inherited but got %s", got)
+	}
+}
+
+func TestMatchesRestartRule(t *testing.T) {
+	isController := true
+	rules := []api.RestartRule{
+		{OwnerKind: "ReplicaSet", RestartReasons: []string{"CrashLoopBackOff"}},
+		{OwnerKind: "StatefulSet", RestartReasons: []string{"ImagePullBackOff", "ErrImagePull"}},
+	}
+	newPodWaitingFor := func(ownerKind, reason string) *v1.Pod {
+		pod := newPodInCrashloop("pod-0", nil)
+		pod.Status.ContainerStatuses[0].State.Waiting.Reason = reason
+		if ownerKind != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: "owner", Controller: &isController}}
+		}
+		return pod
+	}
+
+	tests := []struct {
+		ownerKind     string
+		reason        string
+		expectedMatch bool
+		expectedRule  int
+	}{
+		{"ReplicaSet", "CrashLoopBackOff", true, 0},
+		{"StatefulSet", "ImagePullBackOff", true, 1},
+		{"StatefulSet", "ErrImagePull", true, 1},
+		{"ReplicaSet", "ImagePullBackOff", false, -1},
+		{"StatefulSet", "CrashLoopBackOff", false, -1},
+		{"", "CrashLoopBackOff", false, -1},
+		{"DaemonSet", "CrashLoopBackOff", false, -1},
+	}
+	for _, tc := range tests {
+		matches, rule := MatchesRestartRule(newPodWaitingFor(tc.ownerKind, tc.reason), rules)
+		if matches != tc.expectedMatch {
+			t.Errorf("%s/%s: expected match %v but got %v", tc.ownerKind, tc.reason, tc.expectedMatch, matches)
+			continue
+		}
+		if matches && rule.OwnerKind != rules[tc.expectedRule].OwnerKind {
+			t.Errorf("%s/%s: expected rule %+v but got %+v", tc.ownerKind, tc.reason, rules[tc.expectedRule], rule)
+		}
+	}
+
+	// A rule without owner kind matches pods of any owner.
+	anyOwner := []api.RestartRule{{RestartReasons: []string{"CrashLoopBackOff"}}}
+	if matches, _ := MatchesRestartRule(newPodWaitingFor("", "CrashLoopBackOff"), anyOwner); !matches {
+		t.Errorf("Expected a rule without owner kind to match a pod without owner")
+	}
+}
+
+func TestIsPodAvailableStrict(t *testing.T) {
+	now := metav1.NewTime(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name            string
+		transition      time.Time
+		allowFutureSkew time.Duration
+		expected        bool
+	}{
+		{"transition in the past", now.Add(-time.Minute), 0, true},
+		{"transition in the future", now.Add(time.Minute), 0, false},
+		{"transition in the future within the allowed skew", now.Add(time.Minute), 2 * time.Minute, true},
+		{"transition in the future beyond the allowed skew", now.Add(time.Minute), 30 * time.Second, false},
+	}
+	for _, tc := range tests {
+		pod := newPodHealthy("pod-0", nil)
+		pod.Status.Conditions[0].LastTransitionTime = metav1.NewTime(tc.transition)
+		if !IsPodAvailable(pod, 0, now) {
+			t.Errorf("%s: expected the pod to be available without the guard", tc.name)
+		}
+		if got := IsPodAvailableStrict(pod, 0, now, tc.allowFutureSkew); got != tc.expected {
+			t.Errorf("%s: expected available %v but got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestReasonThresholdMet(t *testing.T) {
+	thresholds := map[string]int32{"CrashLoopBackOff": 3, "ImagePullBackOff": 0}
+	tests := []struct {
+		reason       string
+		restartCount int32
+		expected     bool
+	}{
+		{"CrashLoopBackOff", 2, false},
+		{"CrashLoopBackOff", 3, true},
+		{"ImagePullBackOff", 0, true},
+		{"ErrImagePull", 5, false},
+		{"", 5, false},
+	}
+	for _, tc := range tests {
+		status := v1.ContainerStatus{RestartCount: tc.restartCount}
+		if tc.reason != "" {
+			status.State.Waiting = &v1.ContainerStateWaiting{Reason: tc.reason}
+		}
+		if got := ReasonThresholdMet(status, thresholds); got != tc.expected {
+			t.Errorf("%s after %d restarts: expected %v but got %v", tc.reason, tc.restartCount, tc.expected, got)
+		}
+
+		pod := newPodInCrashloop("pod-0", nil)
+		pod.Status.ContainerStatuses = []v1.ContainerStatus{status}
+		if got := shouldDeleteDependantPod(pod, &api.DependantPods{RestartReasonThresholds: thresholds}); got != tc.expected {
+			t.Errorf("%s after %d restarts: expected deletion %v but got %v", tc.reason, tc.restartCount, tc.expected, got)
+		}
+	}
+}
+
+func TestIsContainerLivenessKilled(t *testing.T) {
+	terminated := func(reason string, exitCode int32) v1.ContainerState {
+		return v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: reason, ExitCode: exitCode}}
+	}
+	tests := []struct {
+		name            string
+		lastTermination v1.ContainerState
+		livenessProbe   bool
+		expected        bool
+	}{
+		{"killed after liveness probe failure", terminated("Error", 137), true, true},
+		{"terminated after liveness probe failure", terminated("Error", 143), true, true},
+		{"exited normally", terminated("Completed", 0), true, false},
+		{"exited with an error", terminated("Error", 1), true, false},
+		{"killed because out of memory", terminated("OOMKilled", 137), true, false},
+		{"killed without a liveness probe", terminated("Error", 137), false, false},
+		{"never terminated", v1.ContainerState{}, true, false},
+	}
+	for _, tc := range tests {
+		pod := newPodHealthy("pod-0", nil)
+		pod.Spec.Containers = []v1.Container{{Name: "Container-0"}}
+		if tc.livenessProbe {
+			pod.Spec.Containers[0].LivenessProbe = &v1.Probe{}
+		}
+		pod.Status.ContainerStatuses[0].LastTerminationState = tc.lastTermination
+		if actual := IsContainerLivenessKilled(pod, pod.Status.ContainerStatuses[0]); actual != tc.expected {
+			t.Errorf("%s: expected %v but got %v", tc.name, tc.expected, actual)
+		}
+		if actual := shouldDeleteDependantPod(pod, &api.DependantPods{RecycleLivenessKilled: true}); actual != tc.expected {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expected, actual)
+		}
+		if shouldDeleteDependantPod(pod, &api.DependantPods{}) {
+			t.Errorf("%s: expected no deletion without recycling liveness-killed pods", tc.name)
+		}
+	}
+}
+
+func TestHasEphemeralContainers(t *testing.T) {
+	debugged := newPodInCrashloop("pod-0", nil)
+	debugged.Spec.EphemeralContainers = []v1.EphemeralContainer{{
+		EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debugger", Image: "busybox"},
+	}}
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		expected bool
+	}{
+		{"with ephemeral containers", debugged, true},
+		{"without ephemeral containers", newPodInCrashloop("pod-1", nil), false},
+	}
+	for _, tc := range tests {
+		if actual := HasEphemeralContainers(tc.pod); actual != tc.expected {
+			t.Errorf("%s: expected %v but got %v", tc.name, tc.expected, actual)
+		}
+		if actual := shouldDeleteDependantPod(tc.pod, &api.DependantPods{SkipDebuggedPods: true}); actual == tc.expected {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, !tc.expected, actual)
+		}
+		if !shouldDeleteDependantPod(tc.pod, &api.DependantPods{}) {
+			t.Errorf("%s: expected deletion without skipping debugged pods", tc.name)
+		}
+	}
+}
+
+func TestIsPodBeingEvicted(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	newEvictedPod := func(name string, status v1.ConditionStatus) *v1.Pod {
+		pod := newPodInCrashloop(name, labels)
+		pod.Status.Conditions = append(pod.Status.Conditions, v1.PodCondition{
+			Type:   podDisruptionTarget,
+			Status: status,
+			Reason: "EvictionByEvictionAPI",
+		})
+		return pod
+	}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		pod              *v1.Pod
+		expectedEvicted  bool
+		expectedDeletion bool
+	}{
+		{"being evicted", newEvictedPod("evicted", v1.ConditionTrue), true, false},
+		{"no longer being evicted", newEvictedPod("recovered", v1.ConditionFalse), false, true},
+		{"without disruption condition", newPodInCrashloop("crashloop", labels), false, true},
+	}
+	for _, tc := range tests {
+		if actual := IsPodBeingEvicted(tc.pod); actual != tc.expectedEvicted {
+			t.Errorf("%s: expected being evicted %v but got %v", tc.name, tc.expectedEvicted, actual)
+		}
+		client := fake.NewSimpleClientset(tc.pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		c := &Controller{clientset: client, serviceDependants: &api.ServiceDependants{}}
+		if err := c.processPod(context.TODO(), tc.pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		_, err := client.CoreV1().Pods(tc.pod.Namespace).Get(tc.pod.Name, metav1.GetOptions{})
+		if deleted := err != nil; deleted != tc.expectedDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expectedDeletion, deleted)
+		}
+	}
+}
+
+func TestIsPodInInitCrashloopBackoff(t *testing.T) {
+	initCrashloop := func(restartPolicy v1.RestartPolicy) *v1.Pod {
+		pod := newPodHealthy("init-crashloop", nil)
+		pod.Spec.RestartPolicy = restartPolicy
+		pod.Status.InitContainerStatuses = []v1.ContainerStatus{{
+			Name:  "setup",
+			State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		}}
+		pod.Status.ContainerStatuses[0].State = v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "PodInitializing"}}
+		return pod
+	}
+	mainCrashloop := newPodInCrashloop("main-crashloop", nil)
+	mainCrashloop.Spec.RestartPolicy = v1.RestartPolicyOnFailure
+
+	tests := []struct {
+		name                   string
+		pod                    *v1.Pod
+		expectedInitCrashloop  bool
+		expectedDeletion       bool
+		expectedDefaultDeleted bool
+	}{
+		{"init container in crashloop", initCrashloop(v1.RestartPolicyOnFailure), true, true, false},
+		{"init container in crashloop restarting always", initCrashloop(v1.RestartPolicyAlways), false, false, false},
+		{"main container in crashloop", mainCrashloop, false, true, true},
+		{"healthy", newPodHealthy("healthy", nil), false, false, false},
+	}
+	for _, tc := range tests {
+		if actual := IsPodInInitCrashloopBackoff(tc.pod); actual != tc.expectedInitCrashloop {
+			t.Errorf("%s: expected init crashloop %v but got %v", tc.name, tc.expectedInitCrashloop, actual)
+		}
+		if actual := shouldDeleteDependantPod(tc.pod, &api.DependantPods{RecycleInitCrashloop: true}); actual != tc.expectedDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expectedDeletion, actual)
+		}
+		if actual := shouldDeleteDependantPod(tc.pod, &api.DependantPods{}); actual != tc.expectedDefaultDeleted {
+			t.Errorf("%s: expected deletion %v without recycling init crashloops but got %v", tc.name, tc.expectedDefaultDeleted, actual)
+		}
+	}
+}
+
+func TestContainerRestartRate(t *testing.T) {
+	now := metav1.Now()
+	weekAgo := metav1.NewTime(now.Add(-7 * 24 * time.Hour))
+	twoMinutesAgo := metav1.NewTime(now.Add(-2 * time.Minute))
+	tests := []struct {
+		name         string
+		startTime    metav1.Time
+		restartCount int32
+		expected     bool
+	}{
+		{"slow restarts", weekAgo, 50, false},
+		{"fast restarts", twoMinutesAgo, 5, true},
+		{"no restarts", twoMinutesAgo, 0, false},
+	}
+	minRestartRate := 1.0
+	for _, tc := range tests {
+		status := v1.ContainerStatus{RestartCount: tc.restartCount}
+		if rate := ContainerRestartRate(status, tc.startTime, now); (rate >= minRestartRate) != tc.expected {
+			t.Errorf("%s: unexpected restart rate %f", tc.name, rate)
+		}
+
+		pod := newPodInCrashloop("pod-0", nil)
+		pod.Status.StartTime = &tc.startTime
+		pod.Status.ContainerStatuses[0].RestartCount = tc.restartCount
+		if got := shouldDeleteDependantPod(pod, &api.DependantPods{MinRestartRate: &minRestartRate}); got != tc.expected {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestIsNamespaceReconciled(t *testing.T) {
+	if !IsNamespaceExcluded("kube-system", defaultExcludedNamespaces) || IsNamespaceExcluded("shoot--dev", defaultExcludedNamespaces) {
+		t.Errorf("Expected only the system namespaces to be excluded by default")
+	}
+
+	tests := []struct {
+		name       string
+		deps       *api.ServiceDependants
+		namespace  string
+		reconciled bool
+	}{
+		{"system namespace skipped by default", &api.ServiceDependants{}, "kube-system", false},
+		{"other namespace reconciled by default", &api.ServiceDependants{}, "shoot--dev", true},
+		{"custom exclusion", &api.ServiceDependants{ExcludedNamespaces: []string{"shoot--dev"}}, "shoot--dev", false},
+		{"custom exclusion replaces defaults", &api.ServiceDependants{ExcludedNamespaces: []string{"shoot--dev"}}, "kube-system", true},
+		{"configured namespace", &api.ServiceDependants{Namespace: "kube-system"}, "kube-system", true},
+		{"other than configured namespace", &api.ServiceDependants{Namespace: "kube-system"}, "shoot--dev", false},
+	}
+	for _, tc := range tests {
+		if got := isNamespaceReconciled(tc.deps, tc.namespace); got != tc.reconciled {
+			t.Errorf("%s: expected reconciled %v but got %v", tc.name, tc.reconciled, got)
+		}
+	}
+}
+
+func TestPodMatchesPredicate(t *testing.T) {
+	now := metav1.NewTime(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	pendingUnschedulable := api.PodPredicate{
+		Phase: v1.PodPending,
+		Conditions: []api.PodConditionRequirement{
+			{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: v1.PodReasonUnschedulable},
+		},
+		For: &metav1.Duration{Duration: 5 * time.Minute},
+	}
+	newPendingPod := func(since time.Duration) *v1.Pod {
+		pod := newPod("pod-0", "")
+		pod.CreationTimestamp = metav1.NewTime(now.Add(-since))
+		pod.Status.Phase = v1.PodPending
+		pod.Status.Conditions = []v1.PodCondition{{
+			Type:               v1.PodScheduled,
+			Status:             v1.ConditionFalse,
+			Reason:             v1.PodReasonUnschedulable,
+			LastTransitionTime: metav1.NewTime(now.Add(-since)),
+		}}
+		return pod
+	}
+	running := newPodHealthy("pod-0", nil)
+	running.Status.Phase = v1.PodRunning
+
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		expected bool
+	}{
+		{"pending and unschedulable for long", newPendingPod(10 * time.Minute), true},
+		{"pending and unschedulable recently", newPendingPod(time.Minute), false},
+		{"running", running, false},
+	}
+	for _, tc := range tests {
+		if got := PodMatchesPredicate(tc.pod, pendingUnschedulable, now); got != tc.expected {
+			t.Errorf("%s: expected match %v but got %v", tc.name, tc.expected, got)
+		}
+	}
+
+	if !PodMatchesPredicate(running, api.PodPredicate{Phase: v1.PodRunning}, now) {
+		t.Errorf("Expected a predicate with only a phase to match a running pod")
+	}
+}
+
+func TestIsPodUnschedulableFor(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	newPendingPod := func(reason string, since time.Duration) *v1.Pod {
+		pod := newPod("pod-0", "")
+		pod.Status.Phase = v1.PodPending
+		pod.Status.Conditions = []v1.PodCondition{{
+			Type:               v1.PodScheduled,
+			Status:             v1.ConditionFalse,
+			Reason:             reason,
+			LastTransitionTime: metav1.NewTime(now.Add(-since)),
+		}}
+		return pod
+	}
+
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		expected bool
+	}{
+		{"unschedulable for 10m", newPendingPod(v1.PodReasonUnschedulable, 10*time.Minute), true},
+		{"freshly pending", newPendingPod(v1.PodReasonUnschedulable, time.Minute), false},
+		{"pending for another reason", newPendingPod("SchedulerError", 10*time.Minute), false},
+		{"scheduled", newPod("pod-0", "node-0"), false},
+	}
+	for _, tc := range tests {
+		if actual := IsPodUnschedulableFor(tc.pod, 5*time.Minute, now); actual != tc.expected {
+			t.Errorf("%s: expected %v but got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestExitCodeSources(t *testing.T) {
+	// A container in CrashLoopBackOff is waiting, the exit code of its previous run is in the last termination state.
+	crashlooping := v1.ContainerStatus{
+		State:                v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: ReasonCrashLoopBackOff}},
+		LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 78}},
+	}
+	// A container which just terminated has its exit code in the current state.
+	terminated := v1.ContainerStatus{
+		State:                v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}},
+		LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 78}},
+	}
+
+	tests := []struct {
+		name             string
+		status           v1.ContainerStatus
+		source           api.ContainerStateSource
+		expectedExitCode int32
+		expectedOK       bool
+	}{
+		{"crashlooping, current state", crashlooping, api.ContainerStateCurrent, 0, false},
+		{"crashlooping, last termination state", crashlooping, api.ContainerStateLastTermination, 78, true},
+		{"terminated, current state", terminated, api.ContainerStateCurrent, 1, true},
+		{"terminated, last termination state", terminated, api.ContainerStateLastTermination, 78, true},
+	}
+	for _, tc := range tests {
+		exitCode, ok := GetContainerExitCode(tc.status, tc.source)
+		if exitCode != tc.expectedExitCode || ok != tc.expectedOK {
+			t.Errorf("%s: expected exit code %d (%v) but got %d (%v)", tc.name, tc.expectedExitCode, tc.expectedOK, exitCode, ok)
+		}
+	}
+
+	pod := newPodInCrashloop("pod-0", nil)
+	pod.Status.ContainerStatuses[0].LastTerminationState = crashlooping.LastTerminationState
+	current := api.ContainerStateCurrent
+	skipTests := []struct {
+		name     string
+		depPods  *api.DependantPods
+		expected bool
+	}{
+		{"no skipped exit codes", &api.DependantPods{}, true},
+		{"skipped exit code of the last run", &api.DependantPods{SkipExitCodes: []int32{78}}, false},
+		{"other skipped exit code", &api.DependantPods{SkipExitCodes: []int32{1}}, true},
+		{"skipped exit code read from the current state", &api.DependantPods{SkipExitCodes: []int32{78}, ExitCodeSource: &current}, true},
+	}
+	for _, tc := range skipTests {
+		if actual := shouldDeleteDependantPod(pod, tc.depPods); actual != tc.expected {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestNextEligibleTime(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	pending := newPod("pod-0", "")
+	pending.Status.Phase = v1.PodPending
+	pending.Status.Conditions = []v1.PodCondition{{
+		Type:               v1.PodScheduled,
+		Status:             v1.ConditionFalse,
+		Reason:             v1.PodReasonUnschedulable,
+		LastTransitionTime: metav1.NewTime(now.Add(-2 * time.Minute)),
+	}}
+	unschedulableFor := func(d time.Duration) *metav1.Duration { return &metav1.Duration{Duration: d} }
+	pendingFor := func(d time.Duration) api.PodPredicate {
+		return api.PodPredicate{Phase: v1.PodPending, For: &metav1.Duration{Duration: d}}
+	}
+
+	tests := []struct {
+		name         string
+		pod          *v1.Pod
+		depPods      *api.DependantPods
+		expectedTime time.Time
+		expectedOK   bool
+	}{
+		{"no time based rules", pending, &api.DependantPods{}, time.Time{}, false},
+		{"unschedulable threshold ahead", pending, &api.DependantPods{UnschedulableThreshold: unschedulableFor(5 * time.Minute)}, now.Add(3 * time.Minute), true},
+		{"unschedulable threshold passed", pending, &api.DependantPods{UnschedulableThreshold: unschedulableFor(time.Minute)}, time.Time{}, false},
+		{"earliest of predicate and threshold", pending, &api.DependantPods{
+			UnschedulableThreshold: unschedulableFor(5 * time.Minute),
+			Predicates:             []api.PodPredicate{pendingFor(time.Hour)},
+		}, now.Add(3 * time.Minute), true},
+		{"predicate not matching", newPod("pod-0", "node-0"), &api.DependantPods{Predicates: []api.PodPredicate{pendingFor(time.Hour)}}, time.Time{}, false},
+	}
+	for _, tc := range tests {
+		next, ok := NextEligibleTime(tc.pod, tc.depPods, now)
+		if ok != tc.expectedOK || !next.Equal(tc.expectedTime) {
+			t.Errorf("%s: expected %s (%v) but got %s (%v)", tc.name, tc.expectedTime, tc.expectedOK, next, ok)
+		}
+	}
+}
+
+func TestPodUsesServiceAccount(t *testing.T) {
+	pod := newPod("pod-0", "node-0")
+	if !PodUsesServiceAccount(pod, "default") {
+		t.Errorf("Expected a pod without a service account to use the default one")
+	}
+	pod.Spec.ServiceAccountName = "kube-controller-manager"
+	if !PodUsesServiceAccount(pod, "kube-controller-manager") {
+		t.Errorf("Expected the pod to use its service account")
+	}
+	if PodUsesServiceAccount(pod, "default") {
+		t.Errorf("Expected the pod not to use the default service account")
+	}
+}
+
+func TestPodPriorityClassAllowed(t *testing.T) {
+	allowed := []string{"low-priority", ""}
+	tests := []struct {
+		priorityClassName string
+		expected          bool
+	}{
+		{"low-priority", true},
+		{"", true},
+		{"high-priority", false},
+		{"system-cluster-critical", false},
+	}
+	for _, tc := range tests {
+		pod := newPodInCrashloop("pod-0", nil)
+		pod.Spec.PriorityClassName = tc.priorityClassName
+		if got := PodPriorityClassAllowed(pod, allowed); got != tc.expected {
+			t.Errorf("%q: expected allowed %v but got %v", tc.priorityClassName, tc.expected, got)
+		}
+		if got := shouldDeleteDependantPod(pod, &api.DependantPods{PriorityClassNames: allowed}); got != tc.expected {
+			t.Errorf("%q: expected deletion %v but got %v", tc.priorityClassName, tc.expected, got)
+		}
+	}
+}
+
+func TestSortPodsByPriority(t *testing.T) {
+	priority := func(p int32) *int32 { return &p }
+	pods := []v1.Pod{*newPod("high", ""), *newPod("none", ""), *newPod("low", ""), *newPod("also-high", "")}
+	pods[0].Spec.Priority = priority(1000)
+	pods[2].Spec.Priority = priority(-10)
+	pods[3].Spec.Priority = priority(1000)
+
+	sortPodsByPriority(pods)
+	var names []string
+	for _, po := range pods {
+		names = append(names, po.Name)
+	}
+	if expected := []string{"low", "none", "high", "also-high"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected the pods to be ordered %v but got %v", expected, names)
+	}
+}
+
+func TestPodReadyState(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []v1.PodCondition
+		expected   ReadyState
+	}{
+		{"ready", []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}, ReadyStateTrue},
+		{"not ready", []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionFalse}}, ReadyStateFalse},
+		{"unknown", []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionUnknown}}, ReadyStateUnknown},
+		{"no ready condition", []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}}, ReadyStateUnknown},
+	}
+	for _, tc := range tests {
+		pod := newPod("pod-0", "node-0")
+		pod.Status.Conditions = tc.conditions
+		if actual := PodReadyState(pod); actual != tc.expected {
+			t.Errorf("%s: expected ready state %s but got %s", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestIsPodConditionReady(t *testing.T) {
+	const dependencyReady v1.PodConditionType = "MyApp/DependencyReady"
+	newPodWithConditions := func(conditions ...v1.PodCondition) *v1.Pod {
+		return &v1.Pod{Status: v1.PodStatus{Conditions: conditions}}
+	}
+	ready := v1.PodCondition{Type: v1.PodReady, Status: v1.ConditionTrue}
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		expected bool
+	}{
+		{"custom condition true", newPodWithConditions(v1.PodCondition{Type: dependencyReady, Status: v1.ConditionTrue}), true},
+		{"custom condition false", newPodWithConditions(ready, v1.PodCondition{Type: dependencyReady, Status: v1.ConditionFalse}), false},
+		{"custom condition absent", newPodWithConditions(ready), false},
+	}
+	now := metav1.Now()
+	for _, tc := range tests {
+		if got := IsPodConditionReady(tc.pod, dependencyReady); got != tc.expected {
+			t.Errorf("%s: expected ready %v but got %v", tc.name, tc.expected, got)
+		}
+		if got := IsPodAvailableByCondition(tc.pod, dependencyReady, 0, now); got != tc.expected {
+			t.Errorf("%s: expected available %v but got %v", tc.name, tc.expected, got)
+		}
+	}
+	if !IsPodConditionReady(newPodWithConditions(ready), "") || !IsPodAvailableByCondition(newPodWithConditions(ready), "", 0, now) {
+		t.Errorf("Expected an empty condition type to check the Ready condition")
+	}
+}
+
+func TestIsDependantPodAvailable(t *testing.T) {
+	newPodWithContainersReady := func(containersReady v1.ConditionStatus) *v1.Pod {
+		return &v1.Pod{Status: v1.PodStatus{Conditions: []v1.PodCondition{
+			{Type: v1.PodReady, Status: v1.ConditionTrue},
+			{Type: v1.ContainersReady, Status: containersReady},
+		}}}
+	}
+	// The pod is ready, e.g. due to a readiness probe of the app container only, while the sidecar is not.
+	sidecarNotReady := newPodWithContainersReady(v1.ConditionFalse)
+	allReady := newPodWithContainersReady(v1.ConditionTrue)
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		depPods  *api.DependantPods
+		expected bool
+	}{
+		{"sidecar not ready, only pod readiness required", sidecarNotReady, &api.DependantPods{}, true},
+		{"sidecar not ready, all containers required", sidecarNotReady, &api.DependantPods{RequireAllContainersReady: true}, false},
+		{"all containers ready", allReady, &api.DependantPods{RequireAllContainersReady: true}, true},
+	}
+	now := metav1.Now()
+	for _, tc := range tests {
+		if got := isDependantPodAvailable(tc.pod, tc.depPods, 0, now); got != tc.expected {
+			t.Errorf("%s: expected available %v but got %v", tc.name, tc.expected, got)
+		}
+	}
+	if AreAllContainersReady(sidecarNotReady) || !AreAllContainersReady(allReady) {
+		t.Errorf("Expected the ContainersReady condition to be read")
+	}
+}
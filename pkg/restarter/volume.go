@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// UsesPersistentVolumeClaim checks if the pod mounts a volume of a persistent volume claim.
+func UsesPersistentVolumeClaim(pod *v1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// podWaitingForVolumesSince returns since when the scheduled pod with a persistent volume claim has been waiting
+// for its volumes to be mounted, i.e. none of its containers has been started yet.
+func podWaitingForVolumesSince(pod *v1.Pod) (time.Time, bool) {
+	if pod.Status.Phase != v1.PodPending || !UsesPersistentVolumeClaim(pod) {
+		return time.Time{}, false
+	}
+	_, scheduled := GetPodCondition(&pod.Status, v1.PodScheduled)
+	if scheduled == nil || scheduled.Status != v1.ConditionTrue {
+		return time.Time{}, false
+	}
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	if len(statuses) == 0 {
+		return time.Time{}, false
+	}
+	for _, status := range statuses {
+		waiting := status.State.Waiting
+		if waiting == nil || waiting.Reason != ReasonContainerCreating && waiting.Reason != ReasonPodInitializing {
+			return time.Time{}, false
+		}
+	}
+	return scheduled.LastTransitionTime.Time, true
+}
+
+// IsPodWaitingForVolumesFor checks if the pod with a persistent volume claim has been waiting for its volumes to be
+// mounted for at least the given duration since it was scheduled. The kubelet does not start any container of the
+// pod until all of its volumes are mounted.
+func IsPodWaitingForVolumesFor(pod *v1.Pod, threshold time.Duration, now time.Time) bool {
+	since, ok := podWaitingForVolumesSince(pod)
+	return ok && !since.Add(threshold).After(now)
+}
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newPodWaitingForVolumes(name string, labels map[string]string, since time.Time) *v1.Pod {
+	pod := newPod(name, "node-0")
+	pod.Labels = labels
+	pod.Spec.Volumes = []v1.Volume{{
+		Name: "data",
+		VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data-" + name},
+		},
+	}}
+	pod.Status.Phase = v1.PodPending
+	pod.Status.Conditions = []v1.PodCondition{{
+		Type:               v1.PodScheduled,
+		Status:             v1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(since),
+	}}
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{{
+		Name:  "Container-0",
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: ReasonContainerCreating}},
+	}}
+	return pod
+}
+
+func TestIsPodWaitingForVolumesFor(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	withoutClaim := newPodWaitingForVolumes("pod-0", nil, now.Add(-10*time.Minute))
+	withoutClaim.Spec.Volumes[0].VolumeSource = v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}
+	started := newPodWaitingForVolumes("pod-0", nil, now.Add(-10*time.Minute))
+	started.Status.ContainerStatuses = append(started.Status.ContainerStatuses, v1.ContainerStatus{
+		Name:  "Container-1",
+		State: v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+	})
+	initializing := newPodWaitingForVolumes("pod-0", nil, now.Add(-10*time.Minute))
+	initializing.Status.InitContainerStatuses = []v1.ContainerStatus{{
+		Name:  "setup",
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: ReasonPodInitializing}},
+	}}
+	unscheduled := newPodWaitingForVolumes("pod-0", nil, now.Add(-10*time.Minute))
+	unscheduled.Status.Conditions[0].Status = v1.ConditionFalse
+
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		expected bool
+	}{
+		{"waiting for 10m", newPodWaitingForVolumes("pod-0", nil, now.Add(-10*time.Minute)), true},
+		{"freshly scheduled", newPodWaitingForVolumes("pod-0", nil, now.Add(-time.Minute)), false},
+		{"waiting for init containers", initializing, true},
+		{"without persistent volume claim", withoutClaim, false},
+		{"container started", started, false},
+		{"not scheduled", unscheduled, false},
+		{"running", newPodHealthy("pod-0", nil), false},
+	}
+	for _, tc := range tests {
+		if actual := IsPodWaitingForVolumesFor(tc.pod, 5*time.Minute, now); actual != tc.expected {
+			t.Errorf("%s: expected %v but got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestRecyclePodWaitingForVolumes(t *testing.T) {
+	labels := map[string]string{"app": "etcd"}
+	threshold := &metav1.Duration{Duration: 5 * time.Minute}
+	depPods := &api.DependantPods{
+		Selector:             &metav1.LabelSelector{MatchLabels: labels},
+		VolumeMountThreshold: threshold,
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		pod             *v1.Pod
+		expectedDeleted bool
+	}{
+		{"mount failure", newPodWaitingForVolumes("etcd-0", labels, time.Now().Add(-10*time.Minute)), true},
+		{"mounting", newPodWaitingForVolumes("etcd-0", labels, time.Now()), false},
+	}
+	for _, tc := range tests {
+		// The endpoint of the provisioner is ready again.
+		client := fake.NewSimpleClientset(tc.pod, newEndpoint("csi-provisioner", metav1.NamespaceDefault, nil))
+		c := &Controller{clientset: client, serviceDependants: &api.ServiceDependants{}}
+		if err := c.processPod(context.TODO(), tc.pod, "csi-provisioner", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+		_, err := client.CoreV1().Pods(tc.pod.Namespace).Get(tc.pod.Name, metav1.GetOptions{})
+		if deleted := apierrors.IsNotFound(err); deleted != tc.expectedDeleted {
+			t.Errorf("%s: expected deletion %v but got %v (%v)", tc.name, tc.expectedDeleted, deleted, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+const eventReasonPendingDeletion = "PendingDeletion"
+
+// warnBeforeDelete warns about the upcoming deletion of the pods and waits for the configured delay, giving an
+// operator the chance to veto the deletion by annotating a pod to be ignored. It returns the latest state of the
+// pods which are still to be deleted, i.e. none if the reconcile ended in the meantime. Pods which are only
+// observed or deleted in a dry run are returned right away.
+func (c *Controller) warnBeforeDelete(ctx context.Context, pods []v1.Pod, service string, depPods *api.DependantPods) []v1.Pod {
+	if len(pods) == 0 {
+		return pods
+	}
+	namespace := pods[0].Namespace
+	opts := getNamespaceOptions(c.getServiceDependants(), namespace)
+	if opts.WarnBeforeDeleteDelay == nil || opts.WarnBeforeDeleteDelay.Duration <= 0 || depPods.ObserveOnly || isDryRun(opts) {
+		return pods
+	}
+	delay := opts.WarnBeforeDeleteDelay.Duration
+	labels := prometheus.Labels{labelNamespace: namespace, labelService: service}
+	for i := range pods {
+		klog.Warningf("Deleting pod %s/%s in %s unless it is annotated with %s=true.", namespace, pods[i].Name, delay, ignoreAnnotationKey)
+		if c.Recorder != nil {
+			c.Recorder.Eventf(&pods[i], v1.EventTypeWarning, eventReasonPendingDeletion,
+				"Deleting pod after service %s became ready in %s unless it is annotated with %s=true", service, delay, ignoreAnnotationKey)
+		}
+		deletionWarningsTotal.With(labels).Inc()
+	}
+
+	select {
+	case <-c.clock.After(delay):
+	case <-ctx.Done():
+		klog.Infof("Reconcile of service %s/%s ended before the warned pod deletions were due. Dropping them.", namespace, service)
+		return nil
+	case <-c.stopCh:
+		return nil
+	}
+
+	var due []v1.Pod
+	for i := range pods {
+		po, err := c.clientset.CoreV1().Pods(namespace).Get(pods[i].Name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				klog.Errorf("Error getting pod %s/%s after the deletion warning: %s", namespace, pods[i].Name, err)
+			}
+			continue
+		}
+		if po.UID != pods[i].UID || IsPodDeleted(po) {
+			continue
+		}
+		if IsPodIgnored(po) {
+			klog.Infof("Deletion of pod %s/%s was vetoed.", namespace, po.Name)
+			deletionVetoesTotal.With(labels).Inc()
+			continue
+		}
+		if !shouldDeleteDependantPod(po, depPods) {
+			klog.Infof("Pod %s/%s recovered before its deletion was due.", namespace, po.Name)
+			continue
+		}
+		due = append(due, *po)
+	}
+	return due
+}
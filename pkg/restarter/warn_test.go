@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestWarnBeforeDelete(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	deps := &api.ServiceDependants{
+		Options: api.Options{WarnBeforeDeleteDelay: &metav1.Duration{Duration: 5 * time.Minute}},
+	}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		veto             bool
+		expectedDeletion bool
+	}{
+		{"vetoed during the delay", true, false},
+		{"not vetoed", false, true},
+	}
+	for _, tc := range tests {
+		fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+		pod := newPodInCrashloop("pod-0", labels)
+		client := fake.NewSimpleClientset(pod, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		recorder := record.NewFakeRecorder(10)
+		c := &Controller{clientset: client, clock: fakeClock, serviceDependants: deps, Recorder: recorder}
+
+		done := make(chan error)
+		go func() {
+			done <- c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector)
+		}()
+		for !fakeClock.HasWaiters() {
+			time.Sleep(time.Millisecond)
+		}
+		if ev := <-recorder.Events; !strings.HasPrefix(ev, "Warning "+eventReasonPendingDeletion) {
+			t.Errorf("%s: expected a warning about the pending deletion but got %q", tc.name, ev)
+		}
+		if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{}); err != nil {
+			t.Fatalf("%s: expected the pod not to be deleted before the delay", tc.name)
+		}
+		if tc.veto {
+			vetoed := pod.DeepCopy()
+			vetoed.Annotations = map[string]string{ignoreAnnotationKey: "true"}
+			if _, err := client.CoreV1().Pods(metav1.NamespaceDefault).Update(vetoed); err != nil {
+				t.Fatalf("%s: error updating pod: %v", tc.name, err)
+			}
+		}
+		fakeClock.Step(5 * time.Minute)
+		if err := <-done; err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.name, err)
+		}
+
+		_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{})
+		if deleted := err != nil; deleted != tc.expectedDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.name, tc.expectedDeletion, deleted)
+		}
+	}
+}
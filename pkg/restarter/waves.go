@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+const (
+	reasonAllPodsUnhealthy   = "all pods unhealthy"
+	defaultWaveSettleTimeout = time.Minute
+	waveSettlePollInterval   = 2 * time.Second
+)
+
+// deletePodsInWaves deletes the pods in waves of the configured size. Before the next wave is deleted, the
+// replacements of the pods deleted so far are given until the settle timeout to become available. Pods exceeding
+// the recycle budget of their owner are deferred to the next wave, for which the budget taken by the settled
+// waves is released again. Without a wave size, all the pods are deleted at once.
+func (c *Controller) deletePodsInWaves(ctx context.Context, pods []v1.Pod, service string, depPods *api.DependantPods, selector labels.Selector) error {
+	if depPods.ObserveOnly {
+		for i := range pods {
+			c.observePod(ctx, &pods[i], service, reasonAllPodsUnhealthy, depPods)
+		}
+		return nil
+	}
+	waveSize := len(pods)
+	if depPods.WaveSize != nil && *depPods.WaveSize > 0 {
+		waveSize = int(*depPods.WaveSize)
+	}
+	settleTimeout := defaultWaveSettleTimeout
+	if depPods.WaveSettleTimeout != nil {
+		settleTimeout = depPods.WaveSettleTimeout.Duration
+	}
+
+	minReadySeconds := c.newMinReadySecondsResolver(depPods)
+	// Only the replacements of the deleted pods settle a wave, not the pods which were available before.
+	existing, err := c.listDependantPodUIDs(pods[0].Namespace, depPods, selector)
+	if err != nil {
+		return err
+	}
+	var deleted []v1.Pod
+	for pending := pods; len(pending) > 0; {
+		if len(deleted) > 0 {
+			proceed, settled := c.waitForAvailablePods(ctx, pods[0].Namespace, depPods, selector, minReadySeconds, existing, len(deleted), settleTimeout)
+			if !proceed {
+				return nil
+			}
+			if settled {
+				for i := range deleted {
+					if err := c.releaseRecycleBudget(ctx, &deleted[i], service); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		depReady, err := c.isDependantDependencyReady(pods[0].Namespace, depPods)
+		if err != nil {
+			return err
+		}
+		var deferred []v1.Pod
+		wave := 0
+		for i := range pending {
+			if wave == waveSize {
+				deferred = append(deferred, pending[i:]...)
+				break
+			}
+			po, recycle, err := c.getRecyclablePod(ctx, &pending[i], depPods, depReady)
+			if err != nil {
+				return err
+			}
+			if !recycle {
+				continue
+			}
+			if !c.isDependencyStillReady(ctx, po.Namespace, service) {
+				return nil
+			}
+			ok, err := c.reserveRecycleBudget(ctx, po, service, depPods)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				deferred = append(deferred, pending[i])
+				continue
+			}
+			if _, err := c.deletePodInSpan(ctx, po, service, reasonAllPodsUnhealthy, depPods); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			deleted = append(deleted, *po)
+			wave++
+		}
+		if wave == 0 {
+			if len(deferred) > 0 {
+				klog.Infof("Deferring the deletion of %d pods with selector %s to the next reconcile, as the recycle budgets of their owners are exhausted.",
+					len(deferred), selector.String())
+			}
+			return nil
+		}
+		pending = deferred
+	}
+	return nil
+}
+
+// getRecyclablePod re-reads the pod right before it is deleted and checks if it should still be recycled, as the
+// pods of later waves may have changed since all of them were found unhealthy.
+func (c *Controller) getRecyclablePod(ctx context.Context, pod *v1.Pod, depPods *api.DependantPods, depReady bool) (*v1.Pod, bool, error) {
+	po, err := c.clientset.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting pod %s: %v", pod.Name, err)
+	}
+	if po.UID != pod.UID {
+		return nil, false, nil
+	}
+	recycle, reason, err := c.shouldRecycleDependantPod(ctx, po, depPods, depReady)
+	if err != nil {
+		return nil, false, err
+	}
+	if !recycle {
+		klog.Infof("Not recycling pod %s/%s any more: %s", po.Namespace, po.Name, reason)
+		return nil, false, nil
+	}
+	return po, true, nil
+}
+
+// listDependantPodUIDs returns the UIDs of the dependant pods matching the selector.
+func (c *Controller) listDependantPodUIDs(namespace string, depPods *api.DependantPods, selector labels.Selector) (sets.String, error) {
+	pl, err := c.clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods with selector %s: %v", selector.String(), err)
+	}
+	uids := sets.NewString()
+	for _, po := range selectDependantPods(pl.Items, depPods) {
+		uids.Insert(string(po.UID))
+	}
+	return uids, nil
+}
+
+// waitForAvailablePods waits until at least count pods matching the selector, which are not among the existing pods,
+// are available and healthy or the timeout expires. These are the replacements of the deleted pods. It returns if
+// the next wave may proceed, which is not the case if the reconcile ended in the meantime, including its deadline
+// expiring, and if the pods settled, i.e. became available before the timeout expired.
+func (c *Controller) waitForAvailablePods(ctx context.Context, namespace string, depPods *api.DependantPods, selector labels.Selector,
+	minReadySeconds *minReadySecondsResolver, existing sets.String, count int, timeout time.Duration) (bool, bool) {
+	waveCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := wait.PollImmediateUntil(waveSettlePollInterval, func() (bool, error) {
+		pl, err := c.clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			klog.Errorf("Error listing pods with selector %s: %s", selector.String(), err)
+			return false, nil
+		}
+		available := 0
+		for _, po := range selectDependantPods(pl.Items, depPods) {
+			if existing.Has(string(po.UID)) {
+				continue
+			}
+			if isDependantPodAvailable(&po, depPods, minReadySeconds.resolve(&po), metav1.NewTime(c.clock.Now())) && !isDependantPodUnhealthy(&po, depPods) {
+				available++
+			}
+		}
+		return available >= count, nil
+	}, waveCtx.Done())
+	if err != nil {
+		if ctx.Err() != nil {
+			klog.Infof("Reconcile ended while waiting for pods with selector %s to become available. Skipping the next wave.", selector.String())
+			return false, false
+		}
+		if waveCtx.Err() == context.DeadlineExceeded {
+			klog.Infof("Pods with selector %s did not become available within %s. Proceeding with the next wave.", selector.String(), timeout)
+			return true, false
+		}
+		return false, false
+	}
+	return true, true
+}
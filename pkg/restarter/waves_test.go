@@ -0,0 +1,218 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+	test "k8s.io/client-go/testing"
+)
+
+var schemaPods = v1.SchemeGroupVersion.WithResource("pods")
+
+func TestDeletePodsInWaves(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	waveSize := int32(2)
+	depPods := &api.DependantPods{
+		Selector:            &metav1.LabelSelector{MatchLabels: labels},
+		RequireAllUnhealthy: true,
+		WaveSize:            &waveSize,
+		WaveSettleTimeout:   &metav1.Duration{Duration: 5 * time.Second},
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	var objects []runtime.Object
+	for _, name := range []string{"pod-0", "pod-1", "pod-2", "pod-3"} {
+		objects = append(objects, newPodInCrashloop(name, labels))
+	}
+	client := fake.NewSimpleClientset(append(objects, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))...)
+
+	// Every deleted pod is replaced by a pod which only becomes available later on.
+	var (
+		mux         sync.Mutex
+		deletedAt   []time.Time
+		replaced    = make(chan string, 4)
+		availableAt time.Time
+	)
+	client.PrependReactor("delete", "pods", func(action test.Action) (bool, runtime.Object, error) {
+		mux.Lock()
+		deletedAt = append(deletedAt, time.Now())
+		mux.Unlock()
+		name := action.(test.DeleteAction).GetName()
+		if err := client.Tracker().Delete(action.GetResource(), action.GetNamespace(), name); err != nil {
+			return true, nil, err
+		}
+		replacement := newPodInCrashloop(name+"-replacement", labels)
+		replacement.UID = types.UID(replacement.Name)
+		replacement.Status.Conditions = nil
+		replaced <- replacement.Name
+		return true, nil, client.Tracker().Add(replacement)
+	})
+	go func() {
+		// Make the replacements of the first wave available after a while.
+		for i := 0; i < int(waveSize); i++ {
+			<-replaced
+		}
+		time.Sleep(100 * time.Millisecond)
+		mux.Lock()
+		availableAt = time.Now()
+		mux.Unlock()
+		for _, name := range []string{"pod-0-replacement", "pod-1-replacement"} {
+			replacement := newPodHealthy(name, labels)
+			replacement.UID = types.UID(name)
+			client.Tracker().Update(schemaPods, replacement, metav1.NamespaceDefault)
+		}
+	}()
+	c := &Controller{clientset: client, clock: clock.RealClock{}}
+
+	if err := c.processPod(context.TODO(), objects[0].(*v1.Pod), "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	if len(deletedAt) != 4 {
+		t.Fatalf("Expected 4 deletions but got %d", len(deletedAt))
+	}
+	if deletedAt[1].After(availableAt) {
+		t.Errorf("Expected the first wave to be deleted at once")
+	}
+	if deletedAt[2].Before(availableAt) {
+		t.Errorf("Expected the second wave to start only after the first wave became available")
+	}
+}
+
+func TestDeletePodsInWavesDefersPodsExceedingTheirOwnerBudget(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	waveSize := int32(2)
+	depPods := &api.DependantPods{
+		Selector:            &metav1.LabelSelector{MatchLabels: labels},
+		RequireAllUnhealthy: true,
+		WaveSize:            &waveSize,
+		WaveSettleTimeout:   &metav1.Duration{Duration: 5 * time.Second},
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	newOwnedPod := func(name, owner string) *v1.Pod {
+		pod := newPodInCrashloop(name, labels)
+		isController := true
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: owner, Controller: &isController}}
+		return pod
+	}
+	objects := []runtime.Object{newOwnedPod("a-0", "a"), newOwnedPod("a-1", "a"), newOwnedPod("b-0", "b")}
+	client := fake.NewSimpleClientset(append(objects, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))...)
+
+	// Every deleted pod is replaced by an available pod right away.
+	var deleted []string
+	client.PrependReactor("delete", "pods", func(action test.Action) (bool, runtime.Object, error) {
+		name := action.(test.DeleteAction).GetName()
+		deleted = append(deleted, name)
+		if err := client.Tracker().Delete(action.GetResource(), action.GetNamespace(), name); err != nil {
+			return true, nil, err
+		}
+		replacement := newPodHealthy(name+"-replacement", labels)
+		replacement.UID = types.UID(replacement.Name)
+		return true, nil, client.Tracker().Add(replacement)
+	})
+	c := &Controller{clientset: client, clock: clock.RealClock{}, recycleBudgets: newRecycleBudgets()}
+
+	if err := c.processPod(context.TODO(), objects[0].(*v1.Pod), "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	// The second pod of owner a exceeds its budget in the first wave, but not the pod of owner b.
+	if expected := []string{"a-0", "b-0", "a-1"}; !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("Expected the deletions %v but got %v", expected, deleted)
+	}
+}
+
+func TestDeletionsAbortWhenDependencyIsNoLongerReady(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{
+		Selector:            &metav1.LabelSelector{MatchLabels: labels},
+		RequireAllUnhealthy: true,
+	}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	var objects []runtime.Object
+	for _, name := range []string{"pod-0", "pod-1", "pod-2"} {
+		objects = append(objects, newPodInCrashloop(name, labels))
+	}
+	client := fake.NewSimpleClientset(append(objects, newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))...)
+	// The service becomes unready again right after the first deletion.
+	client.PrependReactor("delete", "pods", func(action test.Action) (bool, runtime.Object, error) {
+		ep := newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil)
+		ep.Subsets = nil
+		return false, nil, client.Tracker().Update(v1.SchemeGroupVersion.WithResource("endpoints"), ep, metav1.NamespaceDefault)
+	})
+	c := &Controller{clientset: client}
+
+	if err := c.processPod(context.TODO(), objects[0].(*v1.Pod), "kube-apiserver", depPods, selector); err != nil {
+		t.Fatalf("error processing pod: %v", err)
+	}
+	pl, err := client.CoreV1().Pods(metav1.NamespaceDefault).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("error fetching pods: %v", err)
+	}
+	if len(pl.Items) != 2 {
+		t.Errorf("Expected the remaining deletions to be aborted leaving 2 pods but got %d", len(pl.Items))
+	}
+}
+
+func TestWaitForAvailablePods(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	minReadySeconds := int32(60)
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}, MinReadySeconds: &minReadySeconds}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	fakeClock := clock.NewFakeClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	existing := newPodHealthy("pod-0", labels)
+	existing.UID = "pod-0"
+	pod := newPodHealthy("pod-1", labels)
+	pod.UID = "pod-1"
+	pod.Status.Conditions[0].LastTransitionTime = metav1.NewTime(fakeClock.Now().Add(-30 * time.Second))
+	c := &Controller{clientset: fake.NewSimpleClientset(existing, pod), clock: fakeClock}
+	resolver := c.newMinReadySecondsResolver(depPods)
+	uids := sets.NewString("pod-0")
+
+	// The pod is not available for minReadySeconds yet, so the wave settle timeout expires and the next wave proceeds.
+	if proceed, settled := c.waitForAvailablePods(context.TODO(), metav1.NamespaceDefault, depPods, selector, resolver, uids, 1, 10*time.Millisecond); !proceed || settled {
+		t.Errorf("Expected the next wave to proceed unsettled once the settle timeout expired")
+	}
+
+	// Once the reconcile deadline expired, the next wave is skipped even though the settle timeout expired as well.
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	if proceed, _ := c.waitForAvailablePods(ctx, metav1.NamespaceDefault, depPods, selector, resolver, uids, 1, time.Minute); proceed {
+		t.Errorf("Expected the next wave to be skipped once the reconcile ended")
+	}
+
+	fakeClock.Step(time.Minute)
+	// The available pods which existed before the wave do not settle it.
+	if proceed, settled := c.waitForAvailablePods(context.TODO(), metav1.NamespaceDefault, depPods, selector, resolver, sets.NewString("pod-0", "pod-1"), 1, 10*time.Millisecond); !proceed || settled {
+		t.Errorf("Expected only the replacements to settle the wave")
+	}
+	if proceed, settled := c.waitForAvailablePods(context.TODO(), metav1.NamespaceDefault, depPods, selector, resolver, uids, 1, time.Minute); !proceed || !settled {
+		t.Errorf("Expected the pod to be available after minReadySeconds")
+	}
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IsReadyEndpointPresentInZone checks if any of the endpoint slices has a ready endpoint in the given zone.
+// Endpoints with an unknown readiness are considered ready.
+func IsReadyEndpointPresentInZone(slices []discoveryv1beta1.EndpointSlice, zone string) bool {
+	for _, slice := range slices {
+		for _, ep := range slice.Endpoints {
+			if getZone(ep.Topology) != zone {
+				continue
+			}
+			if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getZone returns the zone from the given topology labels or an empty string if it is not known.
+func getZone(labels map[string]string) string {
+	if zone, ok := labels[v1.LabelZoneFailureDomainStable]; ok {
+		return zone
+	}
+	return labels[v1.LabelZoneFailureDomain]
+}
+
+// isReadyEndpointPresentInPodZone checks if the service has a ready endpoint in the zone of the node of the pod.
+// If the zone of the pod cannot be determined, the service is considered ready in it.
+func (c *Controller) isReadyEndpointPresentInPodZone(po *v1.Pod, service string) (bool, error) {
+	if po.Spec.NodeName == "" {
+		return true, nil
+	}
+	node, err := c.clientset.CoreV1().Nodes().Get(po.Spec.NodeName, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error getting node %s: %v", po.Spec.NodeName, err)
+	}
+	zone := getZone(node.Labels)
+	if zone == "" {
+		return true, nil
+	}
+
+	sl, err := c.clientset.DiscoveryV1beta1().EndpointSlices(po.Namespace).List(metav1.ListOptions{
+		LabelSelector: discoveryv1beta1.LabelServiceName + "=" + service,
+	})
+	if err != nil {
+		return false, fmt.Errorf("error listing endpoint slices of service %s: %v", service, err)
+	}
+	return IsReadyEndpointPresentInZone(sl.Items, zone), nil
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gardener/dependency-watchdog/pkg/restarter/api"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newEndpointSlice(service string, endpoints ...discoveryv1beta1.Endpoint) *discoveryv1beta1.EndpointSlice {
+	return &discoveryv1beta1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      service + "-abcde",
+			Labels:    map[string]string{discoveryv1beta1.LabelServiceName: service},
+		},
+		Endpoints: endpoints,
+	}
+}
+
+func newZoneEndpoint(zone string, ready bool) discoveryv1beta1.Endpoint {
+	return discoveryv1beta1.Endpoint{
+		Addresses:  []string{"10.1.0.52"},
+		Conditions: discoveryv1beta1.EndpointConditions{Ready: &ready},
+		Topology:   map[string]string{v1.LabelZoneFailureDomainStable: zone},
+	}
+}
+
+func TestIsReadyEndpointPresentInZone(t *testing.T) {
+	slices := []discoveryv1beta1.EndpointSlice{
+		*newEndpointSlice("kube-apiserver", newZoneEndpoint("zone-a", true), newZoneEndpoint("zone-b", false)),
+	}
+	tests := []struct {
+		zone     string
+		expected bool
+	}{
+		{"zone-a", true},
+		{"zone-b", false},
+		{"zone-c", false},
+	}
+	for _, tc := range tests {
+		if got := IsReadyEndpointPresentInZone(slices, tc.zone); got != tc.expected {
+			t.Errorf("%s: expected %v but got %v", tc.zone, tc.expected, got)
+		}
+	}
+}
+
+func TestZonePinnedDependants(t *testing.T) {
+	labels := map[string]string{"role": "controlplane"}
+	depPods := &api.DependantPods{Selector: &metav1.LabelSelector{MatchLabels: labels}, ZonePinned: true}
+	selector, err := metav1.LabelSelectorAsSelector(depPods.Selector)
+	if err != nil {
+		t.Fatalf("error converting label selector: %v", err)
+	}
+	newNode := func(name, zone string) *v1.Node {
+		return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{v1.LabelZoneFailureDomainStable: zone}}}
+	}
+
+	tests := []struct {
+		node             string
+		expectedDeletion bool
+	}{
+		{"node-a", true},
+		{"node-b", false},
+	}
+	for _, tc := range tests {
+		pod := newPodInCrashloop("pod-0", labels)
+		pod.Spec.NodeName = tc.node
+		client := fake.NewSimpleClientset(pod, newNode("node-a", "zone-a"), newNode("node-b", "zone-b"),
+			newEndpointSlice("kube-apiserver", newZoneEndpoint("zone-a", true)), newEndpoint("kube-apiserver", metav1.NamespaceDefault, nil))
+		c := &Controller{clientset: client}
+
+		if err := c.processPod(context.TODO(), pod, "kube-apiserver", depPods, selector); err != nil {
+			t.Fatalf("%s: error processing pod: %v", tc.node, err)
+		}
+		_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get("pod-0", metav1.GetOptions{})
+		if deleted := err != nil; deleted != tc.expectedDeletion {
+			t.Errorf("%s: expected deletion %v but got %v", tc.node, tc.expectedDeletion, deleted)
+		}
+	}
+}